@@ -1,12 +1,17 @@
 package main
 
 import (
+	"auth_service/internal/clientip"
 	"auth_service/internal/config"
 	"auth_service/internal/handlers"
 	"auth_service/internal/services"
 	"auth_service/internal/storage"
 	"auth_service/internal/storage/database"
 	"auth_service/internal/storage/memory"
+	"context"
+	"os"
+	"os/signal"
+	"syscall"
 	"time"
 
 	"log"
@@ -14,34 +19,99 @@ import (
 )
 
 func main() {
+	if len(os.Args) > 1 && os.Args[1] == "migrate" {
+		if err := runMigrate(os.Args[2:]); err != nil {
+			log.Fatalf("failed to run migrations: %v\n", err)
+		}
+		return
+	}
+
+	ctx := context.Background()
 	var store storage.StorageInterface
 
 	go handlers.СleanupVisitors()
 
+	resolver, err := clientip.NewResolver(config.TrustedProxies)
+	if err != nil {
+		log.Fatalf("failed to parse 'TRUSTED_PROXIES': %v\n", err)
+	}
+	handlers.SetClientIPResolver(resolver)
+
 	switch config.Mode {
 	case "in-memory":
 		store = memory.NewMemoryStore()
 		log.Println("Using in-memory storage")
 	case "postgres":
-		db, err := database.NewDatabaseConection(config.PsqlUrl)
+		db, err := database.NewDatabaseConection(ctx, config.PsqlUrl)
 		if err != nil {
 			log.Printf("failed connection to the database: %v\n", err)
 			return
 		}
 		defer db.Close()
 
-		store = database.NewDatabaseStore(db)
+		store, err = database.NewDatabaseStore(db)
+		if err != nil {
+			log.Fatalf("failed to create database store: %v\n", err)
+		}
 		log.Println("Using PostgreSQL store")
 	default:
 		log.Fatalf("config.Mode is empty in /internal/config/setting.go")
 	}
 
-	authService := services.NewAuthService(store)
+	if err := services.InitSigningKey(); err != nil {
+		log.Fatalf("failed to load signing key: %v\n", err)
+	}
+	go rotateSigningKeyOnSignal()
+	if err := services.ValidateKillLinkKey(); err != nil {
+		log.Fatalf("failed to load kill link key: %v\n", err)
+	}
+	if err := services.ValidateTotpEncryptionKey(); err != nil {
+		log.Fatalf("failed to load totp encryption key: %v\n", err)
+	}
+	mfaEnabled, err := services.MfaEnabled()
+	if err != nil {
+		log.Fatalf("failed to get mfa settings: %v\n", err)
+	}
+
+	notifier, err := services.NewNotifier(config.Notifier)
+	if err != nil {
+		log.Fatalf("failed to create notifier: %v\n", err)
+	}
+
+	authService := services.NewAuthService(store, notifier)
+	go func() {
+		if err := authService.RunOutboxWorker(ctx); err != nil {
+			log.Fatalf("failed to run outbox worker: %v\n", err)
+		}
+	}()
+	go func() {
+		if err := authService.RunJanitor(ctx); err != nil {
+			log.Fatalf("failed to run janitor: %v\n", err)
+		}
+	}()
 	handler := handlers.RegisterAuthHandler(authService)
 	mux := http.NewServeMux()
 
-	mux.HandleFunc("GET /api/auth/{user_id}", handler.GenerateTokens())
+	mux.HandleFunc("POST /api/auth/register", handler.Register())
+	mux.HandleFunc("POST /api/auth/verify-email", handler.VerifyEmail())
+	mux.HandleFunc("POST /api/auth/password/forgot", handler.ForgotPassword())
+	mux.HandleFunc("POST /api/auth/password/reset", handler.ResetPassword())
+	mux.HandleFunc("POST /api/auth/login", handler.Login())
+	mux.HandleFunc("POST /api/auth/mfa/verify", handler.MfaVerify())
+	if mfaEnabled {
+		mux.HandleFunc("POST /api/auth/mfa/totp/enroll", handler.EnrollTotp())
+		mux.HandleFunc("POST /api/auth/mfa/totp/confirm", handler.ConfirmTotp())
+	}
 	mux.HandleFunc("POST /api/auth/refresh", handler.RefreshTokens())
+	mux.HandleFunc("POST /api/auth/logout", handler.Logout())
+	mux.HandleFunc("POST /api/auth/logout-all", handler.LogoutAll())
+	mux.HandleFunc("GET /api/auth/sessions", handler.ListSessions())
+	mux.HandleFunc("DELETE /api/auth/sessions/{jti}", handler.RevokeSession())
+	mux.HandleFunc("POST /api/auth/introspect", handler.Introspect())
+	mux.HandleFunc("GET /api/auth/kill-link", handler.CheckKillLink())
+	mux.HandleFunc("POST /api/auth/kill-link", handler.UseKillLink())
+	mux.HandleFunc("POST /revoke", handler.Revoke())
+	mux.HandleFunc("GET /.well-known/jwks.json", handler.JWKS())
 
 	serv := &http.Server{
 		Addr:         config.ServiceSocket,
@@ -56,3 +126,15 @@ func main() {
 		log.Printf("error when starting the server: %v\n", err)
 	}
 }
+
+// rotateSigningKeyOnSignal выполняет ротацию ключа подписи при получении сигнала SIGHUP.
+// Новый ключ читается из JWT_PRIVATE_KEY_FILE, поэтому для ротации достаточно заменить файл и отправить сигнал.
+func rotateSigningKeyOnSignal() {
+	sighup := make(chan os.Signal, 1)
+	signal.Notify(sighup, syscall.SIGHUP)
+	for range sighup {
+		if err := services.RotateSigningKey(); err != nil {
+			log.Printf("failed to rotate signing key: %v\n", err)
+		}
+	}
+}
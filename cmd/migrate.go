@@ -0,0 +1,95 @@
+package main
+
+import (
+	"auth_service/internal/config"
+	"auth_service/internal/storage/database"
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/signal"
+	"strconv"
+	"syscall"
+	"text/tabwriter"
+	"time"
+)
+
+// migrateUsage - описание аргументов команды migrate.
+const migrateUsage = "usage: auth_service migrate up | down [steps] | status"
+
+// runMigrate выполняет команду migrate над базой данных PSQL_URL:
+// up применяет все новые миграции, down откатывает steps последних (по умолчанию одну),
+// status выводит состояние всех версий схемы.
+func runMigrate(args []string) error {
+	if len(args) == 0 {
+		return errors.New(migrateUsage)
+	}
+
+	steps := 1
+	switch args[0] {
+	case "up", "status":
+		if len(args) != 1 {
+			return errors.New(migrateUsage)
+		}
+	case "down":
+		if len(args) > 2 {
+			return errors.New(migrateUsage)
+		}
+		if len(args) == 2 {
+			var err error
+			steps, err = strconv.Atoi(args[1])
+			if err != nil || steps <= 0 {
+				return fmt.Errorf("number of steps must be a positive integer, got '%s'", args[1])
+			}
+		}
+	default:
+		return errors.New(migrateUsage)
+	}
+
+	// Прерывание команды отменяет ожидание блокировки миграций и выполнение текущей миграции.
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	db, err := database.ConnectDatabase(ctx, config.PsqlUrl)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	switch args[0] {
+	case "up":
+		applied, err := database.MigrateUp(ctx, db)
+		if err != nil {
+			return err
+		}
+		fmt.Printf("Applied %d migration(s)\n", applied)
+	case "down":
+		rolledBack, err := database.MigrateDown(ctx, db, steps)
+		if err != nil {
+			return err
+		}
+		fmt.Printf("Rolled back %d migration(s)\n", rolledBack)
+	case "status":
+		statuses, err := database.GetMigrationStatus(ctx, db)
+		if err != nil {
+			return err
+		}
+
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(w, "VERSION\tNAME\tAPPLIED AT")
+		for _, status := range statuses {
+			name := status.Name
+			if name == "" {
+				name = "(unknown to this build)"
+			}
+			appliedAt := "pending"
+			if status.AppliedAt != nil {
+				appliedAt = status.AppliedAt.Format(time.RFC3339)
+			}
+			fmt.Fprintf(w, "%d\t%s\t%s\n", status.Version, name, appliedAt)
+		}
+		w.Flush()
+	}
+
+	return nil
+}
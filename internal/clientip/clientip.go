@@ -0,0 +1,150 @@
+package clientip
+
+import (
+	"fmt"
+	"net"
+	"net/http"
+	"net/netip"
+	"strings"
+)
+
+// Resolver определяет IP-адрес клиента с учетом доверенных прокси-серверов.
+// Заголовки Forwarded, X-Forwarded-For и X-Real-IP учитываются, только если запрос
+// пришел с адреса из доверенных подсетей, иначе используется адрес соединения.
+type Resolver struct {
+	trustedProxies []netip.Prefix // trustedProxies - подсети доверенных прокси-серверов (балансировщиков).
+}
+
+// NewResolver создает Resolver со списком доверенных подсетей через запятую (CIDR или отдельные IP-адреса).
+// Пустая строка означает, что доверенных прокси нет и заголовки прокси игнорируются.
+func NewResolver(trustedProxies string) (*Resolver, error) {
+	resolver := &Resolver{}
+	for _, proxy := range strings.Split(trustedProxies, ",") {
+		proxy = strings.TrimSpace(proxy)
+		if proxy == "" {
+			continue
+		}
+		if !strings.Contains(proxy, "/") {
+			addr, err := netip.ParseAddr(proxy)
+			if err != nil {
+				return nil, fmt.Errorf("invalid trusted proxy address '%s': %w", proxy, err)
+			}
+			addr = addr.Unmap()
+			resolver.trustedProxies = append(resolver.trustedProxies, netip.PrefixFrom(addr, addr.BitLen()))
+			continue
+		}
+		prefix, err := netip.ParsePrefix(proxy)
+		if err != nil {
+			return nil, fmt.Errorf("invalid trusted proxy CIDR '%s': %w", proxy, err)
+		}
+		resolver.trustedProxies = append(resolver.trustedProxies, prefix.Masked())
+	}
+
+	return resolver, nil
+}
+
+// ClientIP возвращает IP-адрес клиента без порта.
+// Если адрес соединения принадлежит доверенному прокси, цепочка адресов из заголовка Forwarded
+// (или X-Forwarded-For) просматривается справа налево до первого недоверенного адреса.
+// Если заголовков цепочки нет, используется X-Real-IP. Возвращает пустую строку, если адрес соединения не задан.
+func (r *Resolver) ClientIP(req *http.Request) string {
+	remoteIp := Normalize(req.RemoteAddr)
+	if remoteIp == "" || !r.isTrusted(remoteIp) {
+		return remoteIp
+	}
+
+	chain := forwardedChain(req.Header)
+	if len(chain) == 0 {
+		if realIp := Normalize(req.Header.Get("X-Real-IP")); isIP(realIp) {
+			return realIp
+		}
+		return remoteIp
+	}
+	for i := len(chain) - 1; i >= 0; i-- {
+		ip := Normalize(chain[i])
+		if !isIP(ip) {
+			// Адрес, который нельзя разобрать, мог быть подставлен клиентом: дальше цепочке доверять нельзя.
+			break
+		}
+		if !r.isTrusted(ip) || i == 0 {
+			return ip
+		}
+	}
+
+	return remoteIp
+}
+
+// TrustedHeader возвращает значение заголовка name, только если запрос пришел от доверенного прокси-сервера.
+// Позволяет использовать сведения, которые добавляет балансировщик (например, страну клиента), не доверяя самому клиенту.
+func (r *Resolver) TrustedHeader(req *http.Request, name string) string {
+	if name == "" || !r.isTrusted(Normalize(req.RemoteAddr)) {
+		return ""
+	}
+
+	return strings.TrimSpace(req.Header.Get(name))
+}
+
+// isTrusted проверяет, принадлежит ли адрес доверенной подсети.
+func (r *Resolver) isTrusted(ip string) bool {
+	addr, err := netip.ParseAddr(ip)
+	if err != nil {
+		return false
+	}
+	for _, prefix := range r.trustedProxies {
+		if prefix.Contains(addr) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// forwardedChain возвращает цепочку адресов из заголовка Forwarded (RFC 7239),
+// а при его отсутствии - из заголовка X-Forwarded-For.
+func forwardedChain(header http.Header) []string {
+	var chain []string
+	for _, value := range header.Values("Forwarded") {
+		for _, element := range strings.Split(value, ",") {
+			for _, pair := range strings.Split(element, ";") {
+				key, val, found := strings.Cut(strings.TrimSpace(pair), "=")
+				if found && strings.EqualFold(key, "for") {
+					chain = append(chain, strings.Trim(val, `"`))
+				}
+			}
+		}
+	}
+	if len(chain) > 0 {
+		return chain
+	}
+
+	for _, value := range header.Values("X-Forwarded-For") {
+		for _, ip := range strings.Split(value, ",") {
+			chain = append(chain, strings.TrimSpace(ip))
+		}
+	}
+
+	return chain
+}
+
+// Normalize приводит адрес к каноническому виду IP-адреса: удаляет порт, квадратные скобки IPv6 и зону,
+// а IPv4-адрес, отображенный в IPv6, преобразует в IPv4. Если адрес не является IP-адресом, возвращает его без порта.
+func Normalize(addr string) string {
+	addr = strings.TrimSpace(addr)
+	if host, _, err := net.SplitHostPort(addr); err == nil {
+		addr = host
+	}
+	addr = strings.TrimSuffix(strings.TrimPrefix(addr, "["), "]")
+
+	ip, err := netip.ParseAddr(addr)
+	if err != nil {
+		return addr
+	}
+
+	return ip.Unmap().WithZone("").String()
+}
+
+// isIP проверяет, является ли строка IP-адресом.
+func isIP(addr string) bool {
+	_, err := netip.ParseAddr(addr)
+	return err == nil
+}
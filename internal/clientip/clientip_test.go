@@ -0,0 +1,136 @@
+package clientip
+
+import (
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+// TestNormalize проверяет приведение адресов к каноническому виду.
+func TestNormalize(t *testing.T) {
+	tests := []struct {
+		name     string
+		addr     string
+		expected string
+	}{
+		{name: "ipv4 with port", addr: "192.168.0.1:54321", expected: "192.168.0.1"},
+		{name: "ipv4 without port", addr: "192.168.0.1", expected: "192.168.0.1"},
+		{name: "ipv6 with port", addr: "[2001:db8::1]:8080", expected: "2001:db8::1"},
+		{name: "ipv6 in brackets", addr: "[2001:db8::1]", expected: "2001:db8::1"},
+		{name: "ipv6 with zone", addr: "fe80::1%eth0", expected: "fe80::1"},
+		{name: "ipv4-mapped ipv6", addr: "[::ffff:192.168.0.1]:443", expected: "192.168.0.1"},
+		{name: "not ip", addr: "unknown", expected: "unknown"},
+		{name: "empty", addr: "", expected: ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			require.Equal(t, tt.expected, Normalize(tt.addr))
+		})
+	}
+}
+
+// TestNewResolver проверяет разбор списка доверенных подсетей.
+func TestNewResolver(t *testing.T) {
+	resolver, err := NewResolver(" 10.0.0.0/8, 192.168.1.10 ,2001:db8::/32")
+	require.NoError(t, err)
+	require.Len(t, resolver.trustedProxies, 3)
+	require.True(t, resolver.isTrusted("10.1.2.3"))
+	require.True(t, resolver.isTrusted("192.168.1.10"))
+	require.False(t, resolver.isTrusted("192.168.1.11"))
+	require.True(t, resolver.isTrusted("2001:db8::5"))
+
+	_, err = NewResolver("10.0.0.0/33")
+	require.Error(t, err)
+	_, err = NewResolver("not-an-ip")
+	require.Error(t, err)
+}
+
+// TestClientIP проверяет определение IP-адреса клиента с учетом доверенных прокси.
+func TestClientIP(t *testing.T) {
+	resolver, err := NewResolver("10.0.0.0/8")
+	require.NoError(t, err)
+
+	tests := []struct {
+		name       string
+		remoteAddr string
+		headers    map[string]string
+		expected   string
+	}{
+		{
+			name:       "direct connection",
+			remoteAddr: "203.0.113.7:54321",
+			expected:   "203.0.113.7",
+		},
+		{
+			name:       "untrusted peer with spoofed headers",
+			remoteAddr: "203.0.113.7:54321",
+			headers:    map[string]string{"X-Forwarded-For": "198.51.100.1", "X-Real-IP": "198.51.100.2"},
+			expected:   "203.0.113.7",
+		},
+		{
+			name:       "trusted proxy with x-forwarded-for",
+			remoteAddr: "10.0.0.2:54321",
+			headers:    map[string]string{"X-Forwarded-For": "198.51.100.1, 203.0.113.7, 10.0.0.3"},
+			expected:   "203.0.113.7",
+		},
+		{
+			name:       "trusted proxy with forwarded",
+			remoteAddr: "10.0.0.2:54321",
+			headers:    map[string]string{"Forwarded": `for=198.51.100.1, for="[2001:db8::7]:4711";proto=https`, "X-Forwarded-For": "198.51.100.9"},
+			expected:   "2001:db8::7",
+		},
+		{
+			name:       "trusted proxy with x-real-ip",
+			remoteAddr: "10.0.0.2:54321",
+			headers:    map[string]string{"X-Real-IP": "203.0.113.7"},
+			expected:   "203.0.113.7",
+		},
+		{
+			name:       "chain of trusted proxies",
+			remoteAddr: "10.0.0.2:54321",
+			headers:    map[string]string{"X-Forwarded-For": "10.0.0.5, 10.0.0.4"},
+			expected:   "10.0.0.5",
+		},
+		{
+			name:       "invalid address in chain",
+			remoteAddr: "10.0.0.2:54321",
+			headers:    map[string]string{"X-Forwarded-For": "203.0.113.7, garbage"},
+			expected:   "10.0.0.2",
+		},
+		{
+			name:       "empty remote address",
+			remoteAddr: "",
+			expected:   "",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest("GET", "/", nil)
+			req.RemoteAddr = tt.remoteAddr
+			for key, value := range tt.headers {
+				req.Header.Set(key, value)
+			}
+
+			require.Equal(t, tt.expected, resolver.ClientIP(req))
+		})
+	}
+}
+
+// TestTrustedHeader проверяет, что заголовок учитывается только от доверенного прокси-сервера.
+func TestTrustedHeader(t *testing.T) {
+	resolver, err := NewResolver("10.0.0.0/8")
+	require.NoError(t, err)
+
+	req := httptest.NewRequest("GET", "/", nil)
+	req.Header.Set("CF-IPCountry", " RU ")
+
+	req.RemoteAddr = "10.0.0.1:443"
+	require.Equal(t, "RU", resolver.TrustedHeader(req, "CF-IPCountry"))
+	require.Empty(t, resolver.TrustedHeader(req, ""))
+
+	req.RemoteAddr = "203.0.113.7:443"
+	require.Empty(t, resolver.TrustedHeader(req, "CF-IPCountry"))
+}
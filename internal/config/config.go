@@ -10,11 +10,65 @@ var (
 	PsqlUrl       = os.Getenv("PSQL_URL")       // URL для подключения к PostgreSQL, задается через переменную окружения PSQL_URL.
 	Secret        = os.Getenv("SECRET")         // Секретный ключ для шифрования, задается через переменную окружения SECRET.
 
+	DbQueryTimeout = os.Getenv("DB_QUERY_TIMEOUT") // Максимальное время выполнения одной операции с PostgreSQL (в секундах).
+	MigrateOnStart = os.Getenv("MIGRATE_ON_START") // Применять ли миграции схемы PostgreSQL при запуске сервиса (true/false, по умолчанию true).
+
+	JwtAlgorithm      = os.Getenv("JWT_ALGORITHM")        // Алгоритм подписи access-токенов: HS512 (по умолчанию), RS256, ES256 или EdDSA.
+	JwtPrivateKeyFile = os.Getenv("JWT_PRIVATE_KEY_FILE") // Путь к PEM-файлу с приватным ключом (для HS512 - к файлу с секретом, иначе используется SECRET).
+	KeyGracePeriod    = os.Getenv("KEY_GRACE_PERIOD")     // Время, в течение которого выведенный из оборота ключ принимается для проверки токенов (в минутах).
+
+	JwtIssuer         = os.Getenv("JWT_ISSUER")          // Издатель access-токенов (claim iss).
+	JwtAudience       = os.Getenv("JWT_AUDIENCE")        // Получатели access-токенов через запятую (claim aud).
+	JwtLeeway         = os.Getenv("JWT_LEEWAY")          // Допустимое расхождение часов при проверке exp и nbf (в секундах).
+	LegacyTokensUntil = os.Getenv("LEGACY_TOKENS_UNTIL") // Дата в формате RFC 3339, до которой принимаются access-токены старого формата (если не задана, токен принимается в течение REFRESH_TOKEN_TTL с момента выпуска).
+
+	IntrospectionClients = os.Getenv("INTROSPECTION_CLIENTS") // Учетные данные клиентов интроспекции и отзыва токенов в формате client_id:client_secret через запятую.
+	TokenScope           = os.Getenv("TOKEN_SCOPE")           // Области доступа выданных токенов через пробел, возвращаемые интроспекцией (по умолчанию auth).
+
+	Notifier              = os.Getenv("NOTIFIER")                // Способ доставки уведомлений пользователю: smtp, webhook, log (по умолчанию) или none.
+	NotifierWebhookUrl    = os.Getenv("NOTIFIER_WEBHOOK_URL")    // URL, на который webhook-уведомления отправляются POST-запросом в формате JSON.
+	NotifierWebhookSecret = os.Getenv("NOTIFIER_WEBHOOK_SECRET") // Секрет для подписи тела webhook-уведомления (HMAC-SHA256 в заголовке X-Signature).
+
+	EmailTemplatesDir     = os.Getenv("EMAIL_TEMPLATES_DIR")     // Каталог с шаблонами писем по языкам (если не задан, используются встроенные шаблоны).
+	EmailDefaultLocale    = os.Getenv("EMAIL_DEFAULT_LOCALE")    // Язык писем, если язык пользователя не задан или для него нет шаблона (по умолчанию ru).
+	NotificationReportUrl = os.Getenv("NOTIFICATION_REPORT_URL") // Адрес страницы "Это был не я" (GET /api/auth/kill-link), к которому добавляется подписанный одноразовый токен.
+	KillLinkTTL           = os.Getenv("KILL_LINK_TTL")           // Время действия ссылки "Это был не я" (в минутах).
+	KillLinkSecret        = os.Getenv("KILL_LINK_SECRET")        // Ключ подписи ссылок "Это был не я" (HMAC-SHA256), не короче 32 байт и отличный от SECRET (обязателен, если задан NOTIFICATION_REPORT_URL).
+	KillLinkRetention     = os.Getenv("KILL_LINK_RETENTION")     // Время хранения событий использования ссылок "Это был не я" после истечения их срока действия (в минутах).
+
+	RequireEmailVerification = os.Getenv("REQUIRE_EMAIL_VERIFICATION") // Запрещать ли вход пользователям с неподтвержденным email (true/false).
+	EmailVerificationUrl     = os.Getenv("EMAIL_VERIFICATION_URL")     // Адрес страницы подтверждения email, к которому добавляется одноразовый токен (страница передает его в POST /api/auth/verify-email).
+	EmailVerificationTTL     = os.Getenv("EMAIL_VERIFICATION_TTL")     // Время действия токена подтверждения email (в минутах).
+	PasswordResetUrl         = os.Getenv("PASSWORD_RESET_URL")         // Адрес страницы сброса пароля, к которому добавляется одноразовый токен (страница передает его в POST /api/auth/password/reset).
+	PasswordResetTTL         = os.Getenv("PASSWORD_RESET_TTL")         // Время действия токена сброса пароля (в минутах).
+	PasswordResetCooldown    = os.Getenv("PASSWORD_RESET_COOLDOWN")    // Интервал, в течение которого пользователю отправляется не больше одного письма сброса пароля (в минутах, 0 - без ограничения).
+
+	MfaEnabled        = os.Getenv("MFA_ENABLED")         // Включена ли двухфакторная аутентификация (true/false, по умолчанию включена, если задан TOTP_ENCRYPTION_KEY).
+	TotpEncryptionKey = os.Getenv("TOTP_ENCRYPTION_KEY") // Ключ AES-256 в base64 (32 байта) для шифрования TOTP-секретов в хранилище.
+	TotpIssuer        = os.Getenv("TOTP_ISSUER")         // Название сервиса в TOTP-приложении (по умолчанию JWT_ISSUER или auth_service).
+	MfaChallengeTTL   = os.Getenv("MFA_CHALLENGE_TTL")   // Время действия токена MFA-проверки, выдаваемого при входе по паролю (в минутах).
+
+	OutboxPollInterval = os.Getenv("OUTBOX_POLL_INTERVAL") // Интервал опроса outbox фоновым обработчиком уведомлений (в секундах).
+	OutboxBatchSize    = os.Getenv("OUTBOX_BATCH_SIZE")    // Максимальное количество уведомлений, доставляемых за один опрос outbox.
+	OutboxMaxAttempts  = os.Getenv("OUTBOX_MAX_ATTEMPTS")  // Количество попыток доставки уведомления, после которого оно помечается как недоставленное (dead).
+	OutboxRetention    = os.Getenv("OUTBOX_RETENTION")     // Время хранения доставленных и недоставленных уведомлений в outbox (в минутах).
+
+	JanitorInterval  = os.Getenv("JANITOR_INTERVAL")   // Интервал удаления refresh-токенов с истекшим сроком действия (в минутах).
+	JanitorBatchSize = os.Getenv("JANITOR_BATCH_SIZE") // Максимальное количество истекших refresh-токенов, удаляемых одним запросом.
+
 	SenderEmail   = os.Getenv("SENDER_EMAIL")   // Email отправителя, задается через переменную окружения SENDER_EMAIL.
 	PasswordEmail = os.Getenv("PASSWORD_EMAIL") // Пароль для email отправителя, задается через переменную окружения PASSWORD_EMAIL.
 	SmtpHost      = os.Getenv("SMTP_HOST")      // Хост SMTP сервера, задается через переменную окружения SMTP_HOST.
 	SmtpPort      = os.Getenv("SMTP_PORT")      // Порт SMTP сервера, задается через переменную окружения SMTP_PORT.
 
+	AccessTokenTTL     = os.Getenv("ACCESS_TOKEN_TTL")     // Время жизни access-токена (в минутах).
+	RefreshTokenTTL    = os.Getenv("REFRESH_TOKEN_TTL")    // Время жизни refresh-токена (в минутах).
+	MaxSessionLifetime = os.Getenv("MAX_SESSION_LIFETIME") // Максимальное время жизни сессии с учетом всех обновлений (в минутах, 0 - без ограничения).
+	SlidingExpiry      = os.Getenv("SLIDING_EXPIRY")       // Продлевать ли срок действия refresh-токена при каждом обновлении (true/false).
+
+	TrustedProxies = os.Getenv("TRUSTED_PROXIES") // Подсети доверенных прокси-серверов через запятую (CIDR), от которых принимаются заголовки X-Forwarded-For, Forwarded и X-Real-IP.
+	LocationHeader = os.Getenv("LOCATION_HEADER") // Заголовок, в котором доверенный прокси-сервер передает приблизительное местоположение клиента (например, CF-IPCountry).
+
 	MaxTokensPerUser = os.Getenv("MAX_TOKENS_PER_USER") // Максимальное количество активных refresh-токенов для одного пользователя.
 	RateLimit        = os.Getenv("RATE_LIMIT")          // Ограничение RPS (запросов в секунду) для пользователя.
 	BufferLimit      = os.Getenv("BUFFER_LIMIT")        // Ёмкость "ведра" запросов, которые могут обрабатываться поверх RPS ограничения за раз.
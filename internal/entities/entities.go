@@ -9,21 +9,273 @@ type TokensPair struct {
 	RefreshToken string `json:"refresh_token"` // Refresh-токен для обновления Access-токена.
 }
 
+// LoginRequest представляет запрос входа по паролю.
+type LoginRequest struct {
+	Identifier string `json:"identifier"` // Email или идентификатор пользователя.
+	Password   string `json:"password"`   // Пароль пользователя.
+}
+
+// LoginResponse представляет ответ на запрос входа по паролю.
+// Содержит пару токенов или, если у пользователя включена двухфакторная аутентификация, токен MFA-проверки.
+type LoginResponse struct {
+	*TokensPair
+	MfaRequired bool   `json:"mfa_required,omitempty"` // Признак того, что для входа требуется код второго фактора.
+	MfaToken    string `json:"mfa_token,omitempty"`    // Одноразовый токен MFA-проверки для POST /api/auth/mfa/verify.
+}
+
+// MfaVerifyRequest представляет запрос завершения входа кодом второго фактора.
+type MfaVerifyRequest struct {
+	MfaToken string `json:"mfa_token"` // Токен MFA-проверки из ответа на запрос входа.
+	Code     string `json:"code"`      // Код TOTP или код восстановления.
+}
+
+// TotpEnrollment представляет данные для подключения TOTP-приложения.
+type TotpEnrollment struct {
+	Secret     string `json:"secret"`      // Секрет TOTP в base32 для ручного ввода.
+	OtpauthUri string `json:"otpauth_uri"` // Ссылка otpauth:// для QR-кода.
+}
+
+// TotpConfirmRequest представляет запрос подтверждения подключения TOTP первым кодом из приложения.
+type TotpConfirmRequest struct {
+	Code string `json:"code"` // Код TOTP.
+}
+
+// TotpConfirmResponse представляет ответ на подтверждение подключения TOTP.
+type TotpConfirmResponse struct {
+	RecoveryCodes []string `json:"recovery_codes"` // Коды восстановления, показываются пользователю один раз.
+}
+
+// RegisterRequest представляет запрос регистрации пользователя.
+type RegisterRequest struct {
+	Email    string `json:"email"`            // Email пользователя.
+	Password string `json:"password"`         // Пароль пользователя.
+	Locale   string `json:"locale,omitempty"` // Предпочитаемый язык писем.
+}
+
+// RegisterResponse представляет ответ на запрос регистрации пользователя.
+type RegisterResponse struct {
+	UserId string `json:"user_id"` // Идентификатор созданного пользователя.
+}
+
+// VerifyEmailRequest представляет запрос подтверждения email.
+type VerifyEmailRequest struct {
+	Token string `json:"token"` // Одноразовый токен из письма.
+}
+
+// ForgotPasswordRequest представляет запрос на восстановление пароля.
+type ForgotPasswordRequest struct {
+	Email string `json:"email"` // Email пользователя, на который отправляется ссылка для сброса пароля.
+}
+
+// ResetPasswordRequest представляет запрос установки нового пароля.
+type ResetPasswordRequest struct {
+	Token    string `json:"token"`    // Одноразовый токен из письма.
+	Password string `json:"password"` // Новый пароль пользователя.
+}
+
 // AccessTokenClaims представляет claims для access токена (JWT).
 // Используется для проверки подлинности и срока действия access токена.
 type AccessTokenClaims struct {
 	Jti       string    // Уникальный идентификатор токена (JWT ID).
-	CreatedAt time.Time // Время создания токена.
-	UserId    string    // Идентификатор пользователя, которому принадлежит токен.
-	ExpiredAt time.Time // Время истечения срока действия токена.
+	CreatedAt time.Time // Время создания токена (iat).
+	NotBefore time.Time // Время, до которого токен недействителен (nbf).
+	UserId    string    // Идентификатор пользователя, которому принадлежит токен (sub).
+	ExpiredAt time.Time // Время истечения срока действия токена (exp).
+	Issuer    string    // Издатель токена (iss).
+	Audience  []string  // Получатели токена (aud).
+	Amr       []string  // Методы аутентификации, использованные при входе (amr, RFC 8176).
 }
 
+// Методы аутентификации для claim amr (RFC 8176).
+const (
+	AmrPassword = "pwd" // Вход по паролю.
+	AmrOtp      = "otp" // Одноразовый код (TOTP).
+	AmrMfa      = "mfa" // Использовано несколько факторов.
+)
+
 // RefreshTokenRecord представляет запись о refresh токене в базе данных.
 // Используется для хранения и проверки refresh токена.
 type RefreshTokenRecord struct {
-	Jti       string    `db:"jti"`        // Уникальный идентификатор токена (JWT ID).
+	Jti              string    `db:"jti"`                // Уникальный идентификатор токена (JWT ID).
+	CreatedAt        time.Time `db:"created_at"`         // Время создания токена.
+	ExpiredAt        time.Time `db:"expired_at"`         // Время истечения срока действия токена.
+	SessionStartedAt time.Time `db:"session_started_at"` // Время начала сессии (выдачи первой пары токенов), сохраняется при обновлениях.
+	FamilyId         string    `db:"family_id"`          // Идентификатор семейства токенов (сессии), общий для всех токенов, полученных обновлением.
+	Rotated          bool      `db:"rotated"`            // Признак того, что токен уже был обменян на новую пару и не может быть использован повторно.
+	IssuedIp         string    `db:"issued_ip"`          // IP-адрес, с которого был выдан токен.
+	UserAgent        string    `db:"user_agent"`         // User-Agent клиента, которому был выдан токен.
+	DeviceName       string    `db:"device_name"`        // Название устройства, переданное клиентом.
+	LastUsedAt       time.Time `db:"last_used_at"`       // Время последнего использования сессии (выдачи или обновления токенов).
+	TokenHash        string    `db:"token_hash"`         // Хэш refresh-токена для безопасного хранения.
+	Amr              string    `db:"amr"`                // Методы аутентификации, использованные при входе, через пробел; сохраняются при обновлениях.
+}
+
+// ClientInfo представляет сведения о клиенте, запросившем токены.
+// Используется для отображения списка сессий пользователя.
+type ClientInfo struct {
+	Ip         string // IP-адрес клиента.
+	UserAgent  string // Значение заголовка User-Agent.
+	DeviceName string // Название устройства из заголовка X-Device-Name.
+	Location   string // Приблизительное местоположение клиента, переданное доверенным прокси-сервером.
+}
+
+// Session представляет активную сессию пользователя.
+// Используется для отображения устройств, с которых выполнен вход.
+type Session struct {
+	Jti        string    `json:"jti"`          // Идентификатор текущего refresh-токена сессии.
+	DeviceName string    `json:"device_name"`  // Название устройства, переданное клиентом.
+	UserAgent  string    `json:"user_agent"`   // User-Agent клиента.
+	Ip         string    `json:"ip"`           // IP-адрес, с которого сессия использовалась последний раз.
+	CreatedAt  time.Time `json:"created_at"`   // Время начала сессии.
+	LastUsedAt time.Time `json:"last_used_at"` // Время последнего использования сессии.
+	ExpiredAt  time.Time `json:"expired_at"`   // Время истечения срока действия refresh-токена сессии.
+	Current    bool      `json:"current"`      // Признак сессии, которой принадлежит access-токен запроса.
+}
+
+// JWK представляет публичный ключ в формате JSON Web Key (RFC 7517).
+// Используется сторонними сервисами для проверки подписи access токенов.
+type JWK struct {
+	Kid string `json:"kid"`           // Идентификатор ключа.
+	Kty string `json:"kty"`           // Тип ключа: RSA, EC или OKP.
+	Use string `json:"use"`           // Назначение ключа (sig - подпись).
+	Alg string `json:"alg"`           // Алгоритм подписи.
+	N   string `json:"n,omitempty"`   // Модуль RSA-ключа.
+	E   string `json:"e,omitempty"`   // Публичная экспонента RSA-ключа.
+	Crv string `json:"crv,omitempty"` // Кривая для EC и OKP ключей.
+	X   string `json:"x,omitempty"`   // X-координата EC-ключа или публичный ключ Ed25519.
+	Y   string `json:"y,omitempty"`   // Y-координата EC-ключа.
+}
+
+// JWKS представляет набор публичных ключей (JSON Web Key Set).
+type JWKS struct {
+	Keys []*JWK `json:"keys"` // Список публичных ключей.
+}
+
+// ErrorResponse представляет тело ответа с ошибкой.
+// Поле Error содержит машиночитаемый код ошибки, ErrorDescription - описание для человека.
+type ErrorResponse struct {
+	Error            string `json:"error"`             // Код ошибки, например invalid_token или temporarily_unavailable.
+	ErrorDescription string `json:"error_description"` // Описание ошибки.
+}
+
+// IntrospectionResponse представляет ответ на запрос интроспекции токена (RFC 7662).
+// Для недействительного токена заполняется только поле Active.
+type IntrospectionResponse struct {
+	Active    bool   `json:"active"`               // Признак действительности токена.
+	Sub       string `json:"sub,omitempty"`        // Идентификатор пользователя, которому принадлежит токен.
+	Exp       int64  `json:"exp,omitempty"`        // Время истечения срока действия токена (Unix time).
+	Iat       int64  `json:"iat,omitempty"`        // Время выдачи токена (Unix time).
+	Jti       string `json:"jti,omitempty"`        // Уникальный идентификатор токена.
+	Scope     string `json:"scope,omitempty"`      // Области доступа токена через пробел.
+	ClientId  string `json:"client_id,omitempty"`  // Идентификатор клиента, выполнившего интроспекцию.
+	TokenType string `json:"token_type,omitempty"` // Тип токена: access_token или refresh_token.
+}
+
+// NotificationType определяет вид уведомления пользователя.
+type NotificationType string
+
+const (
+	NotificationIpChanged     NotificationType = "ip_changed"     // Обновление токенов с нового IP-адреса.
+	NotificationTokenReuse    NotificationType = "token_reuse"    // Повторное использование refresh-токена и отзыв сессии.
+	NotificationVerifyEmail   NotificationType = "verify_email"   // Подтверждение email после регистрации.
+	NotificationPasswordReset NotificationType = "password_reset" // Ссылка для сброса пароля.
+)
+
+// Notification представляет уведомление пользователя о событии безопасности.
+// Передается реализациям Notifier, которые доставляют его по email, webhook или в лог.
+type Notification struct {
+	Id         string           `json:"id"`                    // Ключ идемпотентности уведомления, одинаковый для всех попыток доставки.
+	Type       NotificationType `json:"type"`                  // Вид уведомления.
+	UserId     string           `json:"user_id"`               // Идентификатор пользователя.
+	Email      string           `json:"email"`                 // Email пользователя.
+	Ip         string           `json:"ip"`                    // IP-адрес клиента, с которого произошло событие.
+	PreviousIp string           `json:"previous_ip,omitempty"` // IP-адрес, с которого был выдан предыдущий токен.
+	UserAgent  string           `json:"user_agent,omitempty"`  // Значение заголовка User-Agent клиента.
+	Location   string           `json:"location,omitempty"`    // Приблизительное местоположение клиента.
+	Locale     string           `json:"locale,omitempty"`      // Предпочитаемый язык пользователя.
+	FamilyId   string           `json:"family_id,omitempty"`   // Идентификатор сессии (семейства токенов), к которой относится событие.
+	ReportUrl  string           `json:"report_url,omitempty"`  // Подписанная одноразовая ссылка "Это был не я" для завершения сессий.
+	ActionUrl  string           `json:"action_url,omitempty"`  // Ссылка для действия пользователя (например, подтверждения email).
+	CreatedAt  time.Time        `json:"created_at"`            // Время события.
+}
+
+// Области действия ссылки "Это был не я".
+const (
+	KillLinkScopeFamily = "family" // Ссылка завершает сессию (семейство токенов), к которой относится уведомление.
+	KillLinkScopeAll    = "all"    // Ссылка завершает все сессии пользователя.
+)
+
+// KillLinkEvent представляет использование ссылки "Это был не я".
+// Сохраняется при первом переходе по ссылке, повторное использование той же ссылки отклоняется.
+type KillLinkEvent struct {
+	Id        string    `db:"id"`         // Идентификатор ссылки.
+	UserId    string    `db:"user_id"`    // Идентификатор пользователя.
+	FamilyId  string    `db:"family_id"`  // Идентификатор завершаемой сессии (пустой для области all).
+	Scope     string    `db:"scope"`      // Область действия ссылки: family или all.
+	Ip        string    `db:"ip"`         // IP-адрес клиента, перешедшего по ссылке.
+	UserAgent string    `db:"user_agent"` // Значение заголовка User-Agent клиента, перешедшего по ссылке.
+	UsedAt    time.Time `db:"used_at"`    // Время использования ссылки.
+	ExpiredAt time.Time `db:"expired_at"` // Срок действия ссылки.
+}
+
+// Статусы сообщений outbox.
+const (
+	OutboxStatusPending   = "pending"   // Сообщение ожидает доставки.
+	OutboxStatusDelivered = "delivered" // Сообщение доставлено.
+	OutboxStatusDead      = "dead"      // Количество попыток доставки исчерпано.
+)
+
+// OutboxMessage представляет сообщение outbox - уведомление, ожидающее асинхронной доставки.
+// Сохраняется вместе с изменением токенов и доставляется фоновым обработчиком с повторными попытками.
+type OutboxMessage struct {
+	Id            string    `db:"id"`              // Ключ идемпотентности: повторное добавление сообщения с тем же ключом игнорируется.
+	Payload       string    `db:"payload"`         // Уведомление в формате JSON.
+	Status        string    `db:"status"`          // Статус сообщения: pending, delivered или dead.
+	Attempts      int       `db:"attempts"`        // Количество выполненных попыток доставки.
+	NextAttemptAt time.Time `db:"next_attempt_at"` // Время следующей попытки доставки.
+	LastError     string    `db:"last_error"`      // Ошибка последней попытки доставки.
+	CreatedAt     time.Time `db:"created_at"`      // Время создания сообщения.
+}
+
+// Статусы пользователя.
+const (
+	UserStatusActive   = "active"   // Пользователю выдаются токены.
+	UserStatusDisabled = "disabled" // Пользователь заблокирован, выдача и обновление токенов запрещены.
+)
+
+// User представляет пользователя сервиса.
+type User struct {
+	Id            string    `db:"id"`             // Идентификатор пользователя.
+	Email         string    `db:"email"`          // Email пользователя, на который отправляются уведомления.
+	EmailVerified bool      `db:"email_verified"` // Подтвержден ли email пользователя.
+	Status        string    `db:"status"`         // Статус пользователя: active или disabled.
+	Locale        string    `db:"locale"`         // Предпочитаемый язык писем (пустая строка - язык по умолчанию).
+	CreatedAt     time.Time `db:"created_at"`     // Время создания пользователя.
+}
+
+// Назначения одноразовых токенов.
+const (
+	OneTimeTokenVerifyEmail   = "verify_email"   // Подтверждение email.
+	OneTimeTokenPasswordReset = "password_reset" // Сброс пароля.
+	OneTimeTokenMfaChallenge  = "mfa_challenge"  // Проверка второго фактора при входе.
+)
+
+// OneTimeToken представляет одноразовый токен, отправленный пользователю по email.
+// Хранится только SHA-256 хэш токена, токен удаляется при первом использовании.
+type OneTimeToken struct {
+	TokenHash string    `db:"token_hash"` // SHA-256 хэш токена в шестнадцатеричном виде.
+	UserId    string    `db:"user_id"`    // Идентификатор пользователя.
+	Purpose   string    `db:"purpose"`    // Назначение токена.
+	ExpiredAt time.Time `db:"expired_at"` // Срок действия токена.
 	CreatedAt time.Time `db:"created_at"` // Время создания токена.
-	ExpiredAt time.Time `db:"expired_at"` // Время истечения срока действия токена.
-	IssuedIp  string    `db:"issued_ip"`  // IP-адрес, с которого был выдан токен.
-	TokenHash string    `db:"token_hash"` // Хэш refresh-токена для безопасного хранения.
+}
+
+// TotpCredential представляет TOTP-секрет пользователя (RFC 6238).
+// Секрет хранится в зашифрованном виде, TOTP считается включенным после подтверждения первым кодом.
+type TotpCredential struct {
+	UserId          string    `db:"user_id"`          // Идентификатор пользователя.
+	EncryptedSecret string    `db:"encrypted_secret"` // Секрет, зашифрованный AES-256-GCM, в base64.
+	Confirmed       bool      `db:"confirmed"`        // Признак подтверждения подключения первым кодом.
+	LastUsedStep    int64     `db:"last_used_step"`   // Номер последнего принятого временного шага, защищает от повторного использования кода.
+	CreatedAt       time.Time `db:"created_at"`       // Время подключения.
 }
@@ -0,0 +1,103 @@
+package handlers
+
+import (
+	"auth_service/internal/entities"
+	"auth_service/internal/services"
+	"auth_service/internal/storage"
+	"context"
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+)
+
+// Коды ошибок в поле error тела ответа.
+const (
+	codeInvalidRequest         = "invalid_request"         // Некорректный запрос: неверный JSON, пустые или слишком длинные поля.
+	codeInvalidClient          = "invalid_client"          // Неверные учетные данные клиента интроспекции.
+	codeInvalidCredentials     = "invalid_credentials"     // Неверный идентификатор или пароль.
+	codeInvalidToken           = "invalid_token"           // Токен отсутствует, не найден или имеет неверную подпись.
+	codeTokenExpired           = "token_expired"           // Срок действия токена истек.
+	codeTokenRevoked           = "token_revoked"           // Токен был отозван.
+	codeTokenReused            = "token_reused"            // Повторное использование refresh-токена, сессия отозвана.
+	codeUnknownUser            = "unknown_user"            // Пользователь, которому принадлежит токен, не найден.
+	codeUserDisabled           = "user_disabled"           // Пользователь заблокирован.
+	codeEmailNotVerified       = "email_not_verified"      // Email пользователя не подтвержден.
+	codeInvalidEmail           = "invalid_email"           // Некорректный email.
+	codeWeakPassword           = "weak_password"           // Слишком короткий пароль.
+	codeEmailTaken             = "email_taken"             // Email уже зарегистрирован.
+	codeInvalidMfaCode         = "invalid_mfa_code"        // Неверный код второго фактора или токен MFA-проверки.
+	codeMfaAlreadyEnabled      = "mfa_already_enabled"     // Двухфакторная аутентификация уже включена.
+	codeMfaNotEnrolled         = "mfa_not_enrolled"        // Подключение TOTP не было начато.
+	codeSessionNotFound        = "session_not_found"       // Сессия пользователя не найдена.
+	codeRateLimited            = "rate_limited"            // Превышен лимит запросов с IP-адреса клиента.
+	codeServerError            = "server_error"            // Непредвиденная ошибка сервера.
+	codeTemporarilyUnavailable = "temporarily_unavailable" // Хранилище временно недоступно, запрос можно повторить.
+)
+
+// retryAfterSeconds - значение заголовка Retry-After для ответов со статусом 503.
+const retryAfterSeconds = "5"
+
+// serviceError описывает ответ на ошибку сервиса.
+type serviceError struct {
+	err     error  // Ошибка сервиса, проверяется через errors.Is.
+	status  int    // HTTP-статус ответа.
+	code    string // Код ошибки.
+	message string // Описание ошибки.
+}
+
+// serviceErrors сопоставляет ошибки сервиса с ответами. Проверяются по порядку,
+// поэтому ErrAccessTokenExpired стоит раньше оборачивающей ее ErrInvalidAccessToken.
+var serviceErrors = []serviceError{
+	{services.ErrInvalidCredentials, http.StatusUnauthorized, codeInvalidCredentials, "Invalid identifier or password"},
+	{services.ErrAccessTokenExpired, http.StatusUnauthorized, codeTokenExpired, "Access token is expired"},
+	{services.ErrInvalidAccessToken, http.StatusUnauthorized, codeInvalidToken, "Access token is invalid"},
+	{services.ErrAccessTokenRevoked, http.StatusUnauthorized, codeTokenRevoked, "Access token is revoked"},
+	{services.ErrInvalidRefreshToken, http.StatusUnauthorized, codeInvalidToken, "Refresh token is invalid"},
+	{services.ErrRefreshTokenExpired, http.StatusUnauthorized, codeTokenExpired, "Refresh token is expired"},
+	{services.ErrRefreshTokenReused, http.StatusUnauthorized, codeTokenReused, "Refresh token reuse detected, session has been revoked"},
+	{services.ErrUnknownUser, http.StatusUnauthorized, codeUnknownUser, "Unknown user"},
+	{services.ErrInvalidMfaToken, http.StatusUnauthorized, codeInvalidMfaCode, "Invalid MFA token or code"},
+	{services.ErrInvalidMfaCode, http.StatusUnauthorized, codeInvalidMfaCode, "Invalid MFA token or code"},
+	{services.ErrInvalidClient, http.StatusUnauthorized, codeInvalidClient, "Invalid client credentials"},
+	{services.ErrUserDisabled, http.StatusForbidden, codeUserDisabled, "User is disabled"},
+	{services.ErrEmailNotVerified, http.StatusForbidden, codeEmailNotVerified, "Email is not verified"},
+	{services.ErrInvalidEmail, http.StatusBadRequest, codeInvalidEmail, "Invalid email"},
+	{services.ErrWeakPassword, http.StatusBadRequest, codeWeakPassword, "Password is too short"},
+	{services.ErrInvalidOneTimeToken, http.StatusBadRequest, codeInvalidToken, "Invalid or expired token"},
+	{services.ErrSessionNotFound, http.StatusNotFound, codeSessionNotFound, "Session not found"},
+	{services.ErrEmailTaken, http.StatusConflict, codeEmailTaken, "Email is already registered"},
+	{services.ErrMfaAlreadyEnabled, http.StatusConflict, codeMfaAlreadyEnabled, "Two-factor authentication is already enabled"},
+	{services.ErrMfaNotEnrolled, http.StatusConflict, codeMfaNotEnrolled, "Two-factor authentication is not enrolled"},
+}
+
+// writeError записывает JSON-ответ с кодом и описанием ошибки.
+func writeError(w http.ResponseWriter, status int, code, message string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-store")
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(&entities.ErrorResponse{Error: code, ErrorDescription: message})
+}
+
+// writeServiceError логирует ошибку сервиса и записывает ответ по таблице serviceErrors.
+// Недоступность хранилища и истечение времени ожидания возвращаются со статусом 503 и заголовком Retry-After,
+// остальные ошибки - со статусом 500. В обоих случаях описанием ошибки служит message.
+func writeServiceError(w http.ResponseWriter, err error, message string) {
+	log.Println(err)
+
+	for _, e := range serviceErrors {
+		if errors.Is(err, e.err) {
+			writeError(w, e.status, e.code, e.message)
+			return
+		}
+	}
+
+	if errors.Is(err, storage.ErrStorageUnavailable) || errors.Is(err, context.DeadlineExceeded) || errors.Is(err, context.Canceled) {
+		w.Header().Set("Retry-After", retryAfterSeconds)
+		writeError(w, http.StatusServiceUnavailable, codeTemporarilyUnavailable, message)
+		return
+	}
+
+	writeError(w, http.StatusInternalServerError, codeServerError, message)
+}
@@ -1,12 +1,20 @@
 package handlers
 
 import (
+	"auth_service/internal/config"
 	"auth_service/internal/entities"
 	"auth_service/internal/services"
 	"encoding/json"
 	"log"
 	"net/http"
-	"strconv"
+	"strings"
+)
+
+const (
+	maxUserAgentLength  = 512  // Максимальная длина сохраняемого User-Agent.
+	maxDeviceNameLength = 128  // Максимальная длина названия устройства из заголовка X-Device-Name.
+	maxLocationLength   = 128  // Максимальная длина местоположения клиента из заголовка LOCATION_HEADER.
+	maxPasswordLength   = 1024 // Максимальная длина пароля в байтах, ограничивает стоимость вычисления хэша.
 )
 
 // AuthHandler представляет обработчик для работы с аутентификацией.
@@ -19,41 +27,51 @@ func RegisterAuthHandler(service services.AuthServiceInterface) *AuthHandler {
 	return &AuthHandler{service: service}
 }
 
-// GenerateTokens обрабатывает GET-запрос для генерации пары токенов (доступа и обновления).
-// Ожидает user_id в параметрах пути и IP-адрес клиента.
-// Возвращает JSON с новой парой токенов.
-func (h *AuthHandler) GenerateTokens() func(http.ResponseWriter, *http.Request) {
+// Login обрабатывает POST-запрос входа по паролю.
+// Ожидает JSON с identifier (email или id пользователя) и password в теле запроса,
+// название устройства можно передать в заголовке X-Device-Name. Возвращает JSON с новой парой токенов,
+// а если у пользователя включен TOTP - JSON с mfa_required и mfa_token для завершения входа через MfaVerify.
+func (h *AuthHandler) Login() func(http.ResponseWriter, *http.Request) {
 	return func(w http.ResponseWriter, r *http.Request) {
-		var (
-			newTokensPair *entities.TokensPair
-			err           error
-		)
+		var req entities.LoginRequest
 
-		userId := r.PathValue("user_id")
-		if _, err := strconv.Atoi(userId); err != nil {
-			http.Error(w, "user_id in URL must be integer", http.StatusBadRequest)
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			log.Println(err)
+			writeError(w, http.StatusBadRequest, codeInvalidRequest, "Invalid JSON")
 			return
 		}
-		ip := r.RemoteAddr
-		if ip == "" {
+
+		switch {
+		case req.Identifier == "":
+			log.Println("identifier is empty")
+			writeError(w, http.StatusBadRequest, codeInvalidRequest, "Identifier is required")
+			return
+		case req.Password == "":
+			log.Println("password is empty")
+			writeError(w, http.StatusBadRequest, codeInvalidRequest, "Password is required")
+			return
+		case len(req.Password) > maxPasswordLength:
+			log.Println("password is too long")
+			writeError(w, http.StatusBadRequest, codeInvalidRequest, "Password is too long")
+			return
+		}
+
+		client := getClientInfo(r)
+		if client.Ip == "" {
 			log.Println("IP address is empty")
-			http.Error(w, "Client IP address is missing", http.StatusBadRequest)
+			writeError(w, http.StatusBadRequest, codeInvalidRequest, "Client IP address is missing")
 			return
 		}
 
-		newTokensPair, err = h.service.GenerateTokens(userId, ip)
+		loginResponse, err := h.service.Login(r.Context(), strings.TrimSpace(req.Identifier), req.Password, client)
 		if err != nil {
-			log.Println(err)
-			http.Error(w, "Failed to generate token pair", http.StatusUnauthorized)
+			writeServiceError(w, err, "Failed to log in")
 			return
 		}
 
-		resp := entities.TokensPair{
-			AccessToken:  newTokensPair.AccessToken,
-			RefreshToken: newTokensPair.RefreshToken,
-		}
 		w.Header().Set("Content-Type", "application/json")
-		json.NewEncoder(w).Encode(resp)
+		w.Header().Set("Cache-Control", "no-store")
+		json.NewEncoder(w).Encode(loginResponse)
 	}
 }
 
@@ -69,32 +87,31 @@ func (s *AuthHandler) RefreshTokens() func(http.ResponseWriter, *http.Request) {
 
 		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
 			log.Println(err)
-			http.Error(w, "Invalid JSON", http.StatusBadRequest)
+			writeError(w, http.StatusBadRequest, codeInvalidRequest, "Invalid JSON")
 			return
 		}
 
 		switch {
 		case req.AccessToken == "":
 			log.Println("access token is empty")
-			http.Error(w, "Access token is required", http.StatusBadRequest)
+			writeError(w, http.StatusBadRequest, codeInvalidRequest, "Access token is required")
 			return
 		case req.RefreshToken == "":
 			log.Println("refresh token is empty")
-			http.Error(w, "Refresh token is required", http.StatusBadRequest)
+			writeError(w, http.StatusBadRequest, codeInvalidRequest, "Refresh token is required")
 			return
 		}
 
-		ip := r.RemoteAddr
-		if ip == "" {
+		client := getClientInfo(r)
+		if client.Ip == "" {
 			log.Println("IP address is empty")
-			http.Error(w, "Client IP address is missing", http.StatusBadRequest)
+			writeError(w, http.StatusBadRequest, codeInvalidRequest, "Client IP address is missing")
 			return
 		}
 
-		updTokensPair, err := s.service.RefreshTokens(ip, &req)
+		updTokensPair, err := s.service.RefreshTokens(r.Context(), client, &req)
 		if err != nil {
-			log.Println(err)
-			http.Error(w, "Failed to refresh Token Pairs", http.StatusUnauthorized)
+			writeServiceError(w, err, "Failed to refresh Token Pairs")
 			return
 		}
 
@@ -106,3 +123,230 @@ func (s *AuthHandler) RefreshTokens() func(http.ResponseWriter, *http.Request) {
 		json.NewEncoder(w).Encode(resp)
 	}
 }
+
+// Logout обрабатывает POST-запрос на завершение текущей сессии.
+// Ожидает JSON с access_token и refresh_token в теле запроса.
+func (h *AuthHandler) Logout() func(http.ResponseWriter, *http.Request) {
+	return func(w http.ResponseWriter, r *http.Request) {
+		var req entities.TokensPair
+
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			log.Println(err)
+			writeError(w, http.StatusBadRequest, codeInvalidRequest, "Invalid JSON")
+			return
+		}
+
+		switch {
+		case req.AccessToken == "":
+			log.Println("access token is empty")
+			writeError(w, http.StatusBadRequest, codeInvalidRequest, "Access token is required")
+			return
+		case req.RefreshToken == "":
+			log.Println("refresh token is empty")
+			writeError(w, http.StatusBadRequest, codeInvalidRequest, "Refresh token is required")
+			return
+		}
+
+		if err := h.service.Logout(r.Context(), &req); err != nil {
+			writeServiceError(w, err, "Failed to logout")
+			return
+		}
+
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
+
+// LogoutAll обрабатывает POST-запрос на завершение всех сессий пользователя.
+// Ожидает access-токен в заголовке Authorization.
+func (h *AuthHandler) LogoutAll() func(http.ResponseWriter, *http.Request) {
+	return func(w http.ResponseWriter, r *http.Request) {
+		accessToken, ok := bearerToken(r)
+		if !ok {
+			log.Println("access token is empty")
+			writeError(w, http.StatusUnauthorized, codeInvalidToken, "Access token is required")
+			return
+		}
+
+		if err := h.service.LogoutAll(r.Context(), accessToken); err != nil {
+			writeServiceError(w, err, "Failed to logout from all sessions")
+			return
+		}
+
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
+
+// ListSessions обрабатывает GET-запрос на получение списка активных сессий пользователя.
+// Ожидает access-токен в заголовке Authorization.
+// Возвращает JSON со списком сессий, текущая сессия помечена полем current.
+func (h *AuthHandler) ListSessions() func(http.ResponseWriter, *http.Request) {
+	return func(w http.ResponseWriter, r *http.Request) {
+		accessToken, ok := bearerToken(r)
+		if !ok {
+			log.Println("access token is empty")
+			writeError(w, http.StatusUnauthorized, codeInvalidToken, "Access token is required")
+			return
+		}
+
+		sessions, err := h.service.ListSessions(r.Context(), accessToken)
+		if err != nil {
+			writeServiceError(w, err, "Failed to get sessions")
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(sessions)
+	}
+}
+
+// RevokeSession обрабатывает DELETE-запрос на завершение сессии пользователя.
+// Ожидает jti сессии в параметрах пути и access-токен в заголовке Authorization.
+func (h *AuthHandler) RevokeSession() func(http.ResponseWriter, *http.Request) {
+	return func(w http.ResponseWriter, r *http.Request) {
+		accessToken, ok := bearerToken(r)
+		if !ok {
+			log.Println("access token is empty")
+			writeError(w, http.StatusUnauthorized, codeInvalidToken, "Access token is required")
+			return
+		}
+		jti := r.PathValue("jti")
+		if jti == "" {
+			writeError(w, http.StatusBadRequest, codeInvalidRequest, "jti in URL is required")
+			return
+		}
+
+		if err := h.service.RevokeSession(r.Context(), accessToken, jti); err != nil {
+			writeServiceError(w, err, "Failed to revoke session")
+			return
+		}
+
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
+
+// getClientInfo собирает сведения о клиенте из запроса.
+// Слишком длинные значения заголовков обрезаются.
+func getClientInfo(r *http.Request) *entities.ClientInfo {
+	return &entities.ClientInfo{
+		Ip:         ipResolver.ClientIP(r),
+		UserAgent:  truncate(r.UserAgent(), maxUserAgentLength),
+		DeviceName: truncate(strings.TrimSpace(r.Header.Get("X-Device-Name")), maxDeviceNameLength),
+		Location:   truncate(ipResolver.TrustedHeader(r, config.LocationHeader), maxLocationLength),
+	}
+}
+
+// truncate обрезает строку до maxLength символов.
+func truncate(s string, maxLength int) string {
+	if runes := []rune(s); len(runes) > maxLength {
+		return string(runes[:maxLength])
+	}
+
+	return s
+}
+
+// bearerToken извлекает access-токен из заголовка Authorization со схемой Bearer.
+func bearerToken(r *http.Request) (string, bool) {
+	scheme, token, found := strings.Cut(r.Header.Get("Authorization"), " ")
+	if !found || !strings.EqualFold(scheme, "Bearer") || token == "" {
+		return "", false
+	}
+
+	return token, true
+}
+
+// Introspect обрабатывает POST-запрос на интроспекцию токена (RFC 7662).
+// Ожидает учетные данные клиента в заголовке Authorization (Basic) или в полях client_id и client_secret,
+// а также token и необязательный token_type_hint в теле запроса (application/x-www-form-urlencoded).
+// Возвращает JSON со сведениями о токене.
+func (h *AuthHandler) Introspect() func(http.ResponseWriter, *http.Request) {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if err := r.ParseForm(); err != nil {
+			log.Println(err)
+			writeError(w, http.StatusBadRequest, codeInvalidRequest, "Invalid form body")
+			return
+		}
+
+		clientId, ok := h.authenticateClient(w, r, "introspect")
+		if !ok {
+			return
+		}
+
+		token := r.PostForm.Get("token")
+		if token == "" {
+			log.Println("token is empty")
+			writeError(w, http.StatusBadRequest, codeInvalidRequest, "Token is required")
+			return
+		}
+
+		resp, err := h.service.Introspect(r.Context(), clientId, token, r.PostForm.Get("token_type_hint"))
+		if err != nil {
+			writeServiceError(w, err, "Failed to introspect token")
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		w.Header().Set("Cache-Control", "no-store")
+		json.NewEncoder(w).Encode(resp)
+	}
+}
+
+// Revoke обрабатывает POST-запрос на отзыв токена (RFC 7009).
+// Ожидает учетные данные клиента в заголовке Authorization (Basic) или в полях client_id и client_secret,
+// а также token и необязательный token_type_hint в теле запроса (application/x-www-form-urlencoded).
+// Возвращает статус 200 и для неизвестных или недействительных токенов.
+func (h *AuthHandler) Revoke() func(http.ResponseWriter, *http.Request) {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if err := r.ParseForm(); err != nil {
+			log.Println(err)
+			writeError(w, http.StatusBadRequest, codeInvalidRequest, "Invalid form body")
+			return
+		}
+
+		if _, ok := h.authenticateClient(w, r, "revoke"); !ok {
+			return
+		}
+
+		token := r.PostForm.Get("token")
+		if token == "" {
+			log.Println("token is empty")
+			writeError(w, http.StatusBadRequest, codeInvalidRequest, "Token is required")
+			return
+		}
+
+		if err := h.service.Revoke(r.Context(), token, r.PostForm.Get("token_type_hint")); err != nil {
+			writeServiceError(w, err, "Failed to revoke token")
+			return
+		}
+
+		w.WriteHeader(http.StatusOK)
+	}
+}
+
+// authenticateClient проверяет учетные данные клиента из заголовка Authorization (Basic)
+// или из полей client_id и client_secret разобранного тела запроса.
+// Возвращает идентификатор клиента. При неверных данных записывает ответ 401 с заголовком WWW-Authenticate
+// для realm и возвращает false.
+func (h *AuthHandler) authenticateClient(w http.ResponseWriter, r *http.Request, realm string) (string, bool) {
+	clientId, clientSecret, ok := r.BasicAuth()
+	if !ok {
+		clientId, clientSecret = r.PostForm.Get("client_id"), r.PostForm.Get("client_secret")
+	}
+	if err := h.service.AuthenticateClient(clientId, clientSecret); err != nil {
+		log.Println(err)
+		w.Header().Set("WWW-Authenticate", `Basic realm="`+realm+`"`)
+		writeError(w, http.StatusUnauthorized, codeInvalidClient, "Invalid client credentials")
+		return "", false
+	}
+
+	return clientId, true
+}
+
+// JWKS обрабатывает GET-запрос на получение публичных ключей проверки подписи access-токенов.
+// Возвращает JSON Web Key Set (RFC 7517).
+func (h *AuthHandler) JWKS() func(http.ResponseWriter, *http.Request) {
+	return func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.Header().Set("Cache-Control", "public, max-age=300")
+		json.NewEncoder(w).Encode(h.service.JWKS())
+	}
+}
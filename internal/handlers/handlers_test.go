@@ -1,43 +1,58 @@
 package handlers
 
 import (
+	"auth_service/internal/clientip"
+	"auth_service/internal/config"
 	"auth_service/internal/entities"
+	"auth_service/internal/services"
 	"auth_service/internal/services/service_mocks"
+	"auth_service/internal/storage"
 	"bytes"
+	"context"
 	"encoding/json"
 	"fmt"
 	"net/http"
 	"net/http/httptest"
+	"net/url"
+	"strings"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/mock"
 	"github.com/stretchr/testify/require"
 )
 
-// TestGenerateTokens проверяет работу обработчика GenerateTokens.
-func TestGenerateTokens(t *testing.T) {
+// TestLogin проверяет работу обработчика Login.
+func TestLogin(t *testing.T) {
 	mockService := service_mocks.NewAuthServiceInterface(t)
 	handler := RegisterAuthHandler(mockService)
 	mux := http.NewServeMux()
-	mux.HandleFunc("/api/auth/{user_id}", handler.GenerateTokens())
-	baseURL := "/api/auth"
+	mux.HandleFunc("/api/auth/login", handler.Login())
+	testURL := "/api/auth/login"
 	tokensPair := entities.TokensPair{
 		AccessToken:  "access-token",
 		RefreshToken: "refresh-token",
 	}
+	loginRequest := entities.LoginRequest{Identifier: " user@gmail.com ", Password: "password"}
 
-	t.Run("successful generated tokens", func(t *testing.T) {
-		t.Cleanup(func() { mockService.ExpectedCalls = nil })
+	newRequest := func(body any) *http.Request {
+		reqBody, err := json.Marshal(body)
+		require.NoError(t, err)
+		return httptest.NewRequest(http.MethodPost, testURL, bytes.NewReader(reqBody))
+	}
 
-		userId := "123"
-		testURL := fmt.Sprintf("%s/%s", baseURL, userId)
+	t.Run("successful login", func(t *testing.T) {
+		t.Cleanup(func() { mockService.ExpectedCalls = nil })
 
-		req := httptest.NewRequest(http.MethodGet, testURL, nil)
+		req := newRequest(loginRequest)
+		req.Header.Set("User-Agent", "test-agent")
+		req.Header.Set("X-Device-Name", " Work laptop ")
 		respRec := httptest.NewRecorder()
 
-		mockService.On("GenerateTokens", mock.Anything, mock.Anything).Return(&tokensPair, nil)
+		mockService.On("Login", mock.Anything, mock.Anything, mock.Anything, mock.Anything).Return(&entities.LoginResponse{TokensPair: &tokensPair}, nil)
 		mux.ServeHTTP(respRec, req)
 		require.Equal(t, http.StatusOK, respRec.Code)
+		require.Equal(t, "no-store", respRec.Header().Get("Cache-Control"))
 
 		var actualTokensPair entities.TokensPair
 
@@ -45,67 +60,144 @@ func TestGenerateTokens(t *testing.T) {
 		require.NoErrorf(t, err, "Ошибка парсинга JSON-ответа: %v", err)
 		require.Equal(t, tokensPair, actualTokensPair)
 
-		mockService.AssertCalled(t, "GenerateTokens", userId, req.RemoteAddr)
+		client := &entities.ClientInfo{Ip: "192.0.2.1", UserAgent: "test-agent", DeviceName: "Work laptop"}
+		// Сервис получает контекст запроса, чтобы отключение клиента прерывало вход.
+		requestCtx := mock.MatchedBy(func(ctx context.Context) bool { return ctx == req.Context() })
+		mockService.AssertCalled(t, "Login", requestCtx, "user@gmail.com", "password", client)
 	})
-	t.Run("user_id in URL is empty", func(t *testing.T) {
+	t.Run("invalid request", func(t *testing.T) {
 		t.Cleanup(func() { mockService.ExpectedCalls = nil })
 
-		testURL := baseURL
+		tests := []struct {
+			name         string
+			body         any
+			expectedBody string
+		}{
+			{name: "invalid JSON", body: "not an object", expectedBody: "Invalid JSON"},
+			{name: "empty identifier", body: entities.LoginRequest{Password: "password"}, expectedBody: "Identifier is required"},
+			{name: "empty password", body: entities.LoginRequest{Identifier: "123"}, expectedBody: "Password is required"},
+			{name: "too long password", body: entities.LoginRequest{Identifier: "123", Password: strings.Repeat("a", maxPasswordLength+1)},
+				expectedBody: "Password is too long"},
+		}
+		for _, tt := range tests {
+			req := newRequest(tt.body)
+			respRec := httptest.NewRecorder()
 
-		req := httptest.NewRequest(http.MethodGet, testURL, nil)
+			mux.ServeHTTP(respRec, req)
+			require.Equal(t, http.StatusBadRequest, respRec.Code, tt.name)
+			require.Contains(t, respRec.Body.String(), tt.expectedBody, tt.name)
+		}
+
+		mockService.AssertNotCalled(t, "Login")
+	})
+	t.Run("IP address is empty", func(t *testing.T) {
+		t.Cleanup(func() { mockService.ExpectedCalls = nil })
+
+		req := newRequest(loginRequest)
+		req.RemoteAddr = ""
 		respRec := httptest.NewRecorder()
 
 		mux.ServeHTTP(respRec, req)
-		require.Equal(t, http.StatusNotFound, respRec.Code)
+		require.Equal(t, http.StatusBadRequest, respRec.Code)
+		require.Contains(t, respRec.Body.String(), "Client IP address is missing")
 
-		mockService.AssertNotCalled(t, "GenerateTokens")
+		mockService.AssertNotCalled(t, "Login")
 	})
-	t.Run("user_id is invalid", func(t *testing.T) {
+	t.Run("mfa required", func(t *testing.T) {
 		t.Cleanup(func() { mockService.ExpectedCalls = nil })
 
-		userId := "123qwerty"
-		testURL := fmt.Sprintf("%s/%s", baseURL, userId)
-
-		req := httptest.NewRequest(http.MethodGet, testURL, nil)
+		req := newRequest(loginRequest)
 		respRec := httptest.NewRecorder()
 
+		loginResponse := &entities.LoginResponse{MfaRequired: true, MfaToken: "mfa-token"}
+		mockService.On("Login", mock.Anything, mock.Anything, mock.Anything, mock.Anything).Return(loginResponse, nil)
 		mux.ServeHTTP(respRec, req)
-		require.Equal(t, http.StatusBadRequest, respRec.Code)
-		require.Contains(t, respRec.Body.String(), "user_id in URL must be integer")
+		require.Equal(t, http.StatusOK, respRec.Code)
+		require.JSONEq(t, `{"mfa_required":true,"mfa_token":"mfa-token"}`, respRec.Body.String())
+	})
+	t.Run("invalid credentials", func(t *testing.T) {
+		t.Cleanup(func() { mockService.ExpectedCalls = nil })
+
+		req := newRequest(loginRequest)
+		respRec := httptest.NewRecorder()
 
-		mockService.AssertNotCalled(t, "GenerateTokens")
+		mockService.On("Login", mock.Anything, mock.Anything, mock.Anything, mock.Anything).Return(nil, fmt.Errorf("wrapped: %w", services.ErrInvalidCredentials))
+		mux.ServeHTTP(respRec, req)
+		require.Equal(t, http.StatusUnauthorized, respRec.Code)
+		require.Contains(t, respRec.Body.String(), "Invalid identifier or password")
 	})
-	t.Run("IP address is empty", func(t *testing.T) {
+	t.Run("disabled user", func(t *testing.T) {
 		t.Cleanup(func() { mockService.ExpectedCalls = nil })
 
-		userId := "123"
-		testURL := fmt.Sprintf("%s/%s", baseURL, userId)
+		req := newRequest(loginRequest)
+		respRec := httptest.NewRecorder()
 
-		req := httptest.NewRequest(http.MethodGet, testURL, nil)
-		req.RemoteAddr = ""
+		mockService.On("Login", mock.Anything, mock.Anything, mock.Anything, mock.Anything).Return(nil, fmt.Errorf("wrapped: %w", services.ErrUserDisabled))
+		mux.ServeHTTP(respRec, req)
+		require.Equal(t, http.StatusForbidden, respRec.Code)
+		require.Contains(t, respRec.Body.String(), "User is disabled")
+	})
+	t.Run("email is not verified", func(t *testing.T) {
+		t.Cleanup(func() { mockService.ExpectedCalls = nil })
+
+		req := newRequest(loginRequest)
 		respRec := httptest.NewRecorder()
 
+		mockService.On("Login", mock.Anything, mock.Anything, mock.Anything, mock.Anything).Return(nil, fmt.Errorf("wrapped: %w", services.ErrEmailNotVerified))
 		mux.ServeHTTP(respRec, req)
-		require.Equal(t, http.StatusBadRequest, respRec.Code)
-		require.Contains(t, respRec.Body.String(), "Client IP address is missing")
+		require.Equal(t, http.StatusForbidden, respRec.Code)
+		require.Contains(t, respRec.Body.String(), "Email is not verified")
+	})
+	t.Run("storage is unavailable", func(t *testing.T) {
+		t.Cleanup(func() { mockService.ExpectedCalls = nil })
+
+		req := newRequest(loginRequest)
+		respRec := httptest.NewRecorder()
 
-		mockService.AssertNotCalled(t, "GenerateTokens")
+		mockService.On("Login", mock.Anything, mock.Anything, mock.Anything, mock.Anything).Return(nil, fmt.Errorf("failed to get user: %w", storage.ErrStorageUnavailable))
+		mux.ServeHTTP(respRec, req)
+		require.Equal(t, http.StatusServiceUnavailable, respRec.Code)
+		require.Equal(t, "5", respRec.Header().Get("Retry-After"))
+		require.Contains(t, respRec.Body.String(), "Failed to log in")
+		require.Contains(t, respRec.Body.String(), "temporarily_unavailable")
 	})
-	t.Run("unsuccessful generated tokens", func(t *testing.T) {
+	t.Run("unexpected error", func(t *testing.T) {
 		t.Cleanup(func() { mockService.ExpectedCalls = nil })
 
-		userId := "123"
-		testURL := fmt.Sprintf("%s/%s", baseURL, userId)
+		req := newRequest(loginRequest)
+		respRec := httptest.NewRecorder()
 
-		req := httptest.NewRequest(http.MethodGet, testURL, nil)
+		mockService.On("Login", mock.Anything, mock.Anything, mock.Anything, mock.Anything).Return(nil, fmt.Errorf("failed to generate token"))
+		mux.ServeHTTP(respRec, req)
+		require.Equal(t, http.StatusInternalServerError, respRec.Code)
+		require.Empty(t, respRec.Header().Get("Retry-After"))
+
+		var resp entities.ErrorResponse
+		require.NoError(t, json.NewDecoder(respRec.Body).Decode(&resp))
+		require.Equal(t, entities.ErrorResponse{Error: "server_error", ErrorDescription: "Failed to log in"}, resp)
+	})
+	t.Run("client IP behind trusted proxy", func(t *testing.T) {
+		t.Cleanup(func() {
+			mockService.ExpectedCalls = nil
+			resolver, _ := clientip.NewResolver("")
+			SetClientIPResolver(resolver)
+			config.LocationHeader = ""
+		})
+		resolver, err := clientip.NewResolver("192.0.2.0/24")
+		require.NoError(t, err)
+		SetClientIPResolver(resolver)
+		config.LocationHeader = "CF-IPCountry"
+
+		req := newRequest(loginRequest)
+		req.Header.Set("X-Forwarded-For", "203.0.113.7")
+		req.Header.Set("CF-IPCountry", "RU")
 		respRec := httptest.NewRecorder()
 
-		mockService.On("GenerateTokens", mock.Anything, mock.Anything).Return(nil, fmt.Errorf("some error"))
+		mockService.On("Login", mock.Anything, mock.Anything, mock.Anything, mock.Anything).Return(&entities.LoginResponse{TokensPair: &tokensPair}, nil)
 		mux.ServeHTTP(respRec, req)
-		require.Equal(t, http.StatusUnauthorized, respRec.Code)
-		require.Contains(t, respRec.Body.String(), "Failed to generate token pair")
+		require.Equal(t, http.StatusOK, respRec.Code)
 
-		mockService.AssertCalled(t, "GenerateTokens", userId, req.RemoteAddr)
+		mockService.AssertCalled(t, "Login", mock.Anything, "user@gmail.com", "password", &entities.ClientInfo{Ip: "203.0.113.7", Location: "RU"})
 	})
 }
 
@@ -133,7 +225,7 @@ func TestRefreshTokens(t *testing.T) {
 		req := httptest.NewRequest(http.MethodPost, baseURL, bytes.NewReader(reqBody))
 		respRec := httptest.NewRecorder()
 
-		mockService.On("RefreshTokens", mock.Anything, mock.Anything).Return(&refreshedTokens, nil)
+		mockService.On("RefreshTokens", mock.Anything, mock.Anything, mock.Anything).Return(&refreshedTokens, nil)
 		mux.ServeHTTP(respRec, req)
 		require.Equal(t, http.StatusOK, respRec.Code)
 
@@ -143,7 +235,7 @@ func TestRefreshTokens(t *testing.T) {
 		require.NoErrorf(t, err, "Ошибка парсинга JSON-ответа: %v", err)
 		require.Equal(t, refreshedTokens, actualTokensPair)
 
-		mockService.AssertCalled(t, "RefreshTokens", req.RemoteAddr, &tokensPair)
+		mockService.AssertCalled(t, "RefreshTokens", mock.Anything, &entities.ClientInfo{Ip: "192.0.2.1"}, &tokensPair)
 	})
 
 	t.Run("invalid request body", func(t *testing.T) {
@@ -226,11 +318,953 @@ func TestRefreshTokens(t *testing.T) {
 		req := httptest.NewRequest(http.MethodPost, baseURL, bytes.NewReader(reqBody))
 		respRec := httptest.NewRecorder()
 
-		mockService.On("RefreshTokens", mock.Anything, mock.Anything).Return(nil, fmt.Errorf("some error"))
+		mockService.On("RefreshTokens", mock.Anything, mock.Anything, mock.Anything).Return(nil, fmt.Errorf("wrapped: %w", services.ErrInvalidRefreshToken))
+		mux.ServeHTTP(respRec, req)
+		require.Equal(t, http.StatusUnauthorized, respRec.Code)
+		require.Contains(t, respRec.Body.String(), "Refresh token is invalid")
+		require.Contains(t, respRec.Body.String(), "invalid_token")
+
+		mockService.AssertCalled(t, "RefreshTokens", mock.Anything, &entities.ClientInfo{Ip: "192.0.2.1"}, &tokensPair)
+	})
+	t.Run("expired refresh token", func(t *testing.T) {
+		t.Cleanup(func() { mockService.ExpectedCalls = nil })
+
+		reqBody, err := json.Marshal(tokensPair)
+		require.NoError(t, err)
+
+		req := httptest.NewRequest(http.MethodPost, baseURL, bytes.NewReader(reqBody))
+		respRec := httptest.NewRecorder()
+
+		mockService.On("RefreshTokens", mock.Anything, mock.Anything, mock.Anything).Return(nil, fmt.Errorf("wrapped: %w", services.ErrRefreshTokenExpired))
+		mux.ServeHTTP(respRec, req)
+		require.Equal(t, http.StatusUnauthorized, respRec.Code)
+		require.Contains(t, respRec.Body.String(), "Refresh token is expired")
+	})
+	t.Run("disabled user", func(t *testing.T) {
+		t.Cleanup(func() { mockService.ExpectedCalls = nil })
+
+		reqBody, err := json.Marshal(tokensPair)
+		require.NoError(t, err)
+
+		req := httptest.NewRequest(http.MethodPost, baseURL, bytes.NewReader(reqBody))
+		respRec := httptest.NewRecorder()
+
+		mockService.On("RefreshTokens", mock.Anything, mock.Anything, mock.Anything).Return(nil, fmt.Errorf("wrapped: %w", services.ErrUserDisabled))
+		mux.ServeHTTP(respRec, req)
+		require.Equal(t, http.StatusForbidden, respRec.Code)
+		require.Contains(t, respRec.Body.String(), "User is disabled")
+	})
+}
+
+// TestLogout проверяет работу обработчика Logout.
+func TestLogout(t *testing.T) {
+	mockService := service_mocks.NewAuthServiceInterface(t)
+	handler := RegisterAuthHandler(mockService)
+	mux := http.NewServeMux()
+	mux.HandleFunc("/api/auth/logout", handler.Logout())
+	testURL := "/api/auth/logout"
+	tokensPair := entities.TokensPair{
+		AccessToken:  "access-token",
+		RefreshToken: "refresh-token",
+	}
+
+	t.Run("successful logout", func(t *testing.T) {
+		t.Cleanup(func() { mockService.ExpectedCalls = nil })
+
+		reqBody, err := json.Marshal(tokensPair)
+		require.NoError(t, err)
+
+		req := httptest.NewRequest(http.MethodPost, testURL, bytes.NewBuffer(reqBody))
+		respRec := httptest.NewRecorder()
+
+		mockService.On("Logout", mock.Anything, &tokensPair).Return(nil)
+		mux.ServeHTTP(respRec, req)
+		require.Equal(t, http.StatusNoContent, respRec.Code)
+	})
+	t.Run("empty refresh token", func(t *testing.T) {
+		t.Cleanup(func() { mockService.ExpectedCalls = nil })
+
+		reqBody, err := json.Marshal(entities.TokensPair{AccessToken: "access-token"})
+		require.NoError(t, err)
+
+		req := httptest.NewRequest(http.MethodPost, testURL, bytes.NewBuffer(reqBody))
+		respRec := httptest.NewRecorder()
+
+		mux.ServeHTTP(respRec, req)
+		require.Equal(t, http.StatusBadRequest, respRec.Code)
+		require.Contains(t, respRec.Body.String(), "Refresh token is required")
+
+		mockService.AssertNotCalled(t, "Logout")
+	})
+	t.Run("unsuccessful logout", func(t *testing.T) {
+		t.Cleanup(func() { mockService.ExpectedCalls = nil })
+
+		reqBody, err := json.Marshal(tokensPair)
+		require.NoError(t, err)
+
+		req := httptest.NewRequest(http.MethodPost, testURL, bytes.NewBuffer(reqBody))
+		respRec := httptest.NewRecorder()
+
+		mockService.On("Logout", mock.Anything, &tokensPair).Return(fmt.Errorf("wrapped: %w", services.ErrInvalidRefreshToken))
+		mux.ServeHTTP(respRec, req)
+		require.Equal(t, http.StatusUnauthorized, respRec.Code)
+		require.Contains(t, respRec.Body.String(), "Refresh token is invalid")
+	})
+}
+
+// TestLogoutAll проверяет работу обработчика LogoutAll.
+func TestLogoutAll(t *testing.T) {
+	mockService := service_mocks.NewAuthServiceInterface(t)
+	handler := RegisterAuthHandler(mockService)
+	mux := http.NewServeMux()
+	mux.HandleFunc("/api/auth/logout-all", handler.LogoutAll())
+	testURL := "/api/auth/logout-all"
+
+	t.Run("successful logout from all sessions", func(t *testing.T) {
+		t.Cleanup(func() { mockService.ExpectedCalls = nil })
+
+		req := httptest.NewRequest(http.MethodPost, testURL, nil)
+		req.Header.Set("Authorization", "Bearer access-token")
+		respRec := httptest.NewRecorder()
+
+		mockService.On("LogoutAll", mock.Anything, "access-token").Return(nil)
+		mux.ServeHTTP(respRec, req)
+		require.Equal(t, http.StatusNoContent, respRec.Code)
+	})
+	t.Run("authorization header is empty", func(t *testing.T) {
+		t.Cleanup(func() { mockService.ExpectedCalls = nil })
+
+		req := httptest.NewRequest(http.MethodPost, testURL, nil)
+		respRec := httptest.NewRecorder()
+
+		mux.ServeHTTP(respRec, req)
+		require.Equal(t, http.StatusUnauthorized, respRec.Code)
+		require.Contains(t, respRec.Body.String(), "Access token is required")
+
+		mockService.AssertNotCalled(t, "LogoutAll")
+	})
+	t.Run("invalid access token", func(t *testing.T) {
+		t.Cleanup(func() { mockService.ExpectedCalls = nil })
+
+		req := httptest.NewRequest(http.MethodPost, testURL, nil)
+		req.Header.Set("Authorization", "Bearer access-token")
+		respRec := httptest.NewRecorder()
+
+		mockService.On("LogoutAll", mock.Anything, "access-token").Return(fmt.Errorf("%w: %w", services.ErrInvalidAccessToken, services.ErrAccessTokenExpired))
+		mux.ServeHTTP(respRec, req)
+		require.Equal(t, http.StatusUnauthorized, respRec.Code)
+		require.Contains(t, respRec.Body.String(), "token_expired")
+	})
+}
+
+// TestListSessions проверяет работу обработчика ListSessions.
+func TestListSessions(t *testing.T) {
+	mockService := service_mocks.NewAuthServiceInterface(t)
+	handler := RegisterAuthHandler(mockService)
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /api/auth/sessions", handler.ListSessions())
+	testURL := "/api/auth/sessions"
+	now := time.Now().UTC().Truncate(time.Second)
+	sessions := []*entities.Session{
+		{
+			Jti:        "jti123",
+			DeviceName: "Work laptop",
+			UserAgent:  "test-agent",
+			Ip:         "192.168.0.1",
+			CreatedAt:  now,
+			LastUsedAt: now,
+			ExpiredAt:  now.Add(24 * time.Hour),
+			Current:    true,
+		},
+	}
+
+	t.Run("successful listed sessions", func(t *testing.T) {
+		t.Cleanup(func() { mockService.ExpectedCalls = nil })
+
+		req := httptest.NewRequest(http.MethodGet, testURL, nil)
+		req.Header.Set("Authorization", "Bearer access-token")
+		respRec := httptest.NewRecorder()
+
+		mockService.On("ListSessions", mock.Anything, "access-token").Return(sessions, nil)
+		mux.ServeHTTP(respRec, req)
+		require.Equal(t, http.StatusOK, respRec.Code)
+
+		var actualSessions []*entities.Session
+		err := json.NewDecoder(respRec.Body).Decode(&actualSessions)
+		require.NoErrorf(t, err, "Ошибка парсинга JSON-ответа: %v", err)
+		require.Equal(t, sessions, actualSessions)
+	})
+	t.Run("authorization header is empty", func(t *testing.T) {
+		t.Cleanup(func() { mockService.ExpectedCalls = nil })
+
+		req := httptest.NewRequest(http.MethodGet, testURL, nil)
+		respRec := httptest.NewRecorder()
+
+		mux.ServeHTTP(respRec, req)
+		require.Equal(t, http.StatusUnauthorized, respRec.Code)
+
+		mockService.AssertNotCalled(t, "ListSessions")
+	})
+	t.Run("invalid access token", func(t *testing.T) {
+		t.Cleanup(func() { mockService.ExpectedCalls = nil })
+
+		req := httptest.NewRequest(http.MethodGet, testURL, nil)
+		req.Header.Set("Authorization", "Bearer access-token")
+		respRec := httptest.NewRecorder()
+
+		mockService.On("ListSessions", mock.Anything, "access-token").Return(nil, fmt.Errorf("wrapped: %w", services.ErrInvalidAccessToken))
+		mux.ServeHTTP(respRec, req)
+		require.Equal(t, http.StatusUnauthorized, respRec.Code)
+		require.Contains(t, respRec.Body.String(), "Access token is invalid")
+	})
+}
+
+// TestRevokeSession проверяет работу обработчика RevokeSession.
+func TestRevokeSession(t *testing.T) {
+	mockService := service_mocks.NewAuthServiceInterface(t)
+	handler := RegisterAuthHandler(mockService)
+	mux := http.NewServeMux()
+	mux.HandleFunc("DELETE /api/auth/sessions/{jti}", handler.RevokeSession())
+	testURL := "/api/auth/sessions/jti123"
+
+	t.Run("successful revoked session", func(t *testing.T) {
+		t.Cleanup(func() { mockService.ExpectedCalls = nil })
+
+		req := httptest.NewRequest(http.MethodDelete, testURL, nil)
+		req.Header.Set("Authorization", "Bearer access-token")
+		respRec := httptest.NewRecorder()
+
+		mockService.On("RevokeSession", mock.Anything, "access-token", "jti123").Return(nil)
+		mux.ServeHTTP(respRec, req)
+		require.Equal(t, http.StatusNoContent, respRec.Code)
+	})
+	t.Run("invalid authorization scheme", func(t *testing.T) {
+		t.Cleanup(func() { mockService.ExpectedCalls = nil })
+
+		req := httptest.NewRequest(http.MethodDelete, testURL, nil)
+		req.Header.Set("Authorization", "Basic access-token")
+		respRec := httptest.NewRecorder()
+
+		mux.ServeHTTP(respRec, req)
+		require.Equal(t, http.StatusUnauthorized, respRec.Code)
+
+		mockService.AssertNotCalled(t, "RevokeSession")
+	})
+	t.Run("session not found", func(t *testing.T) {
+		t.Cleanup(func() { mockService.ExpectedCalls = nil })
+
+		req := httptest.NewRequest(http.MethodDelete, testURL, nil)
+		req.Header.Set("Authorization", "Bearer access-token")
+		respRec := httptest.NewRecorder()
+
+		mockService.On("RevokeSession", mock.Anything, "access-token", "jti123").Return(fmt.Errorf("wrapped: %w", services.ErrSessionNotFound))
+		mux.ServeHTTP(respRec, req)
+		require.Equal(t, http.StatusNotFound, respRec.Code)
+		require.Contains(t, respRec.Body.String(), "session_not_found")
+	})
+}
+
+// TestIntrospect проверяет работу обработчика Introspect.
+func TestIntrospect(t *testing.T) {
+	mockService := service_mocks.NewAuthServiceInterface(t)
+	handler := RegisterAuthHandler(mockService)
+	mux := http.NewServeMux()
+	mux.HandleFunc("/api/auth/introspect", handler.Introspect())
+	testURL := "/api/auth/introspect"
+	introspectionResp := &entities.IntrospectionResponse{
+		Active:    true,
+		Sub:       "123",
+		Exp:       1700003600,
+		Iat:       1700000000,
+		Jti:       "jti123",
+		Scope:     "auth",
+		ClientId:  "gateway",
+		TokenType: "access_token",
+	}
+
+	newRequest := func(form url.Values) *http.Request {
+		req := httptest.NewRequest(http.MethodPost, testURL, strings.NewReader(form.Encode()))
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		return req
+	}
+
+	t.Run("successful introspection with basic auth", func(t *testing.T) {
+		t.Cleanup(func() { mockService.ExpectedCalls = nil })
+
+		req := newRequest(url.Values{"token": {"access-token"}, "token_type_hint": {"access_token"}})
+		req.SetBasicAuth("gateway", "gateway_secret")
+		respRec := httptest.NewRecorder()
+
+		mockService.On("AuthenticateClient", "gateway", "gateway_secret").Return(nil)
+		mockService.On("Introspect", mock.Anything, "gateway", "access-token", "access_token").Return(introspectionResp, nil)
+		mux.ServeHTTP(respRec, req)
+		require.Equal(t, http.StatusOK, respRec.Code)
+		require.Equal(t, "no-store", respRec.Header().Get("Cache-Control"))
+
+		var actualResp entities.IntrospectionResponse
+		err := json.NewDecoder(respRec.Body).Decode(&actualResp)
+		require.NoErrorf(t, err, "Ошибка парсинга JSON-ответа: %v", err)
+		require.Equal(t, introspectionResp, &actualResp)
+	})
+	t.Run("client credentials in form body", func(t *testing.T) {
+		t.Cleanup(func() { mockService.ExpectedCalls = nil })
+
+		req := newRequest(url.Values{"token": {"refresh-token"}, "client_id": {"gateway"}, "client_secret": {"gateway_secret"}})
+		respRec := httptest.NewRecorder()
+
+		mockService.On("AuthenticateClient", "gateway", "gateway_secret").Return(nil)
+		mockService.On("Introspect", mock.Anything, "gateway", "refresh-token", "").Return(&entities.IntrospectionResponse{Active: false}, nil)
+		mux.ServeHTTP(respRec, req)
+		require.Equal(t, http.StatusOK, respRec.Code)
+		require.JSONEq(t, `{"active": false}`, respRec.Body.String())
+	})
+	t.Run("invalid client credentials", func(t *testing.T) {
+		t.Cleanup(func() { mockService.ExpectedCalls = nil })
+
+		req := newRequest(url.Values{"token": {"access-token"}})
+		req.SetBasicAuth("gateway", "wrong_secret")
+		respRec := httptest.NewRecorder()
+
+		mockService.On("AuthenticateClient", "gateway", "wrong_secret").Return(services.ErrInvalidClient)
+		mux.ServeHTTP(respRec, req)
+		require.Equal(t, http.StatusUnauthorized, respRec.Code)
+		require.NotEmpty(t, respRec.Header().Get("WWW-Authenticate"))
+
+		mockService.AssertNotCalled(t, "Introspect")
+	})
+	t.Run("empty token", func(t *testing.T) {
+		t.Cleanup(func() { mockService.ExpectedCalls = nil })
+
+		req := newRequest(url.Values{})
+		req.SetBasicAuth("gateway", "gateway_secret")
+		respRec := httptest.NewRecorder()
+
+		mockService.On("AuthenticateClient", "gateway", "gateway_secret").Return(nil)
+		mux.ServeHTTP(respRec, req)
+		require.Equal(t, http.StatusBadRequest, respRec.Code)
+		require.Contains(t, respRec.Body.String(), "Token is required")
+
+		mockService.AssertNotCalled(t, "Introspect")
+	})
+}
+
+// TestRevoke проверяет работу обработчика Revoke.
+func TestRevoke(t *testing.T) {
+	mockService := service_mocks.NewAuthServiceInterface(t)
+	handler := RegisterAuthHandler(mockService)
+	mux := http.NewServeMux()
+	mux.HandleFunc("/revoke", handler.Revoke())
+	testURL := "/revoke"
+
+	newRequest := func(form url.Values) *http.Request {
+		req := httptest.NewRequest(http.MethodPost, testURL, strings.NewReader(form.Encode()))
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		return req
+	}
+
+	t.Run("successful revoked token", func(t *testing.T) {
+		t.Cleanup(func() { mockService.ExpectedCalls = nil })
+
+		req := newRequest(url.Values{"token": {"refresh-token"}, "token_type_hint": {"refresh_token"}})
+		req.SetBasicAuth("gateway", "gateway_secret")
+		respRec := httptest.NewRecorder()
+
+		mockService.On("AuthenticateClient", "gateway", "gateway_secret").Return(nil)
+		mockService.On("Revoke", mock.Anything, "refresh-token", "refresh_token").Return(nil)
+		mux.ServeHTTP(respRec, req)
+		require.Equal(t, http.StatusOK, respRec.Code)
+	})
+	t.Run("successful revoked token with form credentials", func(t *testing.T) {
+		t.Cleanup(func() { mockService.ExpectedCalls = nil })
+
+		req := newRequest(url.Values{"token": {"access-token"}, "client_id": {"gateway"}, "client_secret": {"gateway_secret"}})
+		respRec := httptest.NewRecorder()
+
+		mockService.On("AuthenticateClient", "gateway", "gateway_secret").Return(nil)
+		mockService.On("Revoke", mock.Anything, "access-token", "").Return(nil)
+		mux.ServeHTTP(respRec, req)
+		require.Equal(t, http.StatusOK, respRec.Code)
+	})
+	t.Run("invalid client credentials", func(t *testing.T) {
+		t.Cleanup(func() { mockService.ExpectedCalls = nil })
+
+		req := newRequest(url.Values{"token": {"refresh-token"}})
+		req.SetBasicAuth("gateway", "wrong_secret")
+		respRec := httptest.NewRecorder()
+
+		mockService.On("AuthenticateClient", "gateway", "wrong_secret").Return(services.ErrInvalidClient)
+		mux.ServeHTTP(respRec, req)
+		require.Equal(t, http.StatusUnauthorized, respRec.Code)
+		require.Equal(t, `Basic realm="revoke"`, respRec.Header().Get("WWW-Authenticate"))
+		require.Contains(t, respRec.Body.String(), "invalid_client")
+
+		mockService.AssertNotCalled(t, "Revoke")
+	})
+	t.Run("missing client credentials", func(t *testing.T) {
+		t.Cleanup(func() { mockService.ExpectedCalls = nil })
+
+		req := newRequest(url.Values{"token": {"refresh-token"}})
+		respRec := httptest.NewRecorder()
+
+		mockService.On("AuthenticateClient", "", "").Return(services.ErrInvalidClient)
+		mux.ServeHTTP(respRec, req)
+		require.Equal(t, http.StatusUnauthorized, respRec.Code)
+
+		mockService.AssertNotCalled(t, "Revoke")
+	})
+	t.Run("empty token", func(t *testing.T) {
+		t.Cleanup(func() { mockService.ExpectedCalls = nil })
+
+		req := newRequest(url.Values{"token_type_hint": {"access_token"}})
+		req.SetBasicAuth("gateway", "gateway_secret")
+		respRec := httptest.NewRecorder()
+
+		mockService.On("AuthenticateClient", "gateway", "gateway_secret").Return(nil)
+		mux.ServeHTTP(respRec, req)
+		require.Equal(t, http.StatusBadRequest, respRec.Code)
+		require.Contains(t, respRec.Body.String(), "Token is required")
+
+		mockService.AssertNotCalled(t, "Revoke")
+	})
+	t.Run("storage is unavailable", func(t *testing.T) {
+		t.Cleanup(func() { mockService.ExpectedCalls = nil })
+
+		req := newRequest(url.Values{"token": {"access-token"}})
+		req.SetBasicAuth("gateway", "gateway_secret")
+		respRec := httptest.NewRecorder()
+
+		mockService.On("AuthenticateClient", "gateway", "gateway_secret").Return(nil)
+		mockService.On("Revoke", mock.Anything, "access-token", "").Return(fmt.Errorf("%w: connection refused", storage.ErrStorageUnavailable))
+		mux.ServeHTTP(respRec, req)
+		require.Equal(t, http.StatusServiceUnavailable, respRec.Code)
+		require.NotEmpty(t, respRec.Header().Get("Retry-After"))
+	})
+}
+
+// TestCheckKillLink проверяет работу обработчика CheckKillLink.
+func TestCheckKillLink(t *testing.T) {
+	mockService := service_mocks.NewAuthServiceInterface(t)
+	handler := RegisterAuthHandler(mockService)
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /api/auth/kill-link", handler.CheckKillLink())
+	testURL := "/api/auth/kill-link"
+
+	t.Run("valid link", func(t *testing.T) {
+		t.Cleanup(func() { mockService.ExpectedCalls = nil })
+
+		req := httptest.NewRequest(http.MethodGet, testURL+"?token=link-token", nil)
+		respRec := httptest.NewRecorder()
+
+		mockService.On("CheckKillLink", "link-token").Return(nil)
+		mux.ServeHTTP(respRec, req)
+		require.Equal(t, http.StatusOK, respRec.Code)
+		require.Equal(t, "no-store", respRec.Header().Get("Cache-Control"))
+		require.Contains(t, respRec.Body.String(), `<form method="POST" action="/api/auth/kill-link">`)
+		require.Contains(t, respRec.Body.String(), `value="link-token"`)
+	})
+	t.Run("invalid link", func(t *testing.T) {
+		t.Cleanup(func() { mockService.ExpectedCalls = nil })
+
+		req := httptest.NewRequest(http.MethodGet, testURL+"?token=forged", nil)
+		respRec := httptest.NewRecorder()
+
+		mockService.On("CheckKillLink", "forged").Return(fmt.Errorf("bad signature: %w", services.ErrInvalidKillLink))
+		mux.ServeHTTP(respRec, req)
+		require.Equal(t, http.StatusBadRequest, respRec.Code)
+		require.Contains(t, respRec.Body.String(), "This link is invalid or has expired.")
+		require.NotContains(t, respRec.Body.String(), "<form")
+	})
+}
+
+// TestUseKillLink проверяет работу обработчика UseKillLink.
+func TestUseKillLink(t *testing.T) {
+	mockService := service_mocks.NewAuthServiceInterface(t)
+	handler := RegisterAuthHandler(mockService)
+	mux := http.NewServeMux()
+	mux.HandleFunc("POST /api/auth/kill-link", handler.UseKillLink())
+	testURL := "/api/auth/kill-link"
+
+	newRequest := func(form url.Values) *http.Request {
+		req := httptest.NewRequest(http.MethodPost, testURL, strings.NewReader(form.Encode()))
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		return req
+	}
+
+	tests := []struct {
+		name         string
+		serviceErr   error
+		expectedCode int
+		expectedBody string
+	}{
+		{name: "sessions revoked", expectedCode: http.StatusOK, expectedBody: "The sessions have been signed out."},
+		{name: "invalid link", serviceErr: services.ErrInvalidKillLink, expectedCode: http.StatusBadRequest,
+			expectedBody: "This link is invalid or has expired."},
+		{name: "link already used", serviceErr: fmt.Errorf("presented again: %w", services.ErrKillLinkUsed),
+			expectedCode: http.StatusGone, expectedBody: "This link has already been used."},
+		{name: "storage is unavailable", serviceErr: fmt.Errorf("%w: connection refused", storage.ErrStorageUnavailable),
+			expectedCode: http.StatusServiceUnavailable, expectedBody: `"error":"temporarily_unavailable"`},
+		{name: "unexpected error", serviceErr: fmt.Errorf("failed to parse kill link token: env 'KILL_LINK_SECRET' is not set"),
+			expectedCode: http.StatusInternalServerError, expectedBody: `"error":"server_error"`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Cleanup(func() { mockService.ExpectedCalls = nil })
+
+			req := newRequest(url.Values{"token": {"link-token"}})
+			req.Header.Set("User-Agent", "Mozilla/5.0")
+			respRec := httptest.NewRecorder()
+
+			mockService.On("UseKillLink", mock.Anything, "link-token", mock.Anything).Return(tt.serviceErr)
+			mux.ServeHTTP(respRec, req)
+			require.Equal(t, tt.expectedCode, respRec.Code)
+			require.Contains(t, respRec.Body.String(), tt.expectedBody)
+
+			mockService.AssertCalled(t, "UseKillLink", mock.Anything, "link-token", &entities.ClientInfo{Ip: "192.0.2.1", UserAgent: "Mozilla/5.0"})
+		})
+	}
+}
+
+// TestRegister проверяет работу обработчика Register.
+func TestRegister(t *testing.T) {
+	mockService := service_mocks.NewAuthServiceInterface(t)
+	handler := RegisterAuthHandler(mockService)
+	mux := http.NewServeMux()
+	mux.HandleFunc("/api/auth/register", handler.Register())
+	testURL := "/api/auth/register"
+	registerRequest := entities.RegisterRequest{Email: "user@gmail.com", Password: "password", Locale: "en"}
+
+	newRequest := func(body any) *http.Request {
+		reqBody, err := json.Marshal(body)
+		require.NoError(t, err)
+		return httptest.NewRequest(http.MethodPost, testURL, bytes.NewReader(reqBody))
+	}
+
+	t.Run("successful registration", func(t *testing.T) {
+		t.Cleanup(func() { mockService.ExpectedCalls = nil })
+
+		respRec := httptest.NewRecorder()
+
+		mockService.On("Register", mock.Anything, "user@gmail.com", "password", "en").Return("user-id", nil)
+		mux.ServeHTTP(respRec, newRequest(registerRequest))
+		require.Equal(t, http.StatusCreated, respRec.Code)
+
+		var response entities.RegisterResponse
+
+		err := json.NewDecoder(respRec.Body).Decode(&response)
+		require.NoErrorf(t, err, "Ошибка парсинга JSON-ответа: %v", err)
+		require.Equal(t, "user-id", response.UserId)
+	})
+	t.Run("invalid request", func(t *testing.T) {
+		t.Cleanup(func() { mockService.ExpectedCalls = nil })
+
+		tests := []struct {
+			name         string
+			body         any
+			expectedBody string
+		}{
+			{name: "invalid JSON", body: "not an object", expectedBody: "Invalid JSON"},
+			{name: "too long password", body: entities.RegisterRequest{Email: "user@gmail.com", Password: strings.Repeat("a", maxPasswordLength+1)},
+				expectedBody: "Password is too long"},
+		}
+		for _, tt := range tests {
+			respRec := httptest.NewRecorder()
+
+			mux.ServeHTTP(respRec, newRequest(tt.body))
+			require.Equal(t, http.StatusBadRequest, respRec.Code, tt.name)
+			require.Contains(t, respRec.Body.String(), tt.expectedBody, tt.name)
+		}
+
+		mockService.AssertNotCalled(t, "Register")
+	})
+	t.Run("service errors", func(t *testing.T) {
+		tests := []struct {
+			name         string
+			err          error
+			expectedCode int
+			expectedBody string
+		}{
+			{name: "invalid email", err: services.ErrInvalidEmail, expectedCode: http.StatusBadRequest, expectedBody: "Invalid email"},
+			{name: "weak password", err: services.ErrWeakPassword, expectedCode: http.StatusBadRequest, expectedBody: "Password is too short"},
+			{name: "email taken", err: services.ErrEmailTaken, expectedCode: http.StatusConflict, expectedBody: "Email is already registered"},
+			{name: "storage is unavailable", err: fmt.Errorf("%w: connection refused", storage.ErrStorageUnavailable), expectedCode: http.StatusServiceUnavailable,
+				expectedBody: "Failed to register"},
+		}
+		for _, tt := range tests {
+			respRec := httptest.NewRecorder()
+
+			mockService.On("Register", mock.Anything, mock.Anything, mock.Anything, mock.Anything).Return("", fmt.Errorf("wrapped: %w", tt.err)).Once()
+			mux.ServeHTTP(respRec, newRequest(registerRequest))
+			require.Equal(t, tt.expectedCode, respRec.Code, tt.name)
+			require.Contains(t, respRec.Body.String(), tt.expectedBody, tt.name)
+		}
+	})
+}
+
+// TestVerifyEmail проверяет работу обработчика VerifyEmail.
+func TestVerifyEmail(t *testing.T) {
+	mockService := service_mocks.NewAuthServiceInterface(t)
+	handler := RegisterAuthHandler(mockService)
+	mux := http.NewServeMux()
+	mux.HandleFunc("/api/auth/verify-email", handler.VerifyEmail())
+	testURL := "/api/auth/verify-email"
+
+	newRequest := func(body string) *http.Request {
+		return httptest.NewRequest(http.MethodPost, testURL, strings.NewReader(body))
+	}
+
+	t.Run("successful verification", func(t *testing.T) {
+		t.Cleanup(func() { mockService.ExpectedCalls = nil })
+
+		respRec := httptest.NewRecorder()
+
+		mockService.On("VerifyEmail", mock.Anything, "token").Return(nil)
+		mux.ServeHTTP(respRec, newRequest(`{"token":"token"}`))
+		require.Equal(t, http.StatusNoContent, respRec.Code)
+	})
+	t.Run("invalid JSON", func(t *testing.T) {
+		respRec := httptest.NewRecorder()
+
+		mux.ServeHTTP(respRec, newRequest("not an object"))
+		require.Equal(t, http.StatusBadRequest, respRec.Code)
+		mockService.AssertNotCalled(t, "VerifyEmail")
+	})
+	t.Run("invalid token", func(t *testing.T) {
+		t.Cleanup(func() { mockService.ExpectedCalls = nil })
+
+		respRec := httptest.NewRecorder()
+
+		mockService.On("VerifyEmail", mock.Anything, "token").Return(fmt.Errorf("wrapped: %w", services.ErrInvalidOneTimeToken))
+		mux.ServeHTTP(respRec, newRequest(`{"token":"token"}`))
+		require.Equal(t, http.StatusBadRequest, respRec.Code)
+		require.Contains(t, respRec.Body.String(), "Invalid or expired token")
+	})
+	t.Run("storage is unavailable", func(t *testing.T) {
+		t.Cleanup(func() { mockService.ExpectedCalls = nil })
+
+		respRec := httptest.NewRecorder()
+
+		mockService.On("VerifyEmail", mock.Anything, "token").Return(fmt.Errorf("%w: connection refused", storage.ErrStorageUnavailable))
+		mux.ServeHTTP(respRec, newRequest(`{"token":"token"}`))
+		require.Equal(t, http.StatusServiceUnavailable, respRec.Code)
+	})
+}
+
+// TestForgotPassword проверяет работу обработчика ForgotPassword.
+func TestForgotPassword(t *testing.T) {
+	mockService := service_mocks.NewAuthServiceInterface(t)
+	handler := RegisterAuthHandler(mockService)
+	mux := http.NewServeMux()
+	mux.HandleFunc("/api/auth/password/forgot", handler.ForgotPassword())
+	testURL := "/api/auth/password/forgot"
+
+	newRequest := func(body string) *http.Request {
+		return httptest.NewRequest(http.MethodPost, testURL, strings.NewReader(body))
+	}
+
+	t.Run("accepted", func(t *testing.T) {
+		t.Cleanup(func() { mockService.ExpectedCalls = nil })
+
+		respRec := httptest.NewRecorder()
+
+		mockService.On("ForgotPassword", mock.Anything, "user@gmail.com").Return(nil)
+		mux.ServeHTTP(respRec, newRequest(`{"email":"user@gmail.com"}`))
+		require.Equal(t, http.StatusAccepted, respRec.Code)
+	})
+	t.Run("storage is unavailable", func(t *testing.T) {
+		t.Cleanup(func() { mockService.ExpectedCalls = nil })
+
+		respRec := httptest.NewRecorder()
+
+		mockService.On("ForgotPassword", mock.Anything, "user@gmail.com").Return(fmt.Errorf("%w: connection refused", storage.ErrStorageUnavailable))
+		mux.ServeHTTP(respRec, newRequest(`{"email":"user@gmail.com"}`))
+		require.Equal(t, http.StatusAccepted, respRec.Code)
+	})
+	t.Run("invalid JSON", func(t *testing.T) {
+		respRec := httptest.NewRecorder()
+
+		mux.ServeHTTP(respRec, newRequest("not an object"))
+		require.Equal(t, http.StatusBadRequest, respRec.Code)
+		mockService.AssertNotCalled(t, "ForgotPassword")
+	})
+}
+
+// TestResetPassword проверяет работу обработчика ResetPassword.
+func TestResetPassword(t *testing.T) {
+	mockService := service_mocks.NewAuthServiceInterface(t)
+	handler := RegisterAuthHandler(mockService)
+	mux := http.NewServeMux()
+	mux.HandleFunc("/api/auth/password/reset", handler.ResetPassword())
+	testURL := "/api/auth/password/reset"
+	resetRequest := entities.ResetPasswordRequest{Token: "token", Password: "new password"}
+
+	newRequest := func(body any) *http.Request {
+		reqBody, err := json.Marshal(body)
+		require.NoError(t, err)
+		return httptest.NewRequest(http.MethodPost, testURL, bytes.NewReader(reqBody))
+	}
+
+	t.Run("successful reset", func(t *testing.T) {
+		t.Cleanup(func() { mockService.ExpectedCalls = nil })
+
+		respRec := httptest.NewRecorder()
+
+		mockService.On("ResetPassword", mock.Anything, "token", "new password").Return(nil)
+		mux.ServeHTTP(respRec, newRequest(resetRequest))
+		require.Equal(t, http.StatusNoContent, respRec.Code)
+	})
+	t.Run("invalid request", func(t *testing.T) {
+		tests := []struct {
+			name         string
+			body         any
+			expectedBody string
+		}{
+			{name: "invalid JSON", body: "not an object", expectedBody: "Invalid JSON"},
+			{name: "too long password", body: entities.ResetPasswordRequest{Token: "token", Password: strings.Repeat("a", maxPasswordLength+1)},
+				expectedBody: "Password is too long"},
+		}
+		for _, tt := range tests {
+			respRec := httptest.NewRecorder()
+
+			mux.ServeHTTP(respRec, newRequest(tt.body))
+			require.Equal(t, http.StatusBadRequest, respRec.Code, tt.name)
+			require.Contains(t, respRec.Body.String(), tt.expectedBody, tt.name)
+		}
+
+		mockService.AssertNotCalled(t, "ResetPassword")
+	})
+	t.Run("service errors", func(t *testing.T) {
+		tests := []struct {
+			name         string
+			err          error
+			expectedCode int
+			expectedBody string
+		}{
+			{name: "weak password", err: services.ErrWeakPassword, expectedCode: http.StatusBadRequest, expectedBody: "Password is too short"},
+			{name: "invalid token", err: services.ErrInvalidOneTimeToken, expectedCode: http.StatusBadRequest, expectedBody: "Invalid or expired token"},
+			{name: "disabled user", err: services.ErrUserDisabled, expectedCode: http.StatusForbidden, expectedBody: "User is disabled"},
+			{name: "storage is unavailable", err: fmt.Errorf("%w: connection refused", storage.ErrStorageUnavailable), expectedCode: http.StatusServiceUnavailable,
+				expectedBody: "Failed to reset password"},
+		}
+		for _, tt := range tests {
+			respRec := httptest.NewRecorder()
+
+			mockService.On("ResetPassword", mock.Anything, mock.Anything, mock.Anything).Return(fmt.Errorf("wrapped: %w", tt.err)).Once()
+			mux.ServeHTTP(respRec, newRequest(resetRequest))
+			require.Equal(t, tt.expectedCode, respRec.Code, tt.name)
+			require.Contains(t, respRec.Body.String(), tt.expectedBody, tt.name)
+		}
+	})
+}
+
+// TestMfaVerify проверяет работу обработчика MfaVerify.
+func TestMfaVerify(t *testing.T) {
+	mockService := service_mocks.NewAuthServiceInterface(t)
+	handler := RegisterAuthHandler(mockService)
+	mux := http.NewServeMux()
+	mux.HandleFunc("/api/auth/mfa/verify", handler.MfaVerify())
+	testURL := "/api/auth/mfa/verify"
+	tokensPair := entities.TokensPair{AccessToken: "access-token", RefreshToken: "refresh-token"}
+
+	newRequest := func(body string) *http.Request {
+		return httptest.NewRequest(http.MethodPost, testURL, strings.NewReader(body))
+	}
+
+	t.Run("successful verification", func(t *testing.T) {
+		t.Cleanup(func() { mockService.ExpectedCalls = nil })
+
+		respRec := httptest.NewRecorder()
+
+		mockService.On("VerifyMfa", mock.Anything, "mfa-token", "123456", mock.Anything).Return(&tokensPair, nil)
+		mux.ServeHTTP(respRec, newRequest(`{"mfa_token":"mfa-token","code":"123456"}`))
+		require.Equal(t, http.StatusOK, respRec.Code)
+		require.Equal(t, "no-store", respRec.Header().Get("Cache-Control"))
+
+		var actualTokensPair entities.TokensPair
+
+		err := json.NewDecoder(respRec.Body).Decode(&actualTokensPair)
+		require.NoErrorf(t, err, "Ошибка парсинга JSON-ответа: %v", err)
+		require.Equal(t, tokensPair, actualTokensPair)
+	})
+	t.Run("invalid request", func(t *testing.T) {
+		for _, body := range []string{"not an object", `{"mfa_token":"mfa-token"}`, `{"code":"123456"}`} {
+			respRec := httptest.NewRecorder()
+
+			mux.ServeHTTP(respRec, newRequest(body))
+			require.Equal(t, http.StatusBadRequest, respRec.Code, body)
+		}
+
+		mockService.AssertNotCalled(t, "VerifyMfa")
+	})
+	t.Run("service errors", func(t *testing.T) {
+		tests := []struct {
+			name         string
+			err          error
+			expectedCode int
+		}{
+			{name: "invalid mfa token", err: services.ErrInvalidMfaToken, expectedCode: http.StatusUnauthorized},
+			{name: "invalid code", err: services.ErrInvalidMfaCode, expectedCode: http.StatusUnauthorized},
+			{name: "disabled user", err: services.ErrUserDisabled, expectedCode: http.StatusForbidden},
+			{name: "storage is unavailable", err: fmt.Errorf("%w: connection refused", storage.ErrStorageUnavailable), expectedCode: http.StatusServiceUnavailable},
+		}
+		for _, tt := range tests {
+			respRec := httptest.NewRecorder()
+
+			mockService.On("VerifyMfa", mock.Anything, mock.Anything, mock.Anything, mock.Anything).Return(nil, fmt.Errorf("wrapped: %w", tt.err)).Once()
+			mux.ServeHTTP(respRec, newRequest(`{"mfa_token":"mfa-token","code":"123456"}`))
+			require.Equal(t, tt.expectedCode, respRec.Code, tt.name)
+		}
+	})
+}
+
+// TestEnrollTotp проверяет работу обработчиков EnrollTotp и ConfirmTotp.
+func TestEnrollTotp(t *testing.T) {
+	mockService := service_mocks.NewAuthServiceInterface(t)
+	handler := RegisterAuthHandler(mockService)
+	mux := http.NewServeMux()
+	mux.HandleFunc("/api/auth/mfa/totp/enroll", handler.EnrollTotp())
+	mux.HandleFunc("/api/auth/mfa/totp/confirm", handler.ConfirmTotp())
+
+	newRequest := func(target, body string) *http.Request {
+		req := httptest.NewRequest(http.MethodPost, target, strings.NewReader(body))
+		req.Header.Set("Authorization", "Bearer access-token")
+		return req
+	}
+
+	t.Run("enroll", func(t *testing.T) {
+		t.Cleanup(func() { mockService.ExpectedCalls = nil })
+
+		respRec := httptest.NewRecorder()
+
+		enrollment := &entities.TotpEnrollment{Secret: "SECRET", OtpauthUri: "otpauth://totp/auth_service:user@gmail.com?secret=SECRET"}
+		mockService.On("EnrollTotp", mock.Anything, "access-token").Return(enrollment, nil)
+		mux.ServeHTTP(respRec, newRequest("/api/auth/mfa/totp/enroll", ""))
+		require.Equal(t, http.StatusOK, respRec.Code)
+		require.Equal(t, "no-store", respRec.Header().Get("Cache-Control"))
+
+		var actualEnrollment entities.TotpEnrollment
+
+		err := json.NewDecoder(respRec.Body).Decode(&actualEnrollment)
+		require.NoErrorf(t, err, "Ошибка парсинга JSON-ответа: %v", err)
+		require.Equal(t, *enrollment, actualEnrollment)
+	})
+	t.Run("enroll errors", func(t *testing.T) {
+		t.Cleanup(func() { mockService.ExpectedCalls = nil })
+
+		respRec := httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodPost, "/api/auth/mfa/totp/enroll", nil)
 		mux.ServeHTTP(respRec, req)
 		require.Equal(t, http.StatusUnauthorized, respRec.Code)
-		require.Contains(t, respRec.Body.String(), "Failed to refresh Token Pairs")
 
-		mockService.AssertCalled(t, "RefreshTokens", req.RemoteAddr, &tokensPair)
+		respRec = httptest.NewRecorder()
+		mockService.On("EnrollTotp", mock.Anything, "access-token").Return(nil, fmt.Errorf("wrapped: %w", services.ErrMfaAlreadyEnabled)).Once()
+		mux.ServeHTTP(respRec, newRequest("/api/auth/mfa/totp/enroll", ""))
+		require.Equal(t, http.StatusConflict, respRec.Code)
+	})
+	t.Run("confirm", func(t *testing.T) {
+		t.Cleanup(func() { mockService.ExpectedCalls = nil })
+
+		respRec := httptest.NewRecorder()
+
+		mockService.On("ConfirmTotp", mock.Anything, "access-token", "123456").Return([]string{"code1", "code2"}, nil)
+		mux.ServeHTTP(respRec, newRequest("/api/auth/mfa/totp/confirm", `{"code":" 123456 "}`))
+		require.Equal(t, http.StatusOK, respRec.Code)
+		require.JSONEq(t, `{"recovery_codes":["code1","code2"]}`, respRec.Body.String())
+	})
+	t.Run("confirm errors", func(t *testing.T) {
+		t.Cleanup(func() { mockService.ExpectedCalls = nil })
+
+		tests := []struct {
+			name         string
+			err          error
+			expectedCode int
+			expectedErr  string
+		}{
+			{name: "invalid code", err: services.ErrInvalidMfaCode, expectedCode: http.StatusUnauthorized, expectedErr: "invalid_mfa_code"},
+			{name: "not enrolled", err: services.ErrMfaNotEnrolled, expectedCode: http.StatusConflict, expectedErr: "mfa_not_enrolled"},
+			{name: "already enabled", err: services.ErrMfaAlreadyEnabled, expectedCode: http.StatusConflict, expectedErr: "mfa_already_enabled"},
+			{name: "invalid access token", err: services.ErrInvalidAccessToken, expectedCode: http.StatusUnauthorized, expectedErr: "invalid_token"},
+		}
+		for _, tt := range tests {
+			respRec := httptest.NewRecorder()
+
+			mockService.On("ConfirmTotp", mock.Anything, mock.Anything, mock.Anything).Return(nil, fmt.Errorf("wrapped: %w", tt.err)).Once()
+			mux.ServeHTTP(respRec, newRequest("/api/auth/mfa/totp/confirm", `{"code":"123456"}`))
+			require.Equal(t, tt.expectedCode, respRec.Code, tt.name)
+
+			var resp entities.ErrorResponse
+			require.NoError(t, json.NewDecoder(respRec.Body).Decode(&resp), tt.name)
+			require.Equal(t, tt.expectedErr, resp.Error, tt.name)
+		}
+
+		respRec := httptest.NewRecorder()
+		mux.ServeHTTP(respRec, newRequest("/api/auth/mfa/totp/confirm", "not an object"))
+		require.Equal(t, http.StatusBadRequest, respRec.Code)
 	})
 }
+
+// TestJWKS проверяет работу обработчика JWKS.
+func TestJWKS(t *testing.T) {
+	mockService := service_mocks.NewAuthServiceInterface(t)
+	handler := RegisterAuthHandler(mockService)
+	mux := http.NewServeMux()
+	mux.HandleFunc("/.well-known/jwks.json", handler.JWKS())
+	jwks := &entities.JWKS{
+		Keys: []*entities.JWK{{Kty: "OKP", Use: "sig", Alg: "EdDSA", Crv: "Ed25519", X: "public-key"}},
+	}
+
+	req := httptest.NewRequest(http.MethodGet, "/.well-known/jwks.json", nil)
+	respRec := httptest.NewRecorder()
+
+	mockService.On("JWKS").Return(jwks)
+	mux.ServeHTTP(respRec, req)
+	require.Equal(t, http.StatusOK, respRec.Code)
+	require.Equal(t, "application/json", respRec.Header().Get("Content-Type"))
+
+	var actualJWKS entities.JWKS
+	err := json.NewDecoder(respRec.Body).Decode(&actualJWKS)
+	require.NoErrorf(t, err, "Ошибка парсинга JSON-ответа: %v", err)
+	require.Equal(t, jwks, &actualJWKS)
+}
+
+// TestWriteServiceError проверяет сопоставление ошибок сервиса и хранилища с HTTP-статусами и кодами ошибок.
+func TestWriteServiceError(t *testing.T) {
+	tests := []struct {
+		name         string
+		err          error
+		expectedCode int
+		expectedBody entities.ErrorResponse
+	}{
+		{name: "expired access token", err: fmt.Errorf("%w: %w", services.ErrInvalidAccessToken, services.ErrAccessTokenExpired),
+			expectedCode: http.StatusUnauthorized, expectedBody: entities.ErrorResponse{Error: "token_expired", ErrorDescription: "Access token is expired"}},
+		{name: "invalid access token", err: services.ErrInvalidAccessToken,
+			expectedCode: http.StatusUnauthorized, expectedBody: entities.ErrorResponse{Error: "invalid_token", ErrorDescription: "Access token is invalid"}},
+		{name: "reused refresh token", err: services.ErrRefreshTokenReused,
+			expectedCode: http.StatusUnauthorized, expectedBody: entities.ErrorResponse{Error: "token_reused", ErrorDescription: "Refresh token reuse detected, session has been revoked"}},
+		{name: "disabled user", err: services.ErrUserDisabled,
+			expectedCode: http.StatusForbidden, expectedBody: entities.ErrorResponse{Error: "user_disabled", ErrorDescription: "User is disabled"}},
+		{name: "weak password", err: services.ErrWeakPassword,
+			expectedCode: http.StatusBadRequest, expectedBody: entities.ErrorResponse{Error: "weak_password", ErrorDescription: "Password is too short"}},
+		{name: "email taken", err: services.ErrEmailTaken,
+			expectedCode: http.StatusConflict, expectedBody: entities.ErrorResponse{Error: "email_taken", ErrorDescription: "Email is already registered"}},
+		{name: "storage is unavailable", err: fmt.Errorf("%w: connection refused", storage.ErrStorageUnavailable),
+			expectedCode: http.StatusServiceUnavailable, expectedBody: entities.ErrorResponse{Error: "temporarily_unavailable", ErrorDescription: "Failed"}},
+		{name: "deadline exceeded", err: context.DeadlineExceeded,
+			expectedCode: http.StatusServiceUnavailable, expectedBody: entities.ErrorResponse{Error: "temporarily_unavailable", ErrorDescription: "Failed"}},
+		{name: "unexpected error", err: fmt.Errorf("some error"),
+			expectedCode: http.StatusInternalServerError, expectedBody: entities.ErrorResponse{Error: "server_error", ErrorDescription: "Failed"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			respRec := httptest.NewRecorder()
+
+			writeServiceError(respRec, fmt.Errorf("wrapped: %w", tt.err), "Failed")
+			require.Equal(t, tt.expectedCode, respRec.Code)
+			require.Equal(t, "application/json", respRec.Header().Get("Content-Type"))
+
+			var actualBody entities.ErrorResponse
+			err := json.NewDecoder(respRec.Body).Decode(&actualBody)
+			require.NoErrorf(t, err, "Ошибка парсинга JSON-ответа: %v", err)
+			require.Equal(t, tt.expectedBody, actualBody)
+		})
+	}
+}
@@ -0,0 +1,94 @@
+package handlers
+
+import (
+	"auth_service/internal/services"
+	"errors"
+	"html/template"
+	"log"
+	"net/http"
+)
+
+// killLinkPage - страница подтверждения и результата перехода по ссылке "Это был не я".
+// Сессии завершаются только POST-запросом формы, чтобы ссылку не использовали почтовые сканеры,
+// которые открывают ссылки из писем GET-запросом.
+var killLinkPage = template.Must(template.New("kill-link").Parse(`<!DOCTYPE html>
+<html>
+<head><meta charset="utf-8"><meta name="robots" content="noindex"><title>Account security</title></head>
+<body>
+<p>{{.Message}}</p>
+{{- if .Token}}
+<form method="POST" action="/api/auth/kill-link">
+<input type="hidden" name="token" value="{{.Token}}">
+<button type="submit">Sign out sessions</button>
+</form>
+{{- end}}
+</body>
+</html>
+`))
+
+// killLinkPageData содержит переменные страницы ссылки "Это был не я".
+type killLinkPageData struct {
+	Message string // Текст страницы.
+	Token   string // Токен ссылки для формы подтверждения (пустой на странице результата).
+}
+
+// CheckKillLink обрабатывает GET-запрос перехода по ссылке "Это был не я" из уведомления.
+// Ожидает подписанный токен в параметре token. Возвращает страницу с формой подтверждения завершения сессий.
+func (h *AuthHandler) CheckKillLink() func(http.ResponseWriter, *http.Request) {
+	return func(w http.ResponseWriter, r *http.Request) {
+		token := r.URL.Query().Get("token")
+		if err := h.service.CheckKillLink(token); err != nil {
+			log.Println(err)
+			writeKillLinkPage(w, http.StatusBadRequest, &killLinkPageData{Message: "This link is invalid or has expired."})
+			return
+		}
+
+		writeKillLinkPage(w, http.StatusOK, &killLinkPageData{
+			Message: "If you did not perform this action, confirm to sign out the affected sessions.",
+			Token:   token,
+		})
+	}
+}
+
+// UseKillLink обрабатывает POST-запрос подтверждения со страницы ссылки "Это был не я".
+// Ожидает подписанный токен в поле формы token. Завершает сессию (или все сессии пользователя), указанную в токене.
+func (h *AuthHandler) UseKillLink() func(http.ResponseWriter, *http.Request) {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if err := r.ParseForm(); err != nil {
+			log.Println(err)
+			writeError(w, http.StatusBadRequest, codeInvalidRequest, "Invalid form body")
+			return
+		}
+
+		err := h.service.UseKillLink(r.Context(), r.PostForm.Get("token"), getClientInfo(r))
+		if errors.Is(err, services.ErrInvalidKillLink) {
+			log.Println(err)
+			writeKillLinkPage(w, http.StatusBadRequest, &killLinkPageData{Message: "This link is invalid or has expired."})
+			return
+		}
+		if errors.Is(err, services.ErrKillLinkUsed) {
+			log.Println(err)
+			writeKillLinkPage(w, http.StatusGone, &killLinkPageData{Message: "This link has already been used."})
+			return
+		}
+		if err != nil {
+			writeServiceError(w, err, "Failed to sign out sessions, please try again later")
+			return
+		}
+
+		writeKillLinkPage(w, http.StatusOK, &killLinkPageData{
+			Message: "The sessions have been signed out. We recommend changing your password.",
+		})
+	}
+}
+
+// writeKillLinkPage записывает страницу ссылки "Это был не я" с указанным статусом ответа.
+func writeKillLinkPage(w http.ResponseWriter, status int, data *killLinkPageData) {
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.Header().Set("Cache-Control", "no-store")
+	w.Header().Set("Referrer-Policy", "no-referrer")
+	w.WriteHeader(status)
+	if err := killLinkPage.Execute(w, data); err != nil {
+		log.Println(err)
+	}
+}
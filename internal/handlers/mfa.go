@@ -0,0 +1,101 @@
+package handlers
+
+import (
+	"auth_service/internal/entities"
+	"encoding/json"
+	"log"
+	"net/http"
+	"strings"
+)
+
+// MfaVerify обрабатывает POST-запрос завершения входа вторым фактором.
+// Ожидает JSON с mfa_token из ответа на запрос входа и code (код TOTP или код восстановления).
+// Возвращает JSON с новой парой токенов.
+func (h *AuthHandler) MfaVerify() func(http.ResponseWriter, *http.Request) {
+	return func(w http.ResponseWriter, r *http.Request) {
+		var req entities.MfaVerifyRequest
+
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			log.Println(err)
+			writeError(w, http.StatusBadRequest, codeInvalidRequest, "Invalid JSON")
+			return
+		}
+		if req.MfaToken == "" || req.Code == "" {
+			log.Println("mfa token or code is empty")
+			writeError(w, http.StatusBadRequest, codeInvalidRequest, "MFA token and code are required")
+			return
+		}
+
+		client := getClientInfo(r)
+		if client.Ip == "" {
+			log.Println("IP address is empty")
+			writeError(w, http.StatusBadRequest, codeInvalidRequest, "Client IP address is missing")
+			return
+		}
+
+		tokensPair, err := h.service.VerifyMfa(r.Context(), req.MfaToken, req.Code, client)
+		if err != nil {
+			writeServiceError(w, err, "Failed to verify MFA code")
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		w.Header().Set("Cache-Control", "no-store")
+		json.NewEncoder(w).Encode(tokensPair)
+	}
+}
+
+// EnrollTotp обрабатывает POST-запрос подключения TOTP.
+// Ожидает access-токен в заголовке Authorization. Возвращает JSON с секретом и ссылкой otpauth:// для QR-кода.
+func (h *AuthHandler) EnrollTotp() func(http.ResponseWriter, *http.Request) {
+	return func(w http.ResponseWriter, r *http.Request) {
+		accessToken, ok := bearerToken(r)
+		if !ok {
+			log.Println("access token is empty")
+			writeError(w, http.StatusUnauthorized, codeInvalidToken, "Access token is required")
+			return
+		}
+
+		enrollment, err := h.service.EnrollTotp(r.Context(), accessToken)
+		if err != nil {
+			writeServiceError(w, err, "Failed to enroll TOTP")
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		w.Header().Set("Cache-Control", "no-store")
+		json.NewEncoder(w).Encode(enrollment)
+	}
+}
+
+// ConfirmTotp обрабатывает POST-запрос подтверждения подключения TOTP.
+// Ожидает access-токен в заголовке Authorization и JSON с code из TOTP-приложения.
+// Возвращает JSON с кодами восстановления.
+func (h *AuthHandler) ConfirmTotp() func(http.ResponseWriter, *http.Request) {
+	return func(w http.ResponseWriter, r *http.Request) {
+		accessToken, ok := bearerToken(r)
+		if !ok {
+			log.Println("access token is empty")
+			writeError(w, http.StatusUnauthorized, codeInvalidToken, "Access token is required")
+			return
+		}
+
+		var req entities.TotpConfirmRequest
+
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			log.Println(err)
+			writeError(w, http.StatusBadRequest, codeInvalidRequest, "Invalid JSON")
+			return
+		}
+
+		recoveryCodes, err := h.service.ConfirmTotp(r.Context(), accessToken, strings.TrimSpace(req.Code))
+		if err != nil {
+			writeServiceError(w, err, "Failed to confirm TOTP")
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		w.Header().Set("Cache-Control", "no-store")
+		json.NewEncoder(w).Encode(&entities.TotpConfirmResponse{RecoveryCodes: recoveryCodes})
+	}
+}
@@ -1,6 +1,7 @@
 package handlers
 
 import (
+	"auth_service/internal/clientip"
 	"auth_service/internal/config"
 	"fmt"
 	"log"
@@ -15,8 +16,15 @@ import (
 var (
 	visitors = make(map[string]*visitor) // visitors словарь для связи ip -> visitor
 	mu       sync.Mutex
+
+	ipResolver, _ = clientip.NewResolver("") // ipResolver определяет IP-адрес клиента, по умолчанию без доверенных прокси.
 )
 
+// SetClientIPResolver задает Resolver, которым обработчики и LimiterMiddleware определяют IP-адрес клиента.
+func SetClientIPResolver(resolver *clientip.Resolver) {
+	ipResolver = resolver
+}
+
 // visitor внутренняя структура для хранения лимитера и времени последнего запроса.
 type visitor struct {
 	limiter  *rate.Limiter
@@ -79,7 +87,7 @@ func getVisitor(ip string) (*rate.Limiter, error) {
 // Если лимит превышен, возвращается ошибка 429 Too Many Requests.
 func LimiterMiddleware(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		ip := r.RemoteAddr
+		ip := ipResolver.ClientIP(r)
 
 		limiter, err := getVisitor(ip)
 		if err != nil {
@@ -87,7 +95,7 @@ func LimiterMiddleware(next http.Handler) http.Handler {
 		}
 		if err == nil && !limiter.Allow() {
 			log.Printf("too many requests for user with ip: %s\n", ip)
-			http.Error(w, fmt.Sprintf("Too Many Requests for the user: %s", ip), http.StatusTooManyRequests)
+			writeError(w, http.StatusTooManyRequests, codeRateLimited, fmt.Sprintf("Too Many Requests for the user: %s", ip))
 			return
 		}
 
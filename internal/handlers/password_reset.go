@@ -0,0 +1,57 @@
+package handlers
+
+import (
+	"auth_service/internal/entities"
+	"encoding/json"
+	"log"
+	"net/http"
+)
+
+// ForgotPassword обрабатывает POST-запрос восстановления пароля.
+// Ожидает JSON с email в теле запроса. Всегда возвращает 202, чтобы по ответу нельзя было определить,
+// зарегистрирован ли email. Письмо со ссылкой для сброса пароля отправляется асинхронно.
+func (h *AuthHandler) ForgotPassword() func(http.ResponseWriter, *http.Request) {
+	return func(w http.ResponseWriter, r *http.Request) {
+		var req entities.ForgotPasswordRequest
+
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			log.Println(err)
+			writeError(w, http.StatusBadRequest, codeInvalidRequest, "Invalid JSON")
+			return
+		}
+
+		if err := h.service.ForgotPassword(r.Context(), req.Email); err != nil {
+			log.Println(err)
+		}
+
+		w.WriteHeader(http.StatusAccepted)
+	}
+}
+
+// ResetPassword обрабатывает POST-запрос установки нового пароля.
+// Ожидает JSON с token из ссылки в письме и новым password. Возвращает 204 после смены пароля,
+// все сессии пользователя при этом завершаются.
+func (h *AuthHandler) ResetPassword() func(http.ResponseWriter, *http.Request) {
+	return func(w http.ResponseWriter, r *http.Request) {
+		var req entities.ResetPasswordRequest
+
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			log.Println(err)
+			writeError(w, http.StatusBadRequest, codeInvalidRequest, "Invalid JSON")
+			return
+		}
+		if len(req.Password) > maxPasswordLength {
+			log.Println("password is too long")
+			writeError(w, http.StatusBadRequest, codeInvalidRequest, "Password is too long")
+			return
+		}
+
+		err := h.service.ResetPassword(r.Context(), req.Token, req.Password)
+		if err != nil {
+			writeServiceError(w, err, "Failed to reset password")
+			return
+		}
+
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
@@ -0,0 +1,62 @@
+package handlers
+
+import (
+	"auth_service/internal/entities"
+	"encoding/json"
+	"log"
+	"net/http"
+)
+
+const maxLocaleLength = 35 // Максимальная длина языкового тега пользователя.
+
+// Register обрабатывает POST-запрос регистрации пользователя.
+// Ожидает JSON с email, password и необязательным locale (язык писем) в теле запроса.
+// Возвращает 201 и JSON с идентификатором пользователя, письмо для подтверждения email отправляется асинхронно.
+func (h *AuthHandler) Register() func(http.ResponseWriter, *http.Request) {
+	return func(w http.ResponseWriter, r *http.Request) {
+		var req entities.RegisterRequest
+
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			log.Println(err)
+			writeError(w, http.StatusBadRequest, codeInvalidRequest, "Invalid JSON")
+			return
+		}
+		if len(req.Password) > maxPasswordLength {
+			log.Println("password is too long")
+			writeError(w, http.StatusBadRequest, codeInvalidRequest, "Password is too long")
+			return
+		}
+
+		userId, err := h.service.Register(r.Context(), req.Email, req.Password, truncate(req.Locale, maxLocaleLength))
+		if err != nil {
+			writeServiceError(w, err, "Failed to register")
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusCreated)
+		json.NewEncoder(w).Encode(&entities.RegisterResponse{UserId: userId})
+	}
+}
+
+// VerifyEmail обрабатывает POST-запрос подтверждения email.
+// Ожидает JSON с token из ссылки в письме. Возвращает 204 при успешном подтверждении.
+func (h *AuthHandler) VerifyEmail() func(http.ResponseWriter, *http.Request) {
+	return func(w http.ResponseWriter, r *http.Request) {
+		var req entities.VerifyEmailRequest
+
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			log.Println(err)
+			writeError(w, http.StatusBadRequest, codeInvalidRequest, "Invalid JSON")
+			return
+		}
+
+		err := h.service.VerifyEmail(r.Context(), req.Token)
+		if err != nil {
+			writeServiceError(w, err, "Failed to verify email")
+			return
+		}
+
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
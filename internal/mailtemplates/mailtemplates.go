@@ -0,0 +1,179 @@
+package mailtemplates
+
+import (
+	"bytes"
+	"embed"
+	"fmt"
+	htmltemplate "html/template"
+	"io/fs"
+	"os"
+	"path"
+	"strings"
+	texttemplate "text/template"
+)
+
+// DefaultLocale используется, если язык по умолчанию не задан.
+const DefaultLocale = "ru"
+
+// Расширения файлов шаблона письма: тема, HTML-версия и текстовая версия.
+const (
+	subjectExt = ".subject.txt"
+	htmlExt    = ".html"
+	textExt    = ".txt"
+)
+
+// embeddedTemplates содержит шаблоны писем, встроенные в бинарный файл сервиса.
+//
+//go:embed templates
+var embeddedTemplates embed.FS
+
+// Email представляет письмо, подготовленное по шаблону.
+type Email struct {
+	Subject string // Тема письма.
+	Html    string // HTML-версия письма.
+	Text    string // Текстовая версия письма.
+}
+
+// emailTemplate внутренняя структура для хранения шаблонов одного письма.
+type emailTemplate struct {
+	subject *texttemplate.Template
+	html    *htmltemplate.Template
+	text    *texttemplate.Template
+}
+
+// Set хранит шаблоны писем, сгруппированные по языкам.
+// Каталог шаблонов содержит по одному подкаталогу на язык (например, ru, en), в котором
+// для каждого письма лежат три файла: <name>.subject.txt, <name>.html и <name>.txt.
+type Set struct {
+	defaultLocale string
+	locales       map[string]map[string]*emailTemplate
+}
+
+// Load загружает шаблоны писем из каталога dir.
+// Если каталог не задан, используются встроенные шаблоны. Если язык по умолчанию не задан, используется DefaultLocale.
+func Load(dir, defaultLocale string) (*Set, error) {
+	if dir == "" {
+		templates, err := fs.Sub(embeddedTemplates, "templates")
+		if err != nil {
+			return nil, fmt.Errorf("failed to open embedded templates: %w", err)
+		}
+		return LoadFS(templates, defaultLocale)
+	}
+
+	return LoadFS(os.DirFS(dir), defaultLocale)
+}
+
+// LoadFS загружает шаблоны писем из файловой системы fsys.
+// Возвращает ошибку, если шаблон не разбирается или для языка по умолчанию нет ни одного шаблона.
+func LoadFS(fsys fs.FS, defaultLocale string) (*Set, error) {
+	if defaultLocale == "" {
+		defaultLocale = DefaultLocale
+	}
+	set := &Set{
+		defaultLocale: normalizeLocale(defaultLocale),
+		locales:       make(map[string]map[string]*emailTemplate),
+	}
+
+	entries, err := fs.ReadDir(fsys, ".")
+	if err != nil {
+		return nil, fmt.Errorf("failed to read templates directory: %w", err)
+	}
+	for _, entry := range entries {
+		if !entry.IsDir() {
+			continue
+		}
+		templates, err := loadLocale(fsys, entry.Name())
+		if err != nil {
+			return nil, fmt.Errorf("failed to load templates for locale '%s': %w", entry.Name(), err)
+		}
+		set.locales[normalizeLocale(entry.Name())] = templates
+	}
+	if len(set.locales[set.defaultLocale]) == 0 {
+		return nil, fmt.Errorf("no templates found for default locale '%s'", set.defaultLocale)
+	}
+
+	return set, nil
+}
+
+// Render формирует письмо name на языке locale.
+// Если для языка нет шаблона, используется шаблон на базовом языке (en для en-US), а затем на языке по умолчанию.
+func (s *Set) Render(locale, name string, data any) (*Email, error) {
+	tmpl := s.lookup(locale, name)
+	if tmpl == nil {
+		return nil, fmt.Errorf("template '%s' was not found", name)
+	}
+
+	var subject, html, text bytes.Buffer
+	if err := tmpl.subject.Execute(&subject, data); err != nil {
+		return nil, fmt.Errorf("failed to render subject of template '%s': %w", name, err)
+	}
+	if err := tmpl.html.Execute(&html, data); err != nil {
+		return nil, fmt.Errorf("failed to render html of template '%s': %w", name, err)
+	}
+	if err := tmpl.text.Execute(&text, data); err != nil {
+		return nil, fmt.Errorf("failed to render text of template '%s': %w", name, err)
+	}
+
+	email := &Email{
+		Subject: strings.TrimSpace(subject.String()),
+		Html:    html.String(),
+		Text:    text.String(),
+	}
+
+	return email, nil
+}
+
+// lookup возвращает шаблон письма для языка с учетом запасных вариантов или nil, если шаблон не найден.
+func (s *Set) lookup(locale, name string) *emailTemplate {
+	locale = normalizeLocale(locale)
+	candidates := []string{locale}
+	if base, _, found := strings.Cut(locale, "-"); found {
+		candidates = append(candidates, base)
+	}
+	candidates = append(candidates, s.defaultLocale)
+
+	for _, candidate := range candidates {
+		if tmpl, ok := s.locales[candidate][name]; ok {
+			return tmpl
+		}
+	}
+
+	return nil
+}
+
+// loadLocale загружает шаблоны писем из подкаталога языка.
+// Каждое письмо должно содержать тему, HTML-версию и текстовую версию.
+func loadLocale(fsys fs.FS, locale string) (map[string]*emailTemplate, error) {
+	subjects, err := fs.Glob(fsys, path.Join(locale, "*"+subjectExt))
+	if err != nil {
+		return nil, err
+	}
+
+	templates := make(map[string]*emailTemplate, len(subjects))
+	for _, subjectFile := range subjects {
+		name := strings.TrimSuffix(path.Base(subjectFile), subjectExt)
+		htmlFile := path.Join(locale, name+htmlExt)
+		textFile := path.Join(locale, name+textExt)
+
+		subject, err := texttemplate.ParseFS(fsys, subjectFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse '%s': %w", subjectFile, err)
+		}
+		html, err := htmltemplate.ParseFS(fsys, htmlFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse '%s': %w", htmlFile, err)
+		}
+		text, err := texttemplate.ParseFS(fsys, textFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse '%s': %w", textFile, err)
+		}
+		templates[name] = &emailTemplate{subject: subject, html: html, text: text}
+	}
+
+	return templates, nil
+}
+
+// normalizeLocale приводит код языка к нижнему регистру и заменяет '_' на '-' (en_US -> en-us).
+func normalizeLocale(locale string) string {
+	return strings.ToLower(strings.ReplaceAll(strings.TrimSpace(locale), "_", "-"))
+}
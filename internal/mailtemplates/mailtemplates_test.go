@@ -0,0 +1,141 @@
+package mailtemplates
+
+import (
+	"testing"
+	"testing/fstest"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+// testData содержит переменные шаблонов для тестов.
+type testData struct {
+	Ip         string
+	PreviousIp string
+	UserAgent  string
+	Location   string
+	ReportUrl  string
+	ActionUrl  string
+	Time       time.Time
+}
+
+// TestLoad проверяет загрузку встроенных шаблонов и шаблонов из файловой системы.
+func TestLoad(t *testing.T) {
+	t.Run("embedded templates", func(t *testing.T) {
+		set, err := Load("", "")
+		require.NoError(t, err)
+		require.Equal(t, DefaultLocale, set.defaultLocale)
+		require.Contains(t, set.locales, "ru")
+		require.Contains(t, set.locales, "en")
+	})
+	t.Run("templates directory", func(t *testing.T) {
+		dir := t.TempDir()
+		_, err := Load(dir, "en")
+		require.ErrorContains(t, err, "no templates found for default locale 'en'")
+	})
+	t.Run("missing html version", func(t *testing.T) {
+		fsys := fstest.MapFS{
+			"en/ip_changed.subject.txt": {Data: []byte("Subject")},
+			"en/ip_changed.txt":         {Data: []byte("Text")},
+		}
+		_, err := LoadFS(fsys, "en")
+		require.ErrorContains(t, err, "en/ip_changed.html")
+	})
+	t.Run("invalid template", func(t *testing.T) {
+		fsys := fstest.MapFS{
+			"en/ip_changed.subject.txt": {Data: []byte("Subject")},
+			"en/ip_changed.html":        {Data: []byte("{{.Ip")},
+			"en/ip_changed.txt":         {Data: []byte("Text")},
+		}
+		_, err := LoadFS(fsys, "en")
+		require.ErrorContains(t, err, "failed to parse")
+	})
+}
+
+// TestRender проверяет формирование письма и выбор языка шаблона.
+func TestRender(t *testing.T) {
+	fsys := fstest.MapFS{
+		"ru/ip_changed.subject.txt": {Data: []byte("Новый IP\n")},
+		"ru/ip_changed.html":        {Data: []byte("<p>{{.Ip}} {{.UserAgent}}</p>")},
+		"ru/ip_changed.txt":         {Data: []byte("{{.Ip}} {{.UserAgent}}")},
+		"en/ip_changed.subject.txt": {Data: []byte("New IP")},
+		"en/ip_changed.html":        {Data: []byte("<p>{{.Ip}}</p>")},
+		"en/ip_changed.txt":         {Data: []byte("{{.Ip}}")},
+	}
+	set, err := LoadFS(fsys, "ru")
+	require.NoError(t, err)
+	data := &testData{Ip: "192.168.0.2", UserAgent: "<script>"}
+
+	tests := []struct {
+		name            string
+		locale          string
+		expectedSubject string
+	}{
+		{name: "exact locale", locale: "en", expectedSubject: "New IP"},
+		{name: "regional locale", locale: "en-US", expectedSubject: "New IP"},
+		{name: "underscore locale", locale: "EN_gb", expectedSubject: "New IP"},
+		{name: "unknown locale", locale: "de", expectedSubject: "Новый IP"},
+		{name: "empty locale", locale: "", expectedSubject: "Новый IP"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			email, err := set.Render(tt.locale, "ip_changed", data)
+			require.NoError(t, err)
+			require.Equal(t, tt.expectedSubject, email.Subject)
+		})
+	}
+
+	t.Run("html is escaped", func(t *testing.T) {
+		email, err := set.Render("ru", "ip_changed", data)
+		require.NoError(t, err)
+		require.Equal(t, "<p>192.168.0.2 &lt;script&gt;</p>", email.Html)
+		require.Equal(t, "192.168.0.2 <script>", email.Text)
+	})
+	t.Run("unknown template", func(t *testing.T) {
+		_, err := set.Render("ru", "unknown", data)
+		require.ErrorContains(t, err, "template 'unknown' was not found")
+	})
+}
+
+// TestEmbeddedTemplates проверяет, что встроенные шаблоны формируются со всеми переменными.
+func TestEmbeddedTemplates(t *testing.T) {
+	set, err := Load("", "")
+	require.NoError(t, err)
+	data := &testData{
+		Ip:         "192.168.0.2",
+		PreviousIp: "192.168.0.1",
+		UserAgent:  "Mozilla/5.0",
+		Location:   "Moscow, RU",
+		ReportUrl:  "https://example.com/report?token=abc&user=1",
+		ActionUrl:  "https://example.com/verify?token=abc&user=1",
+		Time:       time.Date(2025, 3, 1, 12, 30, 0, 0, time.UTC),
+	}
+
+	for _, locale := range []string{"ru", "en"} {
+		for _, name := range []string{"ip_changed", "token_reuse"} {
+			t.Run(locale+"/"+name, func(t *testing.T) {
+				email, err := set.Render(locale, name, data)
+				require.NoError(t, err)
+				require.NotEmpty(t, email.Subject)
+				for _, body := range []string{email.Html, email.Text} {
+					require.Contains(t, body, data.Ip)
+					require.Contains(t, body, data.UserAgent)
+					require.Contains(t, body, data.Location)
+					require.Contains(t, body, "12:30 UTC")
+				}
+				require.Contains(t, email.Text, data.ReportUrl)
+				require.Contains(t, email.Html, "https://example.com/report?token=abc&amp;user=1")
+			})
+		}
+
+		for _, name := range []string{"verify_email", "password_reset"} {
+			t.Run(locale+"/"+name, func(t *testing.T) {
+				email, err := set.Render(locale, name, data)
+				require.NoError(t, err)
+				require.NotEmpty(t, email.Subject)
+				require.Contains(t, email.Text, data.ActionUrl)
+				require.Contains(t, email.Html, "https://example.com/verify?token=abc&amp;user=1")
+			})
+		}
+	}
+}
@@ -1,43 +1,66 @@
 package services
 
 import (
+	"auth_service/internal/clientip"
 	"auth_service/internal/entities"
 	"auth_service/internal/storage"
+	"context"
+	"errors"
 	"fmt"
 	"log"
+	"strings"
+	"sync/atomic"
 	"time"
 )
 
+// reuseRevocationTimeout - максимальное время отзыва семейства токенов при повторном использовании refresh-токена.
+const reuseRevocationTimeout = 10 * time.Second
+
 // AuthService предоставляет методы для работы с токенами аутентификации пользователя.
 // Включает генерацию, обновление и валидацию access/refresh токенов.
 type AuthService struct {
-	storage storage.StorageInterface // Интерфейс для взаимодействия с хранилищем данных (БД или память)
+	storage  storage.StorageInterface // Интерфейс для взаимодействия с хранилищем данных (БД или память)
+	notifier Notifier                 // Способ доставки пользователю уведомлений о событиях безопасности
+
+	purgedRefreshTokens atomic.Int64 // Количество истекших refresh-токенов, удаленных фоновой очисткой
 }
 
-// NewAuthService создает новый экземпляр AuthService с указанным хранилищем.
-func NewAuthService(s storage.StorageInterface) *AuthService {
-	return &AuthService{storage: s}
+// NewAuthService создает новый экземпляр AuthService с указанным хранилищем и способом доставки уведомлений.
+func NewAuthService(s storage.StorageInterface, n Notifier) *AuthService {
+	return &AuthService{storage: s, notifier: n}
 }
 
 // GenerateTokens генерирует новую пару токенов (access и refresh) для пользователя.
-func (s *AuthService) GenerateTokens(userId, ip string) (*entities.TokensPair, error) {
+// Методы аутентификации amr включаются в access-токен и сохраняются в сессии для токенов, полученных обновлением.
+// Сведения о клиенте сохраняются вместе с refresh-токеном для отображения в списке сессий.
+// Если пользователь не найден или заблокирован, возвращает ошибку ErrUnknownUser или ErrUserDisabled,
+// если email не подтвержден и REQUIRE_EMAIL_VERIFICATION запрещает вход - ErrEmailNotVerified.
+func (s *AuthService) GenerateTokens(ctx context.Context, userId string, amr []string, client *entities.ClientInfo) (*entities.TokensPair, error) {
+	user, err := s.getActiveUser(ctx, userId)
+	if err != nil {
+		return nil, err
+	}
+	if err := checkEmailVerified(user); err != nil {
+		return nil, err
+	}
+	lifetimes, err := getTokenLifetimes()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get token lifetimes: %w", err)
+	}
 	jti, err := GenJti()
 	if err != nil {
 		return nil, fmt.Errorf("failed to generate jti: %w", err)
 	}
 
-	accessTokenClaims := &entities.AccessTokenClaims{
-		Jti:       jti,
-		UserId:    userId,
-		CreatedAt: time.Now(),
-		ExpiredAt: time.Now().Add(1 * time.Hour),
-	}
+	now := time.Now()
+	accessTokenClaims := newAccessTokenClaims(jti, userId, now, lifetimes.accessExpiry(now, now))
+	accessTokenClaims.Amr = amr
 	accessToken, err := GenAccessToken(accessTokenClaims)
 	if err != nil {
 		return nil, fmt.Errorf("failed to generate access token: %w", err)
 	}
 
-	refreshToken, err := GenRefreshToken()
+	refreshToken, err := GenRefreshToken(userId, jti)
 	if err != nil {
 		return nil, fmt.Errorf("failed to generate refresh token: %w", err)
 	}
@@ -46,13 +69,19 @@ func (s *AuthService) GenerateTokens(userId, ip string) (*entities.TokensPair, e
 		return nil, fmt.Errorf("failed to generate hash: %w", err)
 	}
 	refreshTokenRecord := &entities.RefreshTokenRecord{
-		Jti:       jti,
-		CreatedAt: time.Now(),
-		ExpiredAt: time.Now().Add(3 * 24 * time.Hour),
-		IssuedIp:  ip,
-		TokenHash: refrTokenHash,
+		Jti:              jti,
+		CreatedAt:        now,
+		ExpiredAt:        lifetimes.refreshExpiry(now, now, time.Time{}),
+		SessionStartedAt: now,
+		FamilyId:         jti,
+		IssuedIp:         client.Ip,
+		UserAgent:        client.UserAgent,
+		DeviceName:       client.DeviceName,
+		LastUsedAt:       now,
+		TokenHash:        refrTokenHash,
+		Amr:              strings.Join(amr, " "),
 	}
-	if err := s.storage.SaveRefreshTokenRecord(userId, refreshTokenRecord); err != nil {
+	if err := s.storage.SaveRefreshTokenRecord(ctx, userId, refreshTokenRecord); err != nil {
 		return nil, fmt.Errorf("failed to save refresh token: %w", err)
 	}
 
@@ -60,56 +89,71 @@ func (s *AuthService) GenerateTokens(userId, ip string) (*entities.TokensPair, e
 		AccessToken:  accessToken,
 		RefreshToken: refreshToken,
 	}
-	log.Printf("Access/refresh tokens issued for userID: '%s', jti: '%s', ip: '%s'\n", userId, jti, ip)
+	log.Printf("Access/refresh tokens issued for userID: '%s', jti: '%s', ip: '%s'\n", userId, jti, client.Ip)
 
 	return tokensPair, nil
 }
 
 // RefreshTokens обновляет пару токенов (access и refresh) для пользователя.
-// Проверяет валидность старых токенов, валидирует refresh token, при необходимости отправляет уведомление о смене IP.
-func (s *AuthService) RefreshTokens(ip string, tokensPair *entities.TokensPair) (*entities.TokensPair, error) {
-	accessTokenClaims, err := parseAccessToken(tokensPair.AccessToken)
+// Проверяет валидность старых токенов (срок действия access-токена не учитывается),
+// валидирует refresh token и его срок действия, при необходимости сохраняет в outbox уведомление о смене IP
+// в одной транзакции с обновлением токенов. Уведомление доставляется асинхронно фоновым обработчиком outbox.
+// Если refresh-токен просрочен, возвращает ошибку ErrRefreshTokenExpired.
+// Если refresh-токен уже был обменян ранее (в том числе одновременным запросом), отзывает всю сессию
+// и возвращает ошибку ErrRefreshTokenReused. Если refresh-токен не найден или не совпадает с сохраненным хэшем,
+// возвращает ошибку ErrInvalidRefreshToken, если access-токен недействителен - ErrInvalidAccessToken.
+// Если клиент не передал название устройства, сохраняется название из предыдущего токена сессии.
+// Если пользователь удален или заблокирован, возвращает ошибку ErrUnknownUser или ErrUserDisabled.
+func (s *AuthService) RefreshTokens(ctx context.Context, client *entities.ClientInfo, tokensPair *entities.TokensPair) (*entities.TokensPair, error) {
+	accessTokenClaims, err := parseExpiredAccessToken(tokensPair.AccessToken)
 	if err != nil {
 		return nil, fmt.Errorf("failed to parse access token: %w", err)
 	}
 
-	refreshTokenRecord, err := s.storage.GetRefreshTokenRecord(accessTokenClaims.Jti, accessTokenClaims.UserId)
+	refreshTokenRecord, err := s.storage.GetRefreshTokenRecord(ctx, accessTokenClaims.Jti, accessTokenClaims.UserId)
+	if errors.Is(err, storage.ErrTokenNotFound) {
+		return nil, fmt.Errorf("refresh token with jti '%s' was not found: %w", accessTokenClaims.Jti, ErrInvalidRefreshToken)
+	}
 	if err != nil {
 		return nil, fmt.Errorf("failed to get token claims: %w", err)
 	}
 	if err := checkRefreshToken(tokensPair.RefreshToken, refreshTokenRecord.TokenHash); err != nil {
 		return nil, fmt.Errorf("failed to check refresh token: %w", err)
 	}
-	if refreshTokenRecord.IssuedIp != ip {
-		if err = CheckConfigVar(); err != nil {
-			return nil, fmt.Errorf("config variable is empty: %w", err)
-		}
-		mockUserEmail, err := s.storage.GetUserEmail(accessTokenClaims.UserId)
-		if err != nil {
-			return nil, fmt.Errorf("failed to get user email: %w", err)
-		}
-		if err = SendWarningMsg(mockUserEmail, refreshTokenRecord.IssuedIp, ip); err != nil {
-			return nil, fmt.Errorf("failed to send warning message to user's Email: %w", err)
-		}
+	if refreshTokenRecord.Rotated {
+		s.revokeReusedTokenFamily(ctx, accessTokenClaims.UserId, refreshTokenRecord, client)
+		return nil, fmt.Errorf("refresh token with jti '%s' was presented again: %w", refreshTokenRecord.Jti, ErrRefreshTokenReused)
+	}
+	if time.Now().After(refreshTokenRecord.ExpiredAt) {
+		return nil, fmt.Errorf("refresh token with jti '%s' expired at %s: %w",
+			refreshTokenRecord.Jti, refreshTokenRecord.ExpiredAt.Format(time.RFC3339), ErrRefreshTokenExpired)
+	}
+	if _, err := s.getActiveUser(ctx, accessTokenClaims.UserId); err != nil {
+		return nil, err
+	}
+	lifetimes, err := getTokenLifetimes()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get token lifetimes: %w", err)
 	}
-
 	newJti, err := GenJti()
 	if err != nil {
 		return nil, fmt.Errorf("failed to generate jti: %w", err)
 	}
 
-	newAccessTokenClaims := &entities.AccessTokenClaims{
-		Jti:       newJti,
-		UserId:    accessTokenClaims.UserId,
-		CreatedAt: time.Now(),
-		ExpiredAt: time.Now().Add(1 * time.Hour),
+	now := time.Now()
+	sessionStartedAt := refreshTokenRecord.SessionStartedAt
+	deviceName := client.DeviceName
+	if deviceName == "" {
+		deviceName = refreshTokenRecord.DeviceName
 	}
-	newAccessToken, err := GenAccessToken(newAccessTokenClaims)
+	newClaims := newAccessTokenClaims(newJti, accessTokenClaims.UserId, now, lifetimes.accessExpiry(now, sessionStartedAt))
+	newClaims.Amr = strings.Fields(refreshTokenRecord.Amr)
+	newAccessToken, err := GenAccessToken(newClaims)
 	if err != nil {
 		return nil, fmt.Errorf("failed to generate access token: %w", err)
 	}
 
-	newRefreshToken, err := GenRefreshToken()
+	newRefreshToken, err := GenRefreshToken(accessTokenClaims.UserId, newJti)
 	if err != nil {
 		return nil, fmt.Errorf("failed to generate refresh token: %w", err)
 	}
@@ -118,13 +162,45 @@ func (s *AuthService) RefreshTokens(ip string, tokensPair *entities.TokensPair)
 		return nil, fmt.Errorf("failed to generate hash: %w", err)
 	}
 	newRefreshTokenRecord := &entities.RefreshTokenRecord{
-		Jti:       newJti,
-		CreatedAt: time.Now(),
-		ExpiredAt: time.Now().Add(3 * 24 * time.Hour),
-		IssuedIp:  ip,
-		TokenHash: newRefrTokenHash,
+		Jti:              newJti,
+		CreatedAt:        now,
+		ExpiredAt:        lifetimes.refreshExpiry(now, sessionStartedAt, refreshTokenRecord.ExpiredAt),
+		SessionStartedAt: sessionStartedAt,
+		FamilyId:         refreshTokenRecord.FamilyId,
+		IssuedIp:         client.Ip,
+		UserAgent:        client.UserAgent,
+		DeviceName:       deviceName,
+		LastUsedAt:       now,
+		TokenHash:        newRefrTokenHash,
+		Amr:              refreshTokenRecord.Amr,
+	}
+	var outboxMessage *entities.OutboxMessage
+	if clientip.Normalize(refreshTokenRecord.IssuedIp) != clientip.Normalize(client.Ip) {
+		outboxMessage, err = newOutboxMessage(&entities.Notification{
+			Id:         fmt.Sprintf("%s:%s", entities.NotificationIpChanged, newJti),
+			Type:       entities.NotificationIpChanged,
+			UserId:     accessTokenClaims.UserId,
+			Ip:         client.Ip,
+			PreviousIp: refreshTokenRecord.IssuedIp,
+			FamilyId:   refreshTokenRecord.FamilyId,
+			UserAgent:  client.UserAgent,
+			Location:   client.Location,
+			CreatedAt:  now,
+		})
+		if err != nil {
+			return nil, fmt.Errorf("failed to create notification: %w", err)
+		}
+	}
+	err = s.storage.UpdateRefreshTokenRecord(ctx, refreshTokenRecord.Jti, accessTokenClaims.UserId, newRefreshTokenRecord, outboxMessage)
+	if errors.Is(err, storage.ErrTokenReused) {
+		// Токен был обменян одновременным запросом.
+		s.revokeReusedTokenFamily(ctx, accessTokenClaims.UserId, refreshTokenRecord, client)
+		return nil, fmt.Errorf("refresh token with jti '%s' was presented again: %w", refreshTokenRecord.Jti, ErrRefreshTokenReused)
+	}
+	if errors.Is(err, storage.ErrTokenNotFound) {
+		return nil, fmt.Errorf("refresh token with jti '%s' was revoked: %w", refreshTokenRecord.Jti, ErrInvalidRefreshToken)
 	}
-	if err = s.storage.UpdateRefreshTokenRecord(refreshTokenRecord.Jti, accessTokenClaims.UserId, newRefreshTokenRecord); err != nil {
+	if err != nil {
 		return nil, fmt.Errorf("failed to update refresh token hash: %w", err)
 	}
 
@@ -133,7 +209,51 @@ func (s *AuthService) RefreshTokens(ip string, tokensPair *entities.TokensPair)
 		RefreshToken: newRefreshToken,
 	}
 	log.Printf("Access/refresh tokens refreshed for userID: '%s', old jti: '%s', new jti: '%s', ip: '%s'\n",
-		accessTokenClaims.UserId, accessTokenClaims.Jti, newJti, ip)
+		accessTokenClaims.UserId, accessTokenClaims.Jti, newJti, client.Ip)
 
 	return newTokensPair, nil
 }
+
+// revokeReusedTokenFamily отзывает все токены семейства, в котором обнаружено повторное использование refresh-токена,
+// и сохраняет в outbox уведомление пользователя о возможной компрометации сессии.
+// Отзыв не прерывается при отмене контекста запроса: отключение клиента контролирует тот, кто предъявил токен,
+// поэтому он выполняется с собственным ограничением времени reuseRevocationTimeout.
+func (s *AuthService) revokeReusedTokenFamily(ctx context.Context, userId string, refreshTokenRecord *entities.RefreshTokenRecord, client *entities.ClientInfo) {
+	ctx, cancel := context.WithTimeout(context.WithoutCancel(ctx), reuseRevocationTimeout)
+	defer cancel()
+
+	log.Printf("refresh token reuse detected for userID: '%s', jti: '%s', family: '%s', ip: '%s'\n",
+		userId, refreshTokenRecord.Jti, refreshTokenRecord.FamilyId, client.Ip)
+
+	if err := s.revokeSession(ctx, userId, refreshTokenRecord.FamilyId); err != nil {
+		log.Printf("failed to revoke token family '%s': %v\n", refreshTokenRecord.FamilyId, err)
+	}
+	s.enqueueNotification(ctx, &entities.Notification{
+		Id:        fmt.Sprintf("%s:%s", entities.NotificationTokenReuse, refreshTokenRecord.FamilyId),
+		Type:      entities.NotificationTokenReuse,
+		UserId:    userId,
+		Ip:        client.Ip,
+		FamilyId:  refreshTokenRecord.FamilyId,
+		UserAgent: client.UserAgent,
+		Location:  client.Location,
+		CreatedAt: time.Now(),
+	})
+}
+
+// enqueueNotification сохраняет уведомление в outbox для асинхронной доставки.
+// Ошибки записываются в лог и не влияют на обработку запроса.
+func (s *AuthService) enqueueNotification(ctx context.Context, notification *entities.Notification) {
+	outboxMessage, err := newOutboxMessage(notification)
+	if err != nil {
+		log.Printf("failed to create notification '%s': %v\n", notification.Id, err)
+		return
+	}
+	if err := s.storage.EnqueueOutboxMessage(ctx, outboxMessage); err != nil {
+		log.Printf("failed to enqueue notification '%s': %v\n", notification.Id, err)
+	}
+}
+
+// JWKS возвращает набор публичных ключей, которыми можно проверить подпись выданных access-токенов.
+func (s *AuthService) JWKS() *entities.JWKS {
+	return GetJWKS()
+}
@@ -0,0 +1,163 @@
+package services
+
+import (
+	"auth_service/internal/config"
+	"auth_service/internal/entities"
+	"auth_service/internal/storage/memory"
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+// TestRefreshTokensReuse проверяет, что повторное использование обменянного refresh-токена
+// приводит к отзыву всей сессии.
+func TestRefreshTokensReuse(t *testing.T) {
+	ctx := context.Background()
+	config.Secret = "test_secret"
+	config.MaxTokensPerUser = "5"
+	notifier := &recordingNotifier{}
+	service := NewAuthService(newTestStore(t), notifier)
+	userId := "123"
+	client := &entities.ClientInfo{Ip: "192.168.0.1"}
+
+	tokensPair, err := service.GenerateTokens(ctx, userId, nil, client)
+	require.NoError(t, err)
+
+	refreshedTokensPair, err := service.RefreshTokens(ctx, client, tokensPair)
+	require.NoError(t, err)
+
+	_, err = service.RefreshTokens(ctx, client, tokensPair)
+	require.ErrorIs(t, err, ErrRefreshTokenReused)
+	_, err = service.deliverOutbox(ctx, &outboxSettings{batchSize: 10, maxAttempts: 3})
+	require.NoError(t, err)
+	require.Len(t, notifier.received(), 1)
+	require.Equal(t, entities.NotificationTokenReuse, notifier.received()[0].Type)
+
+	_, err = service.RefreshTokens(ctx, client, refreshedTokensPair)
+	require.ErrorIs(t, err, ErrInvalidRefreshToken)
+}
+
+// TestRefreshTokensReuseCanceledRequest проверяет, что сессия отзывается и уведомление сохраняется,
+// даже если клиент, повторно предъявивший refresh-токен, отключился во время обработки запроса.
+func TestRefreshTokensReuseCanceledRequest(t *testing.T) {
+	ctx := context.Background()
+	config.Secret = "test_secret"
+	config.MaxTokensPerUser = "5"
+	notifier := &recordingNotifier{}
+	store := &cancelingStore{Memory: newTestStore(t)}
+	service := NewAuthService(store, notifier)
+	client := &entities.ClientInfo{Ip: "192.168.0.1"}
+
+	tokensPair, err := service.GenerateTokens(ctx, "123", nil, client)
+	require.NoError(t, err)
+	refreshedTokensPair, err := service.RefreshTokens(ctx, client, tokensPair)
+	require.NoError(t, err)
+
+	requestCtx, cancel := context.WithCancel(ctx)
+	store.cancel = cancel
+	_, err = service.RefreshTokens(requestCtx, client, tokensPair)
+	require.ErrorIs(t, err, ErrRefreshTokenReused)
+	require.ErrorIs(t, requestCtx.Err(), context.Canceled)
+
+	_, err = service.RefreshTokens(ctx, client, refreshedTokensPair)
+	require.ErrorIs(t, err, ErrInvalidRefreshToken)
+	_, err = service.deliverOutbox(ctx, &outboxSettings{batchSize: 10, maxAttempts: 3})
+	require.NoError(t, err)
+	require.Len(t, notifier.received(), 1)
+	require.Equal(t, entities.NotificationTokenReuse, notifier.received()[0].Type)
+}
+
+// cancelingStore - in-memory хранилище, которое отменяет контекст запроса после чтения refresh-токена,
+// имитируя отключение клиента во время обработки.
+type cancelingStore struct {
+	*memory.Memory
+	cancel context.CancelFunc // Отменяет контекст запроса; nil - контекст не отменяется.
+}
+
+// GetRefreshTokenRecord возвращает запись refresh-токена и отменяет контекст запроса, если задан cancel.
+func (s *cancelingStore) GetRefreshTokenRecord(ctx context.Context, jti, userId string) (*entities.RefreshTokenRecord, error) {
+	record, err := s.Memory.GetRefreshTokenRecord(ctx, jti, userId)
+	if s.cancel != nil {
+		s.cancel()
+	}
+
+	return record, err
+}
+
+// TestRefreshTokensLegacyIssuedIp проверяет, что адрес с портом, сохраненный в старых записях,
+// не считается сменой IP-адреса клиента.
+func TestRefreshTokensLegacyIssuedIp(t *testing.T) {
+	ctx := context.Background()
+	config.Secret = "test_secret"
+	config.MaxTokensPerUser = "5"
+	notifier := &recordingNotifier{}
+	service := NewAuthService(newTestStore(t), notifier)
+	userId := "123"
+
+	tokensPair, err := service.GenerateTokens(ctx, userId, nil, &entities.ClientInfo{Ip: "192.168.0.1:54321"})
+	require.NoError(t, err)
+
+	_, err = service.RefreshTokens(ctx, &entities.ClientInfo{Ip: "192.168.0.1"}, tokensPair)
+	require.NoError(t, err)
+	_, err = service.deliverOutbox(ctx, &outboxSettings{batchSize: 10, maxAttempts: 3})
+	require.NoError(t, err)
+	require.Empty(t, notifier.received())
+}
+
+// TestGenerateTokensUserStatus проверяет отказ в выдаче и обновлении токенов для неизвестных и заблокированных пользователей.
+func TestGenerateTokensUserStatus(t *testing.T) {
+	ctx := context.Background()
+	config.Secret = "test_secret"
+	config.MaxTokensPerUser = "5"
+	client := &entities.ClientInfo{Ip: "192.168.0.1"}
+
+	t.Run("unknown user", func(t *testing.T) {
+		service := NewAuthService(newTestStore(t), &NoopNotifier{})
+		_, err := service.GenerateTokens(ctx, "456", nil, client)
+		require.ErrorIs(t, err, ErrUnknownUser)
+	})
+	t.Run("disabled user", func(t *testing.T) {
+		store := newTestStore(t)
+		service := NewAuthService(store, &NoopNotifier{})
+		tokensPair, err := service.GenerateTokens(ctx, "123", nil, client)
+		require.NoError(t, err)
+
+		user, err := store.GetUser(ctx, "123")
+		require.NoError(t, err)
+		user.Status = entities.UserStatusDisabled
+		require.NoError(t, store.UpdateUser(ctx, user))
+
+		_, err = service.GenerateTokens(ctx, "123", nil, client)
+		require.ErrorIs(t, err, ErrUserDisabled)
+		_, err = service.RefreshTokens(ctx, client, tokensPair)
+		require.ErrorIs(t, err, ErrUserDisabled)
+	})
+	t.Run("deleted user", func(t *testing.T) {
+		store := newTestStore(t)
+		service := NewAuthService(store, &NoopNotifier{})
+		tokensPair, err := service.GenerateTokens(ctx, "123", nil, client)
+		require.NoError(t, err)
+
+		require.NoError(t, store.DeleteUser(ctx, "123"))
+		_, err = service.RefreshTokens(ctx, client, tokensPair)
+		require.ErrorIs(t, err, ErrUnknownUser)
+	})
+}
+
+// newTestStore создает in-memory хранилище с активным пользователем "123" и email user@gmail.com.
+func newTestStore(t *testing.T) *memory.Memory {
+	ctx := context.Background()
+	store := memory.NewMemoryStore()
+	err := store.CreateUser(ctx, &entities.User{
+		Id:            "123",
+		Email:         "user@gmail.com",
+		EmailVerified: true,
+		Status:        entities.UserStatusActive,
+		CreatedAt:     time.Now(),
+	})
+	require.NoError(t, err)
+
+	return store
+}
@@ -0,0 +1,116 @@
+package services
+
+import (
+	"auth_service/internal/config"
+	"auth_service/internal/entities"
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// validateClaims проверяет nbf, exp, iss и aud access-токена с учетом допустимого расхождения часов.
+// Если checkExpiry равен false, срок действия токена (exp) не проверяется.
+func validateClaims(claims *entities.AccessTokenClaims, checkExpiry bool) error {
+	leeway, err := getLeeway()
+	if err != nil {
+		return err
+	}
+	now := time.Now()
+
+	if !claims.NotBefore.IsZero() && now.Add(leeway).Before(claims.NotBefore) {
+		return fmt.Errorf("access token is not valid yet")
+	}
+	if checkExpiry && now.Add(-leeway).After(claims.ExpiredAt) {
+		return fmt.Errorf("expired at %s: %w", claims.ExpiredAt.Format(time.RFC3339), ErrAccessTokenExpired)
+	}
+	if config.JwtIssuer != "" && claims.Issuer != config.JwtIssuer {
+		return fmt.Errorf("invalid issuer: '%s'", claims.Issuer)
+	}
+	if audience := getAudience(); len(audience) > 0 {
+		if !slices.ContainsFunc(claims.Audience, func(aud string) bool { return slices.Contains(audience, aud) }) {
+			return fmt.Errorf("invalid audience: %v", claims.Audience)
+		}
+	}
+
+	return nil
+}
+
+// validateLegacyClaims проверяет access-токен старого формата.
+// Такие токены принимаются только до даты окончания миграции LEGACY_TOKENS_UNTIL. Если она не задана,
+// токен принимается не дольше времени жизни refresh-токена с момента выпуска: за это время все сессии,
+// начатые до перехода на новый формат, либо обновят токены, либо истекут.
+func validateLegacyClaims(claims *entities.AccessTokenClaims, checkExpiry bool) error {
+	deadline, err := getLegacyTokensDeadline(claims.CreatedAt)
+	if err != nil {
+		return err
+	}
+	if time.Now().After(deadline) {
+		return fmt.Errorf("legacy access token format is no longer accepted")
+	}
+	leeway, err := getLeeway()
+	if err != nil {
+		return err
+	}
+	if checkExpiry && time.Now().Add(-leeway).After(claims.ExpiredAt) {
+		return fmt.Errorf("expired at %s: %w", claims.ExpiredAt.Format(time.RFC3339), ErrAccessTokenExpired)
+	}
+
+	return nil
+}
+
+// getLegacyTokensDeadline возвращает дату, до которой принимается access-токен старого формата, выпущенный в createdAt.
+func getLegacyTokensDeadline(createdAt time.Time) (time.Time, error) {
+	if config.LegacyTokensUntil != "" {
+		deadline, err := time.Parse(time.RFC3339, config.LegacyTokensUntil)
+		if err != nil {
+			return time.Time{}, fmt.Errorf("env 'LEGACY_TOKENS_UNTIL' is not RFC 3339 date: %w", err)
+		}
+		return deadline, nil
+	}
+	refreshTTL, err := getMinutes("REFRESH_TOKEN_TTL", config.RefreshTokenTTL, defaultRefreshTokenTTL)
+	if err != nil {
+		return time.Time{}, err
+	}
+
+	return createdAt.Add(refreshTTL), nil
+}
+
+// getLeeway возвращает допустимое расхождение часов из конфига.
+func getLeeway() (time.Duration, error) {
+	if config.JwtLeeway == "" {
+		return 0, nil
+	}
+	leeway, err := strconv.Atoi(config.JwtLeeway)
+	if err != nil {
+		return 0, fmt.Errorf("env 'JWT_LEEWAY' is not number: %w", err)
+	}
+
+	return time.Duration(leeway) * time.Second, nil
+}
+
+// getAudience возвращает список получателей access-токенов из конфига.
+func getAudience() []string {
+	var audience []string
+	for _, aud := range strings.Split(config.JwtAudience, ",") {
+		if aud = strings.TrimSpace(aud); aud != "" {
+			audience = append(audience, aud)
+		}
+	}
+
+	return audience
+}
+
+// newAccessTokenClaims создает claims access-токена с издателем и получателями из конфига.
+func newAccessTokenClaims(jti, userId string, now, expiredAt time.Time) *entities.AccessTokenClaims {
+	return &entities.AccessTokenClaims{
+		Jti:       jti,
+		UserId:    userId,
+		CreatedAt: now,
+		NotBefore: now,
+		ExpiredAt: expiredAt,
+		Issuer:    config.JwtIssuer,
+		Audience:  getAudience(),
+	}
+}
@@ -0,0 +1,60 @@
+package services
+
+import (
+	"auth_service/internal/entities"
+	"context"
+	"fmt"
+	"time"
+)
+
+// ValidateAccessToken проверяет подпись и claims access-токена, а также отсутствие его в списке отозванных.
+// Если токен был отозван, возвращает ошибку ErrAccessTokenRevoked.
+func (s *AuthService) ValidateAccessToken(ctx context.Context, accessToken string) (*entities.AccessTokenClaims, error) {
+	accessTokenClaims, err := parseAccessToken(accessToken)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse access token: %w", err)
+	}
+
+	revoked, err := s.storage.IsAccessTokenRevoked(ctx, accessTokenClaims.Jti)
+	if err != nil {
+		return nil, fmt.Errorf("failed to check access token revocation: %w", err)
+	}
+	if revoked {
+		return nil, fmt.Errorf("access token with jti '%s' is revoked: %w", accessTokenClaims.Jti, ErrAccessTokenRevoked)
+	}
+
+	return accessTokenClaims, nil
+}
+
+// revokeSession удаляет все refresh-токены семейства и отзывает выданные вместе с ними access-токены.
+func (s *AuthService) revokeSession(ctx context.Context, userId, familyId string) error {
+	revokedRecords, err := s.storage.RevokeTokenFamily(ctx, userId, familyId)
+	if err != nil {
+		return fmt.Errorf("failed to revoke token family: %w", err)
+	}
+
+	return s.revokeAccessTokens(ctx, revokedRecords)
+}
+
+// revokeAccessTokens добавляет в список отозванных access-токены, выданные вместе с удаленными refresh-токенами.
+// Access-токен имеет тот же jti, что и refresh-токен пары, а его срок действия не превышает accessTTL с момента выдачи,
+// поэтому токены, срок действия которых уже истек, пропускаются.
+func (s *AuthService) revokeAccessTokens(ctx context.Context, revokedRecords []*entities.RefreshTokenRecord) error {
+	lifetimes, err := getTokenLifetimes()
+	if err != nil {
+		return fmt.Errorf("failed to get token lifetimes: %w", err)
+	}
+
+	now := time.Now()
+	for _, record := range revokedRecords {
+		expiredAt := record.CreatedAt.Add(lifetimes.accessTTL)
+		if !expiredAt.After(now) {
+			continue
+		}
+		if err := s.storage.RevokeAccessToken(ctx, record.Jti, expiredAt); err != nil {
+			return fmt.Errorf("failed to revoke access token with jti '%s': %w", record.Jti, err)
+		}
+	}
+
+	return nil
+}
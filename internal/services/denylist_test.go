@@ -0,0 +1,68 @@
+package services
+
+import (
+	"auth_service/internal/config"
+	"auth_service/internal/entities"
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+// TestValidateAccessToken проверяет, что access-токены отозванных сессий отклоняются до истечения срока действия.
+func TestValidateAccessToken(t *testing.T) {
+	ctx := context.Background()
+	config.Secret = "test_secret"
+	config.MaxTokensPerUser = "5"
+	userId := "123"
+	client := &entities.ClientInfo{Ip: "192.168.0.1"}
+
+	t.Run("valid access token", func(t *testing.T) {
+		service := NewAuthService(newTestStore(t), &NoopNotifier{})
+		tokensPair, err := service.GenerateTokens(ctx, userId, nil, client)
+		require.NoError(t, err)
+
+		claims, err := service.ValidateAccessToken(ctx, tokensPair.AccessToken)
+		require.NoError(t, err)
+		require.Equal(t, userId, claims.UserId)
+	})
+	t.Run("revoked by logout", func(t *testing.T) {
+		service := NewAuthService(newTestStore(t), &NoopNotifier{})
+		tokensPair, err := service.GenerateTokens(ctx, userId, nil, client)
+		require.NoError(t, err)
+		otherTokensPair, err := service.GenerateTokens(ctx, userId, nil, client)
+		require.NoError(t, err)
+
+		require.NoError(t, service.Logout(ctx, tokensPair))
+		_, err = service.ValidateAccessToken(ctx, tokensPair.AccessToken)
+		require.ErrorIs(t, err, ErrAccessTokenRevoked)
+		_, err = service.ValidateAccessToken(ctx, otherTokensPair.AccessToken)
+		require.NoError(t, err)
+	})
+	t.Run("revoked by logout from all sessions", func(t *testing.T) {
+		service := NewAuthService(newTestStore(t), &NoopNotifier{})
+		tokensPair, err := service.GenerateTokens(ctx, userId, nil, client)
+		require.NoError(t, err)
+		otherTokensPair, err := service.GenerateTokens(ctx, userId, nil, client)
+		require.NoError(t, err)
+
+		require.NoError(t, service.LogoutAll(ctx, tokensPair.AccessToken))
+		_, err = service.ValidateAccessToken(ctx, tokensPair.AccessToken)
+		require.ErrorIs(t, err, ErrAccessTokenRevoked)
+		_, err = service.ValidateAccessToken(ctx, otherTokensPair.AccessToken)
+		require.ErrorIs(t, err, ErrAccessTokenRevoked)
+	})
+	t.Run("revoked with rotated tokens of session", func(t *testing.T) {
+		service := NewAuthService(newTestStore(t), &NoopNotifier{})
+		tokensPair, err := service.GenerateTokens(ctx, userId, nil, client)
+		require.NoError(t, err)
+		refreshedTokensPair, err := service.RefreshTokens(ctx, client, tokensPair)
+		require.NoError(t, err)
+
+		require.NoError(t, service.Logout(ctx, refreshedTokensPair))
+		_, err = service.ValidateAccessToken(ctx, tokensPair.AccessToken)
+		require.ErrorIs(t, err, ErrAccessTokenRevoked)
+		_, err = service.ValidateAccessToken(ctx, refreshedTokensPair.AccessToken)
+		require.ErrorIs(t, err, ErrAccessTokenRevoked)
+	})
+}
@@ -0,0 +1,28 @@
+package services
+
+import "errors"
+
+var (
+	ErrRefreshTokenExpired = errors.New("refresh token is expired")                     // Возвращается при попытке обновить пару токенов просроченным refresh-токеном.
+	ErrRefreshTokenReused  = errors.New("refresh token was already used")               // Возвращается при повторном использовании уже обменянного refresh-токена.
+	ErrAccessTokenRevoked  = errors.New("access token is revoked")                      // Возвращается при предъявлении отозванного access-токена.
+	ErrInvalidAccessToken  = errors.New("access token is invalid")                      // Возвращается при неверной подписи или claims access-токена.
+	ErrAccessTokenExpired  = errors.New("access token is expired")                      // Возвращается вместе с ErrInvalidAccessToken, если срок действия access-токена истек.
+	ErrInvalidRefreshToken = errors.New("refresh token is invalid")                     // Возвращается, если refresh-токен не найден или не соответствует сохраненному хэшу.
+	ErrSessionNotFound     = errors.New("session not found")                            // Возвращается при завершении несуществующей сессии пользователя.
+	ErrInvalidClient       = errors.New("invalid client credentials")                   // Возвращается при неверных учетных данных клиента интроспекции.
+	ErrInvalidKillLink     = errors.New("kill link is invalid or expired")              // Возвращается при неверной подписи или истекшем сроке действия ссылки "Это был не я".
+	ErrInvalidCredentials  = errors.New("invalid credentials")                          // Возвращается при входе с неизвестным идентификатором или неверным паролем.
+	ErrUnknownUser         = errors.New("unknown user")                                 // Возвращается при выдаче или обновлении токенов для несуществующего пользователя.
+	ErrUserDisabled        = errors.New("user is disabled")                             // Возвращается при выдаче или обновлении токенов для заблокированного пользователя.
+	ErrKillLinkUsed        = errors.New("kill link was already used")                   // Возвращается при повторном использовании ссылки "Это был не я".
+	ErrInvalidEmail        = errors.New("invalid email")                                // Возвращается при регистрации с некорректным email.
+	ErrWeakPassword        = errors.New("password is too short")                        // Возвращается при регистрации или сбросе пароля с паролем короче minPasswordLength.
+	ErrEmailTaken          = errors.New("email is already registered")                  // Возвращается при регистрации с email существующего пользователя.
+	ErrInvalidOneTimeToken = errors.New("token is invalid or expired")                  // Возвращается при неверном, использованном или истекшем одноразовом токене.
+	ErrEmailNotVerified    = errors.New("email is not verified")                        // Возвращается при выдаче токенов пользователю с неподтвержденным email, если это запрещено.
+	ErrMfaAlreadyEnabled   = errors.New("two-factor authentication is already enabled") // Возвращается при повторном подключении TOTP.
+	ErrMfaNotEnrolled      = errors.New("two-factor authentication is not enrolled")    // Возвращается при подтверждении TOTP без предварительного подключения.
+	ErrInvalidMfaCode      = errors.New("invalid two-factor code")                      // Возвращается при неверном, уже использованном или просроченном коде TOTP или коде восстановления.
+	ErrInvalidMfaToken     = errors.New("mfa token is invalid or expired")              // Возвращается при неверном, использованном или истекшем токене MFA-проверки.
+)
@@ -1,31 +1,54 @@
 package services
 
 import (
-	"auth_service/internal/config"
 	"auth_service/internal/entities"
 	"crypto/rand"
 	"crypto/sha512"
 	"encoding/base64"
 	"fmt"
+	"strings"
 
 	"github.com/golang-jwt/jwt"
 	"golang.org/x/crypto/bcrypt"
 )
 
 // GenAccessToken генерирует access token (JWT) на основе данных из AccessTokenClaims.
-// В payload токена включаются user_id, issued_ip и jti.
-// Для подписи используется алгоритм SHA512 (HS512) и секрет из конфига.
+// В payload токена включаются зарегистрированные claims (RFC 7519): jti, sub, iat, nbf, exp, а также iss, aud и amr (RFC 8176),
+// если они заданы.
+// Для подписи используется активный ключ из связки ключей (HS512, RS256, ES256 или EdDSA),
+// идентификатор ключа передается в заголовке kid.
 func GenAccessToken(accessTokenClaims *entities.AccessTokenClaims) (string, error) {
 	tokenClaims := jwt.MapClaims{
-		"jti":        accessTokenClaims.Jti,
-		"user_id":    accessTokenClaims.UserId,
-		"created_at": accessTokenClaims.CreatedAt.Unix(),
-		"expired_at": accessTokenClaims.ExpiredAt.Unix(),
+		"jti": accessTokenClaims.Jti,
+		"sub": accessTokenClaims.UserId,
+		"iat": accessTokenClaims.CreatedAt.Unix(),
+		"exp": accessTokenClaims.ExpiredAt.Unix(),
+	}
+	if !accessTokenClaims.NotBefore.IsZero() {
+		tokenClaims["nbf"] = accessTokenClaims.NotBefore.Unix()
+	}
+	if accessTokenClaims.Issuer != "" {
+		tokenClaims["iss"] = accessTokenClaims.Issuer
+	}
+	switch len(accessTokenClaims.Audience) {
+	case 0:
+	case 1:
+		tokenClaims["aud"] = accessTokenClaims.Audience[0]
+	default:
+		tokenClaims["aud"] = accessTokenClaims.Audience
 	}
 
-	jwtToken := jwt.NewWithClaims(jwt.SigningMethodHS512, tokenClaims)
+	if len(accessTokenClaims.Amr) > 0 {
+		tokenClaims["amr"] = accessTokenClaims.Amr
+	}
+
+	key := currentSigningKey()
+	jwtToken := jwt.NewWithClaims(key.Method, tokenClaims)
+	if key.Kid != "" {
+		jwtToken.Header["kid"] = key.Kid
+	}
 
-	accessToken, err := jwtToken.SignedString([]byte(config.Secret))
+	accessToken, err := jwtToken.SignedString(key.PrivateKey)
 	if err != nil {
 		return "", fmt.Errorf("failed to sign token %w", err)
 	}
@@ -34,26 +57,32 @@ func GenAccessToken(accessTokenClaims *entities.AccessTokenClaims) (string, erro
 }
 
 // GenRefreshToken генерирует криптографически стойкий refresh token.
-// Использует 32 случайных байта, кодирует их в base64.
-func GenRefreshToken() (string, error) {
+// Токен имеет вид <userId>.<jti>.<secret>, где userId закодирован в base64 без заполнения,
+// а secret - 32 случайных байта в base64 без заполнения. По userId и jti запись токена находится
+// в хранилище без access-токена (например, при интроспекции), подлинность подтверждается хэшем всего токена.
+func GenRefreshToken(userId, jti string) (string, error) {
 	src := make([]byte, 32)
 	_, err := rand.Read(src)
 	if err != nil {
 		return "", fmt.Errorf("failed to generate random source for refresh token %w", err)
 	}
-	refreshToken := base64.StdEncoding.EncodeToString(src)
+	refreshToken := strings.Join([]string{
+		base64.RawURLEncoding.EncodeToString([]byte(userId)),
+		jti,
+		base64.RawURLEncoding.EncodeToString(src),
+	}, ".")
 
 	return refreshToken, nil
 }
 
 // GenJti генерирует уникальный идентификатор токена (JTI).
-// Использует 32 случайных байта, кодирует их в base64.
+// Использует 32 случайных байта, кодирует их в base64 без заполнения, безопасном для URL.
 func GenJti() (string, error) {
 	bytes := make([]byte, 32)
 	if _, err := rand.Read(bytes); err != nil {
 		return "", fmt.Errorf("failed to generate jti: %w", err)
 	}
-	jti := base64.StdEncoding.EncodeToString(bytes)
+	jti := base64.RawURLEncoding.EncodeToString(bytes)
 
 	return jti, nil
 }
@@ -0,0 +1,144 @@
+package services
+
+import (
+	"auth_service/internal/config"
+	"auth_service/internal/entities"
+	"auth_service/internal/storage"
+	"context"
+	"crypto/sha256"
+	"crypto/subtle"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+)
+
+const (
+	accessTokenType  = "access_token"  // Тип access-токена в запросах интроспекции и отзыва.
+	refreshTokenType = "refresh_token" // Тип refresh-токена в запросах интроспекции и отзыва.
+
+	defaultTokenScope = "auth" // Области доступа выданных токенов, если TOKEN_SCOPE не задан.
+)
+
+// AuthenticateClient проверяет учетные данные клиента интроспекции или отзыва токенов из конфига INTROSPECTION_CLIENTS.
+// Секреты сравниваются за постоянное время. При неверных данных возвращает ошибку ErrInvalidClient.
+func (s *AuthService) AuthenticateClient(clientId, clientSecret string) error {
+	if clientId == "" {
+		return fmt.Errorf("client_id is empty: %w", ErrInvalidClient)
+	}
+
+	secretHash := sha256.Sum256([]byte(clientSecret))
+	authenticated := 0
+	for _, client := range strings.Split(config.IntrospectionClients, ",") {
+		id, secret, found := strings.Cut(strings.TrimSpace(client), ":")
+		if !found || id != clientId {
+			continue
+		}
+		validHash := sha256.Sum256([]byte(secret))
+		authenticated |= subtle.ConstantTimeCompare(secretHash[:], validHash[:])
+	}
+	if authenticated == 0 {
+		return fmt.Errorf("client '%s' is not authenticated: %w", clientId, ErrInvalidClient)
+	}
+
+	return nil
+}
+
+// Introspect возвращает сведения о токене в формате RFC 7662.
+// tokenTypeHint (access_token или refresh_token) определяет, какой тип токена проверяется первым.
+// Токены выдаются сервисом без привязки к клиенту, поэтому для активного токена в scope возвращаются
+// области доступа из TOKEN_SCOPE, а в client_id - идентификатор клиента clientId, выполнившего интроспекцию.
+// Для недействительного, просроченного или отозванного токена возвращается ответ с active: false,
+// ошибка возвращается только при недоступности хранилища.
+func (s *AuthService) Introspect(ctx context.Context, clientId, token, tokenTypeHint string) (*entities.IntrospectionResponse, error) {
+	introspectors := []func(context.Context, string) (*entities.IntrospectionResponse, error){s.introspectAccessToken, s.introspectRefreshToken}
+	if tokenTypeHint == refreshTokenType {
+		introspectors[0], introspectors[1] = introspectors[1], introspectors[0]
+	}
+
+	for _, introspect := range introspectors {
+		resp, err := introspect(ctx, token)
+		if err != nil {
+			return nil, err
+		}
+		if resp.Active {
+			resp.Scope = getTokenScope()
+			resp.ClientId = clientId
+			return resp, nil
+		}
+	}
+
+	return &entities.IntrospectionResponse{Active: false}, nil
+}
+
+// introspectAccessToken проверяет подпись, claims и отсутствие access-токена в списке отозванных.
+func (s *AuthService) introspectAccessToken(ctx context.Context, token string) (*entities.IntrospectionResponse, error) {
+	accessTokenClaims, err := parseAccessToken(token)
+	if err != nil {
+		return &entities.IntrospectionResponse{Active: false}, nil
+	}
+
+	revoked, err := s.storage.IsAccessTokenRevoked(ctx, accessTokenClaims.Jti)
+	if err != nil {
+		return nil, fmt.Errorf("failed to check access token revocation: %w", err)
+	}
+	if revoked {
+		log.Printf("introspected access token with jti '%s' is revoked\n", accessTokenClaims.Jti)
+		return &entities.IntrospectionResponse{Active: false}, nil
+	}
+
+	resp := &entities.IntrospectionResponse{
+		Active:    true,
+		Sub:       accessTokenClaims.UserId,
+		Exp:       accessTokenClaims.ExpiredAt.Unix(),
+		Iat:       accessTokenClaims.CreatedAt.Unix(),
+		Jti:       accessTokenClaims.Jti,
+		TokenType: accessTokenType,
+	}
+
+	return resp, nil
+}
+
+// introspectRefreshToken находит запись refresh-токена по userId и jti из самого токена и проверяет ее хэш.
+// Токен активен, если он не был обменян на новую пару и не просрочен.
+func (s *AuthService) introspectRefreshToken(ctx context.Context, token string) (*entities.IntrospectionResponse, error) {
+	userId, jti, ok := splitRefreshToken(token)
+	if !ok {
+		return &entities.IntrospectionResponse{Active: false}, nil
+	}
+
+	refreshTokenRecord, err := s.storage.GetRefreshTokenRecord(ctx, jti, userId)
+	if errors.Is(err, storage.ErrTokenNotFound) {
+		return &entities.IntrospectionResponse{Active: false}, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get introspected refresh token: %w", err)
+	}
+	if err := checkRefreshToken(token, refreshTokenRecord.TokenHash); err != nil {
+		return &entities.IntrospectionResponse{Active: false}, nil
+	}
+	if refreshTokenRecord.Rotated || time.Now().After(refreshTokenRecord.ExpiredAt) {
+		return &entities.IntrospectionResponse{Active: false}, nil
+	}
+
+	resp := &entities.IntrospectionResponse{
+		Active:    true,
+		Sub:       userId,
+		Exp:       refreshTokenRecord.ExpiredAt.Unix(),
+		Iat:       refreshTokenRecord.CreatedAt.Unix(),
+		Jti:       refreshTokenRecord.Jti,
+		TokenType: refreshTokenType,
+	}
+
+	return resp, nil
+}
+
+// getTokenScope возвращает области доступа выданных токенов из конфига или значение по умолчанию.
+func getTokenScope() string {
+	if scope := strings.Join(strings.Fields(config.TokenScope), " "); scope != "" {
+		return scope
+	}
+
+	return defaultTokenScope
+}
@@ -0,0 +1,109 @@
+package services
+
+import (
+	"auth_service/internal/config"
+	"auth_service/internal/entities"
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+// TestAuthenticateClient проверяет аутентификацию клиентов интроспекции.
+func TestAuthenticateClient(t *testing.T) {
+	config.IntrospectionClients = "gateway:gateway_secret, billing:billing_secret"
+	service := NewAuthService(newTestStore(t), &NoopNotifier{})
+
+	require.NoError(t, service.AuthenticateClient("gateway", "gateway_secret"))
+	require.NoError(t, service.AuthenticateClient("billing", "billing_secret"))
+	require.ErrorIs(t, service.AuthenticateClient("gateway", "billing_secret"), ErrInvalidClient)
+	require.ErrorIs(t, service.AuthenticateClient("unknown", "gateway_secret"), ErrInvalidClient)
+	require.ErrorIs(t, service.AuthenticateClient("", ""), ErrInvalidClient)
+}
+
+// TestIntrospect проверяет интроспекцию access и refresh токенов.
+func TestIntrospect(t *testing.T) {
+	ctx := context.Background()
+	config.Secret = "test_secret"
+	config.MaxTokensPerUser = "5"
+	userId := "123"
+	client := &entities.ClientInfo{Ip: "192.168.0.1"}
+	service := NewAuthService(newTestStore(t), &NoopNotifier{})
+
+	tokensPair, err := service.GenerateTokens(ctx, userId, nil, client)
+	require.NoError(t, err)
+	accessTokenClaims, err := parseAccessToken(tokensPair.AccessToken)
+	require.NoError(t, err)
+
+	t.Run("active access token", func(t *testing.T) {
+		resp, err := service.Introspect(ctx, "gateway", tokensPair.AccessToken, "")
+		require.NoError(t, err)
+		require.True(t, resp.Active)
+		require.Equal(t, userId, resp.Sub)
+		require.Equal(t, accessTokenClaims.Jti, resp.Jti)
+		require.Equal(t, accessTokenClaims.ExpiredAt.Unix(), resp.Exp)
+		require.Equal(t, "auth", resp.Scope)
+		require.Equal(t, "gateway", resp.ClientId)
+		require.Equal(t, "access_token", resp.TokenType)
+	})
+	t.Run("active refresh token", func(t *testing.T) {
+		resp, err := service.Introspect(ctx, "gateway", tokensPair.RefreshToken, "refresh_token")
+		require.NoError(t, err)
+		require.True(t, resp.Active)
+		require.Equal(t, userId, resp.Sub)
+		require.Equal(t, accessTokenClaims.Jti, resp.Jti)
+		require.Equal(t, "auth", resp.Scope)
+		require.Equal(t, "gateway", resp.ClientId)
+		require.Equal(t, "refresh_token", resp.TokenType)
+	})
+	t.Run("configured scope", func(t *testing.T) {
+		config.TokenScope = " profile  sessions "
+		defer func() { config.TokenScope = "" }()
+
+		resp, err := service.Introspect(ctx, "billing", tokensPair.AccessToken, "")
+		require.NoError(t, err)
+		require.Equal(t, "profile sessions", resp.Scope)
+		require.Equal(t, "billing", resp.ClientId)
+	})
+	t.Run("wrong token type hint", func(t *testing.T) {
+		resp, err := service.Introspect(ctx, "gateway", tokensPair.RefreshToken, "access_token")
+		require.NoError(t, err)
+		require.True(t, resp.Active)
+	})
+	t.Run("invalid token", func(t *testing.T) {
+		resp, err := service.Introspect(ctx, "gateway", "invalid-token", "")
+		require.NoError(t, err)
+		require.Equal(t, &entities.IntrospectionResponse{Active: false}, resp)
+	})
+	t.Run("rotated and revoked tokens", func(t *testing.T) {
+		refreshedTokensPair, err := service.RefreshTokens(ctx, client, tokensPair)
+		require.NoError(t, err)
+
+		resp, err := service.Introspect(ctx, "gateway", tokensPair.RefreshToken, "refresh_token")
+		require.NoError(t, err)
+		require.False(t, resp.Active)
+
+		require.NoError(t, service.Logout(ctx, refreshedTokensPair))
+		for _, token := range []string{refreshedTokensPair.AccessToken, refreshedTokensPair.RefreshToken, tokensPair.AccessToken} {
+			resp, err := service.Introspect(ctx, "gateway", token, "")
+			require.NoError(t, err)
+			require.False(t, resp.Active)
+		}
+	})
+}
+
+// TestSplitRefreshToken проверяет извлечение userId и jti из refresh-токена.
+func TestSplitRefreshToken(t *testing.T) {
+	refreshToken, err := GenRefreshToken("123", "jti123")
+	require.NoError(t, err)
+
+	userId, jti, ok := splitRefreshToken(refreshToken)
+	require.True(t, ok)
+	require.Equal(t, "123", userId)
+	require.Equal(t, "jti123", jti)
+
+	for _, token := range []string{"", "legacy+token/value=", "a.b", "!!!.jti.secret", ".jti.secret", "MTIz..secret"} {
+		_, _, ok := splitRefreshToken(token)
+		require.False(t, ok, token)
+	}
+}
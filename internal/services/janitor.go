@@ -0,0 +1,162 @@
+package services
+
+import (
+	"auth_service/internal/config"
+	"auth_service/internal/storage"
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"strconv"
+	"time"
+)
+
+const (
+	defaultJanitorInterval   = 10 * time.Minute // Интервал очистки хранилища, если JANITOR_INTERVAL не задан.
+	defaultJanitorBatchSize  = 1000             // Размер пачки удаляемых записей, если JANITOR_BATCH_SIZE не задан.
+	defaultOutboxRetention   = 24 * time.Hour   // Время хранения доставленных и недоставленных уведомлений, если OUTBOX_RETENTION не задан.
+	defaultKillLinkRetention = 24 * time.Hour   // Время хранения событий ссылок после истечения их срока действия, если KILL_LINK_RETENTION не задан.
+)
+
+// janitorSettings содержит настройки фоновой очистки хранилища.
+type janitorSettings struct {
+	interval          time.Duration // Интервал между запусками очистки.
+	batchSize         int           // Максимальное количество записей, удаляемых одним запросом.
+	outboxRetention   time.Duration // Время хранения доставленных и недоставленных уведомлений outbox.
+	killLinkRetention time.Duration // Время хранения событий использования ссылок "Это был не я" после истечения их срока действия.
+}
+
+// RunJanitor периодически удаляет из хранилища refresh-токены с истекшим сроком действия,
+// доставленные и недоставленные уведомления outbox старше OUTBOX_RETENTION,
+// а также события использования ссылок "Это был не я", срок действия которых истек более KILL_LINK_RETENTION назад.
+// Если очистку refresh-токенов в PostgreSQL уже выполняет другая реплика, она пропускается.
+// Очистка останавливается при отмене ctx.
+func (s *AuthService) RunJanitor(ctx context.Context) error {
+	settings, err := getJanitorSettings()
+	if err != nil {
+		return fmt.Errorf("failed to get janitor settings: %w", err)
+	}
+
+	ticker := time.NewTicker(settings.interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+		}
+		if _, err := s.purgeExpiredRefreshTokens(ctx, settings); err != nil {
+			log.Printf("failed to purge expired refresh tokens: %v\n", err)
+		}
+		if _, err := s.purgeOutboxMessages(ctx, settings); err != nil {
+			log.Printf("failed to purge outbox messages: %v\n", err)
+		}
+		if _, err := s.purgeKillLinkEvents(ctx, settings); err != nil {
+			log.Printf("failed to purge kill link events: %v\n", err)
+		}
+	}
+}
+
+// PurgedRefreshTokens возвращает количество истекших refresh-токенов, удаленных очисткой с момента запуска сервиса.
+func (s *AuthService) PurgedRefreshTokens() int64 {
+	return s.purgedRefreshTokens.Load()
+}
+
+// purgeExpiredRefreshTokens удаляет refresh-токены, срок действия которых истек.
+// Каждый запуск пишет в лог количество удаленных токенов и общее количество с момента запуска сервиса.
+// Возвращает количество удаленных токенов (0, если очистку выполняет другая реплика).
+func (s *AuthService) purgeExpiredRefreshTokens(ctx context.Context, settings *janitorSettings) (int64, error) {
+	purged, err := s.storage.PurgeExpiredRefreshTokens(ctx, time.Now().UTC(), settings.batchSize)
+	total := s.purgedRefreshTokens.Add(purged)
+	if errors.Is(err, storage.ErrPurgeInProgress) {
+		log.Printf("janitor run skipped: purge is in progress on another instance, %d expired refresh token(s) purged in total\n", total)
+		return 0, nil
+	}
+	if err != nil {
+		return purged, err
+	}
+
+	log.Printf("janitor run: %d expired refresh token(s) purged, %d in total\n", purged, total)
+
+	return purged, nil
+}
+
+// purgeOutboxMessages удаляет доставленные и недоставленные уведомления outbox, созданные раньше,
+// чем outboxRetention назад. Ожидающие доставки уведомления не удаляются.
+// Возвращает количество удаленных уведомлений.
+func (s *AuthService) purgeOutboxMessages(ctx context.Context, settings *janitorSettings) (int64, error) {
+	purged, err := s.storage.PurgeOutboxMessages(ctx, time.Now().UTC().Add(-settings.outboxRetention), settings.batchSize)
+	if err != nil {
+		return purged, err
+	}
+
+	log.Printf("janitor run: %d finished outbox message(s) purged\n", purged)
+
+	return purged, nil
+}
+
+// purgeKillLinkEvents удаляет события использования ссылок "Это был не я", срок действия которых истек раньше,
+// чем killLinkRetention назад. Просроченная ссылка отклоняется при проверке, поэтому ее событие больше не нужно
+// для защиты от повторного использования. Возвращает количество удаленных событий.
+func (s *AuthService) purgeKillLinkEvents(ctx context.Context, settings *janitorSettings) (int64, error) {
+	purged, err := s.storage.PurgeKillLinkEvents(ctx, time.Now().UTC().Add(-settings.killLinkRetention), settings.batchSize)
+	if err != nil {
+		return purged, err
+	}
+
+	log.Printf("janitor run: %d expired kill link event(s) purged\n", purged)
+
+	return purged, nil
+}
+
+// getJanitorSettings возвращает настройки очистки хранилища из переменных окружения или значения по умолчанию.
+func getJanitorSettings() (*janitorSettings, error) {
+	settings := &janitorSettings{
+		interval:          defaultJanitorInterval,
+		batchSize:         defaultJanitorBatchSize,
+		outboxRetention:   defaultOutboxRetention,
+		killLinkRetention: defaultKillLinkRetention,
+	}
+	if config.JanitorInterval != "" {
+		minutes, err := strconv.Atoi(config.JanitorInterval)
+		if err != nil {
+			return nil, fmt.Errorf("env 'JANITOR_INTERVAL' is not number: %w", err)
+		}
+		if minutes <= 0 {
+			return nil, fmt.Errorf("env 'JANITOR_INTERVAL' must be positive")
+		}
+		settings.interval = time.Duration(minutes) * time.Minute
+	}
+	if config.JanitorBatchSize != "" {
+		batchSize, err := strconv.Atoi(config.JanitorBatchSize)
+		if err != nil {
+			return nil, fmt.Errorf("env 'JANITOR_BATCH_SIZE' is not number: %w", err)
+		}
+		if batchSize <= 0 {
+			return nil, fmt.Errorf("env 'JANITOR_BATCH_SIZE' must be positive")
+		}
+		settings.batchSize = batchSize
+	}
+	if config.OutboxRetention != "" {
+		minutes, err := strconv.Atoi(config.OutboxRetention)
+		if err != nil {
+			return nil, fmt.Errorf("env 'OUTBOX_RETENTION' is not number: %w", err)
+		}
+		if minutes <= 0 {
+			return nil, fmt.Errorf("env 'OUTBOX_RETENTION' must be positive")
+		}
+		settings.outboxRetention = time.Duration(minutes) * time.Minute
+	}
+	if config.KillLinkRetention != "" {
+		minutes, err := strconv.Atoi(config.KillLinkRetention)
+		if err != nil {
+			return nil, fmt.Errorf("env 'KILL_LINK_RETENTION' is not number: %w", err)
+		}
+		if minutes < 0 {
+			return nil, fmt.Errorf("env 'KILL_LINK_RETENTION' must not be negative")
+		}
+		settings.killLinkRetention = time.Duration(minutes) * time.Minute
+	}
+
+	return settings, nil
+}
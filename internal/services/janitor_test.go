@@ -0,0 +1,163 @@
+package services
+
+import (
+	"auth_service/internal/config"
+	"auth_service/internal/entities"
+	"auth_service/internal/storage"
+	"bytes"
+	"context"
+	"fmt"
+	"log"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+// TestPurgeExpiredRefreshTokens проверяет удаление истекших refresh-токенов фоновой очисткой.
+func TestPurgeExpiredRefreshTokens(t *testing.T) {
+	ctx := context.Background()
+	config.Secret = "test_secret"
+	config.MaxTokensPerUser = "5"
+	client := &entities.ClientInfo{Ip: "192.168.0.1"}
+
+	store := newTestStore(t)
+	service := NewAuthService(store, &NoopNotifier{})
+	tokensPair, err := service.GenerateTokens(ctx, "123", nil, client)
+	require.NoError(t, err)
+
+	now := time.Now().UTC()
+	for i := range 3 {
+		err := store.SaveRefreshTokenRecord(ctx, fmt.Sprintf("user%d", i), &entities.RefreshTokenRecord{
+			Jti:       fmt.Sprintf("expired%d", i),
+			CreatedAt: now.Add(-2 * time.Hour),
+			ExpiredAt: now.Add(-time.Hour),
+			TokenHash: fmt.Sprintf("hash%d", i),
+		})
+		require.NoError(t, err)
+	}
+
+	purged, err := service.purgeExpiredRefreshTokens(ctx, &janitorSettings{batchSize: 2})
+	require.NoError(t, err)
+	require.Equal(t, int64(3), purged)
+	require.Equal(t, int64(3), service.PurgedRefreshTokens())
+
+	var buf bytes.Buffer
+	log.SetOutput(&buf)
+	t.Cleanup(func() { log.SetOutput(os.Stderr) })
+	purged, err = service.purgeExpiredRefreshTokens(ctx, &janitorSettings{batchSize: 2})
+	require.NoError(t, err)
+	require.Zero(t, purged)
+	require.Equal(t, int64(3), service.PurgedRefreshTokens())
+	require.Contains(t, buf.String(), "0 expired refresh token(s) purged, 3 in total")
+
+	_, err = service.RefreshTokens(ctx, client, tokensPair)
+	require.NoError(t, err)
+}
+
+// TestGetJanitorSettings проверяет чтение настроек фоновой очистки из переменных окружения.
+func TestGetJanitorSettings(t *testing.T) {
+	t.Cleanup(func() {
+		config.JanitorInterval = ""
+		config.JanitorBatchSize = ""
+		config.OutboxRetention = ""
+	})
+
+	settings, err := getJanitorSettings()
+	require.NoError(t, err)
+	require.Equal(t, &janitorSettings{
+		interval:          defaultJanitorInterval,
+		batchSize:         defaultJanitorBatchSize,
+		outboxRetention:   defaultOutboxRetention,
+		killLinkRetention: defaultKillLinkRetention,
+	}, settings)
+
+	config.JanitorInterval = "30"
+	config.JanitorBatchSize = "200"
+	config.OutboxRetention = "60"
+	config.KillLinkRetention = "0"
+	settings, err = getJanitorSettings()
+	require.NoError(t, err)
+	require.Equal(t, &janitorSettings{interval: 30 * time.Minute, batchSize: 200, outboxRetention: time.Hour}, settings)
+
+	for _, value := range []string{"abc", "0", "-1"} {
+		config.JanitorInterval = value
+		_, err := getJanitorSettings()
+		require.Error(t, err, value)
+	}
+	config.JanitorInterval = ""
+	for _, value := range []string{"abc", "0"} {
+		config.JanitorBatchSize = value
+		_, err := getJanitorSettings()
+		require.Error(t, err, value)
+	}
+	config.JanitorBatchSize = ""
+	for _, value := range []string{"abc", "0"} {
+		config.OutboxRetention = value
+		_, err := getJanitorSettings()
+		require.Error(t, err, value)
+	}
+	config.OutboxRetention = ""
+	for _, value := range []string{"abc", "-1"} {
+		config.KillLinkRetention = value
+		_, err := getJanitorSettings()
+		require.Error(t, err, value)
+	}
+}
+
+// TestPurgeOutboxMessages проверяет удаление завершенных уведомлений outbox фоновой очисткой.
+func TestPurgeOutboxMessages(t *testing.T) {
+	ctx := context.Background()
+	store := newTestStore(t)
+	service := NewAuthService(store, &NoopNotifier{})
+
+	now := time.Now().UTC()
+	for i, createdAt := range []time.Time{now.Add(-2 * time.Hour), now} {
+		message := &entities.OutboxMessage{
+			Id:            fmt.Sprintf("message%d", i),
+			Payload:       "{}",
+			Status:        entities.OutboxStatusPending,
+			NextAttemptAt: now,
+			CreatedAt:     createdAt,
+		}
+		require.NoError(t, store.EnqueueOutboxMessage(ctx, message))
+		require.NoError(t, store.MarkOutboxMessageDelivered(ctx, message.Id))
+	}
+
+	purged, err := service.purgeOutboxMessages(ctx, &janitorSettings{batchSize: 10, outboxRetention: time.Hour})
+	require.NoError(t, err)
+	require.Equal(t, int64(1), purged)
+	require.ErrorIs(t, store.MarkOutboxMessageDelivered(ctx, "message0"), storage.ErrOutboxMessageNotFound)
+	require.NoError(t, store.MarkOutboxMessageDelivered(ctx, "message1"))
+}
+
+// TestPurgeKillLinkEvents проверяет удаление событий просроченных ссылок "Это был не я" фоновой очисткой.
+func TestPurgeKillLinkEvents(t *testing.T) {
+	ctx := context.Background()
+	store := newTestStore(t)
+	service := NewAuthService(store, &NoopNotifier{})
+
+	now := time.Now().UTC()
+	for id, expiredAt := range map[string]time.Time{"expired": now.Add(-2 * time.Hour), "recent": now.Add(-time.Minute), "active": now.Add(time.Hour)} {
+		consumed, err := store.ConsumeKillLink(ctx, &entities.KillLinkEvent{
+			Id:        id,
+			UserId:    "123",
+			Scope:     entities.KillLinkScopeAll,
+			UsedAt:    now,
+			ExpiredAt: expiredAt,
+		})
+		require.NoError(t, err)
+		require.True(t, consumed)
+	}
+
+	purged, err := service.purgeKillLinkEvents(ctx, &janitorSettings{batchSize: 10, killLinkRetention: time.Hour})
+	require.NoError(t, err)
+	require.Equal(t, int64(1), purged)
+
+	for id, used := range map[string]bool{"expired": false, "recent": true, "active": true} {
+		actual, err := store.IsKillLinkUsed(ctx, id)
+		require.NoError(t, err)
+		require.Equal(t, used, actual, id)
+	}
+}
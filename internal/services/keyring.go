@@ -0,0 +1,125 @@
+package services
+
+import (
+	"auth_service/internal/config"
+	"log"
+	"sync"
+	"time"
+)
+
+// defaultKeyGracePeriod используется, если переменная окружения KEY_GRACE_PERIOD не задана.
+// Совпадает со временем жизни access-токена по умолчанию, чтобы все выпущенные старым ключом токены успели истечь.
+const defaultKeyGracePeriod = 1 * time.Hour
+
+// keyring - связка ключей подписи сервиса.
+var keyring = NewKeyring()
+
+// retiredKey внутренняя структура для хранения выведенного из оборота ключа.
+type retiredKey struct {
+	key       *SigningKey
+	retiredAt time.Time
+}
+
+// Keyring хранит активный ключ подписи и выведенные из оборота ключи.
+// Новые токены подписываются активным ключом, а выведенные ключи принимаются
+// для проверки подписи до окончания grace-периода.
+type Keyring struct {
+	active      *SigningKey   // active - ключ, которым подписываются новые токены.
+	retired     []*retiredKey // retired - ключи, замененные при ротации.
+	gracePeriod time.Duration // gracePeriod - время, в течение которого выведенный ключ принимается для проверки.
+	mu          sync.RWMutex  // mu обеспечивает потокобезопасность ротации ключей.
+}
+
+// NewKeyring создает пустую связку ключей.
+func NewKeyring() *Keyring {
+	return &Keyring{gracePeriod: defaultKeyGracePeriod}
+}
+
+// Reset делает ключ активным и удаляет все выведенные из оборота ключи.
+func (k *Keyring) Reset(key *SigningKey, gracePeriod time.Duration) {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+
+	k.active = key
+	k.retired = nil
+	k.gracePeriod = gracePeriod
+}
+
+// Rotate делает ключ активным, а предыдущий активный ключ выводит из оборота.
+// Возвращает false, если ключ с таким kid уже активен.
+func (k *Keyring) Rotate(key *SigningKey) bool {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+
+	if k.active != nil && k.active.Kid == key.Kid {
+		return false
+	}
+	if k.active != nil {
+		k.retired = append(k.retired, &retiredKey{key: k.active, retiredAt: time.Now()})
+	}
+	k.active = key
+	k.pruneRetired()
+	log.Printf("signing key rotated, active kid: '%s'\n", key.Kid)
+
+	return true
+}
+
+// Active возвращает активный ключ подписи или nil, если связка не инициализирована.
+func (k *Keyring) Active() *SigningKey {
+	k.mu.RLock()
+	defer k.mu.RUnlock()
+
+	return k.active
+}
+
+// Get возвращает ключ для проверки подписи по kid.
+// Выведенные из оборота ключи возвращаются только до окончания grace-периода.
+func (k *Keyring) Get(kid string) *SigningKey {
+	k.mu.RLock()
+	defer k.mu.RUnlock()
+
+	if k.active != nil && k.active.Kid == kid {
+		return k.active
+	}
+	for _, retired := range k.retired {
+		if retired.key.Kid == kid && time.Since(retired.retiredAt) < k.gracePeriod {
+			return retired.key
+		}
+	}
+
+	return nil
+}
+
+// VerificationKeys возвращает все ключи, которые принимаются для проверки подписи.
+func (k *Keyring) VerificationKeys() []*SigningKey {
+	k.mu.RLock()
+	defer k.mu.RUnlock()
+
+	keys := make([]*SigningKey, 0, len(k.retired)+1)
+	if k.active != nil {
+		keys = append(keys, k.active)
+	}
+	for _, retired := range k.retired {
+		if time.Since(retired.retiredAt) < k.gracePeriod {
+			keys = append(keys, retired.key)
+		}
+	}
+
+	return keys
+}
+
+// pruneRetired удаляет ключи, grace-период которых истек.
+func (k *Keyring) pruneRetired() {
+	actual := k.retired[:0]
+	for _, retired := range k.retired {
+		if time.Since(retired.retiredAt) < k.gracePeriod {
+			actual = append(actual, retired)
+		}
+	}
+	k.retired = actual
+}
+
+// getKeyGracePeriod возвращает grace-период выведенных ключей из конфига.
+func getKeyGracePeriod() (time.Duration, error) {
+	return getMinutes("KEY_GRACE_PERIOD", config.KeyGracePeriod, defaultKeyGracePeriod)
+}
@@ -0,0 +1,67 @@
+package services
+
+import (
+	"auth_service/internal/entities"
+	"crypto/ed25519"
+	"crypto/rand"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt"
+	"github.com/stretchr/testify/require"
+)
+
+// TestKeyringRotation проверяет, что после ротации токены, подписанные старым ключом,
+// принимаются только до окончания grace-периода.
+func TestKeyringRotation(t *testing.T) {
+	t.Cleanup(func() { keyring = NewKeyring() })
+
+	_, oldEdKey, err := ed25519.GenerateKey(rand.Reader)
+	require.NoError(t, err)
+	_, newEdKey, err := ed25519.GenerateKey(rand.Reader)
+	require.NoError(t, err)
+	oldKey := newSigningKey(jwt.SigningMethodEdDSA, oldEdKey, oldEdKey.Public())
+	newKey := newSigningKey(jwt.SigningMethodEdDSA, newEdKey, newEdKey.Public())
+	require.NotEqual(t, oldKey.Kid, newKey.Kid)
+
+	claims := &entities.AccessTokenClaims{
+		Jti:       "test-jti",
+		UserId:    "123",
+		CreatedAt: time.Now(),
+		ExpiredAt: time.Now().Add(time.Hour),
+	}
+
+	t.Run("old tokens are valid during grace period", func(t *testing.T) {
+		keyring.Reset(oldKey, time.Hour)
+		oldToken, err := GenAccessToken(claims)
+		require.NoError(t, err)
+
+		require.True(t, keyring.Rotate(newKey))
+		require.False(t, keyring.Rotate(newKey))
+		require.Equal(t, newKey, keyring.Active())
+
+		_, err = parseAccessToken(oldToken)
+		require.NoError(t, err)
+
+		newToken, err := GenAccessToken(claims)
+		require.NoError(t, err)
+		_, err = parseAccessToken(newToken)
+		require.NoError(t, err)
+
+		jwks := GetJWKS()
+		require.Len(t, jwks.Keys, 2)
+		require.Equal(t, newKey.Kid, jwks.Keys[0].Kid)
+		require.Equal(t, oldKey.Kid, jwks.Keys[1].Kid)
+	})
+	t.Run("old tokens are rejected after grace period", func(t *testing.T) {
+		keyring.Reset(oldKey, 0)
+		oldToken, err := GenAccessToken(claims)
+		require.NoError(t, err)
+
+		require.True(t, keyring.Rotate(newKey))
+
+		_, err = parseAccessToken(oldToken)
+		require.ErrorContains(t, err, "was not found")
+		require.Len(t, GetJWKS().Keys, 1)
+	})
+}
@@ -0,0 +1,251 @@
+package services
+
+import (
+	"auth_service/internal/config"
+	"auth_service/internal/entities"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/url"
+	"strings"
+	"time"
+)
+
+const (
+	defaultKillLinkTTL   = 24 * time.Hour // Используется, если переменная окружения KILL_LINK_TTL не задана.
+	minKillLinkKeyLength = 32             // Минимальная длина ключа подписи ссылок KILL_LINK_SECRET в байтах.
+)
+
+// killLinkClaims содержит данные, подписанные в токене ссылки "Это был не я".
+type killLinkClaims struct {
+	Id        string `json:"id"`            // Идентификатор ссылки, обеспечивает однократное использование.
+	UserId    string `json:"sub"`           // Идентификатор пользователя.
+	FamilyId  string `json:"fid,omitempty"` // Идентификатор завершаемой сессии.
+	Scope     string `json:"scope"`         // Область действия ссылки: family или all.
+	ExpiredAt int64  `json:"exp"`           // Срок действия ссылки (Unix-время).
+}
+
+// GenKillLinkToken генерирует подписанный токен ссылки "Это был не я".
+// Токен имеет вид <payload>.<signature>, где payload - claims в формате JSON, а signature - HMAC-SHA256 от payload
+// на ключе KILL_LINK_SECRET. Оба значения закодированы в base64 без заполнения, безопасном для URL.
+func GenKillLinkToken(claims *killLinkClaims) (string, error) {
+	payload, err := json.Marshal(claims)
+	if err != nil {
+		return "", fmt.Errorf("failed to marshal kill link claims: %w", err)
+	}
+	encodedPayload := base64.RawURLEncoding.EncodeToString(payload)
+	signature, err := signKillLink(encodedPayload)
+	if err != nil {
+		return "", err
+	}
+
+	return encodedPayload + "." + base64.RawURLEncoding.EncodeToString(signature), nil
+}
+
+// ValidateKillLinkKey проверяет ключ подписи ссылок "Это был не я" из KILL_LINK_SECRET.
+// Вызывается при запуске сервиса: без ключа ссылки можно было бы подделать.
+// Если адрес страницы NOTIFICATION_REPORT_URL не задан, ссылки не выдаются и ключ не требуется.
+func ValidateKillLinkKey() error {
+	if config.NotificationReportUrl == "" {
+		return nil
+	}
+	_, err := killLinkKey()
+	return err
+}
+
+// CheckKillLink проверяет подпись и срок действия токена ссылки "Это был не я", не используя ссылку.
+func (s *AuthService) CheckKillLink(token string) error {
+	if _, err := parseKillLinkToken(token); err != nil {
+		return fmt.Errorf("failed to parse kill link token: %w", err)
+	}
+
+	return nil
+}
+
+// UseKillLink завершает сессию (или все сессии пользователя), указанную в токене ссылки "Это был не я".
+// Ссылка используется однократно: событие сохраняется в хранилище, повторное использование возвращает ErrKillLinkUsed.
+// Ссылка помечается использованной только после завершения сессий, поэтому при ошибке хранилища ее можно открыть снова.
+func (s *AuthService) UseKillLink(ctx context.Context, token string, client *entities.ClientInfo) error {
+	claims, err := parseKillLinkToken(token)
+	if err != nil {
+		return fmt.Errorf("failed to parse kill link token: %w", err)
+	}
+
+	used, err := s.storage.IsKillLinkUsed(ctx, claims.Id)
+	if err != nil {
+		return fmt.Errorf("failed to check kill link: %w", err)
+	}
+	if used {
+		return fmt.Errorf("kill link '%s' was presented again: %w", claims.Id, ErrKillLinkUsed)
+	}
+
+	switch claims.Scope {
+	case entities.KillLinkScopeFamily:
+		err = s.killSessionFamily(ctx, claims.UserId, claims.FamilyId)
+	case entities.KillLinkScopeAll:
+		var revokedRecords []*entities.RefreshTokenRecord
+		revokedRecords, err = s.storage.RevokeAllRefreshTokens(ctx, claims.UserId)
+		if err == nil {
+			err = s.revokeAccessTokens(ctx, revokedRecords)
+		}
+	}
+	if err != nil {
+		return fmt.Errorf("failed to revoke sessions: %w", err)
+	}
+
+	// Одновременный запрос с той же ссылкой мог сохранить событие раньше: сессии уже завершены обоими запросами.
+	consumed, err := s.storage.ConsumeKillLink(ctx, &entities.KillLinkEvent{
+		Id:        claims.Id,
+		UserId:    claims.UserId,
+		FamilyId:  claims.FamilyId,
+		Scope:     claims.Scope,
+		Ip:        client.Ip,
+		UserAgent: client.UserAgent,
+		UsedAt:    time.Now(),
+		ExpiredAt: time.Unix(claims.ExpiredAt, 0),
+	})
+	if err != nil {
+		return fmt.Errorf("failed to save kill link event: %w", err)
+	}
+	if !consumed {
+		return fmt.Errorf("kill link '%s' was presented again: %w", claims.Id, ErrKillLinkUsed)
+	}
+	log.Printf("Sessions revoked by kill link for userID: '%s', scope: '%s', family: '%s', ip: '%s'\n",
+		claims.UserId, claims.Scope, claims.FamilyId, client.Ip)
+
+	return nil
+}
+
+// killSessionFamily завершает сессию пользователя, если она еще активна.
+// Уже завершенная сессия не считается ошибкой: пользователь получает нужный результат.
+func (s *AuthService) killSessionFamily(ctx context.Context, userId, familyId string) error {
+	refreshTokenRecords, err := s.storage.GetRefreshTokenRecords(ctx, userId)
+	if err != nil {
+		return fmt.Errorf("failed to get sessions: %w", err)
+	}
+	for _, record := range refreshTokenRecords {
+		if record.FamilyId == familyId {
+			return s.revokeSession(ctx, userId, familyId)
+		}
+	}
+
+	return nil
+}
+
+// killLinkUrl формирует ссылку "Это был не я" для уведомления.
+// Уведомление о смене IP-адреса завершает свою сессию, уведомление о повторном использовании токена - все сессии
+// пользователя, так как скомпрометированная сессия к этому моменту уже завершена.
+// Возвращает пустую строку, если адрес страницы NOTIFICATION_REPORT_URL не задан.
+func killLinkUrl(notification *entities.Notification) (string, error) {
+	if config.NotificationReportUrl == "" {
+		return "", nil
+	}
+	reportUrl, err := url.Parse(config.NotificationReportUrl)
+	if err != nil {
+		return "", fmt.Errorf("env 'NOTIFICATION_REPORT_URL' is not valid URL: %w", err)
+	}
+	ttl, err := getMinutes("KILL_LINK_TTL", config.KillLinkTTL, defaultKillLinkTTL)
+	if err != nil {
+		return "", err
+	}
+	id, err := GenJti()
+	if err != nil {
+		return "", err
+	}
+
+	claims := &killLinkClaims{
+		Id:        id,
+		UserId:    notification.UserId,
+		FamilyId:  notification.FamilyId,
+		Scope:     entities.KillLinkScopeFamily,
+		ExpiredAt: time.Now().Add(ttl).Unix(),
+	}
+	if notification.Type != entities.NotificationIpChanged || notification.FamilyId == "" {
+		claims.FamilyId = ""
+		claims.Scope = entities.KillLinkScopeAll
+	}
+	token, err := GenKillLinkToken(claims)
+	if err != nil {
+		return "", err
+	}
+
+	query := reportUrl.Query()
+	query.Set("token", token)
+	reportUrl.RawQuery = query.Encode()
+
+	return reportUrl.String(), nil
+}
+
+// parseKillLinkToken проверяет подпись и срок действия токена ссылки и возвращает его claims.
+// Все ошибки проверки оборачивают ErrInvalidKillLink. Если ссылки отключены (NOTIFICATION_REPORT_URL не задан)
+// и ключ подписи не настроен, любой токен считается недействительным.
+func parseKillLinkToken(token string) (*killLinkClaims, error) {
+	encodedPayload, encodedSignature, ok := strings.Cut(token, ".")
+	if !ok {
+		return nil, fmt.Errorf("malformed token: %w", ErrInvalidKillLink)
+	}
+	expectedSignature, err := signKillLink(encodedPayload)
+	if err != nil && config.NotificationReportUrl == "" {
+		return nil, fmt.Errorf("kill links are disabled: %w", ErrInvalidKillLink)
+	}
+	if err != nil {
+		return nil, err
+	}
+	signature, err := base64.RawURLEncoding.DecodeString(encodedSignature)
+	if err != nil || !hmac.Equal(signature, expectedSignature) {
+		return nil, fmt.Errorf("invalid signature: %w", ErrInvalidKillLink)
+	}
+	payload, err := base64.RawURLEncoding.DecodeString(encodedPayload)
+	if err != nil {
+		return nil, fmt.Errorf("malformed payload: %w", ErrInvalidKillLink)
+	}
+
+	var claims killLinkClaims
+	if err := json.Unmarshal(payload, &claims); err != nil {
+		return nil, fmt.Errorf("malformed claims: %w", ErrInvalidKillLink)
+	}
+	if claims.Id == "" || claims.UserId == "" {
+		return nil, fmt.Errorf("missing claims: %w", ErrInvalidKillLink)
+	}
+	if claims.Scope != entities.KillLinkScopeAll && (claims.Scope != entities.KillLinkScopeFamily || claims.FamilyId == "") {
+		return nil, fmt.Errorf("invalid scope '%s': %w", claims.Scope, ErrInvalidKillLink)
+	}
+	if time.Now().After(time.Unix(claims.ExpiredAt, 0)) {
+		return nil, fmt.Errorf("link expired at %s: %w", time.Unix(claims.ExpiredAt, 0).Format(time.RFC3339), ErrInvalidKillLink)
+	}
+
+	return &claims, nil
+}
+
+// signKillLink вычисляет подпись payload токена ссылки на ключе KILL_LINK_SECRET.
+func signKillLink(encodedPayload string) ([]byte, error) {
+	key, err := killLinkKey()
+	if err != nil {
+		return nil, err
+	}
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte("kill-link."))
+	mac.Write([]byte(encodedPayload))
+
+	return mac.Sum(nil), nil
+}
+
+// killLinkKey возвращает ключ подписи ссылок "Это был не я".
+// Пустой, короткий или совпадающий с SECRET ключ не принимается: SECRET может быть пустым при подписи
+// access-токенов асимметричным ключом, а общий секрет связал бы компрометацию одного ключа с другим.
+func killLinkKey() ([]byte, error) {
+	switch {
+	case config.KillLinkSecret == "":
+		return nil, fmt.Errorf("env 'KILL_LINK_SECRET' is not set")
+	case len(config.KillLinkSecret) < minKillLinkKeyLength:
+		return nil, fmt.Errorf("env 'KILL_LINK_SECRET' must be at least %d bytes, got %d", minKillLinkKeyLength, len(config.KillLinkSecret))
+	case config.KillLinkSecret == config.Secret:
+		return nil, fmt.Errorf("env 'KILL_LINK_SECRET' must differ from 'SECRET'")
+	}
+
+	return []byte(config.KillLinkSecret), nil
+}
@@ -0,0 +1,334 @@
+package services
+
+import (
+	"auth_service/internal/config"
+	"auth_service/internal/entities"
+	"auth_service/internal/storage"
+	"auth_service/internal/storage/memory"
+	"context"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+// testKillLinkSecret - ключ подписи ссылок "Это был не я" в тестах.
+const testKillLinkSecret = "test_kill_link_secret_0123456789abcdef"
+
+// TestKillLinkKey проверяет, что ссылки не подписываются пустым, коротким или общим с SECRET ключом.
+func TestKillLinkKey(t *testing.T) {
+	config.Secret = "test_secret"
+	config.NotificationReportUrl = "https://auth.example.com/api/auth/kill-link"
+	t.Cleanup(func() {
+		config.KillLinkSecret = testKillLinkSecret
+		config.NotificationReportUrl = ""
+	})
+	claims := &killLinkClaims{Id: "link123", UserId: "123", Scope: entities.KillLinkScopeAll, ExpiredAt: time.Now().Add(time.Hour).Unix()}
+
+	config.KillLinkSecret = testKillLinkSecret
+	require.NoError(t, ValidateKillLinkKey())
+	token, err := GenKillLinkToken(claims)
+	require.NoError(t, err)
+
+	tests := []struct {
+		name   string
+		secret string
+	}{
+		{name: "empty", secret: ""},
+		{name: "too short", secret: "short_secret"},
+		{name: "shared with SECRET", secret: "shared_secret_0123456789abcdefghij"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Cleanup(func() { config.Secret = "test_secret" })
+			config.Secret = "shared_secret_0123456789abcdefghij"
+			config.KillLinkSecret = tt.secret
+
+			require.Error(t, ValidateKillLinkKey())
+			_, err := GenKillLinkToken(claims)
+			require.Error(t, err)
+			_, err = parseKillLinkToken(token)
+			require.Error(t, err)
+			require.NotErrorIs(t, err, ErrInvalidKillLink)
+		})
+	}
+
+	t.Run("links are disabled", func(t *testing.T) {
+		t.Cleanup(func() { config.NotificationReportUrl = "https://auth.example.com/api/auth/kill-link" })
+		config.NotificationReportUrl = ""
+		config.KillLinkSecret = ""
+
+		require.NoError(t, ValidateKillLinkKey())
+		_, err := parseKillLinkToken(token)
+		require.ErrorIs(t, err, ErrInvalidKillLink)
+	})
+}
+
+// TestKillLinkUrl проверяет формирование ссылки "Это был не я" для уведомлений.
+func TestKillLinkUrl(t *testing.T) {
+	config.Secret = "test_secret"
+	config.KillLinkSecret = testKillLinkSecret
+	t.Cleanup(func() { config.NotificationReportUrl = "" })
+	notification := &entities.Notification{
+		Type:     entities.NotificationIpChanged,
+		UserId:   "123",
+		FamilyId: "family123",
+	}
+
+	t.Run("report url is not set", func(t *testing.T) {
+		config.NotificationReportUrl = ""
+		reportUrl, err := killLinkUrl(notification)
+		require.NoError(t, err)
+		require.Empty(t, reportUrl)
+	})
+	t.Run("ip changed", func(t *testing.T) {
+		config.NotificationReportUrl = "https://auth.example.com/api/auth/kill-link?lang=ru"
+		claims := parseKillLinkUrl(t, notification)
+		require.Equal(t, "123", claims.UserId)
+		require.Equal(t, "family123", claims.FamilyId)
+		require.Equal(t, entities.KillLinkScopeFamily, claims.Scope)
+		require.NotEmpty(t, claims.Id)
+		require.WithinDuration(t, time.Now().Add(defaultKillLinkTTL), time.Unix(claims.ExpiredAt, 0), time.Minute)
+	})
+	t.Run("token reuse", func(t *testing.T) {
+		config.NotificationReportUrl = "https://auth.example.com/api/auth/kill-link"
+		claims := parseKillLinkUrl(t, &entities.Notification{
+			Type:     entities.NotificationTokenReuse,
+			UserId:   "123",
+			FamilyId: "family123",
+		})
+		require.Empty(t, claims.FamilyId)
+		require.Equal(t, entities.KillLinkScopeAll, claims.Scope)
+	})
+}
+
+// TestParseKillLinkToken проверяет отклонение поддельных и просроченных токенов ссылки.
+func TestParseKillLinkToken(t *testing.T) {
+	config.Secret = "test_secret"
+	config.KillLinkSecret = testKillLinkSecret
+	claims := &killLinkClaims{
+		Id:        "link123",
+		UserId:    "123",
+		FamilyId:  "family123",
+		Scope:     entities.KillLinkScopeFamily,
+		ExpiredAt: time.Now().Add(time.Hour).Unix(),
+	}
+	token, err := GenKillLinkToken(claims)
+	require.NoError(t, err)
+
+	actual, err := parseKillLinkToken(token)
+	require.NoError(t, err)
+	require.Equal(t, claims, actual)
+
+	expired := *claims
+	expired.ExpiredAt = time.Now().Add(-time.Minute).Unix()
+	expiredToken, err := GenKillLinkToken(&expired)
+	require.NoError(t, err)
+
+	noFamily := *claims
+	noFamily.FamilyId = ""
+	noFamilyToken, err := GenKillLinkToken(&noFamily)
+	require.NoError(t, err)
+
+	payload, _, _ := strings.Cut(token, ".")
+	_, otherSignature, _ := strings.Cut(expiredToken, ".")
+
+	tests := []struct {
+		name  string
+		token string
+	}{
+		{name: "empty", token: ""},
+		{name: "malformed", token: "not-a-token"},
+		{name: "forged signature", token: payload + "." + otherSignature},
+		{name: "expired", token: expiredToken},
+		{name: "family scope without family", token: noFamilyToken},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := parseKillLinkToken(tt.token)
+			require.ErrorIs(t, err, ErrInvalidKillLink)
+		})
+	}
+
+	t.Run("other secret", func(t *testing.T) {
+		t.Cleanup(func() { config.KillLinkSecret = testKillLinkSecret })
+		config.KillLinkSecret = "other_kill_link_secret_0123456789"
+		_, err := parseKillLinkToken(token)
+		require.ErrorIs(t, err, ErrInvalidKillLink)
+	})
+}
+
+// TestUseKillLink проверяет завершение сессий по ссылке "Это был не я" и однократность ее использования.
+func TestUseKillLink(t *testing.T) {
+	ctx := context.Background()
+	config.Secret = "test_secret"
+	config.KillLinkSecret = testKillLinkSecret
+	config.MaxTokensPerUser = "5"
+	userId := "123"
+	client := &entities.ClientInfo{Ip: "192.168.0.1"}
+
+	t.Run("revoke session family", func(t *testing.T) {
+		service := NewAuthService(newTestStore(t), &NoopNotifier{})
+		tokensPair, err := service.GenerateTokens(ctx, userId, nil, client)
+		require.NoError(t, err)
+		otherTokensPair, err := service.GenerateTokens(ctx, userId, nil, client)
+		require.NoError(t, err)
+		sessions, err := service.ListSessions(ctx, tokensPair.AccessToken)
+		require.NoError(t, err)
+		require.Len(t, sessions, 2)
+
+		familyId := familyOf(t, service, tokensPair.AccessToken)
+		token := genKillLink(t, userId, familyId, entities.KillLinkScopeFamily)
+		require.NoError(t, service.CheckKillLink(token))
+		require.NoError(t, service.UseKillLink(ctx, token, client))
+
+		_, err = service.ValidateAccessToken(ctx, tokensPair.AccessToken)
+		require.ErrorIs(t, err, ErrAccessTokenRevoked)
+		_, err = service.RefreshTokens(ctx, client, tokensPair)
+		require.ErrorContains(t, err, "not found")
+		_, err = service.RefreshTokens(ctx, client, otherTokensPair)
+		require.NoError(t, err)
+
+		err = service.UseKillLink(ctx, token, client)
+		require.ErrorIs(t, err, ErrKillLinkUsed)
+	})
+	t.Run("revoke all sessions", func(t *testing.T) {
+		service := NewAuthService(newTestStore(t), &NoopNotifier{})
+		tokensPair, err := service.GenerateTokens(ctx, userId, nil, client)
+		require.NoError(t, err)
+		otherTokensPair, err := service.GenerateTokens(ctx, userId, nil, client)
+		require.NoError(t, err)
+
+		token := genKillLink(t, userId, "", entities.KillLinkScopeAll)
+		require.NoError(t, service.UseKillLink(ctx, token, client))
+
+		_, err = service.RefreshTokens(ctx, client, tokensPair)
+		require.ErrorContains(t, err, "not found")
+		_, err = service.RefreshTokens(ctx, client, otherTokensPair)
+		require.ErrorContains(t, err, "not found")
+	})
+	t.Run("session already ended", func(t *testing.T) {
+		service := NewAuthService(newTestStore(t), &NoopNotifier{})
+		token := genKillLink(t, userId, "not_exist_family", entities.KillLinkScopeFamily)
+		require.NoError(t, service.UseKillLink(ctx, token, client))
+	})
+	t.Run("retry after storage failure", func(t *testing.T) {
+		store := &failingRevokeStore{Memory: newTestStore(t), fail: true}
+		service := NewAuthService(store, &NoopNotifier{})
+		tokensPair, err := service.GenerateTokens(ctx, userId, nil, client)
+		require.NoError(t, err)
+
+		token := genKillLink(t, userId, "", entities.KillLinkScopeAll)
+		require.ErrorIs(t, service.UseKillLink(ctx, token, client), storage.ErrStorageUnavailable)
+		used, err := store.IsKillLinkUsed(ctx, mustParseKillLink(t, token).Id)
+		require.NoError(t, err)
+		require.False(t, used)
+
+		store.fail = false
+		require.NoError(t, service.UseKillLink(ctx, token, client))
+		_, err = service.RefreshTokens(ctx, client, tokensPair)
+		require.ErrorIs(t, err, ErrInvalidRefreshToken)
+		require.ErrorIs(t, service.UseKillLink(ctx, token, client), ErrKillLinkUsed)
+	})
+	t.Run("invalid link", func(t *testing.T) {
+		service := NewAuthService(newTestStore(t), &NoopNotifier{})
+		require.ErrorIs(t, service.CheckKillLink("not-a-token"), ErrInvalidKillLink)
+		require.ErrorIs(t, service.UseKillLink(ctx, "not-a-token", client), ErrInvalidKillLink)
+	})
+}
+
+// TestKillLinkNotification проверяет, что доставленное уведомление содержит рабочую ссылку "Это был не я".
+func TestKillLinkNotification(t *testing.T) {
+	ctx := context.Background()
+	config.Secret = "test_secret"
+	config.KillLinkSecret = testKillLinkSecret
+	config.MaxTokensPerUser = "5"
+	config.NotificationReportUrl = "https://auth.example.com/api/auth/kill-link"
+	t.Cleanup(func() { config.NotificationReportUrl = "" })
+	notifier := &recordingNotifier{}
+	service := NewAuthService(newTestStore(t), notifier)
+	userId := "123"
+
+	tokensPair, err := service.GenerateTokens(ctx, userId, nil, &entities.ClientInfo{Ip: "192.168.0.1"})
+	require.NoError(t, err)
+	newClient := &entities.ClientInfo{Ip: "192.168.0.2"}
+	refreshedTokensPair, err := service.RefreshTokens(ctx, newClient, tokensPair)
+	require.NoError(t, err)
+	_, err = service.deliverOutbox(ctx, &outboxSettings{batchSize: 10, maxAttempts: 3})
+	require.NoError(t, err)
+
+	notifications := notifier.received()
+	require.Len(t, notifications, 1)
+	reportUrl, err := url.Parse(notifications[0].ReportUrl)
+	require.NoError(t, err)
+	require.NoError(t, service.UseKillLink(ctx, reportUrl.Query().Get("token"), newClient))
+
+	_, err = service.RefreshTokens(ctx, newClient, refreshedTokensPair)
+	require.ErrorContains(t, err, "not found")
+}
+
+// parseKillLinkUrl формирует ссылку для уведомления и возвращает claims ее токена.
+func parseKillLinkUrl(t *testing.T, notification *entities.Notification) *killLinkClaims {
+	reportUrl, err := killLinkUrl(notification)
+	require.NoError(t, err)
+	parsedUrl, err := url.Parse(reportUrl)
+	require.NoError(t, err)
+	require.Equal(t, "auth.example.com", parsedUrl.Host)
+
+	claims, err := parseKillLinkToken(parsedUrl.Query().Get("token"))
+	require.NoError(t, err)
+
+	return claims
+}
+
+// genKillLink генерирует токен ссылки со сроком действия один час.
+func genKillLink(t *testing.T, userId, familyId, scope string) string {
+	id, err := GenJti()
+	require.NoError(t, err)
+	token, err := GenKillLinkToken(&killLinkClaims{
+		Id:        id,
+		UserId:    userId,
+		FamilyId:  familyId,
+		Scope:     scope,
+		ExpiredAt: time.Now().Add(time.Hour).Unix(),
+	})
+	require.NoError(t, err)
+
+	return token
+}
+
+// mustParseKillLink возвращает claims токена ссылки.
+func mustParseKillLink(t *testing.T, token string) *killLinkClaims {
+	claims, err := parseKillLinkToken(token)
+	require.NoError(t, err)
+
+	return claims
+}
+
+// failingRevokeStore - in-memory хранилище, в котором завершение всех сессий пользователя может завершаться ошибкой.
+type failingRevokeStore struct {
+	*memory.Memory
+	fail bool // Возвращать ли ErrStorageUnavailable из RevokeAllRefreshTokens.
+}
+
+// RevokeAllRefreshTokens возвращает ErrStorageUnavailable, если установлен флаг fail.
+func (s *failingRevokeStore) RevokeAllRefreshTokens(ctx context.Context, userId string) ([]*entities.RefreshTokenRecord, error) {
+	if s.fail {
+		return nil, storage.ErrStorageUnavailable
+	}
+
+	return s.Memory.RevokeAllRefreshTokens(ctx, userId)
+}
+
+// familyOf возвращает идентификатор сессии, к которой относится access-токен.
+func familyOf(t *testing.T, service *AuthService, accessToken string) string {
+	ctx := context.Background()
+	claims, err := service.ValidateAccessToken(ctx, accessToken)
+	require.NoError(t, err)
+	record, err := service.storage.GetRefreshTokenRecord(ctx, claims.Jti, claims.UserId)
+	require.NoError(t, err)
+
+	return record.FamilyId
+}
@@ -0,0 +1,97 @@
+package services
+
+import (
+	"auth_service/internal/config"
+	"fmt"
+	"strconv"
+	"time"
+)
+
+const (
+	defaultAccessTokenTTL  = 1 * time.Hour      // Время жизни access-токена, если ACCESS_TOKEN_TTL не задан.
+	defaultRefreshTokenTTL = 3 * 24 * time.Hour // Время жизни refresh-токена, если REFRESH_TOKEN_TTL не задан.
+)
+
+// tokenLifetimes содержит настройки времени жизни токенов и сессий.
+type tokenLifetimes struct {
+	accessTTL          time.Duration // Время жизни access-токена.
+	refreshTTL         time.Duration // Время жизни refresh-токена.
+	maxSessionLifetime time.Duration // Максимальное время жизни сессии (0 - без ограничения).
+	slidingExpiry      bool          // Продлевать ли срок действия refresh-токена при обновлении.
+}
+
+// getTokenLifetimes возвращает настройки времени жизни токенов из конфига.
+func getTokenLifetimes() (*tokenLifetimes, error) {
+	accessTTL, err := getMinutes("ACCESS_TOKEN_TTL", config.AccessTokenTTL, defaultAccessTokenTTL)
+	if err != nil {
+		return nil, err
+	}
+	refreshTTL, err := getMinutes("REFRESH_TOKEN_TTL", config.RefreshTokenTTL, defaultRefreshTokenTTL)
+	if err != nil {
+		return nil, err
+	}
+	maxSessionLifetime, err := getMinutes("MAX_SESSION_LIFETIME", config.MaxSessionLifetime, 0)
+	if err != nil {
+		return nil, err
+	}
+	slidingExpiry := true
+	if config.SlidingExpiry != "" {
+		slidingExpiry, err = strconv.ParseBool(config.SlidingExpiry)
+		if err != nil {
+			return nil, fmt.Errorf("env 'SLIDING_EXPIRY' is not bool: %w", err)
+		}
+	}
+
+	lifetimes := &tokenLifetimes{
+		accessTTL:          accessTTL,
+		refreshTTL:         refreshTTL,
+		maxSessionLifetime: maxSessionLifetime,
+		slidingExpiry:      slidingExpiry,
+	}
+
+	return lifetimes, nil
+}
+
+// refreshExpiry вычисляет срок действия нового refresh-токена.
+// При скользящем сроке действия токен продлевается на refreshTTL от текущего момента,
+// иначе наследует срок действия предыдущего токена сессии (prevExpiredAt).
+// Срок действия не может превышать максимальное время жизни сессии.
+func (l *tokenLifetimes) refreshExpiry(now, sessionStartedAt, prevExpiredAt time.Time) time.Time {
+	expiredAt := now.Add(l.refreshTTL)
+	if !l.slidingExpiry && !prevExpiredAt.IsZero() {
+		expiredAt = prevExpiredAt
+	}
+
+	return l.capToSession(expiredAt, sessionStartedAt)
+}
+
+// accessExpiry вычисляет срок действия access-токена, не превышающий максимальное время жизни сессии.
+func (l *tokenLifetimes) accessExpiry(now, sessionStartedAt time.Time) time.Time {
+	return l.capToSession(now.Add(l.accessTTL), sessionStartedAt)
+}
+
+// capToSession ограничивает срок действия токена максимальным временем жизни сессии.
+func (l *tokenLifetimes) capToSession(expiredAt, sessionStartedAt time.Time) time.Time {
+	if l.maxSessionLifetime <= 0 || sessionStartedAt.IsZero() {
+		return expiredAt
+	}
+	if sessionEnd := sessionStartedAt.Add(l.maxSessionLifetime); expiredAt.After(sessionEnd) {
+		return sessionEnd
+	}
+
+	return expiredAt
+}
+
+// getMinutes разбирает значение переменной окружения в минутах.
+// Если значение не задано, возвращает значение по умолчанию.
+func getMinutes(name, value string, defaultValue time.Duration) (time.Duration, error) {
+	if value == "" {
+		return defaultValue, nil
+	}
+	minutes, err := strconv.Atoi(value)
+	if err != nil {
+		return 0, fmt.Errorf("env '%s' is not number: %w", name, err)
+	}
+
+	return time.Duration(minutes) * time.Minute, nil
+}
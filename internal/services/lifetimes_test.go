@@ -0,0 +1,71 @@
+package services
+
+import (
+	"auth_service/internal/config"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+// TestTokenLifetimes проверяет вычисление сроков действия токенов с учетом настроек конфига.
+func TestTokenLifetimes(t *testing.T) {
+	t.Cleanup(func() {
+		config.AccessTokenTTL = ""
+		config.RefreshTokenTTL = ""
+		config.MaxSessionLifetime = ""
+		config.SlidingExpiry = ""
+	})
+	now := time.Now()
+
+	t.Run("default lifetimes", func(t *testing.T) {
+		lifetimes, err := getTokenLifetimes()
+		require.NoError(t, err)
+
+		require.Equal(t, now.Add(time.Hour), lifetimes.accessExpiry(now, now))
+		require.Equal(t, now.Add(72*time.Hour), lifetimes.refreshExpiry(now, now, time.Time{}))
+	})
+	t.Run("sliding expiry", func(t *testing.T) {
+		config.RefreshTokenTTL = "60"
+		config.SlidingExpiry = "true"
+		lifetimes, err := getTokenLifetimes()
+		require.NoError(t, err)
+
+		sessionStartedAt := now.Add(-30 * time.Minute)
+		prevExpiredAt := sessionStartedAt.Add(time.Hour)
+		require.Equal(t, now.Add(time.Hour), lifetimes.refreshExpiry(now, sessionStartedAt, prevExpiredAt))
+	})
+	t.Run("fixed expiry", func(t *testing.T) {
+		config.RefreshTokenTTL = "60"
+		config.SlidingExpiry = "false"
+		lifetimes, err := getTokenLifetimes()
+		require.NoError(t, err)
+
+		sessionStartedAt := now.Add(-30 * time.Minute)
+		prevExpiredAt := sessionStartedAt.Add(time.Hour)
+		require.Equal(t, prevExpiredAt, lifetimes.refreshExpiry(now, sessionStartedAt, prevExpiredAt))
+	})
+	t.Run("max session lifetime", func(t *testing.T) {
+		config.AccessTokenTTL = "60"
+		config.RefreshTokenTTL = "600"
+		config.MaxSessionLifetime = "120"
+		config.SlidingExpiry = "true"
+		lifetimes, err := getTokenLifetimes()
+		require.NoError(t, err)
+
+		sessionStartedAt := now.Add(-100 * time.Minute)
+		sessionEnd := sessionStartedAt.Add(120 * time.Minute)
+		require.Equal(t, sessionEnd, lifetimes.refreshExpiry(now, sessionStartedAt, time.Time{}))
+		require.Equal(t, sessionEnd, lifetimes.accessExpiry(now, sessionStartedAt))
+	})
+	t.Run("invalid config", func(t *testing.T) {
+		config.AccessTokenTTL = "one hour"
+		_, err := getTokenLifetimes()
+		require.ErrorContains(t, err, "env 'ACCESS_TOKEN_TTL' is not number")
+
+		config.AccessTokenTTL = ""
+		config.SlidingExpiry = "maybe"
+		_, err = getTokenLifetimes()
+		require.ErrorContains(t, err, "env 'SLIDING_EXPIRY' is not bool")
+	})
+}
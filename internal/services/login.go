@@ -0,0 +1,62 @@
+package services
+
+import (
+	"auth_service/internal/entities"
+	"auth_service/internal/storage"
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// Login аутентифицирует пользователя по идентификатору (email или id) и паролю и выдает пару токенов.
+// Неизвестный пользователь, отсутствие пароля и неверный пароль неразличимы ни по ошибке (ErrInvalidCredentials),
+// ни по времени ответа. Статус пользователя проверяется только после проверки пароля (ErrUserDisabled).
+// Если у пользователя включен TOTP, вместо токенов возвращается токен MFA-проверки для VerifyMfa.
+func (s *AuthService) Login(ctx context.Context, identifier, password string, client *entities.ClientInfo) (*entities.LoginResponse, error) {
+	user, err := s.findUser(ctx, identifier)
+	if errors.Is(err, storage.ErrUserNotFound) {
+		return nil, fmt.Errorf("user '%s' was not found: %w", identifier, checkDummyPassword(password))
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get user: %w", err)
+	}
+
+	passwordHash, err := s.storage.GetPasswordHash(ctx, user.Id)
+	if errors.Is(err, storage.ErrCredentialsNotFound) {
+		return nil, fmt.Errorf("user '%s' has no password: %w", user.Id, checkDummyPassword(password))
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get password hash: %w", err)
+	}
+	if err := checkPassword(password, passwordHash); err != nil {
+		return nil, fmt.Errorf("failed to check password for userID '%s': %w", user.Id, err)
+	}
+
+	mfaToken, err := s.mfaChallenge(ctx, user.Id)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create mfa challenge: %w", err)
+	}
+	if mfaToken != "" {
+		if _, err := s.getActiveUser(ctx, user.Id); err != nil {
+			return nil, err
+		}
+		return &entities.LoginResponse{MfaRequired: true, MfaToken: mfaToken}, nil
+	}
+
+	tokensPair, err := s.GenerateTokens(ctx, user.Id, []string{entities.AmrPassword}, client)
+	if err != nil {
+		return nil, err
+	}
+
+	return &entities.LoginResponse{TokensPair: tokensPair}, nil
+}
+
+// findUser возвращает пользователя по email (если идентификатор содержит '@') или по id.
+func (s *AuthService) findUser(ctx context.Context, identifier string) (*entities.User, error) {
+	if strings.Contains(identifier, "@") {
+		return s.storage.GetUserByEmail(ctx, identifier)
+	}
+
+	return s.storage.GetUser(ctx, identifier)
+}
@@ -0,0 +1,85 @@
+package services
+
+import (
+	"auth_service/internal/config"
+	"auth_service/internal/entities"
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+// TestLogin проверяет вход по email или идентификатору пользователя и паролю.
+func TestLogin(t *testing.T) {
+	ctx := context.Background()
+	config.Secret = "test_secret"
+	config.MaxTokensPerUser = "5"
+	client := &entities.ClientInfo{Ip: "192.168.0.1"}
+	store := newTestStore(t)
+	service := NewAuthService(store, &NoopNotifier{})
+	passwordHash, err := HashPassword("password")
+	require.NoError(t, err)
+	require.NoError(t, store.SavePasswordHash(ctx, "123", passwordHash))
+
+	t.Run("login by email", func(t *testing.T) {
+		tokensPair, err := service.Login(ctx, "USER@gmail.com", "password", client)
+		require.NoError(t, err)
+
+		claims, err := service.ValidateAccessToken(ctx, tokensPair.AccessToken)
+		require.NoError(t, err)
+		require.Equal(t, "123", claims.UserId)
+	})
+	t.Run("login by id", func(t *testing.T) {
+		_, err := service.Login(ctx, "123", "password", client)
+		require.NoError(t, err)
+	})
+	t.Run("wrong password", func(t *testing.T) {
+		_, err := service.Login(ctx, "user@gmail.com", "wrong password", client)
+		require.ErrorIs(t, err, ErrInvalidCredentials)
+	})
+	t.Run("unknown user", func(t *testing.T) {
+		_, err := service.Login(ctx, "unknown@gmail.com", "password", client)
+		require.ErrorIs(t, err, ErrInvalidCredentials)
+		_, err = service.Login(ctx, "456", "password", client)
+		require.ErrorIs(t, err, ErrInvalidCredentials)
+	})
+	t.Run("user without password", func(t *testing.T) {
+		require.NoError(t, store.CreateUser(ctx, &entities.User{
+			Id:        "456",
+			Email:     "nopassword@gmail.com",
+			Status:    entities.UserStatusActive,
+			CreatedAt: time.Now(),
+		}))
+		_, err := service.Login(ctx, "nopassword@gmail.com", "", client)
+		require.ErrorIs(t, err, ErrInvalidCredentials)
+	})
+	t.Run("disabled user", func(t *testing.T) {
+		user, err := store.GetUser(ctx, "123")
+		require.NoError(t, err)
+		user.Status = entities.UserStatusDisabled
+		require.NoError(t, store.UpdateUser(ctx, user))
+		t.Cleanup(func() {
+			user.Status = entities.UserStatusActive
+			store.UpdateUser(ctx, user)
+		})
+
+		_, err = service.Login(ctx, "user@gmail.com", "wrong password", client)
+		require.ErrorIs(t, err, ErrInvalidCredentials)
+		_, err = service.Login(ctx, "user@gmail.com", "password", client)
+		require.ErrorIs(t, err, ErrUserDisabled)
+	})
+	t.Run("canceled context", func(t *testing.T) {
+		sessions, err := store.GetRefreshTokenRecords(ctx, "123")
+		require.NoError(t, err)
+
+		canceledCtx, cancel := context.WithCancel(ctx)
+		cancel()
+		_, err = service.Login(canceledCtx, "user@gmail.com", "password", client)
+		require.ErrorIs(t, err, context.Canceled)
+
+		actualSessions, err := store.GetRefreshTokenRecords(ctx, "123")
+		require.NoError(t, err)
+		require.Len(t, actualSessions, len(sessions))
+	})
+}
@@ -0,0 +1,218 @@
+package services
+
+import (
+	"auth_service/internal/config"
+	"auth_service/internal/entities"
+	"auth_service/internal/storage"
+	"context"
+	"crypto/rand"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+const (
+	recoveryCodesCount     = 10              // Количество кодов восстановления, выдаваемых при подключении TOTP.
+	recoveryCodeLength     = 10              // Длина кода восстановления в байтах (80 бит, 16 символов base32).
+	defaultMfaChallengeTTL = 5 * time.Minute // Время действия токена MFA-проверки, если MFA_CHALLENGE_TTL не задан.
+)
+
+// EnrollTotp начинает подключение TOTP для владельца access-токена: генерирует и сохраняет зашифрованный секрет.
+// TOTP включается только после подтверждения первым кодом (ConfirmTotp), до этого повторный вызов заменяет секрет.
+// Если TOTP уже включен, возвращает ErrMfaAlreadyEnabled.
+func (s *AuthService) EnrollTotp(ctx context.Context, accessToken string) (*entities.TotpEnrollment, error) {
+	accessTokenClaims, err := s.ValidateAccessToken(ctx, accessToken)
+	if err != nil {
+		return nil, fmt.Errorf("failed to validate access token: %w", err)
+	}
+	user, err := s.getActiveUser(ctx, accessTokenClaims.UserId)
+	if err != nil {
+		return nil, err
+	}
+	credential, err := s.storage.GetTotpCredential(ctx, user.Id)
+	if err != nil && !errors.Is(err, storage.ErrCredentialsNotFound) {
+		return nil, fmt.Errorf("failed to get totp credential: %w", err)
+	}
+	if credential != nil && credential.Confirmed {
+		return nil, fmt.Errorf("user '%s': %w", user.Id, ErrMfaAlreadyEnabled)
+	}
+
+	secret, err := genTotpSecret()
+	if err != nil {
+		return nil, err
+	}
+	encryptedSecret, err := encryptTotpSecret(user.Id, secret)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encrypt totp secret: %w", err)
+	}
+	err = s.storage.SaveTotpCredential(ctx, &entities.TotpCredential{
+		UserId:          user.Id,
+		EncryptedSecret: encryptedSecret,
+		CreatedAt:       time.Now().UTC(),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to save totp credential: %w", err)
+	}
+
+	enrollment := &entities.TotpEnrollment{
+		Secret:     totpEncoding.EncodeToString(secret),
+		OtpauthUri: totpUri(user.Email, secret),
+	}
+
+	return enrollment, nil
+}
+
+// ConfirmTotp включает TOTP для владельца access-токена после проверки первого кода из приложения.
+// Возвращает коды восстановления, которые показываются пользователю один раз; в хранилище сохраняются только их хэши.
+func (s *AuthService) ConfirmTotp(ctx context.Context, accessToken, code string) ([]string, error) {
+	accessTokenClaims, err := s.ValidateAccessToken(ctx, accessToken)
+	if err != nil {
+		return nil, fmt.Errorf("failed to validate access token: %w", err)
+	}
+	userId := accessTokenClaims.UserId
+	credential, err := s.storage.GetTotpCredential(ctx, userId)
+	if errors.Is(err, storage.ErrCredentialsNotFound) {
+		return nil, fmt.Errorf("user '%s': %w", userId, ErrMfaNotEnrolled)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get totp credential: %w", err)
+	}
+	if credential.Confirmed {
+		return nil, fmt.Errorf("user '%s': %w", userId, ErrMfaAlreadyEnabled)
+	}
+	secret, err := decryptTotpSecret(userId, credential.EncryptedSecret)
+	if err != nil {
+		return nil, err
+	}
+	step, ok := matchTotpCode(secret, strings.TrimSpace(code), time.Now())
+	if !ok {
+		return nil, fmt.Errorf("user '%s': %w", userId, ErrInvalidMfaCode)
+	}
+
+	recoveryCodes, codeHashes, err := genRecoveryCodes()
+	if err != nil {
+		return nil, err
+	}
+	if err := s.storage.SaveRecoveryCodes(ctx, userId, codeHashes); err != nil {
+		return nil, fmt.Errorf("failed to save recovery codes: %w", err)
+	}
+	credential.Confirmed = true
+	credential.LastUsedStep = step
+	if err := s.storage.SaveTotpCredential(ctx, credential); err != nil {
+		return nil, fmt.Errorf("failed to save totp credential: %w", err)
+	}
+
+	return recoveryCodes, nil
+}
+
+// VerifyMfa завершает вход пользователя с включенным TOTP: проверяет токен MFA-проверки, выданный Login,
+// и код TOTP или код восстановления, после чего выдает пару токенов.
+// Токен MFA-проверки одноразовый, поэтому после неверного кода вход нужно начать заново.
+func (s *AuthService) VerifyMfa(ctx context.Context, mfaToken, code string, client *entities.ClientInfo) (*entities.TokensPair, error) {
+	if mfaToken == "" {
+		return nil, fmt.Errorf("empty token: %w", ErrInvalidMfaToken)
+	}
+	challenge, err := s.storage.ConsumeOneTimeToken(ctx, entities.OneTimeTokenMfaChallenge, hashOneTimeToken(mfaToken), time.Now())
+	if errors.Is(err, storage.ErrTokenNotFound) || errors.Is(err, storage.ErrTokenExpired) {
+		return nil, fmt.Errorf("failed to consume token: %w", ErrInvalidMfaToken)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to consume token: %w", err)
+	}
+
+	amr, err := s.checkSecondFactor(ctx, challenge.UserId, code)
+	if err != nil {
+		return nil, err
+	}
+
+	return s.GenerateTokens(ctx, challenge.UserId, amr, client)
+}
+
+// checkSecondFactor проверяет код TOTP или код восстановления пользователя и возвращает методы аутентификации для claim amr.
+// Каждый код TOTP и каждый код восстановления принимается только один раз.
+func (s *AuthService) checkSecondFactor(ctx context.Context, userId, code string) ([]string, error) {
+	credential, err := s.storage.GetTotpCredential(ctx, userId)
+	if errors.Is(err, storage.ErrCredentialsNotFound) {
+		return nil, fmt.Errorf("user '%s': %w", userId, ErrMfaNotEnrolled)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get totp credential: %w", err)
+	}
+
+	code = strings.TrimSpace(code)
+	if len(code) == totpDigits {
+		secret, err := decryptTotpSecret(userId, credential.EncryptedSecret)
+		if err != nil {
+			return nil, err
+		}
+		step, ok := matchTotpCode(secret, code, time.Now())
+		if !ok {
+			return nil, fmt.Errorf("user '%s': %w", userId, ErrInvalidMfaCode)
+		}
+		used, err := s.storage.UseTotpStep(ctx, userId, step)
+		if err != nil {
+			return nil, fmt.Errorf("failed to use totp step: %w", err)
+		}
+		if !used {
+			return nil, fmt.Errorf("user '%s': totp code was already used: %w", userId, ErrInvalidMfaCode)
+		}
+		return []string{entities.AmrPassword, entities.AmrOtp, entities.AmrMfa}, nil
+	}
+
+	consumed, err := s.storage.ConsumeRecoveryCode(ctx, userId, hashOneTimeToken(normalizeRecoveryCode(code)))
+	if err != nil {
+		return nil, fmt.Errorf("failed to consume recovery code: %w", err)
+	}
+	if !consumed {
+		return nil, fmt.Errorf("user '%s': %w", userId, ErrInvalidMfaCode)
+	}
+
+	return []string{entities.AmrPassword, entities.AmrMfa}, nil
+}
+
+// mfaChallenge возвращает одноразовый токен MFA-проверки, если у пользователя включен TOTP, иначе пустую строку.
+func (s *AuthService) mfaChallenge(ctx context.Context, userId string) (string, error) {
+	credential, err := s.storage.GetTotpCredential(ctx, userId)
+	if errors.Is(err, storage.ErrCredentialsNotFound) {
+		return "", nil
+	}
+	if err != nil {
+		return "", fmt.Errorf("failed to get totp credential: %w", err)
+	}
+	if !credential.Confirmed {
+		return "", nil
+	}
+	ttl, err := getMinutes("MFA_CHALLENGE_TTL", config.MfaChallengeTTL, defaultMfaChallengeTTL)
+	if err != nil {
+		return "", err
+	}
+
+	return s.newOneTimeToken(ctx, userId, entities.OneTimeTokenMfaChallenge, ttl)
+}
+
+// genRecoveryCodes генерирует коды восстановления вида xxxx-xxxx-xxxx-xxxx и их SHA-256 хэши.
+func genRecoveryCodes() ([]string, []string, error) {
+	recoveryCodes := make([]string, 0, recoveryCodesCount)
+	codeHashes := make([]string, 0, recoveryCodesCount)
+	for range recoveryCodesCount {
+		bytes := make([]byte, recoveryCodeLength)
+		if _, err := rand.Read(bytes); err != nil {
+			return nil, nil, fmt.Errorf("failed to generate recovery code: %w", err)
+		}
+		code := strings.ToLower(totpEncoding.EncodeToString(bytes))
+		recoveryCodes = append(recoveryCodes, strings.Join([]string{code[0:4], code[4:8], code[8:12], code[12:16]}, "-"))
+		codeHashes = append(codeHashes, hashOneTimeToken(code))
+	}
+
+	return recoveryCodes, codeHashes, nil
+}
+
+// normalizeRecoveryCode приводит введенный код восстановления к виду, от которого вычисляется хэш:
+// без дефисов и пробелов, в нижнем регистре.
+func normalizeRecoveryCode(code string) string {
+	code = strings.ReplaceAll(code, "-", "")
+	code = strings.ReplaceAll(code, " ", "")
+
+	return strings.ToLower(code)
+}
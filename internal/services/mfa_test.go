@@ -0,0 +1,119 @@
+package services
+
+import (
+	"auth_service/internal/config"
+	"auth_service/internal/entities"
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+// TestMfa проверяет подключение TOTP, вход со вторым фактором и claim amr в access-токенах.
+func TestMfa(t *testing.T) {
+	ctx := context.Background()
+	config.Secret = "test_secret"
+	config.MaxTokensPerUser = "5"
+	config.TotpEncryptionKey = "MDEyMzQ1Njc4OWFiY2RlZjAxMjM0NTY3ODlhYmNkZWY="
+	t.Cleanup(func() { config.TotpEncryptionKey = "" })
+	client := &entities.ClientInfo{Ip: "192.168.0.1"}
+	store := newTestStore(t)
+	service := NewAuthService(store, &NoopNotifier{})
+	passwordHash, err := HashPassword("password")
+	require.NoError(t, err)
+	require.NoError(t, store.SavePasswordHash(ctx, "123", passwordHash))
+
+	loginResponse, err := service.Login(ctx, "user@gmail.com", "password", client)
+	require.NoError(t, err)
+	require.False(t, loginResponse.MfaRequired)
+	claims, err := service.ValidateAccessToken(ctx, loginResponse.AccessToken)
+	require.NoError(t, err)
+	require.Equal(t, []string{entities.AmrPassword}, claims.Amr)
+	accessToken := loginResponse.AccessToken
+
+	_, err = service.ConfirmTotp(ctx, accessToken, "000000")
+	require.ErrorIs(t, err, ErrMfaNotEnrolled)
+
+	enrollment, err := service.EnrollTotp(ctx, accessToken)
+	require.NoError(t, err)
+	require.Contains(t, enrollment.OtpauthUri, "user@gmail.com")
+	secret, err := totpEncoding.DecodeString(enrollment.Secret)
+	require.NoError(t, err)
+	credential, err := store.GetTotpCredential(ctx, "123")
+	require.NoError(t, err)
+	require.False(t, credential.Confirmed)
+	require.NotContains(t, credential.EncryptedSecret, enrollment.Secret)
+
+	// Подключение еще не подтверждено, поэтому вход по паролю не требует второго фактора.
+	loginResponse, err = service.Login(ctx, "user@gmail.com", "password", client)
+	require.NoError(t, err)
+	require.False(t, loginResponse.MfaRequired)
+
+	_, err = service.ConfirmTotp(ctx, accessToken, "wrong")
+	require.ErrorIs(t, err, ErrInvalidMfaCode)
+	now := time.Now()
+	recoveryCodes, err := service.ConfirmTotp(ctx, accessToken, totpCode(secret, totpStep(now)))
+	require.NoError(t, err)
+	require.Len(t, recoveryCodes, recoveryCodesCount)
+	_, err = service.EnrollTotp(ctx, accessToken)
+	require.ErrorIs(t, err, ErrMfaAlreadyEnabled)
+
+	login := func(t *testing.T) string {
+		loginResponse, err := service.Login(ctx, "user@gmail.com", "password", client)
+		require.NoError(t, err)
+		require.True(t, loginResponse.MfaRequired)
+		require.NotEmpty(t, loginResponse.MfaToken)
+		require.Nil(t, loginResponse.TokensPair)
+		return loginResponse.MfaToken
+	}
+
+	t.Run("totp code", func(t *testing.T) {
+		mfaToken := login(t)
+		tokensPair, err := service.VerifyMfa(ctx, mfaToken, totpCode(secret, totpStep(now)+1), client)
+		require.NoError(t, err)
+		claims, err := service.ValidateAccessToken(ctx, tokensPair.AccessToken)
+		require.NoError(t, err)
+		require.Equal(t, []string{entities.AmrPassword, entities.AmrOtp, entities.AmrMfa}, claims.Amr)
+
+		refreshedTokensPair, err := service.RefreshTokens(ctx, client, tokensPair)
+		require.NoError(t, err)
+		claims, err = service.ValidateAccessToken(ctx, refreshedTokensPair.AccessToken)
+		require.NoError(t, err)
+		require.Equal(t, []string{entities.AmrPassword, entities.AmrOtp, entities.AmrMfa}, claims.Amr)
+
+		_, err = service.VerifyMfa(ctx, mfaToken, totpCode(secret, totpStep(now)+1), client)
+		require.ErrorIs(t, err, ErrInvalidMfaToken)
+	})
+	t.Run("reused totp code", func(t *testing.T) {
+		_, err := service.VerifyMfa(ctx, login(t), totpCode(secret, totpStep(now)+1), client)
+		require.ErrorIs(t, err, ErrInvalidMfaCode)
+	})
+	t.Run("wrong code consumes challenge", func(t *testing.T) {
+		mfaToken := login(t)
+		_, err := service.VerifyMfa(ctx, mfaToken, "wrong-code", client)
+		require.ErrorIs(t, err, ErrInvalidMfaCode)
+		_, err = service.VerifyMfa(ctx, mfaToken, recoveryCodes[0], client)
+		require.ErrorIs(t, err, ErrInvalidMfaToken)
+	})
+	t.Run("recovery code", func(t *testing.T) {
+		tokensPair, err := service.VerifyMfa(ctx, login(t), " "+recoveryCodes[0]+" ", client)
+		require.NoError(t, err)
+		claims, err := service.ValidateAccessToken(ctx, tokensPair.AccessToken)
+		require.NoError(t, err)
+		require.Equal(t, []string{entities.AmrPassword, entities.AmrMfa}, claims.Amr)
+
+		_, err = service.VerifyMfa(ctx, login(t), recoveryCodes[0], client)
+		require.ErrorIs(t, err, ErrInvalidMfaCode)
+	})
+	t.Run("invalid mfa token", func(t *testing.T) {
+		_, err := service.VerifyMfa(ctx, "", "000000", client)
+		require.ErrorIs(t, err, ErrInvalidMfaToken)
+		_, err = service.VerifyMfa(ctx, "invalid", "000000", client)
+		require.ErrorIs(t, err, ErrInvalidMfaToken)
+	})
+	t.Run("wrong password", func(t *testing.T) {
+		_, err := service.Login(ctx, "user@gmail.com", "wrong password", client)
+		require.ErrorIs(t, err, ErrInvalidCredentials)
+	})
+}
@@ -0,0 +1,109 @@
+package services
+
+import (
+	"auth_service/internal/config"
+	"auth_service/internal/entities"
+	"auth_service/internal/mailtemplates"
+	"context"
+	"fmt"
+	"log"
+	"time"
+)
+
+// Notifier определяет интерфейс для доставки пользователю уведомлений о событиях безопасности и писем для подтверждения email.
+type Notifier interface {
+	Notify(ctx context.Context, notification *entities.Notification) error // Доставляет уведомление пользователю; отмена ctx прерывает доставку.
+}
+
+// NewNotifier создает Notifier по названию способа доставки: smtp, webhook, log или none.
+// Если способ не задан, используется log. Для smtp и webhook проверяет наличие необходимых переменных окружения.
+func NewNotifier(kind string) (Notifier, error) {
+	switch kind {
+	case "smtp":
+		if err := CheckConfigVar(); err != nil {
+			return nil, fmt.Errorf("config variable is empty: %w", err)
+		}
+		return NewSmtpNotifier(config.EmailTemplatesDir, config.EmailDefaultLocale)
+	case "webhook":
+		return NewWebhookNotifier(config.NotifierWebhookUrl, config.NotifierWebhookSecret)
+	case "log", "":
+		return &LogNotifier{}, nil
+	case "none":
+		return &NoopNotifier{}, nil
+	default:
+		return nil, fmt.Errorf("unknown notifier: '%s'", kind)
+	}
+}
+
+// SmtpNotifier доставляет уведомления по email через SMTP-сервер из конфига.
+// Письма формируются по шаблонам на языке пользователя.
+type SmtpNotifier struct {
+	templates *mailtemplates.Set
+}
+
+// NewSmtpNotifier создает SmtpNotifier с шаблонами писем из каталога templatesDir.
+// Если каталог не задан, используются встроенные шаблоны.
+func NewSmtpNotifier(templatesDir, defaultLocale string) (*SmtpNotifier, error) {
+	templates, err := mailtemplates.Load(templatesDir, defaultLocale)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load email templates: %w", err)
+	}
+
+	return &SmtpNotifier{templates: templates}, nil
+}
+
+// emailData содержит переменные, доступные в шаблонах писем.
+type emailData struct {
+	Ip         string    // IP-адрес клиента, с которого произошло событие.
+	PreviousIp string    // IP-адрес, с которого был выдан предыдущий токен.
+	UserAgent  string    // Значение заголовка User-Agent клиента.
+	Location   string    // Приблизительное местоположение клиента.
+	ReportUrl  string    // Ссылка "Это был не я".
+	ActionUrl  string    // Ссылка для подтверждения действия пользователя (подтверждение email, сброс пароля).
+	Time       time.Time // Время события в UTC.
+}
+
+// Notify отправляет письмо, соответствующее виду уведомления.
+func (n *SmtpNotifier) Notify(ctx context.Context, notification *entities.Notification) error {
+	switch notification.Type {
+	case entities.NotificationIpChanged, entities.NotificationTokenReuse, entities.NotificationVerifyEmail, entities.NotificationPasswordReset:
+	default:
+		return fmt.Errorf("unsupported notification type: '%s'", notification.Type)
+	}
+
+	data := &emailData{
+		Ip:         notification.Ip,
+		PreviousIp: notification.PreviousIp,
+		UserAgent:  notification.UserAgent,
+		Location:   notification.Location,
+		ReportUrl:  notification.ReportUrl,
+		ActionUrl:  notification.ActionUrl,
+		Time:       notification.CreatedAt.UTC(),
+	}
+	email, err := n.templates.Render(notification.Locale, string(notification.Type), data)
+	if err != nil {
+		return fmt.Errorf("failed to render email: %w", err)
+	}
+
+	return SendEmail(ctx, notification.Email, email)
+}
+
+// LogNotifier записывает уведомления в лог сервиса вместо отправки пользователю.
+type LogNotifier struct{}
+
+// Notify записывает в лог вид уведомления и получателя.
+// Ссылки из уведомления в лог не попадают: они содержат действующие токены подтверждения email,
+// сброса пароля и завершения сессий, и любой, кто читает лог, смог бы ими воспользоваться.
+func (n *LogNotifier) Notify(ctx context.Context, notification *entities.Notification) error {
+	log.Printf("notification '%s' for userID: '%s', email: '%s'\n", notification.Type, notification.UserId, notification.Email)
+
+	return nil
+}
+
+// NoopNotifier игнорирует уведомления.
+type NoopNotifier struct{}
+
+// Notify ничего не делает.
+func (n *NoopNotifier) Notify(ctx context.Context, notification *entities.Notification) error {
+	return nil
+}
@@ -0,0 +1,242 @@
+package services
+
+import (
+	"auth_service/internal/config"
+	"auth_service/internal/entities"
+	"auth_service/internal/mailtemplates"
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"io"
+	"log"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+// recordingNotifier сохраняет полученные уведомления для проверки в тестах.
+type recordingNotifier struct {
+	notifications []*entities.Notification
+	err           error
+	cancel        context.CancelFunc // Если задан, вызывается после получения уведомления.
+	mu            sync.Mutex
+}
+
+// Notify сохраняет уведомление и возвращает заданную ошибку.
+func (n *recordingNotifier) Notify(ctx context.Context, notification *entities.Notification) error {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	n.notifications = append(n.notifications, notification)
+	if n.cancel != nil {
+		n.cancel()
+	}
+
+	return n.err
+}
+
+// received возвращает сохраненные уведомления.
+func (n *recordingNotifier) received() []*entities.Notification {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+
+	return n.notifications
+}
+
+// TestLogNotifier проверяет, что в лог не попадают ссылки с токенами из уведомления.
+func TestLogNotifier(t *testing.T) {
+	var buf bytes.Buffer
+	log.SetOutput(&buf)
+	t.Cleanup(func() { log.SetOutput(os.Stderr) })
+
+	err := (&LogNotifier{}).Notify(context.Background(), &entities.Notification{
+		Type:      entities.NotificationPasswordReset,
+		UserId:    "123",
+		Email:     "user@gmail.com",
+		ActionUrl: "https://auth.example.com/reset?token=secret-reset-token",
+		ReportUrl: "https://auth.example.com/api/auth/kill-link?token=secret-kill-link",
+	})
+	require.NoError(t, err)
+	require.Contains(t, buf.String(), "password_reset")
+	require.Contains(t, buf.String(), "user@gmail.com")
+	require.NotContains(t, buf.String(), "secret-reset-token")
+	require.NotContains(t, buf.String(), "secret-kill-link")
+}
+
+// TestNewNotifier проверяет выбор реализации Notifier по конфигу.
+func TestNewNotifier(t *testing.T) {
+	t.Run("log by default", func(t *testing.T) {
+		notifier, err := NewNotifier("")
+		require.NoError(t, err)
+		require.IsType(t, &LogNotifier{}, notifier)
+	})
+	t.Run("noop", func(t *testing.T) {
+		notifier, err := NewNotifier("none")
+		require.NoError(t, err)
+		require.IsType(t, &NoopNotifier{}, notifier)
+	})
+	t.Run("smtp without config", func(t *testing.T) {
+		senderEmail := config.SenderEmail
+		t.Cleanup(func() { config.SenderEmail = senderEmail })
+		config.SenderEmail = ""
+
+		_, err := NewNotifier("smtp")
+		require.ErrorContains(t, err, "'SENDER_EMAIL' is not set")
+	})
+	t.Run("smtp with missing templates", func(t *testing.T) {
+		senderEmail, smtpHost, smtpPort, passwordEmail := config.SenderEmail, config.SmtpHost, config.SmtpPort, config.PasswordEmail
+		t.Cleanup(func() {
+			config.SenderEmail, config.SmtpHost, config.SmtpPort, config.PasswordEmail = senderEmail, smtpHost, smtpPort, passwordEmail
+			config.EmailTemplatesDir = ""
+		})
+		config.SenderEmail, config.SmtpHost, config.SmtpPort, config.PasswordEmail = "security@gmail.com", "localhost", "1025", "secret"
+
+		config.EmailTemplatesDir = t.TempDir()
+		_, err := NewNotifier("smtp")
+		require.ErrorContains(t, err, "failed to load email templates")
+
+		config.EmailTemplatesDir = ""
+		notifier, err := NewNotifier("smtp")
+		require.NoError(t, err)
+		require.IsType(t, &SmtpNotifier{}, notifier)
+	})
+	t.Run("webhook", func(t *testing.T) {
+		t.Cleanup(func() { config.NotifierWebhookUrl = "" })
+
+		config.NotifierWebhookUrl = ""
+		_, err := NewNotifier("webhook")
+		require.ErrorContains(t, err, "'NOTIFIER_WEBHOOK_URL' is not set")
+
+		config.NotifierWebhookUrl = "ftp://example.com"
+		_, err = NewNotifier("webhook")
+		require.ErrorContains(t, err, "is not valid http(s) URL")
+
+		config.NotifierWebhookUrl = "https://example.com/hooks/auth"
+		notifier, err := NewNotifier("webhook")
+		require.NoError(t, err)
+		require.IsType(t, &WebhookNotifier{}, notifier)
+	})
+	t.Run("unknown", func(t *testing.T) {
+		_, err := NewNotifier("pigeon")
+		require.ErrorContains(t, err, "unknown notifier")
+	})
+}
+
+// TestWebhookNotifier проверяет отправку уведомления на webhook и подпись тела запроса.
+func TestWebhookNotifier(t *testing.T) {
+	ctx := context.Background()
+	secret := "webhook_secret"
+	notification := &entities.Notification{
+		Type:       entities.NotificationIpChanged,
+		UserId:     "123",
+		Email:      "user@gmail.com",
+		Ip:         "192.168.0.2",
+		PreviousIp: "192.168.0.1",
+		CreatedAt:  time.Now().UTC().Truncate(time.Second),
+	}
+
+	t.Run("successful delivery", func(t *testing.T) {
+		var received entities.Notification
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			body, err := io.ReadAll(r.Body)
+			require.NoError(t, err)
+
+			mac := hmac.New(sha256.New, []byte(secret))
+			mac.Write(body)
+			require.Equal(t, "sha256="+hex.EncodeToString(mac.Sum(nil)), r.Header.Get("X-Signature"))
+			require.Equal(t, "application/json", r.Header.Get("Content-Type"))
+			require.NoError(t, json.Unmarshal(body, &received))
+
+			w.WriteHeader(http.StatusAccepted)
+		}))
+		defer server.Close()
+
+		notifier, err := NewWebhookNotifier(server.URL, secret)
+		require.NoError(t, err)
+		require.NoError(t, notifier.Notify(ctx, notification))
+		require.Equal(t, notification, &received)
+	})
+	t.Run("unsuccessful status", func(t *testing.T) {
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusInternalServerError)
+		}))
+		defer server.Close()
+
+		notifier, err := NewWebhookNotifier(server.URL, "")
+		require.NoError(t, err)
+		require.ErrorContains(t, notifier.Notify(ctx, notification), "status 500")
+	})
+	t.Run("canceled delivery", func(t *testing.T) {
+		// Webhook не отвечает, пока тест не завершится.
+		release := make(chan struct{})
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			<-release
+		}))
+		defer server.Close()
+		defer close(release)
+
+		notifier, err := NewWebhookNotifier(server.URL, "")
+		require.NoError(t, err)
+		ctx, cancel := context.WithTimeout(ctx, 100*time.Millisecond)
+		defer cancel()
+		require.ErrorIs(t, notifier.Notify(ctx, notification), context.DeadlineExceeded)
+	})
+}
+
+// TestSendEmailCancellation проверяет, что отмена контекста прерывает отправку письма, не дожидаясь SMTP-сервера.
+func TestSendEmailCancellation(t *testing.T) {
+	// SMTP-сервер принимает соединение, но не отвечает.
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	var (
+		conns []net.Conn
+		mu    sync.Mutex
+	)
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			mu.Lock()
+			conns = append(conns, conn)
+			mu.Unlock()
+		}
+	}()
+	t.Cleanup(func() {
+		listener.Close()
+		mu.Lock()
+		defer mu.Unlock()
+		for _, conn := range conns {
+			conn.Close()
+		}
+	})
+
+	host, port, err := net.SplitHostPort(listener.Addr().String())
+	require.NoError(t, err)
+	smtpHost, smtpPort := config.SmtpHost, config.SmtpPort
+	config.SmtpHost, config.SmtpPort = host, port
+	t.Cleanup(func() { config.SmtpHost, config.SmtpPort = smtpHost, smtpPort })
+	email := &mailtemplates.Email{Subject: "Subject", Text: "Text", Html: "<p>Text</p>"}
+
+	t.Run("canceled context", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+		require.ErrorIs(t, SendEmail(ctx, "user@gmail.com", email), context.Canceled)
+	})
+	t.Run("deadline exceeded", func(t *testing.T) {
+		ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+		defer cancel()
+		start := time.Now()
+		require.ErrorIs(t, SendEmail(ctx, "user@gmail.com", email), context.DeadlineExceeded)
+		require.Less(t, time.Since(start), time.Second)
+	})
+}
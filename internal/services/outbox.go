@@ -0,0 +1,218 @@
+package services
+
+import (
+	"auth_service/internal/config"
+	"auth_service/internal/entities"
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"strconv"
+	"time"
+)
+
+const (
+	defaultOutboxPollInterval = 5 * time.Second // Интервал опроса outbox, если OUTBOX_POLL_INTERVAL не задан.
+	defaultOutboxBatchSize    = 50              // Размер пачки уведомлений, если OUTBOX_BATCH_SIZE не задан.
+	defaultOutboxMaxAttempts  = 10              // Количество попыток доставки, если OUTBOX_MAX_ATTEMPTS не задан.
+
+	outboxLease       = 1 * time.Minute  // Время, на которое захваченное сообщение скрывается от других обработчиков.
+	outboxBaseBackoff = 30 * time.Second // Задержка перед второй попыткой доставки, удваивается с каждой попыткой.
+	outboxMaxBackoff  = 1 * time.Hour    // Максимальная задержка между попытками доставки.
+
+	outboxDeliveryTimeout = 30 * time.Second // Максимальное время доставки одного уведомления.
+)
+
+// outboxSettings содержит настройки фонового обработчика outbox.
+type outboxSettings struct {
+	pollInterval time.Duration // Интервал опроса outbox.
+	batchSize    int           // Максимальное количество уведомлений за один опрос.
+	maxAttempts  int           // Количество попыток доставки до перевода уведомления в dead.
+}
+
+// RunOutboxWorker периодически доставляет уведомления из outbox через Notifier.
+// Неудачная доставка повторяется с экспоненциальной задержкой, после исчерпания попыток уведомление помечается как dead.
+// Обработчик останавливается при отмене ctx.
+func (s *AuthService) RunOutboxWorker(ctx context.Context) error {
+	settings, err := getOutboxSettings()
+	if err != nil {
+		return fmt.Errorf("failed to get outbox settings: %w", err)
+	}
+
+	ticker := time.NewTicker(settings.pollInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+		}
+		if _, err := s.deliverOutbox(ctx, settings); err != nil {
+			log.Printf("failed to deliver notifications from outbox: %v\n", err)
+		}
+	}
+}
+
+// deliverOutbox захватывает пачку уведомлений, время доставки которых наступило, и доставляет их.
+// Возвращает количество успешно доставленных уведомлений.
+func (s *AuthService) deliverOutbox(ctx context.Context, settings *outboxSettings) (int, error) {
+	now := time.Now().UTC()
+	outboxMessages, err := s.storage.ClaimOutboxMessages(ctx, now, outboxLease, settings.batchSize)
+	if err != nil {
+		return 0, fmt.Errorf("failed to claim outbox messages: %w", err)
+	}
+
+	delivered := 0
+	for _, outboxMessage := range outboxMessages {
+		deliveryCtx, cancel := context.WithTimeout(ctx, outboxDeliveryTimeout)
+		deliveryErr := s.deliverOutboxMessage(deliveryCtx, outboxMessage)
+		cancel()
+		if err := ctx.Err(); err != nil {
+			// Захваченные сообщения будут доставлены повторно после истечения outboxLease.
+			return delivered, err
+		}
+		if deliveryErr == nil {
+			if err := s.storage.MarkOutboxMessageDelivered(ctx, outboxMessage.Id); err != nil {
+				log.Printf("failed to mark outbox message '%s' as delivered: %v\n", outboxMessage.Id, err)
+			}
+			delivered++
+			continue
+		}
+
+		attempts := outboxMessage.Attempts + 1
+		if attempts >= settings.maxAttempts {
+			log.Printf("outbox message '%s' moved to dead letters after %d attempts: %v\n", outboxMessage.Id, attempts, deliveryErr)
+			if err := s.storage.MarkOutboxMessageDead(ctx, outboxMessage.Id, deliveryErr.Error()); err != nil {
+				log.Printf("failed to mark outbox message '%s' as dead: %v\n", outboxMessage.Id, err)
+			}
+			continue
+		}
+		nextAttemptAt := now.Add(outboxBackoff(attempts))
+		log.Printf("failed to deliver outbox message '%s' (attempt %d), next attempt at %s: %v\n",
+			outboxMessage.Id, attempts, nextAttemptAt.Format(time.RFC3339), deliveryErr)
+		if err := s.storage.MarkOutboxMessageFailed(ctx, outboxMessage.Id, deliveryErr.Error(), nextAttemptAt); err != nil {
+			log.Printf("failed to reschedule outbox message '%s': %v\n", outboxMessage.Id, err)
+		}
+	}
+
+	return delivered, nil
+}
+
+// deliverOutboxMessage дополняет уведомление email пользователя и ссылками и передает его Notifier.
+// Email и одноразовые токены создаются в момент доставки, поэтому в outbox не хранятся персональные данные и секреты.
+// Письмо для подтверждения уже подтвержденного email и ссылка для сброса пароля заблокированному пользователю не отправляются.
+func (s *AuthService) deliverOutboxMessage(ctx context.Context, outboxMessage *entities.OutboxMessage) error {
+	var notification entities.Notification
+	if err := json.Unmarshal([]byte(outboxMessage.Payload), &notification); err != nil {
+		return fmt.Errorf("failed to unmarshal notification: %w", err)
+	}
+
+	user, err := s.storage.GetUser(ctx, notification.UserId)
+	if err != nil {
+		return fmt.Errorf("failed to get user: %w", err)
+	}
+	notification.Email = user.Email
+	notification.Locale = user.Locale
+	switch notification.Type {
+	case entities.NotificationVerifyEmail:
+		if user.EmailVerified {
+			return nil
+		}
+		actionUrl, err := s.emailVerificationUrl(ctx, user.Id)
+		if err != nil {
+			return fmt.Errorf("failed to create email verification link: %w", err)
+		}
+		notification.ActionUrl = actionUrl
+	case entities.NotificationPasswordReset:
+		if user.Status != entities.UserStatusActive {
+			return nil
+		}
+		actionUrl, err := s.passwordResetUrl(ctx, user.Id)
+		if err != nil {
+			return fmt.Errorf("failed to create password reset link: %w", err)
+		}
+		notification.ActionUrl = actionUrl
+	default:
+		reportUrl, err := killLinkUrl(&notification)
+		if err != nil {
+			return fmt.Errorf("failed to create kill link: %w", err)
+		}
+		notification.ReportUrl = reportUrl
+	}
+
+	return s.notifier.Notify(ctx, &notification)
+}
+
+// newOutboxMessage создает сообщение outbox для уведомления.
+// Ключом идемпотентности служит идентификатор уведомления.
+func newOutboxMessage(notification *entities.Notification) (*entities.OutboxMessage, error) {
+	payload, err := json.Marshal(notification)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal notification: %w", err)
+	}
+
+	now := time.Now().UTC()
+	outboxMessage := &entities.OutboxMessage{
+		Id:            notification.Id,
+		Payload:       string(payload),
+		Status:        entities.OutboxStatusPending,
+		NextAttemptAt: now,
+		CreatedAt:     now,
+	}
+
+	return outboxMessage, nil
+}
+
+// outboxBackoff возвращает задержку перед следующей попыткой доставки после attempts неудачных попыток.
+func outboxBackoff(attempts int) time.Duration {
+	backoff := outboxBaseBackoff
+	for i := 1; i < attempts; i++ {
+		backoff *= 2
+		if backoff >= outboxMaxBackoff {
+			return outboxMaxBackoff
+		}
+	}
+
+	return backoff
+}
+
+// getOutboxSettings возвращает настройки фонового обработчика outbox из конфига.
+func getOutboxSettings() (*outboxSettings, error) {
+	settings := &outboxSettings{
+		pollInterval: defaultOutboxPollInterval,
+		batchSize:    defaultOutboxBatchSize,
+		maxAttempts:  defaultOutboxMaxAttempts,
+	}
+	if config.OutboxPollInterval != "" {
+		seconds, err := strconv.Atoi(config.OutboxPollInterval)
+		if err != nil {
+			return nil, fmt.Errorf("env 'OUTBOX_POLL_INTERVAL' is not number: %w", err)
+		}
+		if seconds <= 0 {
+			return nil, fmt.Errorf("env 'OUTBOX_POLL_INTERVAL' must be positive")
+		}
+		settings.pollInterval = time.Duration(seconds) * time.Second
+	}
+	if config.OutboxBatchSize != "" {
+		batchSize, err := strconv.Atoi(config.OutboxBatchSize)
+		if err != nil {
+			return nil, fmt.Errorf("env 'OUTBOX_BATCH_SIZE' is not number: %w", err)
+		}
+		if batchSize <= 0 {
+			return nil, fmt.Errorf("env 'OUTBOX_BATCH_SIZE' must be positive")
+		}
+		settings.batchSize = batchSize
+	}
+	if config.OutboxMaxAttempts != "" {
+		maxAttempts, err := strconv.Atoi(config.OutboxMaxAttempts)
+		if err != nil {
+			return nil, fmt.Errorf("env 'OUTBOX_MAX_ATTEMPTS' is not number: %w", err)
+		}
+		if maxAttempts <= 0 {
+			return nil, fmt.Errorf("env 'OUTBOX_MAX_ATTEMPTS' must be positive")
+		}
+		settings.maxAttempts = maxAttempts
+	}
+
+	return settings, nil
+}
@@ -0,0 +1,188 @@
+package services
+
+import (
+	"auth_service/internal/config"
+	"auth_service/internal/entities"
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+// TestOutboxBackoff проверяет экспоненциальный рост задержки между попытками доставки.
+func TestOutboxBackoff(t *testing.T) {
+	require.Equal(t, 30*time.Second, outboxBackoff(1))
+	require.Equal(t, 1*time.Minute, outboxBackoff(2))
+	require.Equal(t, 2*time.Minute, outboxBackoff(3))
+	require.Equal(t, 32*time.Minute, outboxBackoff(7))
+	require.Equal(t, outboxMaxBackoff, outboxBackoff(8))
+	require.Equal(t, outboxMaxBackoff, outboxBackoff(100))
+}
+
+// TestRefreshTokensNotification проверяет, что уведомление о смене IP-адреса сохраняется в outbox
+// и доставляется фоновым обработчиком, а ошибка доставки не прерывает обновление токенов.
+func TestRefreshTokensNotification(t *testing.T) {
+	ctx := context.Background()
+	config.Secret = "test_secret"
+	config.MaxTokensPerUser = "5"
+	userId := "123"
+	client := &entities.ClientInfo{Ip: "192.168.0.1"}
+	newClient := &entities.ClientInfo{Ip: "192.168.0.2", UserAgent: "Mozilla/5.0", Location: "RU"}
+	settings := &outboxSettings{batchSize: 10, maxAttempts: 3}
+
+	t.Run("ip changed", func(t *testing.T) {
+		notifier := &recordingNotifier{}
+		service := NewAuthService(newTestStore(t), notifier)
+		tokensPair, err := service.GenerateTokens(ctx, userId, nil, client)
+		require.NoError(t, err)
+
+		_, err = service.RefreshTokens(ctx, newClient, tokensPair)
+		require.NoError(t, err)
+		require.Empty(t, notifier.received())
+
+		delivered, err := service.deliverOutbox(ctx, settings)
+		require.NoError(t, err)
+		require.Equal(t, 1, delivered)
+
+		notifications := notifier.received()
+		require.Len(t, notifications, 1)
+		require.Equal(t, entities.NotificationIpChanged, notifications[0].Type)
+		require.NotEmpty(t, notifications[0].Id)
+		require.Equal(t, userId, notifications[0].UserId)
+		require.Equal(t, "user@gmail.com", notifications[0].Email)
+		require.Equal(t, client.Ip, notifications[0].PreviousIp)
+		require.Equal(t, newClient.Ip, notifications[0].Ip)
+		require.Equal(t, newClient.UserAgent, notifications[0].UserAgent)
+		require.Equal(t, newClient.Location, notifications[0].Location)
+
+		delivered, err = service.deliverOutbox(ctx, settings)
+		require.NoError(t, err)
+		require.Zero(t, delivered)
+		require.Len(t, notifier.received(), 1)
+	})
+	t.Run("same ip", func(t *testing.T) {
+		notifier := &recordingNotifier{}
+		service := NewAuthService(newTestStore(t), notifier)
+		tokensPair, err := service.GenerateTokens(ctx, userId, nil, client)
+		require.NoError(t, err)
+
+		_, err = service.RefreshTokens(ctx, client, tokensPair)
+		require.NoError(t, err)
+
+		delivered, err := service.deliverOutbox(ctx, settings)
+		require.NoError(t, err)
+		require.Zero(t, delivered)
+		require.Empty(t, notifier.received())
+	})
+	t.Run("notifier is unavailable", func(t *testing.T) {
+		notifier := &recordingNotifier{err: fmt.Errorf("connection refused")}
+		service := NewAuthService(newTestStore(t), notifier)
+		tokensPair, err := service.GenerateTokens(ctx, userId, nil, client)
+		require.NoError(t, err)
+
+		_, err = service.RefreshTokens(ctx, newClient, tokensPair)
+		require.NoError(t, err)
+
+		delivered, err := service.deliverOutbox(ctx, settings)
+		require.NoError(t, err)
+		require.Zero(t, delivered)
+		require.Len(t, notifier.received(), 1)
+	})
+}
+
+// TestDeliverOutbox проверяет повторные попытки доставки, перевод в dead и идемпотентность outbox.
+func TestDeliverOutbox(t *testing.T) {
+	ctx := context.Background()
+	settings := &outboxSettings{batchSize: 10, maxAttempts: 3}
+	notification := &entities.Notification{
+		Id:        "token_reuse:family123",
+		Type:      entities.NotificationTokenReuse,
+		UserId:    "123",
+		Ip:        "192.168.0.1",
+		CreatedAt: time.Now().UTC(),
+	}
+
+	t.Run("retry with backoff", func(t *testing.T) {
+		store := newTestStore(t)
+		notifier := &recordingNotifier{err: fmt.Errorf("connection refused")}
+		service := NewAuthService(store, notifier)
+		service.enqueueNotification(ctx, notification)
+
+		_, err := service.deliverOutbox(ctx, settings)
+		require.NoError(t, err)
+		require.Len(t, notifier.received(), 1)
+
+		// Следующая попытка отложена, поэтому повторный опрос ничего не доставляет.
+		_, err = service.deliverOutbox(ctx, settings)
+		require.NoError(t, err)
+		require.Len(t, notifier.received(), 1)
+
+		claimed, err := store.ClaimOutboxMessages(ctx, time.Now().Add(outboxBackoff(1)+time.Second), outboxLease, 10)
+		require.NoError(t, err)
+		require.Len(t, claimed, 1)
+		require.Equal(t, 1, claimed[0].Attempts)
+		require.Equal(t, "connection refused", claimed[0].LastError)
+	})
+	t.Run("dead letter after max attempts", func(t *testing.T) {
+		store := newTestStore(t)
+		notifier := &recordingNotifier{err: fmt.Errorf("connection refused")}
+		service := NewAuthService(store, notifier)
+		service.enqueueNotification(ctx, notification)
+
+		for attempt := 1; attempt < settings.maxAttempts; attempt++ {
+			_, err := service.deliverOutbox(ctx, settings)
+			require.NoError(t, err)
+			require.NoError(t, store.MarkOutboxMessageFailed(ctx, notification.Id, "connection refused", time.Now()))
+		}
+		_, err := service.deliverOutbox(ctx, &outboxSettings{batchSize: 10, maxAttempts: 3})
+		require.NoError(t, err)
+
+		claimed, err := store.ClaimOutboxMessages(ctx, time.Now().Add(outboxMaxBackoff), outboxLease, 10)
+		require.NoError(t, err)
+		require.Empty(t, claimed)
+	})
+	t.Run("idempotency key", func(t *testing.T) {
+		notifier := &recordingNotifier{}
+		service := NewAuthService(newTestStore(t), notifier)
+		service.enqueueNotification(ctx, notification)
+		service.enqueueNotification(ctx, notification)
+
+		delivered, err := service.deliverOutbox(ctx, settings)
+		require.NoError(t, err)
+		require.Equal(t, 1, delivered)
+
+		service.enqueueNotification(ctx, notification)
+		delivered, err = service.deliverOutbox(ctx, settings)
+		require.NoError(t, err)
+		require.Zero(t, delivered)
+		require.Len(t, notifier.received(), 1)
+	})
+	t.Run("canceled delivery", func(t *testing.T) {
+		store := newTestStore(t)
+		ctx, cancel := context.WithCancel(ctx)
+		defer cancel()
+		// Контекст отменяется во время доставки первого сообщения, остальные не отправляются.
+		notifier := &recordingNotifier{cancel: cancel}
+		service := NewAuthService(store, notifier)
+		for i := range 3 {
+			service.enqueueNotification(ctx, &entities.Notification{
+				Id:        fmt.Sprintf("token_reuse:family%d", i),
+				Type:      entities.NotificationTokenReuse,
+				UserId:    "123",
+				CreatedAt: time.Now().UTC(),
+			})
+		}
+
+		delivered, err := service.deliverOutbox(ctx, settings)
+		require.ErrorIs(t, err, context.Canceled)
+		require.Zero(t, delivered)
+		require.Len(t, notifier.received(), 1)
+
+		// Захваченные сообщения доставляются повторно после истечения аренды.
+		claimed, err := store.ClaimOutboxMessages(context.Background(), time.Now().Add(outboxLease+time.Second), outboxLease, 10)
+		require.NoError(t, err)
+		require.Len(t, claimed, 3)
+	})
+}
@@ -1,21 +1,55 @@
 package services
 
 import (
-	"auth_service/internal/config"
 	"auth_service/internal/entities"
 	"crypto/sha512"
+	"encoding/base64"
 	"fmt"
+	"strings"
 	"time"
 
 	"github.com/golang-jwt/jwt"
 	"golang.org/x/crypto/bcrypt"
 )
 
-// parseAccessToken разбирает access-токен.
+// parseAccessToken разбирает access-токен и проверяет его claims, включая срок действия.
+// Ключ для проверки подписи выбирается по заголовку kid.
 func parseAccessToken(accessToken string) (*entities.AccessTokenClaims, error) {
-	token, err := jwt.Parse(accessToken, func(t *jwt.Token) (interface{}, error) {
-		secret := []byte(config.Secret)
-		return secret, nil
+	return parseToken(accessToken, true)
+}
+
+// parseExpiredAccessToken разбирает access-токен без проверки срока действия (exp).
+// Используется при обновлении пары токенов, когда access-токен уже может быть просрочен.
+func parseExpiredAccessToken(accessToken string) (*entities.AccessTokenClaims, error) {
+	return parseToken(accessToken, false)
+}
+
+// parseToken разбирает access-токен. Любая ошибка разбора или проверки токена оборачивает ErrInvalidAccessToken,
+// а истекший срок действия - дополнительно ErrAccessTokenExpired.
+func parseToken(accessToken string, checkExpiry bool) (*entities.AccessTokenClaims, error) {
+	accessTokenClaims, err := parseTokenClaims(accessToken, checkExpiry)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrInvalidAccessToken, err)
+	}
+
+	return accessTokenClaims, nil
+}
+
+// parseTokenClaims проверяет подпись access-токена и разбирает его claims.
+// Токены с claim sub разбираются как токены с зарегистрированными claims (RFC 7519),
+// остальные - как токены старого формата (user_id, created_at, expired_at).
+func parseTokenClaims(accessToken string, checkExpiry bool) (*entities.AccessTokenClaims, error) {
+	parser := &jwt.Parser{SkipClaimsValidation: true}
+	token, err := parser.Parse(accessToken, func(t *jwt.Token) (interface{}, error) {
+		kid, _ := t.Header["kid"].(string)
+		key, err := verificationKey(kid)
+		if err != nil {
+			return nil, err
+		}
+		if t.Method.Alg() != key.Method.Alg() {
+			return nil, fmt.Errorf("unexpected signing method: '%v'", t.Header["alg"])
+		}
+		return key.PublicKey, nil
 	})
 	if err != nil {
 		return nil, fmt.Errorf("failed to parse jwt: %w", err)
@@ -30,54 +64,184 @@ func parseAccessToken(accessToken string) (*entities.AccessTokenClaims, error) {
 		return nil, fmt.Errorf("failed to typecast to jwt.MapClaims")
 	}
 
-	if payLoad["jti"] == nil {
-		return nil, fmt.Errorf("'jti' in claims is empty")
+	if _, has := payLoad["sub"]; !has {
+		accessTokenClaims, err := parseLegacyClaims(payLoad)
+		if err != nil {
+			return nil, err
+		}
+		if err := validateLegacyClaims(accessTokenClaims, checkExpiry); err != nil {
+			return nil, err
+		}
+		return accessTokenClaims, nil
 	}
-	jti, ok := payLoad["jti"].(string)
-	if !ok {
-		return nil, fmt.Errorf("failed to typecast 'jti' to string")
+
+	accessTokenClaims, err := parseRegisteredClaims(payLoad)
+	if err != nil {
+		return nil, err
+	}
+	if err := validateClaims(accessTokenClaims, checkExpiry); err != nil {
+		return nil, err
 	}
 
-	if payLoad["user_id"] == nil {
-		return nil, fmt.Errorf("'user_id' in claims is empty")
+	return accessTokenClaims, nil
+}
+
+// parseRegisteredClaims разбирает зарегистрированные claims (jti, sub, iat, nbf, exp, iss, aud) и claim amr.
+func parseRegisteredClaims(payLoad jwt.MapClaims) (*entities.AccessTokenClaims, error) {
+	jti, err := stringClaim(payLoad, "jti", true)
+	if err != nil {
+		return nil, err
 	}
-	userId, ok := payLoad["user_id"].(string)
-	if !ok {
-		return nil, fmt.Errorf("failed to typecast 'user_id' to string")
+	userId, err := stringClaim(payLoad, "sub", true)
+	if err != nil {
+		return nil, err
+	}
+	issuedAt, err := timeClaim(payLoad, "iat", true)
+	if err != nil {
+		return nil, err
+	}
+	expiredAt, err := timeClaim(payLoad, "exp", true)
+	if err != nil {
+		return nil, err
+	}
+	notBefore, err := timeClaim(payLoad, "nbf", false)
+	if err != nil {
+		return nil, err
+	}
+	issuer, err := stringClaim(payLoad, "iss", false)
+	if err != nil {
+		return nil, err
 	}
 
-	if payLoad["created_at"] == nil {
-		return nil, fmt.Errorf("'created_at' in claims is empty")
+	var audience []string
+	switch aud := payLoad["aud"].(type) {
+	case nil:
+	case string:
+		audience = []string{aud}
+	case []interface{}:
+		for _, a := range aud {
+			value, ok := a.(string)
+			if !ok {
+				return nil, fmt.Errorf("failed to typecast 'aud' to string")
+			}
+			audience = append(audience, value)
+		}
+	default:
+		return nil, fmt.Errorf("failed to typecast 'aud' to string")
 	}
-	createdAt, ok := payLoad["created_at"].(float64)
-	if !ok {
-		return nil, fmt.Errorf("failed to typecast 'created_at' to float64")
+
+	var amr []string
+	switch methods := payLoad["amr"].(type) {
+	case nil:
+	case []interface{}:
+		for _, method := range methods {
+			value, ok := method.(string)
+			if !ok {
+				return nil, fmt.Errorf("failed to typecast 'amr' to string")
+			}
+			amr = append(amr, value)
+		}
+	default:
+		return nil, fmt.Errorf("failed to typecast 'amr' to array")
+	}
+
+	accessTokenClaims := &entities.AccessTokenClaims{
+		Jti:       jti,
+		UserId:    userId,
+		CreatedAt: issuedAt,
+		NotBefore: notBefore,
+		ExpiredAt: expiredAt,
+		Issuer:    issuer,
+		Audience:  audience,
+		Amr:       amr,
 	}
 
-	if payLoad["expired_at"] == nil {
-		return nil, fmt.Errorf("'expired_at' in claims is empty")
+	return accessTokenClaims, nil
+}
+
+// parseLegacyClaims разбирает claims токенов старого формата (jti, user_id, created_at, expired_at).
+func parseLegacyClaims(payLoad jwt.MapClaims) (*entities.AccessTokenClaims, error) {
+	jti, err := stringClaim(payLoad, "jti", true)
+	if err != nil {
+		return nil, err
 	}
-	expiredAt, ok := payLoad["expired_at"].(float64)
-	if !ok {
-		return nil, fmt.Errorf("failed to typecast 'expired_at' to float64")
+	userId, err := stringClaim(payLoad, "user_id", true)
+	if err != nil {
+		return nil, err
+	}
+	createdAt, err := timeClaim(payLoad, "created_at", true)
+	if err != nil {
+		return nil, err
+	}
+	expiredAt, err := timeClaim(payLoad, "expired_at", true)
+	if err != nil {
+		return nil, err
 	}
 
 	accessTokenClaims := &entities.AccessTokenClaims{
 		Jti:       jti,
 		UserId:    userId,
-		CreatedAt: time.Unix(int64(createdAt), 0),
-		ExpiredAt: time.Unix(int64(expiredAt), 0),
+		CreatedAt: createdAt,
+		ExpiredAt: expiredAt,
 	}
 
 	return accessTokenClaims, nil
 }
 
+// stringClaim возвращает строковый claim. Если claim обязателен и отсутствует, возвращает ошибку.
+func stringClaim(payLoad jwt.MapClaims, name string, required bool) (string, error) {
+	if payLoad[name] == nil {
+		if required {
+			return "", fmt.Errorf("'%s' in claims is empty", name)
+		}
+		return "", nil
+	}
+	value, ok := payLoad[name].(string)
+	if !ok {
+		return "", fmt.Errorf("failed to typecast '%s' to string", name)
+	}
+
+	return value, nil
+}
+
+// timeClaim возвращает claim с временем в формате Unix. Если claim обязателен и отсутствует, возвращает ошибку.
+func timeClaim(payLoad jwt.MapClaims, name string, required bool) (time.Time, error) {
+	if payLoad[name] == nil {
+		if required {
+			return time.Time{}, fmt.Errorf("'%s' in claims is empty", name)
+		}
+		return time.Time{}, nil
+	}
+	value, ok := payLoad[name].(float64)
+	if !ok {
+		return time.Time{}, fmt.Errorf("failed to typecast '%s' to float64", name)
+	}
+
+	return time.Unix(int64(value), 0), nil
+}
+
 // checkRefreshToken проверяет хэш refresh-токена на соответствие с валидным хэшем.
+// Если хэш не совпадает, возвращает ошибку ErrInvalidRefreshToken.
 func checkRefreshToken(refreshToken, validRefrTokenHash string) error {
 	hash := sha512.Sum512([]byte(refreshToken))
 	if err := bcrypt.CompareHashAndPassword([]byte(validRefrTokenHash), hash[:]); err != nil {
-		return fmt.Errorf("refresh token hash is invalid: %w", err)
+		return fmt.Errorf("%w: hash mismatch: %w", ErrInvalidRefreshToken, err)
 	}
 
 	return nil
 }
+
+// splitRefreshToken извлекает userId и jti из refresh-токена формата <userId>.<jti>.<secret>.
+// Для токенов старого формата (без userId и jti) возвращает false.
+func splitRefreshToken(refreshToken string) (userId, jti string, ok bool) {
+	parts := strings.Split(refreshToken, ".")
+	if len(parts) != 3 || parts[1] == "" || parts[2] == "" {
+		return "", "", false
+	}
+	rawUserId, err := base64.RawURLEncoding.DecodeString(parts[0])
+	if err != nil || len(rawUserId) == 0 {
+		return "", "", false
+	}
+
+	return string(rawUserId), parts[1], true
+}
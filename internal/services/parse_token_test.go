@@ -155,6 +155,114 @@ func TestParseAccessToken(t *testing.T) {
 	})
 }
 
+// TestParseRegisteredClaims проверяет разбор и валидацию зарегистрированных claims access токена.
+func TestParseRegisteredClaims(t *testing.T) {
+	config.Secret = "test_secret"
+	config.JwtIssuer = "auth_service"
+	config.JwtAudience = "api,gateway"
+	t.Cleanup(func() {
+		config.JwtIssuer = ""
+		config.JwtAudience = ""
+		config.JwtLeeway = ""
+		config.LegacyTokensUntil = ""
+	})
+	now := time.Now().Unix()
+
+	t.Run("successful parsing", func(t *testing.T) {
+		accessToken, err := GenAccessToken(newAccessTokenClaims("test-jti", "123", time.Now(), time.Now().Add(time.Hour)))
+		require.NoError(t, err)
+
+		accessTokenClaims, err := parseAccessToken(accessToken)
+		require.NoError(t, err)
+		require.Equal(t, "test-jti", accessTokenClaims.Jti)
+		require.Equal(t, "123", accessTokenClaims.UserId)
+		require.Equal(t, "auth_service", accessTokenClaims.Issuer)
+		require.Equal(t, []string{"api", "gateway"}, accessTokenClaims.Audience)
+	})
+	t.Run("expired token", func(t *testing.T) {
+		claims := jwt.MapClaims{"jti": "test-jti", "sub": "123", "iss": "auth_service", "aud": "api", "iat": now - 120, "exp": now - 10}
+		accessToken, err := generateTestAccessToken(config.Secret, claims)
+		require.NoError(t, err)
+
+		_, err = parseAccessToken(accessToken)
+		require.ErrorIs(t, err, ErrAccessTokenExpired)
+
+		_, err = parseExpiredAccessToken(accessToken)
+		require.NoError(t, err)
+
+		config.JwtLeeway = "30"
+		defer func() { config.JwtLeeway = "" }()
+		_, err = parseAccessToken(accessToken)
+		require.NoError(t, err)
+	})
+	t.Run("token is not valid yet", func(t *testing.T) {
+		claims := jwt.MapClaims{"jti": "test-jti", "sub": "123", "iss": "auth_service", "aud": "api", "iat": now, "nbf": now + 60, "exp": now + 3600}
+		accessToken, err := generateTestAccessToken(config.Secret, claims)
+		require.NoError(t, err)
+
+		_, err = parseAccessToken(accessToken)
+		require.ErrorContains(t, err, "access token is not valid yet")
+	})
+	t.Run("invalid issuer", func(t *testing.T) {
+		claims := jwt.MapClaims{"jti": "test-jti", "sub": "123", "iss": "other_service", "aud": "api", "iat": now, "exp": now + 3600}
+		accessToken, err := generateTestAccessToken(config.Secret, claims)
+		require.NoError(t, err)
+
+		_, err = parseAccessToken(accessToken)
+		require.ErrorContains(t, err, "invalid issuer")
+	})
+	t.Run("invalid audience", func(t *testing.T) {
+		claims := jwt.MapClaims{"jti": "test-jti", "sub": "123", "iss": "auth_service", "aud": []string{"billing"}, "iat": now, "exp": now + 3600}
+		accessToken, err := generateTestAccessToken(config.Secret, claims)
+		require.NoError(t, err)
+
+		_, err = parseAccessToken(accessToken)
+		require.ErrorContains(t, err, "invalid audience")
+	})
+	t.Run("missing exp", func(t *testing.T) {
+		claims := jwt.MapClaims{"jti": "test-jti", "sub": "123", "iss": "auth_service", "aud": "api", "iat": now}
+		accessToken, err := generateTestAccessToken(config.Secret, claims)
+		require.NoError(t, err)
+
+		_, err = parseAccessToken(accessToken)
+		require.ErrorContains(t, err, "'exp' in claims is empty")
+	})
+	t.Run("legacy token after migration window", func(t *testing.T) {
+		claims := jwt.MapClaims{"jti": "test-jti", "user_id": "123", "created_at": now, "expired_at": now + 3600}
+		accessToken, err := generateTestAccessToken(config.Secret, claims)
+		require.NoError(t, err)
+
+		config.LegacyTokensUntil = time.Now().Add(time.Hour).Format(time.RFC3339)
+		_, err = parseAccessToken(accessToken)
+		require.NoError(t, err)
+
+		config.LegacyTokensUntil = time.Now().Add(-time.Hour).Format(time.RFC3339)
+		_, err = parseAccessToken(accessToken)
+		require.ErrorContains(t, err, "legacy access token format is no longer accepted")
+	})
+	t.Run("legacy token without migration deadline", func(t *testing.T) {
+		config.LegacyTokensUntil = ""
+		defer func() { config.RefreshTokenTTL = "" }()
+
+		claims := jwt.MapClaims{"jti": "test-jti", "user_id": "123", "created_at": now, "expired_at": now + 3600}
+		accessToken, err := generateTestAccessToken(config.Secret, claims)
+		require.NoError(t, err)
+		_, err = parseAccessToken(accessToken)
+		require.NoError(t, err)
+
+		createdAt := now - int64(defaultRefreshTokenTTL.Seconds()) - 60
+		claims = jwt.MapClaims{"jti": "test-jti", "user_id": "123", "created_at": createdAt, "expired_at": now + 3600}
+		accessToken, err = generateTestAccessToken(config.Secret, claims)
+		require.NoError(t, err)
+		_, err = parseExpiredAccessToken(accessToken)
+		require.ErrorContains(t, err, "legacy access token format is no longer accepted")
+
+		config.RefreshTokenTTL = "10080"
+		_, err = parseExpiredAccessToken(accessToken)
+		require.NoError(t, err)
+	})
+}
+
 // TestCheckRefreshToken проверяет валидацию refresh токена.
 func TestCheckRefreshToken(t *testing.T) {
 	t.Run("valid refresh token", func(t *testing.T) {
@@ -175,7 +283,7 @@ func TestCheckRefreshToken(t *testing.T) {
 		require.NoError(t, err)
 
 		err = checkRefreshToken(invalidToken, string(validHash))
-		require.ErrorContains(t, err, "refresh token hash is invalid")
+		require.ErrorIs(t, err, ErrInvalidRefreshToken)
 	})
 
 	t.Run("invalid hash format", func(t *testing.T) {
@@ -183,6 +291,6 @@ func TestCheckRefreshToken(t *testing.T) {
 		invalidHash := "not_a_bcrypt_hash"
 
 		err := checkRefreshToken(refreshToken, invalidHash)
-		require.ErrorContains(t, err, "refresh token hash is invalid")
+		require.ErrorIs(t, err, ErrInvalidRefreshToken)
 	})
 }
@@ -0,0 +1,91 @@
+package services
+
+import (
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/base64"
+	"fmt"
+	"strings"
+	"sync"
+
+	"golang.org/x/crypto/argon2"
+)
+
+// Параметры argon2id для новых хэшей паролей (RFC 9106, рекомендация для систем с ограниченной памятью).
+// Параметры сохраняются в самом хэше, поэтому их изменение не влияет на проверку существующих паролей.
+const (
+	argon2Time    = 3         // Количество проходов.
+	argon2Memory  = 64 * 1024 // Объем памяти в КиБ.
+	argon2Threads = 4         // Степень параллелизма.
+	argon2KeyLen  = 32        // Длина хэша в байтах.
+	argon2SaltLen = 16        // Длина соли в байтах.
+)
+
+var (
+	dummyPasswordHash     string    // dummyPasswordHash - хэш, с которым сравнивается пароль неизвестного пользователя.
+	dummyPasswordHashOnce sync.Once // dummyPasswordHashOnce обеспечивает однократное вычисление dummyPasswordHash.
+)
+
+// HashPassword вычисляет хэш пароля argon2id со случайной солью.
+// Хэш имеет формат PHC: $argon2id$v=19$m=<память>,t=<проходы>,p=<потоки>$<соль>$<хэш>.
+func HashPassword(password string) (string, error) {
+	salt := make([]byte, argon2SaltLen)
+	if _, err := rand.Read(salt); err != nil {
+		return "", fmt.Errorf("failed to generate salt: %w", err)
+	}
+	key := argon2.IDKey([]byte(password), salt, argon2Time, argon2Memory, argon2Threads, argon2KeyLen)
+
+	passwordHash := fmt.Sprintf("$argon2id$v=%d$m=%d,t=%d,p=%d$%s$%s", argon2.Version, argon2Memory, argon2Time, argon2Threads,
+		base64.RawStdEncoding.EncodeToString(salt), base64.RawStdEncoding.EncodeToString(key))
+
+	return passwordHash, nil
+}
+
+// checkPassword проверяет пароль по хэшу argon2id. Хэши сравниваются за постоянное время.
+// Возвращает ErrInvalidCredentials, если пароль не совпадает.
+func checkPassword(password, passwordHash string) error {
+	parts := strings.Split(passwordHash, "$")
+	if len(parts) != 6 || parts[1] != "argon2id" {
+		return fmt.Errorf("unsupported password hash format")
+	}
+
+	var version int
+	if _, err := fmt.Sscanf(parts[2], "v=%d", &version); err != nil || version != argon2.Version {
+		return fmt.Errorf("unsupported argon2 version: '%s'", parts[2])
+	}
+	var (
+		memory  uint32
+		passes  uint32
+		threads uint8
+	)
+	if _, err := fmt.Sscanf(parts[3], "m=%d,t=%d,p=%d", &memory, &passes, &threads); err != nil {
+		return fmt.Errorf("invalid argon2 parameters: %w", err)
+	}
+	salt, err := base64.RawStdEncoding.DecodeString(parts[4])
+	if err != nil {
+		return fmt.Errorf("invalid argon2 salt: %w", err)
+	}
+	key, err := base64.RawStdEncoding.DecodeString(parts[5])
+	if err != nil {
+		return fmt.Errorf("invalid argon2 hash: %w", err)
+	}
+
+	actual := argon2.IDKey([]byte(password), salt, passes, memory, threads, uint32(len(key)))
+	if subtle.ConstantTimeCompare(actual, key) != 1 {
+		return fmt.Errorf("password does not match: %w", ErrInvalidCredentials)
+	}
+
+	return nil
+}
+
+// checkDummyPassword проверяет пароль по заранее вычисленному хэшу.
+// Используется, если пользователь не найден или у него нет пароля, чтобы время ответа
+// не позволяло отличить неизвестного пользователя от неверного пароля. Всегда возвращает ErrInvalidCredentials.
+func checkDummyPassword(password string) error {
+	dummyPasswordHashOnce.Do(func() {
+		dummyPasswordHash, _ = HashPassword("dummy password")
+	})
+	checkPassword(password, dummyPasswordHash)
+
+	return ErrInvalidCredentials
+}
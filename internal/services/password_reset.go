@@ -0,0 +1,125 @@
+package services
+
+import (
+	"auth_service/internal/config"
+	"auth_service/internal/entities"
+	"auth_service/internal/storage"
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+const (
+	defaultPasswordResetTTL      = 1 * time.Hour   // Время действия токена сброса пароля, если PASSWORD_RESET_TTL не задан.
+	defaultPasswordResetCooldown = 5 * time.Minute // Интервал между письмами сброса пароля, если PASSWORD_RESET_COOLDOWN не задан.
+)
+
+// ForgotPassword ставит в outbox письмо со ссылкой для сброса пароля пользователю с указанным email.
+// Для неизвестного email ошибка не возвращается, чтобы по ответу нельзя было определить наличие учетной записи.
+// Токен создается при доставке письма, заблокированным пользователям письмо не отправляется.
+// Повторные запросы в течение PASSWORD_RESET_COOLDOWN получают тот же ключ идемпотентности и не ставят новое письмо.
+func (s *AuthService) ForgotPassword(ctx context.Context, email string) error {
+	cooldown, err := getMinutes("PASSWORD_RESET_COOLDOWN", config.PasswordResetCooldown, defaultPasswordResetCooldown)
+	if err != nil {
+		return err
+	}
+	user, err := s.storage.GetUserByEmail(ctx, strings.TrimSpace(email))
+	if errors.Is(err, storage.ErrUserNotFound) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to get user: %w", err)
+	}
+
+	id, err := passwordResetNotificationId(user.Id, cooldown, time.Now())
+	if err != nil {
+		return err
+	}
+	s.enqueueNotification(ctx, &entities.Notification{
+		Id:        id,
+		Type:      entities.NotificationPasswordReset,
+		UserId:    user.Id,
+		CreatedAt: time.Now().UTC(),
+	})
+
+	return nil
+}
+
+// ResetPassword устанавливает новый пароль пользователя по одноразовому токену из письма.
+// После смены пароля удаляет остальные токены сброса пароля, завершает все сессии пользователя
+// и отзывает выданные в них access-токены.
+func (s *AuthService) ResetPassword(ctx context.Context, token, password string) error {
+	if len(password) < minPasswordLength {
+		return fmt.Errorf("password must be at least %d bytes: %w", minPasswordLength, ErrWeakPassword)
+	}
+	if token == "" {
+		return fmt.Errorf("empty token: %w", ErrInvalidOneTimeToken)
+	}
+	oneTimeToken, err := s.storage.ConsumeOneTimeToken(ctx, entities.OneTimeTokenPasswordReset, hashOneTimeToken(token), time.Now())
+	if errors.Is(err, storage.ErrTokenNotFound) || errors.Is(err, storage.ErrTokenExpired) {
+		return fmt.Errorf("failed to consume token: %w", ErrInvalidOneTimeToken)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to consume token: %w", err)
+	}
+
+	user, err := s.getActiveUser(ctx, oneTimeToken.UserId)
+	if errors.Is(err, ErrUnknownUser) {
+		return fmt.Errorf("failed to get user: %w", ErrInvalidOneTimeToken)
+	}
+	if err != nil {
+		return err
+	}
+	passwordHash, err := HashPassword(password)
+	if err != nil {
+		return fmt.Errorf("failed to hash password: %w", err)
+	}
+	if err := s.storage.SavePasswordHash(ctx, user.Id, passwordHash); err != nil {
+		return fmt.Errorf("failed to save password hash: %w", err)
+	}
+	if err := s.storage.DeleteOneTimeTokens(ctx, user.Id, entities.OneTimeTokenPasswordReset); err != nil {
+		return fmt.Errorf("failed to delete password reset tokens: %w", err)
+	}
+
+	revokedRecords, err := s.storage.RevokeAllRefreshTokens(ctx, user.Id)
+	if err != nil {
+		return fmt.Errorf("failed to revoke sessions: %w", err)
+	}
+	if err := s.revokeAccessTokens(ctx, revokedRecords); err != nil {
+		return fmt.Errorf("failed to revoke access tokens: %w", err)
+	}
+
+	return nil
+}
+
+// passwordResetUrl создает одноразовый токен сброса пароля и формирует ссылку для письма.
+// Ранее выданные токены сброса пароля пользователя удаляются, поэтому действует только ссылка из последнего письма,
+// в том числе при повторных попытках доставки.
+func (s *AuthService) passwordResetUrl(ctx context.Context, userId string) (string, error) {
+	ttl, err := getMinutes("PASSWORD_RESET_TTL", config.PasswordResetTTL, defaultPasswordResetTTL)
+	if err != nil {
+		return "", err
+	}
+	if err := s.storage.DeleteOneTimeTokens(ctx, userId, entities.OneTimeTokenPasswordReset); err != nil {
+		return "", fmt.Errorf("failed to delete previous password reset tokens: %w", err)
+	}
+
+	return s.oneTimeTokenUrl(ctx, "PASSWORD_RESET_URL", config.PasswordResetUrl, userId, entities.OneTimeTokenPasswordReset, ttl)
+}
+
+// passwordResetNotificationId возвращает идентификатор уведомления о сбросе пароля.
+// Идентификатор одинаков для всех запросов пользователя в пределах интервала cooldown, поэтому outbox
+// принимает только первое письмо. При cooldown, равном нулю, каждый запрос получает новый идентификатор.
+func passwordResetNotificationId(userId string, cooldown time.Duration, now time.Time) (string, error) {
+	if cooldown <= 0 {
+		id, err := GenJti()
+		if err != nil {
+			return "", err
+		}
+		return fmt.Sprintf("%s:%s", entities.NotificationPasswordReset, id), nil
+	}
+
+	return fmt.Sprintf("%s:%s:%d", entities.NotificationPasswordReset, userId, now.UnixNano()/int64(cooldown)), nil
+}
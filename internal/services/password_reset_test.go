@@ -0,0 +1,169 @@
+package services
+
+import (
+	"auth_service/internal/config"
+	"auth_service/internal/entities"
+	"context"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+// TestResetPassword проверяет восстановление пароля по ссылке из письма и завершение всех сессий пользователя.
+func TestResetPassword(t *testing.T) {
+	ctx := context.Background()
+	config.Secret = "test_secret"
+	config.MaxTokensPerUser = "5"
+	config.PasswordResetUrl = "https://example.com/reset-password"
+	config.PasswordResetCooldown = "0"
+	t.Cleanup(func() {
+		config.PasswordResetUrl = ""
+		config.PasswordResetCooldown = ""
+	})
+	client := &entities.ClientInfo{Ip: "192.168.0.1"}
+	store := newTestStore(t)
+	notifier := &recordingNotifier{}
+	service := NewAuthService(store, notifier)
+	passwordHash, err := HashPassword("old password")
+	require.NoError(t, err)
+	require.NoError(t, store.SavePasswordHash(ctx, "123", passwordHash))
+
+	requestToken := func(t *testing.T) string {
+		require.NoError(t, service.ForgotPassword(ctx, "USER@gmail.com"))
+		_, err := service.deliverOutbox(ctx, &outboxSettings{batchSize: 10, maxAttempts: 3})
+		require.NoError(t, err)
+		notifications := notifier.received()
+		require.NotEmpty(t, notifications)
+		notification := notifications[len(notifications)-1]
+		require.Equal(t, entities.NotificationPasswordReset, notification.Type)
+		require.Equal(t, "user@gmail.com", notification.Email)
+		actionUrl, err := url.Parse(notification.ActionUrl)
+		require.NoError(t, err)
+		token := actionUrl.Query().Get("token")
+		require.NotEmpty(t, token)
+		return token
+	}
+
+	t.Run("reset password", func(t *testing.T) {
+		tokensPair, err := service.Login(ctx, "user@gmail.com", "old password", client)
+		require.NoError(t, err)
+		firstToken := requestToken(t)
+		token := requestToken(t)
+
+		err = service.ResetPassword(ctx, firstToken, "new password")
+		require.ErrorIs(t, err, ErrInvalidOneTimeToken)
+		require.NoError(t, service.ResetPassword(ctx, token, "new password"))
+
+		_, err = service.Login(ctx, "user@gmail.com", "old password", client)
+		require.ErrorIs(t, err, ErrInvalidCredentials)
+		_, err = service.Login(ctx, "user@gmail.com", "new password", client)
+		require.NoError(t, err)
+
+		_, err = service.ValidateAccessToken(ctx, tokensPair.AccessToken)
+		require.ErrorIs(t, err, ErrAccessTokenRevoked)
+		records, err := store.GetRefreshTokenRecords(ctx, "123")
+		require.NoError(t, err)
+		require.Len(t, records, 1)
+
+		err = service.ResetPassword(ctx, token, "other password")
+		require.ErrorIs(t, err, ErrInvalidOneTimeToken)
+		err = service.ResetPassword(ctx, firstToken, "other password")
+		require.ErrorIs(t, err, ErrInvalidOneTimeToken)
+	})
+	t.Run("unknown email", func(t *testing.T) {
+		sent := len(notifier.received())
+		require.NoError(t, service.ForgotPassword(ctx, "unknown@gmail.com"))
+		_, err := service.deliverOutbox(ctx, &outboxSettings{batchSize: 10, maxAttempts: 3})
+		require.NoError(t, err)
+		require.Len(t, notifier.received(), sent)
+	})
+	t.Run("invalid token", func(t *testing.T) {
+		require.ErrorIs(t, service.ResetPassword(ctx, "", "new password"), ErrInvalidOneTimeToken)
+		require.ErrorIs(t, service.ResetPassword(ctx, "invalid", "new password"), ErrInvalidOneTimeToken)
+	})
+	t.Run("weak password", func(t *testing.T) {
+		token := requestToken(t)
+		require.ErrorIs(t, service.ResetPassword(ctx, token, "short"), ErrWeakPassword)
+	})
+	t.Run("disabled user", func(t *testing.T) {
+		token := requestToken(t)
+		user, err := store.GetUser(ctx, "123")
+		require.NoError(t, err)
+		user.Status = entities.UserStatusDisabled
+		require.NoError(t, store.UpdateUser(ctx, user))
+
+		err = service.ResetPassword(ctx, token, "new password")
+		require.ErrorIs(t, err, ErrUserDisabled)
+
+		sent := len(notifier.received())
+		require.NoError(t, service.ForgotPassword(ctx, "user@gmail.com"))
+		_, err = service.deliverOutbox(ctx, &outboxSettings{batchSize: 10, maxAttempts: 3})
+		require.NoError(t, err)
+		require.Len(t, notifier.received(), sent)
+	})
+}
+
+// TestForgotPasswordCooldown проверяет, что повторные запросы сброса пароля в пределах PASSWORD_RESET_COOLDOWN
+// не ставят новые письма, а повторная доставка письма оставляет действующим только последний токен.
+func TestForgotPasswordCooldown(t *testing.T) {
+	ctx := context.Background()
+	config.PasswordResetUrl = "https://example.com/reset-password"
+	t.Cleanup(func() { config.PasswordResetUrl = "" })
+	store := newTestStore(t)
+	notifier := &recordingNotifier{}
+	service := NewAuthService(store, notifier)
+
+	for range 3 {
+		require.NoError(t, service.ForgotPassword(ctx, "user@gmail.com"))
+	}
+	delivered, err := service.deliverOutbox(ctx, &outboxSettings{batchSize: 10, maxAttempts: 3})
+	require.NoError(t, err)
+	require.Equal(t, 1, delivered)
+	require.Len(t, notifier.received(), 1)
+
+	firstUrl, err := service.passwordResetUrl(ctx, "123")
+	require.NoError(t, err)
+	secondUrl, err := service.passwordResetUrl(ctx, "123")
+	require.NoError(t, err)
+	err = service.ResetPassword(ctx, tokenFromUrl(t, notifier.received()[0].ActionUrl), "new password")
+	require.ErrorIs(t, err, ErrInvalidOneTimeToken)
+	err = service.ResetPassword(ctx, tokenFromUrl(t, firstUrl), "new password")
+	require.ErrorIs(t, err, ErrInvalidOneTimeToken)
+	require.NoError(t, service.ResetPassword(ctx, tokenFromUrl(t, secondUrl), "new password"))
+}
+
+// TestPasswordResetNotificationId проверяет ключ идемпотентности письма сброса пароля.
+func TestPasswordResetNotificationId(t *testing.T) {
+	now := time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC)
+
+	id, err := passwordResetNotificationId("123", 5*time.Minute, now)
+	require.NoError(t, err)
+	sameWindowId, err := passwordResetNotificationId("123", 5*time.Minute, now.Add(time.Minute))
+	require.NoError(t, err)
+	require.Equal(t, id, sameWindowId)
+
+	nextWindowId, err := passwordResetNotificationId("123", 5*time.Minute, now.Add(5*time.Minute))
+	require.NoError(t, err)
+	require.NotEqual(t, id, nextWindowId)
+	otherUserId, err := passwordResetNotificationId("456", 5*time.Minute, now)
+	require.NoError(t, err)
+	require.NotEqual(t, id, otherUserId)
+
+	randomId, err := passwordResetNotificationId("123", 0, now)
+	require.NoError(t, err)
+	otherRandomId, err := passwordResetNotificationId("123", 0, now)
+	require.NoError(t, err)
+	require.NotEqual(t, randomId, otherRandomId)
+}
+
+// tokenFromUrl возвращает одноразовый токен из параметра token ссылки.
+func tokenFromUrl(t *testing.T, rawUrl string) string {
+	parsedUrl, err := url.Parse(rawUrl)
+	require.NoError(t, err)
+	token := parsedUrl.Query().Get("token")
+	require.NotEmpty(t, token)
+
+	return token
+}
@@ -0,0 +1,49 @@
+package services
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+// TestHashPassword проверяет формат хэша argon2id и проверку пароля по нему.
+func TestHashPassword(t *testing.T) {
+	passwordHash, err := HashPassword("correct horse battery staple")
+	require.NoError(t, err)
+	require.True(t, strings.HasPrefix(passwordHash, "$argon2id$v=19$m=65536,t=3,p=4$"))
+
+	otherHash, err := HashPassword("correct horse battery staple")
+	require.NoError(t, err)
+	require.NotEqual(t, passwordHash, otherHash, "соль должна быть случайной")
+
+	require.NoError(t, checkPassword("correct horse battery staple", passwordHash))
+	require.ErrorIs(t, checkPassword("wrong password", passwordHash), ErrInvalidCredentials)
+}
+
+// TestCheckPassword проверяет разбор хэшей с другими параметрами и отклонение неподдерживаемых форматов.
+func TestCheckPassword(t *testing.T) {
+	// Хэш пароля "password" с параметрами m=1024,t=1,p=1, вычисленный заранее.
+	lowCostHash := "$argon2id$v=19$m=1024,t=1,p=1$c29tZXNhbHRzb21lc2FsdA$FiU+I6KbINfHoMBfkVDnS6qmzxgy7cg41IT8JQoCvvk"
+	require.NoError(t, checkPassword("password", lowCostHash))
+	require.ErrorIs(t, checkPassword("Password", lowCostHash), ErrInvalidCredentials)
+
+	tests := []struct {
+		name         string
+		passwordHash string
+	}{
+		{name: "bcrypt hash", passwordHash: "$2a$10$abcdefghijklmnopqrstuv"},
+		{name: "argon2i hash", passwordHash: "$argon2i$v=19$m=1024,t=1,p=1$c29tZXNhbHQ$aGFzaA"},
+		{name: "unknown version", passwordHash: "$argon2id$v=16$m=1024,t=1,p=1$c29tZXNhbHQ$aGFzaA"},
+		{name: "invalid parameters", passwordHash: "$argon2id$v=19$m=x,t=1,p=1$c29tZXNhbHQ$aGFzaA"},
+		{name: "invalid salt", passwordHash: "$argon2id$v=19$m=1024,t=1,p=1$!!!$aGFzaA"},
+		{name: "empty", passwordHash: ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := checkPassword("password", tt.passwordHash)
+			require.Error(t, err)
+			require.NotErrorIs(t, err, ErrInvalidCredentials)
+		})
+	}
+}
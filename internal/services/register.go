@@ -0,0 +1,204 @@
+package services
+
+import (
+	"auth_service/internal/config"
+	"auth_service/internal/entities"
+	"auth_service/internal/storage"
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"log"
+	"net/mail"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	minPasswordLength           = 8              // Минимальная длина пароля при регистрации.
+	defaultEmailVerificationTTL = 24 * time.Hour // Время действия токена подтверждения email, если EMAIL_VERIFICATION_TTL не задан.
+)
+
+// Register создает пользователя с неподтвержденным email и ставит в outbox письмо для подтверждения email.
+// Возвращает идентификатор созданного пользователя.
+func (s *AuthService) Register(ctx context.Context, email, password, locale string) (string, error) {
+	email = strings.TrimSpace(email)
+	address, err := mail.ParseAddress(email)
+	if err != nil || address.Address != email {
+		return "", fmt.Errorf("email '%s': %w", email, ErrInvalidEmail)
+	}
+	if len(password) < minPasswordLength {
+		return "", fmt.Errorf("password must be at least %d bytes: %w", minPasswordLength, ErrWeakPassword)
+	}
+	passwordHash, err := HashPassword(password)
+	if err != nil {
+		return "", fmt.Errorf("failed to hash password: %w", err)
+	}
+	userId, err := GenUserId()
+	if err != nil {
+		return "", err
+	}
+
+	user := &entities.User{
+		Id:            userId,
+		Email:         email,
+		EmailVerified: false,
+		Status:        entities.UserStatusActive,
+		Locale:        locale,
+		CreatedAt:     time.Now().UTC(),
+	}
+	err = s.storage.CreateUser(ctx, user)
+	if errors.Is(err, storage.ErrUserExists) {
+		return "", fmt.Errorf("email '%s': %w", email, ErrEmailTaken)
+	}
+	if err != nil {
+		return "", fmt.Errorf("failed to create user: %w", err)
+	}
+	if err := s.storage.SavePasswordHash(ctx, userId, passwordHash); err != nil {
+		if deleteErr := s.storage.DeleteUser(ctx, userId); deleteErr != nil {
+			log.Printf("failed to delete user '%s' without password: %v\n", userId, deleteErr)
+		}
+		return "", fmt.Errorf("failed to save password hash: %w", err)
+	}
+
+	s.enqueueNotification(ctx, &entities.Notification{
+		Id:        fmt.Sprintf("%s:%s", entities.NotificationVerifyEmail, userId),
+		Type:      entities.NotificationVerifyEmail,
+		UserId:    userId,
+		CreatedAt: user.CreatedAt,
+	})
+
+	return userId, nil
+}
+
+// VerifyEmail подтверждает email пользователя по одноразовому токену из письма.
+// Неверный, уже использованный или истекший токен приводит к ErrInvalidOneTimeToken.
+func (s *AuthService) VerifyEmail(ctx context.Context, token string) error {
+	if token == "" {
+		return fmt.Errorf("empty token: %w", ErrInvalidOneTimeToken)
+	}
+	oneTimeToken, err := s.storage.ConsumeOneTimeToken(ctx, entities.OneTimeTokenVerifyEmail, hashOneTimeToken(token), time.Now())
+	if errors.Is(err, storage.ErrTokenNotFound) || errors.Is(err, storage.ErrTokenExpired) {
+		return fmt.Errorf("failed to consume token: %w", ErrInvalidOneTimeToken)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to consume token: %w", err)
+	}
+
+	user, err := s.storage.GetUser(ctx, oneTimeToken.UserId)
+	if errors.Is(err, storage.ErrUserNotFound) {
+		return fmt.Errorf("user '%s' was not found: %w", oneTimeToken.UserId, ErrInvalidOneTimeToken)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to get user: %w", err)
+	}
+	if user.EmailVerified {
+		return nil
+	}
+	user.EmailVerified = true
+	if err := s.storage.UpdateUser(ctx, user); err != nil {
+		return fmt.Errorf("failed to update user: %w", err)
+	}
+
+	return nil
+}
+
+// checkEmailVerified возвращает ErrEmailNotVerified, если email пользователя не подтвержден,
+// а REQUIRE_EMAIL_VERIFICATION запрещает вход таким пользователям.
+func checkEmailVerified(user *entities.User) error {
+	if user.EmailVerified || config.RequireEmailVerification == "" {
+		return nil
+	}
+	required, err := strconv.ParseBool(config.RequireEmailVerification)
+	if err != nil {
+		return fmt.Errorf("env 'REQUIRE_EMAIL_VERIFICATION' is not bool: %w", err)
+	}
+	if required {
+		return fmt.Errorf("user '%s': %w", user.Id, ErrEmailNotVerified)
+	}
+
+	return nil
+}
+
+// emailVerificationUrl создает одноразовый токен подтверждения email и формирует ссылку для письма.
+// Предыдущие токены подтверждения пользователя удаляются, поэтому повторные попытки доставки письма
+// не оставляют в хранилище несколько действующих токенов.
+func (s *AuthService) emailVerificationUrl(ctx context.Context, userId string) (string, error) {
+	ttl, err := getMinutes("EMAIL_VERIFICATION_TTL", config.EmailVerificationTTL, defaultEmailVerificationTTL)
+	if err != nil {
+		return "", err
+	}
+	if err := s.storage.DeleteOneTimeTokens(ctx, userId, entities.OneTimeTokenVerifyEmail); err != nil {
+		return "", fmt.Errorf("failed to delete previous email verification tokens: %w", err)
+	}
+
+	return s.oneTimeTokenUrl(ctx, "EMAIL_VERIFICATION_URL", config.EmailVerificationUrl, userId, entities.OneTimeTokenVerifyEmail, ttl)
+}
+
+// oneTimeTokenUrl создает одноразовый токен и добавляет его в параметр token адреса страницы pageUrl,
+// заданного переменной окружения name. В хранилище сохраняется только SHA-256 хэш токена.
+func (s *AuthService) oneTimeTokenUrl(ctx context.Context, name, pageUrl, userId, purpose string, ttl time.Duration) (string, error) {
+	if pageUrl == "" {
+		return "", fmt.Errorf("env '%s' is not set", name)
+	}
+	tokenUrl, err := url.Parse(pageUrl)
+	if err != nil {
+		return "", fmt.Errorf("env '%s' is not valid URL: %w", name, err)
+	}
+	token, err := s.newOneTimeToken(ctx, userId, purpose, ttl)
+	if err != nil {
+		return "", err
+	}
+
+	query := tokenUrl.Query()
+	query.Set("token", token)
+	tokenUrl.RawQuery = query.Encode()
+
+	return tokenUrl.String(), nil
+}
+
+// newOneTimeToken генерирует одноразовый токен с указанным назначением, сохраняет его хэш и возвращает сам токен.
+func (s *AuthService) newOneTimeToken(ctx context.Context, userId, purpose string, ttl time.Duration) (string, error) {
+	token, err := GenJti()
+	if err != nil {
+		return "", fmt.Errorf("failed to generate one-time token: %w", err)
+	}
+
+	now := time.Now().UTC()
+	oneTimeToken := &entities.OneTimeToken{
+		TokenHash: hashOneTimeToken(token),
+		UserId:    userId,
+		Purpose:   purpose,
+		ExpiredAt: now.Add(ttl),
+		CreatedAt: now,
+	}
+	if err := s.storage.SaveOneTimeToken(ctx, oneTimeToken); err != nil {
+		return "", fmt.Errorf("failed to save one-time token: %w", err)
+	}
+
+	return token, nil
+}
+
+// hashOneTimeToken возвращает SHA-256 хэш одноразового токена в шестнадцатеричном виде.
+// Токены содержат 256 бит случайных данных, поэтому медленное хэширование для них не требуется.
+func hashOneTimeToken(token string) string {
+	hash := sha256.Sum256([]byte(token))
+
+	return hex.EncodeToString(hash[:])
+}
+
+// GenUserId генерирует идентификатор пользователя в формате UUID версии 4.
+func GenUserId() (string, error) {
+	bytes := make([]byte, 16)
+	if _, err := rand.Read(bytes); err != nil {
+		return "", fmt.Errorf("failed to generate user id: %w", err)
+	}
+	bytes[6] = (bytes[6] & 0x0f) | 0x40
+	bytes[8] = (bytes[8] & 0x3f) | 0x80
+
+	return fmt.Sprintf("%x-%x-%x-%x-%x", bytes[0:4], bytes[4:6], bytes[6:8], bytes[8:10], bytes[10:]), nil
+}
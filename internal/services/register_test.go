@@ -0,0 +1,104 @@
+package services
+
+import (
+	"auth_service/internal/config"
+	"auth_service/internal/entities"
+	"context"
+	"net/url"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+// TestRegister проверяет регистрацию пользователя и подтверждение email по ссылке из письма.
+func TestRegister(t *testing.T) {
+	ctx := context.Background()
+	config.Secret = "test_secret"
+	config.MaxTokensPerUser = "5"
+	config.EmailVerificationUrl = "https://example.com/verify-email"
+	t.Cleanup(func() { config.EmailVerificationUrl = "" })
+	client := &entities.ClientInfo{Ip: "192.168.0.1"}
+	store := newTestStore(t)
+	notifier := &recordingNotifier{}
+	service := NewAuthService(store, notifier)
+
+	userId, err := service.Register(ctx, " new@gmail.com ", "password", "en")
+	require.NoError(t, err)
+	user, err := store.GetUser(ctx, userId)
+	require.NoError(t, err)
+	require.Equal(t, "new@gmail.com", user.Email)
+	require.Equal(t, "en", user.Locale)
+	require.False(t, user.EmailVerified)
+
+	_, err = service.deliverOutbox(ctx, &outboxSettings{batchSize: 10, maxAttempts: 3})
+	require.NoError(t, err)
+	notifications := notifier.received()
+	require.Len(t, notifications, 1)
+	require.Equal(t, entities.NotificationVerifyEmail, notifications[0].Type)
+	require.Equal(t, "new@gmail.com", notifications[0].Email)
+	require.Empty(t, notifications[0].ReportUrl)
+	actionUrl, err := url.Parse(notifications[0].ActionUrl)
+	require.NoError(t, err)
+	token := actionUrl.Query().Get("token")
+	require.NotEmpty(t, token)
+
+	t.Run("login before verification", func(t *testing.T) {
+		_, err := service.Login(ctx, "new@gmail.com", "password", client)
+		require.NoError(t, err)
+
+		config.RequireEmailVerification = "true"
+		t.Cleanup(func() { config.RequireEmailVerification = "" })
+		_, err = service.Login(ctx, "new@gmail.com", "password", client)
+		require.ErrorIs(t, err, ErrEmailNotVerified)
+	})
+	t.Run("verify email", func(t *testing.T) {
+		require.NoError(t, service.VerifyEmail(ctx, token))
+		user, err := store.GetUser(ctx, userId)
+		require.NoError(t, err)
+		require.True(t, user.EmailVerified)
+
+		config.RequireEmailVerification = "true"
+		t.Cleanup(func() { config.RequireEmailVerification = "" })
+		_, err = service.Login(ctx, "new@gmail.com", "password", client)
+		require.NoError(t, err)
+	})
+	t.Run("reused token", func(t *testing.T) {
+		err := service.VerifyEmail(ctx, token)
+		require.ErrorIs(t, err, ErrInvalidOneTimeToken)
+	})
+	t.Run("invalid token", func(t *testing.T) {
+		require.ErrorIs(t, service.VerifyEmail(ctx, ""), ErrInvalidOneTimeToken)
+		require.ErrorIs(t, service.VerifyEmail(ctx, "invalid"), ErrInvalidOneTimeToken)
+	})
+	t.Run("email taken", func(t *testing.T) {
+		_, err := service.Register(ctx, "NEW@gmail.com", "password", "")
+		require.ErrorIs(t, err, ErrEmailTaken)
+	})
+	t.Run("invalid email", func(t *testing.T) {
+		for _, email := range []string{"", "new", "New <other@gmail.com>"} {
+			_, err := service.Register(ctx, email, "password", "")
+			require.ErrorIs(t, err, ErrInvalidEmail)
+		}
+	})
+	t.Run("weak password", func(t *testing.T) {
+		_, err := service.Register(ctx, "other@gmail.com", "short", "")
+		require.ErrorIs(t, err, ErrWeakPassword)
+	})
+}
+
+// TestEmailVerificationUrl проверяет, что при повторной доставке письма действует только последний токен подтверждения.
+func TestEmailVerificationUrl(t *testing.T) {
+	ctx := context.Background()
+	config.EmailVerificationUrl = "https://example.com/verify-email"
+	t.Cleanup(func() { config.EmailVerificationUrl = "" })
+	store := newTestStore(t)
+	service := NewAuthService(store, &NoopNotifier{})
+
+	firstUrl, err := service.emailVerificationUrl(ctx, "123")
+	require.NoError(t, err)
+	secondUrl, err := service.emailVerificationUrl(ctx, "123")
+	require.NoError(t, err)
+
+	require.ErrorIs(t, service.VerifyEmail(ctx, tokenFromUrl(t, firstUrl)), ErrInvalidOneTimeToken)
+	require.NoError(t, service.VerifyEmail(ctx, tokenFromUrl(t, secondUrl)))
+}
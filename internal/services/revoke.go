@@ -0,0 +1,76 @@
+package services
+
+import (
+	"auth_service/internal/storage"
+	"context"
+	"errors"
+	"fmt"
+	"log"
+)
+
+// Revoke отзывает токен по RFC 7009.
+// tokenTypeHint (access_token или refresh_token) определяет, какой тип токена проверяется первым.
+// Отзыв refresh-токена удаляет его сессию (семейство токенов) из хранилища и отзывает выданные в ней access-токены,
+// отзыв access-токена добавляет его jti в список отозванных. Недействительные и неизвестные токены игнорируются,
+// ошибка возвращается только при недоступности хранилища.
+func (s *AuthService) Revoke(ctx context.Context, token, tokenTypeHint string) error {
+	revokers := []func(context.Context, string) (bool, error){s.revokeAccessToken, s.revokeRefreshToken}
+	if tokenTypeHint == refreshTokenType {
+		revokers[0], revokers[1] = revokers[1], revokers[0]
+	}
+
+	for _, revoke := range revokers {
+		revoked, err := revoke(ctx, token)
+		if err != nil {
+			return err
+		}
+		if revoked {
+			return nil
+		}
+	}
+	log.Println("revocation requested for unknown token")
+
+	return nil
+}
+
+// revokeAccessToken добавляет действительный access-токен в список отозванных до истечения его срока действия.
+// Возвращает false, если токен не является действительным access-токеном.
+func (s *AuthService) revokeAccessToken(ctx context.Context, token string) (bool, error) {
+	accessTokenClaims, err := parseAccessToken(token)
+	if err != nil {
+		return false, nil
+	}
+
+	if err := s.storage.RevokeAccessToken(ctx, accessTokenClaims.Jti, accessTokenClaims.ExpiredAt); err != nil {
+		return false, fmt.Errorf("failed to revoke access token with jti '%s': %w", accessTokenClaims.Jti, err)
+	}
+	log.Printf("Access token revoked for userID: '%s', jti: '%s'\n", accessTokenClaims.UserId, accessTokenClaims.Jti)
+
+	return true, nil
+}
+
+// revokeRefreshToken удаляет сессию, к которой относится refresh-токен, и отзывает выданные в ней access-токены.
+// Возвращает false, если токен не является refresh-токеном, сохраненным в хранилище.
+func (s *AuthService) revokeRefreshToken(ctx context.Context, token string) (bool, error) {
+	userId, jti, ok := splitRefreshToken(token)
+	if !ok {
+		return false, nil
+	}
+
+	refreshTokenRecord, err := s.storage.GetRefreshTokenRecord(ctx, jti, userId)
+	if errors.Is(err, storage.ErrTokenNotFound) {
+		return false, nil
+	}
+	if err != nil {
+		return false, fmt.Errorf("failed to get refresh token for revocation: %w", err)
+	}
+	if err := checkRefreshToken(token, refreshTokenRecord.TokenHash); err != nil {
+		return false, nil
+	}
+	if err := s.revokeSession(ctx, userId, refreshTokenRecord.FamilyId); err != nil {
+		return false, fmt.Errorf("failed to revoke session: %w", err)
+	}
+	log.Printf("Refresh token revoked for userID: '%s', jti: '%s'\n", userId, jti)
+
+	return true, nil
+}
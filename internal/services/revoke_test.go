@@ -0,0 +1,64 @@
+package services
+
+import (
+	"auth_service/internal/config"
+	"auth_service/internal/entities"
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+// TestRevoke проверяет отзыв access и refresh токенов по RFC 7009.
+func TestRevoke(t *testing.T) {
+	ctx := context.Background()
+	config.Secret = "test_secret"
+	config.MaxTokensPerUser = "5"
+	userId := "123"
+	client := &entities.ClientInfo{Ip: "192.168.0.1"}
+
+	t.Run("revoke access token", func(t *testing.T) {
+		service := NewAuthService(newTestStore(t), &NoopNotifier{})
+		tokensPair, err := service.GenerateTokens(ctx, userId, nil, client)
+		require.NoError(t, err)
+
+		require.NoError(t, service.Revoke(ctx, tokensPair.AccessToken, "access_token"))
+		_, err = service.ValidateAccessToken(ctx, tokensPair.AccessToken)
+		require.ErrorIs(t, err, ErrAccessTokenRevoked)
+
+		_, err = service.RefreshTokens(ctx, client, tokensPair)
+		require.NoError(t, err)
+	})
+	t.Run("revoke refresh token", func(t *testing.T) {
+		service := NewAuthService(newTestStore(t), &NoopNotifier{})
+		tokensPair, err := service.GenerateTokens(ctx, userId, nil, client)
+		require.NoError(t, err)
+
+		require.NoError(t, service.Revoke(ctx, tokensPair.RefreshToken, "refresh_token"))
+		_, err = service.RefreshTokens(ctx, client, tokensPair)
+		require.ErrorContains(t, err, "not found")
+		_, err = service.ValidateAccessToken(ctx, tokensPair.AccessToken)
+		require.ErrorIs(t, err, ErrAccessTokenRevoked)
+	})
+	t.Run("revoke refresh token with wrong hint", func(t *testing.T) {
+		service := NewAuthService(newTestStore(t), &NoopNotifier{})
+		tokensPair, err := service.GenerateTokens(ctx, userId, nil, client)
+		require.NoError(t, err)
+
+		require.NoError(t, service.Revoke(ctx, tokensPair.RefreshToken, "access_token"))
+		_, err = service.RefreshTokens(ctx, client, tokensPair)
+		require.ErrorContains(t, err, "not found")
+	})
+	t.Run("unknown token", func(t *testing.T) {
+		service := NewAuthService(newTestStore(t), &NoopNotifier{})
+		tokensPair, err := service.GenerateTokens(ctx, userId, nil, client)
+		require.NoError(t, err)
+		forgedToken, err := GenRefreshToken(userId, "not_exist_jti")
+		require.NoError(t, err)
+
+		require.NoError(t, service.Revoke(ctx, "unknown-token", ""))
+		require.NoError(t, service.Revoke(ctx, forgedToken, "refresh_token"))
+		_, err = service.RefreshTokens(ctx, client, tokensPair)
+		require.NoError(t, err)
+	})
+}
@@ -2,6 +2,7 @@ package services_test
 
 import (
 	"auth_service/internal/config"
+	"auth_service/internal/entities"
 	"auth_service/internal/services"
 	"context"
 	"encoding/json"
@@ -14,6 +15,7 @@ import (
 	"path/filepath"
 	"strings"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/require"
 	"github.com/testcontainers/testcontainers-go"
@@ -90,16 +92,26 @@ func TestMain(m *testing.M) {
 	os.Exit(code)
 }
 
-// TestSendWarningMsg проверяет функцию отправки предупреждающего письма пользователю.
-func TestSendWarningMsg(t *testing.T) {
-	testUserEmail := "user@gmail.com"
-	issuedIp := "122.124.123"
-	ip := "122.124.129"
+// TestSmtpNotifier проверяет отправку предупреждающего письма пользователю по шаблону.
+func TestSmtpNotifier(t *testing.T) {
+	ctx := context.Background()
+	notifier, err := services.NewSmtpNotifier("", "")
+	require.NoError(t, err)
+	notification := &entities.Notification{
+		Id:         "ip_changed:jti123",
+		Type:       entities.NotificationIpChanged,
+		UserId:     "123",
+		Email:      "user@gmail.com",
+		Ip:         "122.124.129",
+		PreviousIp: "122.124.123",
+		UserAgent:  "Mozilla/5.0",
+		CreatedAt:  time.Now(),
+	}
 	t.Run("successful sending", func(t *testing.T) {
 		msgs, err := getMsgs()
 		require.NoError(t, err)
 
-		err = services.SendWarningMsg(testUserEmail, issuedIp, ip)
+		err = notifier.Notify(ctx, notification)
 		require.NoError(t, err)
 
 		actualMsgs, err := getMsgs()
@@ -113,12 +125,12 @@ func TestSendWarningMsg(t *testing.T) {
 		defer updateConfig()
 
 		config.SmtpPort = "not_a_number"
-		err := services.SendWarningMsg(testUserEmail, issuedIp, ip)
+		err := notifier.Notify(ctx, notification)
 		require.ErrorContains(t, err, "failed to converte 'smtPort'")
 	})
 
 	t.Run("check email body and recipient", func(t *testing.T) {
-		err := services.SendWarningMsg(testUserEmail, issuedIp, ip)
+		err := notifier.Notify(ctx, notification)
 		require.NoError(t, err)
 
 		msgs, err := getMsgs()
@@ -126,7 +138,7 @@ func TestSendWarningMsg(t *testing.T) {
 		require.Greater(t, len(msgs.Items), 0, "Письмо не было отправлено")
 
 		headers := msgs.Items[len(msgs.Items)-1].Content.Headers
-		require.Equal(t, testUserEmail, headers.To[0])
+		require.Equal(t, notification.Email, headers.To[0])
 		require.Equal(t, config.SenderEmail, headers.From[0])
 
 		contentBody := msgs.Items[len(msgs.Items)-1].Content.Body
@@ -134,13 +146,20 @@ func TestSendWarningMsg(t *testing.T) {
 		require.NoError(t, err)
 		expectedLines := []string{
 			"Была предпринята попытка обновления токена с нового IP-адреса:",
-			fmt.Sprintf("Старый IP:</strong> %s", issuedIp),
-			fmt.Sprintf("Новый IP:</strong> %s", ip),
+			fmt.Sprintf("Старый IP:</strong> %s", notification.PreviousIp),
+			fmt.Sprintf("Новый IP:</strong> %s", notification.Ip),
+			fmt.Sprintf("Старый IP: %s", notification.PreviousIp),
+			notification.UserAgent,
 		}
 		for _, line := range expectedLines {
 			require.Contains(t, string(body), line)
 		}
 	})
+
+	t.Run("unsupported notification type", func(t *testing.T) {
+		err := notifier.Notify(ctx, &entities.Notification{Type: "unknown", Email: notification.Email})
+		require.ErrorContains(t, err, "unsupported notification type")
+	})
 }
 
 // TestCheckConfigVar проверяет функцию checkConfigVar на отсутствие обязательных переменных конфигурации.
@@ -2,31 +2,30 @@ package services
 
 import (
 	"auth_service/internal/config"
+	"auth_service/internal/mailtemplates"
+	"context"
 	"fmt"
 	"strconv"
 
 	"gopkg.in/gomail.v2"
 )
 
-// SendWarningMsg отправляет предупреждающее сообщение на указанный email.
-// Сообщение содержит информацию о попытке обновления токена с нового IP-адреса.
-func SendWarningMsg(userEmail string, issuedIp string, ip string) error {
+// SendEmail отправляет письмо на указанный email.
+// Письмо содержит текстовую и HTML-версии (multipart/alternative).
+// Клиент SMTP не поддерживает контекст, поэтому при отмене ctx SendEmail возвращает ошибку, не дожидаясь завершения отправки.
+func SendEmail(ctx context.Context, userEmail string, email *mailtemplates.Email) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
 	msg := gomail.NewMessage()
 
 	msg.SetHeader("From", config.SenderEmail)
 	msg.SetHeader("To", userEmail)
-	msg.SetHeader("Subject", "Подозрительная активность: IP-адрес изменён")
-
-	body := fmt.Sprintf(`
-        <p>Была предпринята попытка обновления токена с нового IP-адреса:</p>
-        <ul>
-            <li><strong>Старый IP:</strong> %s</li>
-            <li><strong>Новый IP:</strong> %s</li>
-        </ul>
-        <p>Если это были не вы, рекомендуем сменить пароль и завершить все активные сессии.</p>
-    `, issuedIp, ip)
+	msg.SetHeader("Subject", email.Subject)
 
-	msg.SetBody("text/html", body)
+	msg.SetBody("text/plain", email.Text)
+	msg.AddAlternative("text/html", email.Html)
 
 	smtPort, err := strconv.Atoi(config.SmtpPort)
 	if err != nil {
@@ -34,11 +33,18 @@ func SendWarningMsg(userEmail string, issuedIp string, ip string) error {
 	}
 
 	serv := gomail.NewDialer(config.SmtpHost, smtPort, config.SenderEmail, config.PasswordEmail)
-	if err := serv.DialAndSend(msg); err != nil {
-		return fmt.Errorf("failed to send email message^ %w", err)
-	}
+	sent := make(chan error, 1)
+	go func() { sent <- serv.DialAndSend(msg) }()
 
-	return nil
+	select {
+	case err := <-sent:
+		if err != nil {
+			return fmt.Errorf("failed to send email message^ %w", err)
+		}
+		return nil
+	case <-ctx.Done():
+		return fmt.Errorf("failed to send email message: %w", ctx.Err())
+	}
 }
 
 // CheckConfigVar проверяет наличие необходимых переменных окружения для отправки email.
@@ -1,10 +1,32 @@
 package services
 
-import "auth_service/internal/entities"
+import (
+	"auth_service/internal/entities"
+	"context"
+)
 
 // AuthServiceInterface - интерфейс для работы с токенами аутентификации.
-// Определяет методы для генерации и обновления токенов.
+// Определяет методы для генерации, обновления и отзыва токенов, а также публикации ключей проверки подписи.
+// Методы, обращающиеся к хранилищу, принимают контекст запроса, отмена которого прерывает обработку.
 type AuthServiceInterface interface {
-	GenerateTokens(userId, ip string) (*entities.TokensPair, error)
-	RefreshTokens(ip string, tokensPair *entities.TokensPair) (*entities.TokensPair, error)
+	Register(ctx context.Context, email, password, locale string) (string, error)
+	VerifyEmail(ctx context.Context, token string) error
+	ForgotPassword(ctx context.Context, email string) error
+	ResetPassword(ctx context.Context, token, password string) error
+	Login(ctx context.Context, identifier, password string, client *entities.ClientInfo) (*entities.LoginResponse, error)
+	VerifyMfa(ctx context.Context, mfaToken, code string, client *entities.ClientInfo) (*entities.TokensPair, error)
+	EnrollTotp(ctx context.Context, accessToken string) (*entities.TotpEnrollment, error)
+	ConfirmTotp(ctx context.Context, accessToken, code string) ([]string, error)
+	RefreshTokens(ctx context.Context, client *entities.ClientInfo, tokensPair *entities.TokensPair) (*entities.TokensPair, error)
+	ValidateAccessToken(ctx context.Context, accessToken string) (*entities.AccessTokenClaims, error)
+	Logout(ctx context.Context, tokensPair *entities.TokensPair) error
+	LogoutAll(ctx context.Context, accessToken string) error
+	ListSessions(ctx context.Context, accessToken string) ([]*entities.Session, error)
+	RevokeSession(ctx context.Context, accessToken, jti string) error
+	AuthenticateClient(clientId, clientSecret string) error
+	Introspect(ctx context.Context, clientId, token, tokenTypeHint string) (*entities.IntrospectionResponse, error)
+	Revoke(ctx context.Context, token, tokenTypeHint string) error
+	CheckKillLink(token string) error
+	UseKillLink(ctx context.Context, token string, client *entities.ClientInfo) error
+	JWKS() *entities.JWKS
 }
@@ -4,6 +4,7 @@ package service_mocks
 
 import (
 	entities "auth_service/internal/entities"
+	context "context"
 
 	mock "github.com/stretchr/testify/mock"
 )
@@ -13,29 +14,289 @@ type AuthServiceInterface struct {
 	mock.Mock
 }
 
-// GenerateTokens provides a mock function with given fields: userId, ip
-func (_m *AuthServiceInterface) GenerateTokens(userId string, ip string) (*entities.TokensPair, error) {
-	ret := _m.Called(userId, ip)
+// AuthenticateClient provides a mock function with given fields: clientId, clientSecret
+func (_m *AuthServiceInterface) AuthenticateClient(clientId string, clientSecret string) error {
+	ret := _m.Called(clientId, clientSecret)
 
 	if len(ret) == 0 {
-		panic("no return value specified for GenerateTokens")
+		panic("no return value specified for AuthenticateClient")
+	}
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(string, string) error); ok {
+		r0 = rf(clientId, clientSecret)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// CheckKillLink provides a mock function with given fields: token
+func (_m *AuthServiceInterface) CheckKillLink(token string) error {
+	ret := _m.Called(token)
+
+	if len(ret) == 0 {
+		panic("no return value specified for CheckKillLink")
+	}
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(string) error); ok {
+		r0 = rf(token)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// ConfirmTotp provides a mock function with given fields: ctx, accessToken, code
+func (_m *AuthServiceInterface) ConfirmTotp(ctx context.Context, accessToken string, code string) ([]string, error) {
+	ret := _m.Called(ctx, accessToken, code)
+
+	if len(ret) == 0 {
+		panic("no return value specified for ConfirmTotp")
+	}
+
+	var r0 []string
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, string, string) ([]string, error)); ok {
+		return rf(ctx, accessToken, code)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, string, string) []string); ok {
+		r0 = rf(ctx, accessToken, code)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]string)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, string, string) error); ok {
+		r1 = rf(ctx, accessToken, code)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// EnrollTotp provides a mock function with given fields: ctx, accessToken
+func (_m *AuthServiceInterface) EnrollTotp(ctx context.Context, accessToken string) (*entities.TotpEnrollment, error) {
+	ret := _m.Called(ctx, accessToken)
+
+	if len(ret) == 0 {
+		panic("no return value specified for EnrollTotp")
+	}
+
+	var r0 *entities.TotpEnrollment
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, string) (*entities.TotpEnrollment, error)); ok {
+		return rf(ctx, accessToken)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, string) *entities.TotpEnrollment); ok {
+		r0 = rf(ctx, accessToken)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*entities.TotpEnrollment)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, string) error); ok {
+		r1 = rf(ctx, accessToken)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// ForgotPassword provides a mock function with given fields: ctx, email
+func (_m *AuthServiceInterface) ForgotPassword(ctx context.Context, email string) error {
+	ret := _m.Called(ctx, email)
+
+	if len(ret) == 0 {
+		panic("no return value specified for ForgotPassword")
+	}
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, string) error); ok {
+		r0 = rf(ctx, email)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// Introspect provides a mock function with given fields: ctx, clientId, token, tokenTypeHint
+func (_m *AuthServiceInterface) Introspect(ctx context.Context, clientId string, token string, tokenTypeHint string) (*entities.IntrospectionResponse, error) {
+	ret := _m.Called(ctx, clientId, token, tokenTypeHint)
+
+	if len(ret) == 0 {
+		panic("no return value specified for Introspect")
+	}
+
+	var r0 *entities.IntrospectionResponse
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, string, string, string) (*entities.IntrospectionResponse, error)); ok {
+		return rf(ctx, clientId, token, tokenTypeHint)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, string, string, string) *entities.IntrospectionResponse); ok {
+		r0 = rf(ctx, clientId, token, tokenTypeHint)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*entities.IntrospectionResponse)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, string, string, string) error); ok {
+		r1 = rf(ctx, clientId, token, tokenTypeHint)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// JWKS provides a mock function with no fields
+func (_m *AuthServiceInterface) JWKS() *entities.JWKS {
+	ret := _m.Called()
+
+	if len(ret) == 0 {
+		panic("no return value specified for JWKS")
+	}
+
+	var r0 *entities.JWKS
+	if rf, ok := ret.Get(0).(func() *entities.JWKS); ok {
+		r0 = rf()
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*entities.JWKS)
+		}
+	}
+
+	return r0
+}
+
+// ListSessions provides a mock function with given fields: ctx, accessToken
+func (_m *AuthServiceInterface) ListSessions(ctx context.Context, accessToken string) ([]*entities.Session, error) {
+	ret := _m.Called(ctx, accessToken)
+
+	if len(ret) == 0 {
+		panic("no return value specified for ListSessions")
+	}
+
+	var r0 []*entities.Session
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, string) ([]*entities.Session, error)); ok {
+		return rf(ctx, accessToken)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, string) []*entities.Session); ok {
+		r0 = rf(ctx, accessToken)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]*entities.Session)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, string) error); ok {
+		r1 = rf(ctx, accessToken)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// Login provides a mock function with given fields: ctx, identifier, password, client
+func (_m *AuthServiceInterface) Login(ctx context.Context, identifier string, password string, client *entities.ClientInfo) (*entities.LoginResponse, error) {
+	ret := _m.Called(ctx, identifier, password, client)
+
+	if len(ret) == 0 {
+		panic("no return value specified for Login")
+	}
+
+	var r0 *entities.LoginResponse
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, string, string, *entities.ClientInfo) (*entities.LoginResponse, error)); ok {
+		return rf(ctx, identifier, password, client)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, string, string, *entities.ClientInfo) *entities.LoginResponse); ok {
+		r0 = rf(ctx, identifier, password, client)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*entities.LoginResponse)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, string, string, *entities.ClientInfo) error); ok {
+		r1 = rf(ctx, identifier, password, client)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// Logout provides a mock function with given fields: ctx, tokensPair
+func (_m *AuthServiceInterface) Logout(ctx context.Context, tokensPair *entities.TokensPair) error {
+	ret := _m.Called(ctx, tokensPair)
+
+	if len(ret) == 0 {
+		panic("no return value specified for Logout")
+	}
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, *entities.TokensPair) error); ok {
+		r0 = rf(ctx, tokensPair)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// LogoutAll provides a mock function with given fields: ctx, accessToken
+func (_m *AuthServiceInterface) LogoutAll(ctx context.Context, accessToken string) error {
+	ret := _m.Called(ctx, accessToken)
+
+	if len(ret) == 0 {
+		panic("no return value specified for LogoutAll")
+	}
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, string) error); ok {
+		r0 = rf(ctx, accessToken)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// RefreshTokens provides a mock function with given fields: ctx, client, tokensPair
+func (_m *AuthServiceInterface) RefreshTokens(ctx context.Context, client *entities.ClientInfo, tokensPair *entities.TokensPair) (*entities.TokensPair, error) {
+	ret := _m.Called(ctx, client, tokensPair)
+
+	if len(ret) == 0 {
+		panic("no return value specified for RefreshTokens")
 	}
 
 	var r0 *entities.TokensPair
 	var r1 error
-	if rf, ok := ret.Get(0).(func(string, string) (*entities.TokensPair, error)); ok {
-		return rf(userId, ip)
+	if rf, ok := ret.Get(0).(func(context.Context, *entities.ClientInfo, *entities.TokensPair) (*entities.TokensPair, error)); ok {
+		return rf(ctx, client, tokensPair)
 	}
-	if rf, ok := ret.Get(0).(func(string, string) *entities.TokensPair); ok {
-		r0 = rf(userId, ip)
+	if rf, ok := ret.Get(0).(func(context.Context, *entities.ClientInfo, *entities.TokensPair) *entities.TokensPair); ok {
+		r0 = rf(ctx, client, tokensPair)
 	} else {
 		if ret.Get(0) != nil {
 			r0 = ret.Get(0).(*entities.TokensPair)
 		}
 	}
 
-	if rf, ok := ret.Get(1).(func(string, string) error); ok {
-		r1 = rf(userId, ip)
+	if rf, ok := ret.Get(1).(func(context.Context, *entities.ClientInfo, *entities.TokensPair) error); ok {
+		r1 = rf(ctx, client, tokensPair)
 	} else {
 		r1 = ret.Error(1)
 	}
@@ -43,29 +304,177 @@ func (_m *AuthServiceInterface) GenerateTokens(userId string, ip string) (*entit
 	return r0, r1
 }
 
-// RefreshTokens provides a mock function with given fields: ip, tokensPair
-func (_m *AuthServiceInterface) RefreshTokens(ip string, tokensPair *entities.TokensPair) (*entities.TokensPair, error) {
-	ret := _m.Called(ip, tokensPair)
+// Register provides a mock function with given fields: ctx, email, password, locale
+func (_m *AuthServiceInterface) Register(ctx context.Context, email string, password string, locale string) (string, error) {
+	ret := _m.Called(ctx, email, password, locale)
 
 	if len(ret) == 0 {
-		panic("no return value specified for RefreshTokens")
+		panic("no return value specified for Register")
+	}
+
+	var r0 string
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, string, string, string) (string, error)); ok {
+		return rf(ctx, email, password, locale)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, string, string, string) string); ok {
+		r0 = rf(ctx, email, password, locale)
+	} else {
+		r0 = ret.Get(0).(string)
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, string, string, string) error); ok {
+		r1 = rf(ctx, email, password, locale)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// ResetPassword provides a mock function with given fields: ctx, token, password
+func (_m *AuthServiceInterface) ResetPassword(ctx context.Context, token string, password string) error {
+	ret := _m.Called(ctx, token, password)
+
+	if len(ret) == 0 {
+		panic("no return value specified for ResetPassword")
+	}
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, string, string) error); ok {
+		r0 = rf(ctx, token, password)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// Revoke provides a mock function with given fields: ctx, token, tokenTypeHint
+func (_m *AuthServiceInterface) Revoke(ctx context.Context, token string, tokenTypeHint string) error {
+	ret := _m.Called(ctx, token, tokenTypeHint)
+
+	if len(ret) == 0 {
+		panic("no return value specified for Revoke")
+	}
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, string, string) error); ok {
+		r0 = rf(ctx, token, tokenTypeHint)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// RevokeSession provides a mock function with given fields: ctx, accessToken, jti
+func (_m *AuthServiceInterface) RevokeSession(ctx context.Context, accessToken string, jti string) error {
+	ret := _m.Called(ctx, accessToken, jti)
+
+	if len(ret) == 0 {
+		panic("no return value specified for RevokeSession")
+	}
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, string, string) error); ok {
+		r0 = rf(ctx, accessToken, jti)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// UseKillLink provides a mock function with given fields: ctx, token, client
+func (_m *AuthServiceInterface) UseKillLink(ctx context.Context, token string, client *entities.ClientInfo) error {
+	ret := _m.Called(ctx, token, client)
+
+	if len(ret) == 0 {
+		panic("no return value specified for UseKillLink")
+	}
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, string, *entities.ClientInfo) error); ok {
+		r0 = rf(ctx, token, client)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// ValidateAccessToken provides a mock function with given fields: ctx, accessToken
+func (_m *AuthServiceInterface) ValidateAccessToken(ctx context.Context, accessToken string) (*entities.AccessTokenClaims, error) {
+	ret := _m.Called(ctx, accessToken)
+
+	if len(ret) == 0 {
+		panic("no return value specified for ValidateAccessToken")
+	}
+
+	var r0 *entities.AccessTokenClaims
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, string) (*entities.AccessTokenClaims, error)); ok {
+		return rf(ctx, accessToken)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, string) *entities.AccessTokenClaims); ok {
+		r0 = rf(ctx, accessToken)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*entities.AccessTokenClaims)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, string) error); ok {
+		r1 = rf(ctx, accessToken)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// VerifyEmail provides a mock function with given fields: ctx, token
+func (_m *AuthServiceInterface) VerifyEmail(ctx context.Context, token string) error {
+	ret := _m.Called(ctx, token)
+
+	if len(ret) == 0 {
+		panic("no return value specified for VerifyEmail")
+	}
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, string) error); ok {
+		r0 = rf(ctx, token)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// VerifyMfa provides a mock function with given fields: ctx, mfaToken, code, client
+func (_m *AuthServiceInterface) VerifyMfa(ctx context.Context, mfaToken string, code string, client *entities.ClientInfo) (*entities.TokensPair, error) {
+	ret := _m.Called(ctx, mfaToken, code, client)
+
+	if len(ret) == 0 {
+		panic("no return value specified for VerifyMfa")
 	}
 
 	var r0 *entities.TokensPair
 	var r1 error
-	if rf, ok := ret.Get(0).(func(string, *entities.TokensPair) (*entities.TokensPair, error)); ok {
-		return rf(ip, tokensPair)
+	if rf, ok := ret.Get(0).(func(context.Context, string, string, *entities.ClientInfo) (*entities.TokensPair, error)); ok {
+		return rf(ctx, mfaToken, code, client)
 	}
-	if rf, ok := ret.Get(0).(func(string, *entities.TokensPair) *entities.TokensPair); ok {
-		r0 = rf(ip, tokensPair)
+	if rf, ok := ret.Get(0).(func(context.Context, string, string, *entities.ClientInfo) *entities.TokensPair); ok {
+		r0 = rf(ctx, mfaToken, code, client)
 	} else {
 		if ret.Get(0) != nil {
 			r0 = ret.Get(0).(*entities.TokensPair)
 		}
 	}
 
-	if rf, ok := ret.Get(1).(func(string, *entities.TokensPair) error); ok {
-		r1 = rf(ip, tokensPair)
+	if rf, ok := ret.Get(1).(func(context.Context, string, string, *entities.ClientInfo) error); ok {
+		r1 = rf(ctx, mfaToken, code, client)
 	} else {
 		r1 = ret.Error(1)
 	}
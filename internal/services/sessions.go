@@ -0,0 +1,119 @@
+package services
+
+import (
+	"auth_service/internal/entities"
+	"auth_service/internal/storage"
+	"context"
+	"errors"
+	"fmt"
+	"log"
+)
+
+// Logout завершает сессию, к которой относится переданная пара токенов, и отзывает ее access-токены.
+// Срок действия access-токена не учитывается, refresh-токен должен соответствовать сохраненному хэшу,
+// иначе возвращается ошибка ErrInvalidRefreshToken.
+func (s *AuthService) Logout(ctx context.Context, tokensPair *entities.TokensPair) error {
+	accessTokenClaims, err := parseExpiredAccessToken(tokensPair.AccessToken)
+	if err != nil {
+		return fmt.Errorf("failed to parse access token: %w", err)
+	}
+
+	refreshTokenRecord, err := s.storage.GetRefreshTokenRecord(ctx, accessTokenClaims.Jti, accessTokenClaims.UserId)
+	if errors.Is(err, storage.ErrTokenNotFound) {
+		return fmt.Errorf("refresh token with jti '%s' was not found: %w", accessTokenClaims.Jti, ErrInvalidRefreshToken)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to get token claims: %w", err)
+	}
+	if err := checkRefreshToken(tokensPair.RefreshToken, refreshTokenRecord.TokenHash); err != nil {
+		return fmt.Errorf("failed to check refresh token: %w", err)
+	}
+	err = s.revokeSession(ctx, accessTokenClaims.UserId, refreshTokenRecord.FamilyId)
+	if errors.Is(err, storage.ErrTokenNotFound) {
+		return fmt.Errorf("session of refresh token with jti '%s' was already revoked: %w", accessTokenClaims.Jti, ErrInvalidRefreshToken)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to revoke session: %w", err)
+	}
+	log.Printf("Session revoked by logout for userID: '%s', jti: '%s'\n", accessTokenClaims.UserId, accessTokenClaims.Jti)
+
+	return nil
+}
+
+// LogoutAll завершает все сессии пользователя, которому принадлежит access-токен.
+// Выданные в этих сессиях access-токены отзываются.
+func (s *AuthService) LogoutAll(ctx context.Context, accessToken string) error {
+	accessTokenClaims, err := s.ValidateAccessToken(ctx, accessToken)
+	if err != nil {
+		return fmt.Errorf("failed to validate access token: %w", err)
+	}
+
+	revokedRecords, err := s.storage.RevokeAllRefreshTokens(ctx, accessTokenClaims.UserId)
+	if err != nil {
+		return fmt.Errorf("failed to revoke sessions: %w", err)
+	}
+	if err := s.revokeAccessTokens(ctx, revokedRecords); err != nil {
+		return fmt.Errorf("failed to revoke access tokens: %w", err)
+	}
+	log.Printf("All sessions revoked for userID: '%s'\n", accessTokenClaims.UserId)
+
+	return nil
+}
+
+// ListSessions возвращает активные сессии пользователя, которому принадлежит access-токен.
+// Сессия, к которой относится сам access-токен, помечается как текущая.
+func (s *AuthService) ListSessions(ctx context.Context, accessToken string) ([]*entities.Session, error) {
+	accessTokenClaims, err := s.ValidateAccessToken(ctx, accessToken)
+	if err != nil {
+		return nil, fmt.Errorf("failed to validate access token: %w", err)
+	}
+
+	refreshTokenRecords, err := s.storage.GetRefreshTokenRecords(ctx, accessTokenClaims.UserId)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get sessions: %w", err)
+	}
+
+	sessions := make([]*entities.Session, 0, len(refreshTokenRecords))
+	for _, record := range refreshTokenRecords {
+		sessions = append(sessions, &entities.Session{
+			Jti:        record.Jti,
+			DeviceName: record.DeviceName,
+			UserAgent:  record.UserAgent,
+			Ip:         record.IssuedIp,
+			CreatedAt:  record.SessionStartedAt,
+			LastUsedAt: record.LastUsedAt,
+			ExpiredAt:  record.ExpiredAt,
+			Current:    record.Jti == accessTokenClaims.Jti,
+		})
+	}
+
+	return sessions, nil
+}
+
+// RevokeSession завершает сессию пользователя по jti ее текущего refresh-токена.
+// Пользователь определяется по access-токену, поэтому завершить можно только собственную сессию.
+// Если сессия не найдена, возвращает ошибку ErrSessionNotFound.
+func (s *AuthService) RevokeSession(ctx context.Context, accessToken, jti string) error {
+	accessTokenClaims, err := s.ValidateAccessToken(ctx, accessToken)
+	if err != nil {
+		return fmt.Errorf("failed to validate access token: %w", err)
+	}
+
+	refreshTokenRecord, err := s.storage.GetRefreshTokenRecord(ctx, jti, accessTokenClaims.UserId)
+	if errors.Is(err, storage.ErrTokenNotFound) {
+		return fmt.Errorf("refresh token with jti '%s' was not found: %w", jti, ErrSessionNotFound)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to get token claims: %w", err)
+	}
+	err = s.revokeSession(ctx, accessTokenClaims.UserId, refreshTokenRecord.FamilyId)
+	if errors.Is(err, storage.ErrTokenNotFound) {
+		return fmt.Errorf("session of refresh token with jti '%s' was already revoked: %w", jti, ErrSessionNotFound)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to revoke session: %w", err)
+	}
+	log.Printf("Session revoked for userID: '%s', jti: '%s'\n", accessTokenClaims.UserId, jti)
+
+	return nil
+}
@@ -0,0 +1,97 @@
+package services
+
+import (
+	"auth_service/internal/config"
+	"auth_service/internal/entities"
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+// TestLogout проверяет завершение текущей сессии, всех сессий и сессии по jti.
+func TestLogout(t *testing.T) {
+	ctx := context.Background()
+	config.Secret = "test_secret"
+	config.MaxTokensPerUser = "5"
+	userId := "123"
+	client := &entities.ClientInfo{Ip: "192.168.0.1"}
+
+	t.Run("logout current session", func(t *testing.T) {
+		service := NewAuthService(newTestStore(t), &NoopNotifier{})
+		tokensPair, err := service.GenerateTokens(ctx, userId, nil, client)
+		require.NoError(t, err)
+		otherTokensPair, err := service.GenerateTokens(ctx, userId, nil, client)
+		require.NoError(t, err)
+
+		err = service.Logout(ctx, &entities.TokensPair{AccessToken: tokensPair.AccessToken, RefreshToken: otherTokensPair.RefreshToken})
+		require.ErrorIs(t, err, ErrInvalidRefreshToken)
+
+		require.NoError(t, service.Logout(ctx, tokensPair))
+		_, err = service.RefreshTokens(ctx, client, tokensPair)
+		require.ErrorContains(t, err, "not found")
+		_, err = service.RefreshTokens(ctx, client, otherTokensPair)
+		require.NoError(t, err)
+	})
+	t.Run("logout all sessions", func(t *testing.T) {
+		service := NewAuthService(newTestStore(t), &NoopNotifier{})
+		tokensPair, err := service.GenerateTokens(ctx, userId, nil, client)
+		require.NoError(t, err)
+		otherTokensPair, err := service.GenerateTokens(ctx, userId, nil, client)
+		require.NoError(t, err)
+
+		require.NoError(t, service.LogoutAll(ctx, tokensPair.AccessToken))
+		_, err = service.RefreshTokens(ctx, client, tokensPair)
+		require.ErrorContains(t, err, "not found")
+		_, err = service.RefreshTokens(ctx, client, otherTokensPair)
+		require.ErrorContains(t, err, "not found")
+	})
+	t.Run("revoke session by jti", func(t *testing.T) {
+		service := NewAuthService(newTestStore(t), &NoopNotifier{})
+		tokensPair, err := service.GenerateTokens(ctx, userId, nil, client)
+		require.NoError(t, err)
+		otherTokensPair, err := service.GenerateTokens(ctx, userId, nil, client)
+		require.NoError(t, err)
+		otherClaims, err := parseAccessToken(otherTokensPair.AccessToken)
+		require.NoError(t, err)
+
+		require.NoError(t, service.RevokeSession(ctx, tokensPair.AccessToken, otherClaims.Jti))
+		_, err = service.RefreshTokens(ctx, client, otherTokensPair)
+		require.ErrorContains(t, err, "not found")
+		_, err = service.RefreshTokens(ctx, client, tokensPair)
+		require.NoError(t, err)
+
+		err = service.RevokeSession(ctx, tokensPair.AccessToken, "not_exist_jti")
+		require.ErrorIs(t, err, ErrSessionNotFound)
+	})
+}
+
+// TestListSessions проверяет получение списка сессий с пометкой текущей сессии.
+func TestListSessions(t *testing.T) {
+	ctx := context.Background()
+	config.Secret = "test_secret"
+	config.MaxTokensPerUser = "5"
+	service := NewAuthService(newTestStore(t), &NoopNotifier{})
+	userId := "123"
+	laptop := &entities.ClientInfo{Ip: "192.168.0.1", UserAgent: "test-agent", DeviceName: "Work laptop"}
+	phone := &entities.ClientInfo{Ip: "192.168.0.2", UserAgent: "test-agent", DeviceName: "Phone"}
+
+	laptopTokensPair, err := service.GenerateTokens(ctx, userId, nil, laptop)
+	require.NoError(t, err)
+	_, err = service.GenerateTokens(ctx, userId, nil, phone)
+	require.NoError(t, err)
+	laptopTokensPair, err = service.RefreshTokens(ctx, &entities.ClientInfo{Ip: laptop.Ip, UserAgent: "new-agent"}, laptopTokensPair)
+	require.NoError(t, err)
+
+	sessions, err := service.ListSessions(ctx, laptopTokensPair.AccessToken)
+	require.NoError(t, err)
+	require.Len(t, sessions, 2)
+
+	current := sessions[0]
+	require.True(t, current.Current)
+	require.Equal(t, "Work laptop", current.DeviceName)
+	require.Equal(t, "new-agent", current.UserAgent)
+	require.Equal(t, laptop.Ip, current.Ip)
+	require.False(t, sessions[1].Current)
+	require.Equal(t, "Phone", sessions[1].DeviceName)
+}
@@ -0,0 +1,222 @@
+package services
+
+import (
+	"auth_service/internal/config"
+	"auth_service/internal/entities"
+	"bytes"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"os"
+
+	"github.com/golang-jwt/jwt"
+)
+
+// SigningKey представляет ключ, которым подписываются и проверяются access-токены.
+type SigningKey struct {
+	Kid        string            // Идентификатор ключа (JWK thumbprint, RFC 7638), передается в заголовке kid.
+	Method     jwt.SigningMethod // Алгоритм подписи.
+	PrivateKey interface{}       // Ключ для подписи (для HMAC - секрет).
+	PublicKey  interface{}       // Ключ для проверки подписи (для HMAC - тот же секрет).
+}
+
+// InitSigningKey загружает ключ подписи согласно переменным окружения JWT_ALGORITHM и JWT_PRIVATE_KEY_FILE
+// и делает его активным ключом связки.
+func InitSigningKey() error {
+	key, err := LoadSigningKey(config.JwtAlgorithm, config.JwtPrivateKeyFile)
+	if err != nil {
+		return err
+	}
+	gracePeriod, err := getKeyGracePeriod()
+	if err != nil {
+		return err
+	}
+	keyring.Reset(key, gracePeriod)
+
+	return nil
+}
+
+// RotateSigningKey повторно читает ключ подписи из конфигурации и, если он изменился, делает его активным.
+// Предыдущий ключ остается доступным для проверки токенов до окончания grace-периода.
+func RotateSigningKey() error {
+	key, err := LoadSigningKey(config.JwtAlgorithm, config.JwtPrivateKeyFile)
+	if err != nil {
+		return err
+	}
+	if !keyring.Rotate(key) {
+		return fmt.Errorf("signing key with kid '%s' is already active", key.Kid)
+	}
+
+	return nil
+}
+
+// LoadSigningKey создает ключ подписи для указанного алгоритма.
+// Для асимметричных алгоритмов приватный ключ читается из PEM-файла, публичный ключ вычисляется из приватного.
+// Для HS512 секрет читается из файла, а если файл не задан - берется из переменной окружения SECRET.
+func LoadSigningKey(alg, keyFile string) (*SigningKey, error) {
+	if alg == "" || alg == jwt.SigningMethodHS512.Alg() {
+		secret := []byte(config.Secret)
+		if keyFile != "" {
+			fileSecret, err := os.ReadFile(keyFile)
+			if err != nil {
+				return nil, fmt.Errorf("failed to read secret file: %w", err)
+			}
+			secret = bytes.TrimSpace(fileSecret)
+		}
+		if len(secret) == 0 {
+			return nil, fmt.Errorf("'SECRET' is not set in the environment variables")
+		}
+		return newSigningKey(jwt.SigningMethodHS512, secret, secret), nil
+	}
+	if keyFile == "" {
+		return nil, fmt.Errorf("'JWT_PRIVATE_KEY_FILE' is not set for algorithm '%s'", alg)
+	}
+	pemBytes, err := os.ReadFile(keyFile)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read private key file: %w", err)
+	}
+
+	switch alg {
+	case jwt.SigningMethodRS256.Alg():
+		privateKey, err := jwt.ParseRSAPrivateKeyFromPEM(pemBytes)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse RSA private key: %w", err)
+		}
+		return newSigningKey(jwt.SigningMethodRS256, privateKey, &privateKey.PublicKey), nil
+	case jwt.SigningMethodES256.Alg():
+		privateKey, err := jwt.ParseECPrivateKeyFromPEM(pemBytes)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse ECDSA private key: %w", err)
+		}
+		if privateKey.Curve != elliptic.P256() {
+			return nil, fmt.Errorf("ECDSA private key must use P-256 curve")
+		}
+		return newSigningKey(jwt.SigningMethodES256, privateKey, &privateKey.PublicKey), nil
+	case jwt.SigningMethodEdDSA.Alg():
+		privateKey, err := jwt.ParseEdPrivateKeyFromPEM(pemBytes)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse Ed25519 private key: %w", err)
+		}
+		edKey := privateKey.(ed25519.PrivateKey)
+		return newSigningKey(jwt.SigningMethodEdDSA, edKey, edKey.Public()), nil
+	}
+
+	return nil, fmt.Errorf("unsupported signing algorithm: '%s'", alg)
+}
+
+// newSigningKey создает ключ подписи и вычисляет его идентификатор.
+func newSigningKey(method jwt.SigningMethod, privateKey, publicKey interface{}) *SigningKey {
+	key := &SigningKey{Method: method, PrivateKey: privateKey, PublicKey: publicKey}
+	key.Kid = key.thumbprint()
+
+	return key
+}
+
+// currentSigningKey возвращает ключ, которым подписываются новые access-токены.
+// Если связка ключей не инициализирована, используется HS512 с секретом из конфига.
+func currentSigningKey() *SigningKey {
+	if key := keyring.Active(); key != nil {
+		return key
+	}
+	secret := []byte(config.Secret)
+
+	return &SigningKey{Method: jwt.SigningMethodHS512, PrivateKey: secret, PublicKey: secret}
+}
+
+// verificationKey возвращает ключ для проверки подписи токена по его заголовку kid.
+// Токены без kid, выпущенные до введения ротации ключей, проверяются текущим ключом.
+func verificationKey(kid string) (*SigningKey, error) {
+	if kid == "" || keyring.Active() == nil {
+		return currentSigningKey(), nil
+	}
+	key := keyring.Get(kid)
+	if key == nil {
+		return nil, fmt.Errorf("signing key with kid '%s' was not found", kid)
+	}
+
+	return key, nil
+}
+
+// JWK возвращает публичную часть ключа в формате JSON Web Key (RFC 7517).
+// Для симметричных ключей возвращает false, так как секрет не может быть опубликован.
+func (k *SigningKey) JWK() (*entities.JWK, bool) {
+	jwk := &entities.JWK{Kid: k.Kid, Use: "sig", Alg: k.Method.Alg()}
+
+	switch publicKey := k.PublicKey.(type) {
+	case *rsa.PublicKey:
+		jwk.Kty = "RSA"
+		jwk.N = base64.RawURLEncoding.EncodeToString(publicKey.N.Bytes())
+		jwk.E = base64.RawURLEncoding.EncodeToString(big.NewInt(int64(publicKey.E)).Bytes())
+	case *ecdsa.PublicKey:
+		size := (publicKey.Curve.Params().BitSize + 7) / 8
+		jwk.Kty = "EC"
+		jwk.Crv = publicKey.Curve.Params().Name
+		jwk.X = base64.RawURLEncoding.EncodeToString(publicKey.X.FillBytes(make([]byte, size)))
+		jwk.Y = base64.RawURLEncoding.EncodeToString(publicKey.Y.FillBytes(make([]byte, size)))
+	case ed25519.PublicKey:
+		jwk.Kty = "OKP"
+		jwk.Crv = "Ed25519"
+		jwk.X = base64.RawURLEncoding.EncodeToString(publicKey)
+	default:
+		return nil, false
+	}
+
+	return jwk, true
+}
+
+// thumbprint вычисляет JWK thumbprint ключа (RFC 7638), который используется в качестве kid.
+func (k *SigningKey) thumbprint() string {
+	var members interface{}
+	if jwk, ok := k.JWK(); ok {
+		switch jwk.Kty {
+		case "RSA":
+			members = struct {
+				E   string `json:"e"`
+				Kty string `json:"kty"`
+				N   string `json:"n"`
+			}{jwk.E, jwk.Kty, jwk.N}
+		case "EC":
+			members = struct {
+				Crv string `json:"crv"`
+				Kty string `json:"kty"`
+				X   string `json:"x"`
+				Y   string `json:"y"`
+			}{jwk.Crv, jwk.Kty, jwk.X, jwk.Y}
+		default:
+			members = struct {
+				Crv string `json:"crv"`
+				Kty string `json:"kty"`
+				X   string `json:"x"`
+			}{jwk.Crv, jwk.Kty, jwk.X}
+		}
+	} else {
+		secret, _ := k.PublicKey.([]byte)
+		members = struct {
+			K   string `json:"k"`
+			Kty string `json:"kty"`
+		}{base64.RawURLEncoding.EncodeToString(secret), "oct"}
+	}
+	data, _ := json.Marshal(members)
+	hash := sha256.Sum256(data)
+
+	return base64.RawURLEncoding.EncodeToString(hash[:])
+}
+
+// GetJWKS возвращает набор публичных ключей для проверки access-токенов:
+// активный ключ и ключи, grace-период которых еще не истек.
+func GetJWKS() *entities.JWKS {
+	jwks := &entities.JWKS{Keys: []*entities.JWK{}}
+	for _, key := range keyring.VerificationKeys() {
+		if jwk, ok := key.JWK(); ok {
+			jwks.Keys = append(jwks.Keys, jwk)
+		}
+	}
+
+	return jwks
+}
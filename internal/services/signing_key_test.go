@@ -0,0 +1,111 @@
+package services
+
+import (
+	"auth_service/internal/config"
+	"auth_service/internal/entities"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/pem"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+// writeTestKey сохраняет приватный ключ в PEM-файл во временной директории теста.
+func writeTestKey(t *testing.T, privateKey interface{}) string {
+	der, err := x509.MarshalPKCS8PrivateKey(privateKey)
+	require.NoError(t, err)
+
+	path := filepath.Join(t.TempDir(), "key.pem")
+	err = os.WriteFile(path, pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der}), 0600)
+	require.NoError(t, err)
+
+	return path
+}
+
+// TestLoadSigningKey проверяет загрузку асимметричных ключей, подпись и проверку access токена.
+func TestLoadSigningKey(t *testing.T) {
+	t.Cleanup(func() { keyring = NewKeyring() })
+
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+	ecKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	_, edKey, err := ed25519.GenerateKey(rand.Reader)
+	require.NoError(t, err)
+
+	testCases := []struct {
+		alg        string
+		privateKey interface{}
+		kty        string
+	}{
+		{alg: "RS256", privateKey: rsaKey, kty: "RSA"},
+		{alg: "ES256", privateKey: ecKey, kty: "EC"},
+		{alg: "EdDSA", privateKey: edKey, kty: "OKP"},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.alg, func(t *testing.T) {
+			key, err := LoadSigningKey(tc.alg, writeTestKey(t, tc.privateKey))
+			require.NoError(t, err)
+			keyring.Reset(key, time.Hour)
+
+			claims := &entities.AccessTokenClaims{
+				Jti:       "test-jti",
+				UserId:    "123",
+				CreatedAt: time.Now(),
+				ExpiredAt: time.Now().Add(time.Hour),
+			}
+			accessToken, err := GenAccessToken(claims)
+			require.NoError(t, err)
+
+			actualClaims, err := parseAccessToken(accessToken)
+			require.NoError(t, err)
+			require.Equal(t, claims.Jti, actualClaims.Jti)
+			require.Equal(t, claims.UserId, actualClaims.UserId)
+
+			jwks := GetJWKS()
+			require.Len(t, jwks.Keys, 1)
+			require.Equal(t, tc.kty, jwks.Keys[0].Kty)
+			require.Equal(t, tc.alg, jwks.Keys[0].Alg)
+			require.Equal(t, key.Kid, jwks.Keys[0].Kid)
+		})
+	}
+
+	t.Run("algorithm mismatch", func(t *testing.T) {
+		config.Secret = "test_secret"
+		keyring = NewKeyring()
+		hsToken, err := generateTestAccessToken(config.Secret, map[string]interface{}{"jti": "test-jti"})
+		require.NoError(t, err)
+
+		key, err := LoadSigningKey("RS256", writeTestKey(t, rsaKey))
+		require.NoError(t, err)
+		keyring.Reset(key, time.Hour)
+
+		_, err = parseAccessToken(hsToken)
+		require.ErrorContains(t, err, "unexpected signing method")
+	})
+	t.Run("unsupported algorithm", func(t *testing.T) {
+		_, err := LoadSigningKey("PS512", writeTestKey(t, rsaKey))
+		require.ErrorContains(t, err, "unsupported signing algorithm")
+	})
+	t.Run("key file is not set", func(t *testing.T) {
+		_, err := LoadSigningKey("RS256", "")
+		require.ErrorContains(t, err, "'JWT_PRIVATE_KEY_FILE' is not set")
+	})
+	t.Run("symmetric key is not published", func(t *testing.T) {
+		config.Secret = "test_secret"
+		key, err := LoadSigningKey("HS512", "")
+		require.NoError(t, err)
+		keyring.Reset(key, time.Hour)
+
+		require.NotEmpty(t, key.Kid)
+		require.Empty(t, GetJWKS().Keys)
+	})
+}
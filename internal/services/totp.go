@@ -0,0 +1,190 @@
+package services
+
+import (
+	"auth_service/internal/config"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha1"
+	"crypto/subtle"
+	"encoding/base32"
+	"encoding/base64"
+	"encoding/binary"
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	totpDigits       = 6                // Количество цифр в коде TOTP.
+	totpPeriod       = 30 * time.Second // Длительность временного шага TOTP.
+	totpSkew         = 1                // Количество соседних временных шагов, коды которых принимаются из-за расхождения часов.
+	totpSecretLength = 20               // Длина TOTP-секрета в байтах (160 бит, рекомендация RFC 4226).
+
+	defaultTotpIssuer = "auth_service" // Название сервиса в TOTP-приложении, если TOTP_ISSUER и JWT_ISSUER не заданы.
+)
+
+// totpEncoding - кодирование TOTP-секрета в base32 без заполнения, принятое в TOTP-приложениях.
+var totpEncoding = base32.StdEncoding.WithPadding(base32.NoPadding)
+
+// genTotpSecret генерирует случайный TOTP-секрет.
+func genTotpSecret() ([]byte, error) {
+	secret := make([]byte, totpSecretLength)
+	if _, err := rand.Read(secret); err != nil {
+		return nil, fmt.Errorf("failed to generate totp secret: %w", err)
+	}
+
+	return secret, nil
+}
+
+// totpCode вычисляет код TOTP (RFC 6238) для временного шага: HOTP (RFC 4226) с HMAC-SHA1.
+func totpCode(secret []byte, step int64) string {
+	counter := make([]byte, 8)
+	binary.BigEndian.PutUint64(counter, uint64(step))
+	mac := hmac.New(sha1.New, secret)
+	mac.Write(counter)
+	sum := mac.Sum(nil)
+
+	offset := sum[len(sum)-1] & 0x0f
+	value := binary.BigEndian.Uint32(sum[offset:offset+4]) & 0x7fffffff
+
+	return fmt.Sprintf("%0*d", totpDigits, value%1_000_000)
+}
+
+// totpStep возвращает номер временного шага TOTP для момента времени t.
+func totpStep(t time.Time) int64 {
+	return t.Unix() / int64(totpPeriod/time.Second)
+}
+
+// matchTotpCode проверяет код TOTP для текущего и соседних временных шагов и возвращает номер совпавшего шага.
+func matchTotpCode(secret []byte, code string, now time.Time) (int64, bool) {
+	if len(code) != totpDigits {
+		return 0, false
+	}
+	if _, err := strconv.Atoi(code); err != nil {
+		return 0, false
+	}
+
+	current := totpStep(now)
+	for step := current - totpSkew; step <= current+totpSkew; step++ {
+		if subtle.ConstantTimeCompare([]byte(totpCode(secret, step)), []byte(code)) == 1 {
+			return step, true
+		}
+	}
+
+	return 0, false
+}
+
+// totpUri формирует ссылку otpauth:// для подключения TOTP-приложения по QR-коду.
+func totpUri(accountName string, secret []byte) string {
+	issuer := config.TotpIssuer
+	if issuer == "" {
+		issuer = config.JwtIssuer
+	}
+	if issuer == "" {
+		issuer = defaultTotpIssuer
+	}
+
+	query := url.Values{}
+	query.Set("secret", totpEncoding.EncodeToString(secret))
+	query.Set("issuer", issuer)
+	query.Set("algorithm", "SHA1")
+	query.Set("digits", strconv.Itoa(totpDigits))
+	query.Set("period", strconv.Itoa(int(totpPeriod/time.Second)))
+	uri := &url.URL{
+		Scheme:   "otpauth",
+		Host:     "totp",
+		Path:     "/" + issuer + ":" + accountName,
+		RawQuery: query.Encode(),
+	}
+
+	return uri.String()
+}
+
+// encryptTotpSecret шифрует TOTP-секрет ключом TOTP_ENCRYPTION_KEY (AES-256-GCM).
+// Идентификатор пользователя используется как дополнительные данные, поэтому секрет нельзя перенести другому пользователю.
+// Возвращает nonce и шифртекст в base64.
+func encryptTotpSecret(userId string, secret []byte) (string, error) {
+	aead, err := totpCipher()
+	if err != nil {
+		return "", err
+	}
+	nonce := make([]byte, aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return "", fmt.Errorf("failed to generate nonce: %w", err)
+	}
+	ciphertext := aead.Seal(nonce, nonce, secret, []byte(userId))
+
+	return base64.StdEncoding.EncodeToString(ciphertext), nil
+}
+
+// decryptTotpSecret расшифровывает TOTP-секрет, зашифрованный encryptTotpSecret.
+func decryptTotpSecret(userId, encryptedSecret string) ([]byte, error) {
+	aead, err := totpCipher()
+	if err != nil {
+		return nil, err
+	}
+	ciphertext, err := base64.StdEncoding.DecodeString(encryptedSecret)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode totp secret: %w", err)
+	}
+	if len(ciphertext) < aead.NonceSize() {
+		return nil, fmt.Errorf("encrypted totp secret is too short")
+	}
+	nonce, ciphertext := ciphertext[:aead.NonceSize()], ciphertext[aead.NonceSize():]
+	secret, err := aead.Open(nil, nonce, ciphertext, []byte(userId))
+	if err != nil {
+		return nil, fmt.Errorf("failed to decrypt totp secret: %w", err)
+	}
+
+	return secret, nil
+}
+
+// ValidateTotpEncryptionKey проверяет ключ шифрования TOTP-секретов из TOTP_ENCRYPTION_KEY.
+// Вызывается при запуске сервиса, чтобы отсутствующий или неверный ключ обнаруживался сразу,
+// а не ошибками сервера при подключении TOTP и входе. Если двухфакторная аутентификация выключена, ключ не требуется.
+func ValidateTotpEncryptionKey() error {
+	enabled, err := MfaEnabled()
+	if err != nil || !enabled {
+		return err
+	}
+	_, err = totpCipher()
+	return err
+}
+
+// MfaEnabled возвращает, включена ли двухфакторная аутентификация (MFA_ENABLED).
+// Если переменная не задана, двухфакторная аутентификация считается включенной при заданном TOTP_ENCRYPTION_KEY.
+func MfaEnabled() (bool, error) {
+	if config.MfaEnabled == "" {
+		return config.TotpEncryptionKey != "", nil
+	}
+	enabled, err := strconv.ParseBool(config.MfaEnabled)
+	if err != nil {
+		return false, fmt.Errorf("env 'MFA_ENABLED' is not bool: %w", err)
+	}
+
+	return enabled, nil
+}
+
+// totpCipher создает AES-256-GCM по ключу из TOTP_ENCRYPTION_KEY.
+func totpCipher() (cipher.AEAD, error) {
+	if config.TotpEncryptionKey == "" {
+		return nil, fmt.Errorf("env 'TOTP_ENCRYPTION_KEY' is not set")
+	}
+	key, err := base64.StdEncoding.DecodeString(strings.TrimSpace(config.TotpEncryptionKey))
+	if err != nil {
+		return nil, fmt.Errorf("env 'TOTP_ENCRYPTION_KEY' is not base64: %w", err)
+	}
+	if len(key) != 32 {
+		return nil, fmt.Errorf("env 'TOTP_ENCRYPTION_KEY' must be 32 bytes, got %d", len(key))
+	}
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create cipher: %w", err)
+	}
+
+	return cipher.NewGCM(block)
+}
@@ -0,0 +1,110 @@
+package services
+
+import (
+	"auth_service/internal/config"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+// TestTotpCode проверяет вычисление кодов TOTP по тестовым векторам RFC 6238 (SHA1, последние 6 цифр).
+func TestTotpCode(t *testing.T) {
+	secret := []byte("12345678901234567890")
+	tests := []struct {
+		unixTime int64
+		code     string
+	}{
+		{unixTime: 59, code: "287082"},
+		{unixTime: 1111111109, code: "081804"},
+		{unixTime: 1111111111, code: "050471"},
+		{unixTime: 1234567890, code: "005924"},
+		{unixTime: 2000000000, code: "279037"},
+	}
+	for _, tt := range tests {
+		require.Equal(t, tt.code, totpCode(secret, totpStep(time.Unix(tt.unixTime, 0))), tt.unixTime)
+	}
+
+	t.Run("clock skew", func(t *testing.T) {
+		now := time.Unix(1234567890, 0)
+		step, ok := matchTotpCode(secret, "005924", now.Add(totpPeriod))
+		require.True(t, ok)
+		require.Equal(t, totpStep(now), step)
+		_, ok = matchTotpCode(secret, "005924", now.Add(3*totpPeriod))
+		require.False(t, ok)
+	})
+	t.Run("invalid code", func(t *testing.T) {
+		now := time.Unix(1234567890, 0)
+		for _, code := range []string{"", "00592", "0059245", "00592a"} {
+			_, ok := matchTotpCode(secret, code, now)
+			require.False(t, ok, code)
+		}
+	})
+}
+
+// TestTotpSecretEncryption проверяет шифрование TOTP-секрета и привязку шифртекста к пользователю.
+func TestTotpSecretEncryption(t *testing.T) {
+	config.TotpEncryptionKey = "MDEyMzQ1Njc4OWFiY2RlZjAxMjM0NTY3ODlhYmNkZWY="
+	t.Cleanup(func() { config.TotpEncryptionKey = "" })
+	secret, err := genTotpSecret()
+	require.NoError(t, err)
+
+	encryptedSecret, err := encryptTotpSecret("123", secret)
+	require.NoError(t, err)
+	require.NotContains(t, encryptedSecret, totpEncoding.EncodeToString(secret))
+	actual, err := decryptTotpSecret("123", encryptedSecret)
+	require.NoError(t, err)
+	require.Equal(t, secret, actual)
+
+	t.Run("other user", func(t *testing.T) {
+		_, err := decryptTotpSecret("456", encryptedSecret)
+		require.Error(t, err)
+	})
+	t.Run("invalid key", func(t *testing.T) {
+		config.TotpEncryptionKey = "c2hvcnQ="
+		_, err := encryptTotpSecret("123", secret)
+		require.ErrorContains(t, err, "must be 32 bytes")
+		config.TotpEncryptionKey = ""
+		_, err = encryptTotpSecret("123", secret)
+		require.ErrorContains(t, err, "is not set")
+	})
+}
+
+// TestValidateTotpEncryptionKey проверяет проверку ключа шифрования TOTP-секретов при запуске сервиса.
+func TestValidateTotpEncryptionKey(t *testing.T) {
+	t.Cleanup(func() {
+		config.MfaEnabled = ""
+		config.TotpEncryptionKey = ""
+	})
+
+	require.NoError(t, ValidateTotpEncryptionKey())
+	config.TotpEncryptionKey = "MDEyMzQ1Njc4OWFiY2RlZjAxMjM0NTY3ODlhYmNkZWY="
+	require.NoError(t, ValidateTotpEncryptionKey())
+	config.TotpEncryptionKey = "c2hvcnQ="
+	require.ErrorContains(t, ValidateTotpEncryptionKey(), "must be 32 bytes")
+
+	config.MfaEnabled = "true"
+	config.TotpEncryptionKey = ""
+	require.ErrorContains(t, ValidateTotpEncryptionKey(), "is not set")
+	config.MfaEnabled = "false"
+	require.NoError(t, ValidateTotpEncryptionKey())
+	config.MfaEnabled = "maybe"
+	require.ErrorContains(t, ValidateTotpEncryptionKey(), "MFA_ENABLED")
+}
+
+// TestTotpUri проверяет формат ссылки otpauth:// для TOTP-приложений.
+func TestTotpUri(t *testing.T) {
+	config.TotpIssuer = "Example"
+	t.Cleanup(func() { config.TotpIssuer = "" })
+
+	uri, err := url.Parse(totpUri("user@gmail.com", []byte("12345678901234567890")))
+	require.NoError(t, err)
+	require.Equal(t, "otpauth", uri.Scheme)
+	require.Equal(t, "totp", uri.Host)
+	require.Equal(t, "/Example:user@gmail.com", uri.Path)
+	require.Equal(t, "GEZDGNBVGY3TQOJQGEZDGNBVGY3TQOJQ", uri.Query().Get("secret"))
+	require.Equal(t, "Example", uri.Query().Get("issuer"))
+	require.Equal(t, "6", uri.Query().Get("digits"))
+	require.Equal(t, "30", uri.Query().Get("period"))
+}
@@ -0,0 +1,26 @@
+package services
+
+import (
+	"auth_service/internal/entities"
+	"auth_service/internal/storage"
+	"context"
+	"errors"
+	"fmt"
+)
+
+// getActiveUser возвращает пользователя, которому разрешена выдача токенов.
+// Если пользователь не найден, возвращает ErrUnknownUser, если заблокирован - ErrUserDisabled.
+func (s *AuthService) getActiveUser(ctx context.Context, userId string) (*entities.User, error) {
+	user, err := s.storage.GetUser(ctx, userId)
+	if errors.Is(err, storage.ErrUserNotFound) {
+		return nil, fmt.Errorf("user '%s' was not found: %w", userId, ErrUnknownUser)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get user: %w", err)
+	}
+	if user.Status != entities.UserStatusActive {
+		return nil, fmt.Errorf("user '%s' has status '%s': %w", userId, user.Status, ErrUserDisabled)
+	}
+
+	return user, nil
+}
@@ -0,0 +1,77 @@
+package services
+
+import (
+	"auth_service/internal/entities"
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"time"
+)
+
+// webhookTimeout - максимальное время ожидания ответа от webhook.
+const webhookTimeout = 5 * time.Second
+
+// WebhookNotifier доставляет уведомления POST-запросом в формате JSON на заданный URL.
+// Если задан секрет, тело запроса подписывается HMAC-SHA256, подпись передается в заголовке X-Signature.
+type WebhookNotifier struct {
+	url    string       // url - адрес, на который отправляются уведомления.
+	secret string       // secret - секрет для подписи тела запроса.
+	client *http.Client // client - HTTP-клиент с ограничением времени ожидания.
+}
+
+// NewWebhookNotifier создает WebhookNotifier для указанного URL.
+// Возвращает ошибку, если URL не задан или не является абсолютным http(s) адресом.
+func NewWebhookNotifier(webhookUrl, secret string) (*WebhookNotifier, error) {
+	if webhookUrl == "" {
+		return nil, fmt.Errorf("'NOTIFIER_WEBHOOK_URL' is not set in the environment variables")
+	}
+	parsedUrl, err := url.Parse(webhookUrl)
+	if err != nil || (parsedUrl.Scheme != "http" && parsedUrl.Scheme != "https") || parsedUrl.Host == "" {
+		return nil, fmt.Errorf("'NOTIFIER_WEBHOOK_URL' is not valid http(s) URL: '%s'", webhookUrl)
+	}
+
+	notifier := &WebhookNotifier{
+		url:    webhookUrl,
+		secret: secret,
+		client: &http.Client{Timeout: webhookTimeout},
+	}
+
+	return notifier, nil
+}
+
+// Notify отправляет уведомление на webhook. Ответ со статусом, отличным от 2xx, считается ошибкой.
+func (n *WebhookNotifier) Notify(ctx context.Context, notification *entities.Notification) error {
+	body, err := json.Marshal(notification)
+	if err != nil {
+		return fmt.Errorf("failed to marshal notification: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, n.url, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("failed to create webhook request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if n.secret != "" {
+		mac := hmac.New(sha256.New, []byte(n.secret))
+		mac.Write(body)
+		req.Header.Set("X-Signature", "sha256="+hex.EncodeToString(mac.Sum(nil)))
+	}
+
+	resp, err := n.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to send webhook notification: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("webhook responded with status %d", resp.StatusCode)
+	}
+
+	return nil
+}
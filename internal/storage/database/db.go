@@ -3,29 +3,87 @@ package database
 import (
 	"auth_service/internal/config"
 	"auth_service/internal/entities"
+	"auth_service/internal/storage"
+	"context"
+	"database/sql"
+	"errors"
 	"fmt"
 	"log"
 	"strconv"
-	"strings"
+	"time"
 
+	"github.com/jackc/pgx/v5/pgconn"
 	_ "github.com/jackc/pgx/v5/stdlib"
 	"github.com/jmoiron/sqlx"
 )
 
+// insertRefreshTokenQuery - запрос на добавление refresh-токена в таблицу refresh_tokens.
+const insertRefreshTokenQuery = `
+	INSERT INTO refresh_tokens 
+	(jti, user_id, created_at, expired_at, session_started_at, family_id, issued_ip, user_agent, device_name, last_used_at, token_hash, amr) 
+	VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12)
+	`
+
+// insertOutboxMessageQuery - запрос на добавление сообщения в таблицу notification_outbox.
+// Сообщение с существующим ключом идемпотентности игнорируется.
+const insertOutboxMessageQuery = `
+	INSERT INTO notification_outbox
+	(id, payload, status, attempts, next_attempt_at, last_error, created_at)
+	VALUES ($1, $2, $3, $4, $5, $6, $7)
+	ON CONFLICT (id) DO NOTHING
+	`
+
+// userSessionsLockNamespace - первый ключ advisory lock, под которой изменяются refresh-токены одного пользователя
+// (второй ключ - хэш идентификатора пользователя).
+const userSessionsLockNamespace int32 = 1
+
+// purgeLockId - ключ advisory lock, под которой выполняется очистка истекших refresh-токенов.
+// Очистку одновременно выполняет только одна реплика сервиса, остальные пропускают запуск.
+const purgeLockId int64 = 4_210_677_302
+
+// defaultQueryTimeout - максимальное время выполнения одной операции с базой данных, если DB_QUERY_TIMEOUT не задан.
+const defaultQueryTimeout = 5 * time.Second
+
+// Коды ошибок PostgreSQL.
+const (
+	uniqueViolationCode     = "23505" // Нарушение ограничения уникальности.
+	foreignKeyViolationCode = "23503" // Нарушение ограничения внешнего ключа.
+)
+
 // Database представляет собой структуру для работы с базой данных
 // и выполнения операций с таблицей refresh_tokens.
 type Database struct {
-	db *sqlx.DB // db — соединение с базой данных через sqlx
+	db           *sqlx.DB      // db — соединение с базой данных через sqlx
+	queryTimeout time.Duration // queryTimeout — максимальное время выполнения одной операции с базой данных
 }
 
 // NewDatabaseStore возвращает объект базы данных для работы с таблицами.
-func NewDatabaseStore(db *sqlx.DB) *Database {
-	return &Database{db: db}
+// Время выполнения каждой операции ограничивается DB_QUERY_TIMEOUT (в секундах).
+func NewDatabaseStore(db *sqlx.DB) (*Database, error) {
+	queryTimeout := defaultQueryTimeout
+	if config.DbQueryTimeout != "" {
+		seconds, err := strconv.Atoi(config.DbQueryTimeout)
+		if err != nil {
+			return nil, fmt.Errorf("env 'DB_QUERY_TIMEOUT' is not number: %w", err)
+		}
+		if seconds <= 0 {
+			return nil, fmt.Errorf("env 'DB_QUERY_TIMEOUT' must be positive")
+		}
+		queryTimeout = time.Duration(seconds) * time.Second
+	}
+
+	return &Database{db: db, queryTimeout: queryTimeout}, nil
+}
+
+// operationContext ограничивает время выполнения одной операции с базой данных.
+// Операция прерывается и при отмене родительского контекста (например, при отключении клиента).
+func (d *Database) operationContext(ctx context.Context) (context.Context, context.CancelFunc) {
+	return context.WithTimeout(ctx, d.queryTimeout)
 }
 
-// NewDatabaseConection устанавливает соединение с базой данных и создает таблицу refresh_tokens, если она не существует.
+// ConnectDatabase устанавливает соединение с базой данных без применения миграций.
 // Возвращает объект подключения к базе данных или ошибку.
-func NewDatabaseConection(psqlUrl string) (*sqlx.DB, error) {
+func ConnectDatabase(ctx context.Context, psqlUrl string) (*sqlx.DB, error) {
 	if psqlUrl == "" {
 		return nil, fmt.Errorf("'PSQL_URL' environment variable is empty")
 	}
@@ -35,158 +93,983 @@ func NewDatabaseConection(psqlUrl string) (*sqlx.DB, error) {
 		return nil, fmt.Errorf("failed to open database: %w", err)
 	}
 
-	query := `
-	CREATE TABLE IF NOT EXISTS refresh_tokens (
-	jti TEXT PRIMARY KEY,
-	user_id TEXT NOT NULL,
-	created_at TIMESTAMP NOT NULL,
-	expired_at  TIMESTAMP NOT NULL,
-	issued_ip TEXT NOT NULL,
-	token_hash TEXT UNIQUE NOT NULL
-    );
-	
-	CREATE INDEX IF NOT EXISTS user_id__hash_indx ON refresh_tokens USING hash (user_id);
-	CREATE INDEX IF NOT EXISTS token__hash_indx ON refresh_tokens USING hash (token_hash);
-	`
+	if err := db.PingContext(ctx); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("failed to connect to database: %w", err)
+	}
+
+	return db, nil
+}
+
+// NewDatabaseConection устанавливает соединение с базой данных и применяет непримененные миграции схемы,
+// если это не отключено переменной MIGRATE_ON_START.
+// Возвращает объект подключения к базе данных или ошибку.
+func NewDatabaseConection(ctx context.Context, psqlUrl string) (*sqlx.DB, error) {
+	db, err := ConnectDatabase(ctx, psqlUrl)
+	if err != nil {
+		return nil, err
+	}
 
-	if _, err := db.Exec(query); err != nil {
-		return nil, fmt.Errorf("failed to create table in database: %w", err)
+	migrateOnStart := true
+	if config.MigrateOnStart != "" {
+		migrateOnStart, err = strconv.ParseBool(config.MigrateOnStart)
+		if err != nil {
+			db.Close()
+			return nil, fmt.Errorf("env 'MIGRATE_ON_START' is not boolean: %w", err)
+		}
+	}
+
+	if migrateOnStart {
+		if _, err := MigrateUp(ctx, db); err != nil {
+			db.Close()
+			return nil, fmt.Errorf("failed to migrate database: %w", err)
+		}
 	}
 
 	log.Println("Starting the database ...")
 
-	return db, err
+	return db, nil
 }
 
 // SaveRefreshTokenRecord сохраняет хэш refresh-токена для указанного пользователя.
-// Если у пользователя уже максимальное количество токенов, удаляет самый старый токен.
-// Возвращает ошибку, если токен с таким jti уже существует.
-func (d *Database) SaveRefreshTokenRecord(userId string, refreshTokenRecord *entities.RefreshTokenRecord) error {
+// Если у пользователя уже максимальное количество активных токенов, в той же транзакции удаляет
+// столько самых старых токенов, сколько нужно, чтобы с новым токеном лимит не был превышен.
+// Одновременные входы одного пользователя выполняются последовательно под блокировкой пользователя.
+// Возвращает ErrTokenExists, если токен с таким jti уже существует.
+func (d *Database) SaveRefreshTokenRecord(ctx context.Context, userId string, refreshTokenRecord *entities.RefreshTokenRecord) error {
+	ctx, cancel := d.operationContext(ctx)
+	defer cancel()
+
 	maxTokensPerUser, err := strconv.Atoi(config.MaxTokensPerUser)
 	if err != nil {
 		return fmt.Errorf("env 'MAX_TOKENS_PER_USER' is not number: %w", err)
 	}
-	if err := d.checkActiveTokens(userId, maxTokensPerUser); err != nil {
-		if !strings.Contains(err.Error(), "exceeding the limit for userID") {
-			return fmt.Errorf("failed to check active tokens userID: '%s': %w", userId, err)
-		}
-		log.Println(err)
-		if err := d.deleteOldestRefreshToken(userId); err != nil {
-			return fmt.Errorf("failed to delete oldest refresh token for userID: '%s': %w", userId, err)
-		}
-		log.Printf("the latest token has been deleted due to exceeding the limit for userID: '%s'\n", userId)
+	if maxTokensPerUser < 1 {
+		return fmt.Errorf("env 'MAX_TOKENS_PER_USER' must be positive")
 	}
 
-	query := `
-	INSERT INTO refresh_tokens 
-	(jti, user_id, created_at, expired_at, issued_ip, token_hash) 
-	VALUES ($1, $2, $3, $4, $5, $6)
-	`
+	tx, err := d.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction for userID: '%s': %w", userId, unavailable(err))
+	}
+	defer tx.Rollback()
 
-	if _, err := d.db.Exec(query, refreshTokenRecord.Jti, userId, refreshTokenRecord.CreatedAt,
-		refreshTokenRecord.ExpiredAt, refreshTokenRecord.IssuedIp, refreshTokenRecord.TokenHash); err != nil {
-		return fmt.Errorf("failed to insert row into 'refresh_tokens' for userID: '%s': %w", userId, err)
+	if err := lockUserSessions(ctx, tx, userId); err != nil {
+		return err
+	}
+
+	evicted, err := evictOldestRefreshTokens(ctx, tx, userId, maxTokensPerUser-1)
+	if err != nil {
+		return err
+	}
+	if evicted > 0 {
+		log.Printf("%d oldest token(s) have been deleted due to exceeding the limit for userID: '%s'\n", evicted, userId)
+	}
+
+	_, err = tx.ExecContext(ctx, insertRefreshTokenQuery, insertRefreshTokenArgs(userId, refreshTokenRecord)...)
+	if isUniqueViolation(err) {
+		return fmt.Errorf("refresh token with jti '%s': %w", refreshTokenRecord.Jti, storage.ErrTokenExists)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to insert row into 'refresh_tokens' for userID: '%s': %w", userId, unavailable(err))
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit transaction for userID: '%s': %w", userId, unavailable(err))
 	}
 
 	return nil
 }
 
 // UpdateRefreshTokenRecord обновляет refresh-токен пользователя по старому jti.
-// Удаляет старый токен и добавляет новый.
-// Если запись не найдена, возвращает ошибку.
-func (d *Database) UpdateRefreshTokenRecord(oldJti, userId string, newRefreshTokenRecord *entities.RefreshTokenRecord) error {
-	query := `
-	UPDATE refresh_tokens
-	SET jti = $1, created_at = $2, expired_at = $3, issued_ip = $4, token_hash = $5  
-    WHERE jti = $6 AND user_id = $7
-	`
+// В одной транзакции помечает старый токен как использованный, добавляет новый токен в то же семейство
+// и сохраняет сообщение outbox (если задано), поэтому уведомление не теряется и не создается без обновления токенов.
+// Обновление выполняется под блокировкой пользователя, чтобы одновременный вход не удалил обновляемый токен
+// и не превысил лимит активных токенов.
+// Возвращает ErrTokenNotFound, если запись не найдена, или ErrTokenReused, если токен уже был использован.
+func (d *Database) UpdateRefreshTokenRecord(ctx context.Context, oldJti, userId string, newRefreshTokenRecord *entities.RefreshTokenRecord,
+	outboxMessage *entities.OutboxMessage) error {
+	ctx, cancel := d.operationContext(ctx)
+	defer cancel()
 
-	result, err := d.db.Exec(query, newRefreshTokenRecord.Jti, newRefreshTokenRecord.CreatedAt, newRefreshTokenRecord.ExpiredAt,
-		newRefreshTokenRecord.IssuedIp, newRefreshTokenRecord.TokenHash, oldJti, userId)
+	tx, err := d.db.BeginTxx(ctx, nil)
 	if err != nil {
-		return fmt.Errorf("failed to update row from 'refresh_tokens' for for userID: '%s': %w", userId, err)
+		return fmt.Errorf("failed to begin transaction for userID: '%s': %w", userId, unavailable(err))
 	}
+	defer tx.Rollback()
 
-	rowsAffected, err := result.RowsAffected()
+	if err := lockUserSessions(ctx, tx, userId); err != nil {
+		return err
+	}
+
+	var rotated bool
+	err = tx.GetContext(ctx, &rotated, "SELECT rotated FROM refresh_tokens WHERE jti = $1 AND user_id = $2 FOR UPDATE", oldJti, userId)
+	if errors.Is(err, sql.ErrNoRows) {
+		return fmt.Errorf("refresh token with jti '%s' for userID: '%s': %w", oldJti, userId, storage.ErrTokenNotFound)
+	}
 	if err != nil {
-		return fmt.Errorf("failed to get rows affected for userID: '%s': %w", userId, err)
+		return fmt.Errorf("failed to select row from 'refresh_tokens' for userID: '%s': %w", userId, unavailable(err))
 	}
-	if rowsAffected == 0 {
-		return fmt.Errorf("no rows updated for userID: '%s': jti '%s' not found", userId, oldJti)
+	if rotated {
+		return fmt.Errorf("refresh token with jti '%s': %w", oldJti, storage.ErrTokenReused)
+	}
+
+	if _, err := tx.ExecContext(ctx, "UPDATE refresh_tokens SET rotated = TRUE WHERE jti = $1 AND user_id = $2", oldJti, userId); err != nil {
+		return fmt.Errorf("failed to update row from 'refresh_tokens' for userID: '%s': %w", userId, unavailable(err))
+	}
+
+	if _, err := tx.ExecContext(ctx, insertRefreshTokenQuery, insertRefreshTokenArgs(userId, newRefreshTokenRecord)...); err != nil {
+		return fmt.Errorf("failed to insert row into 'refresh_tokens' for userID: '%s': %w", userId, unavailable(err))
+	}
+
+	if outboxMessage != nil {
+		if _, err := tx.ExecContext(ctx, insertOutboxMessageQuery, outboxMessage.Id, outboxMessage.Payload, outboxMessage.Status,
+			outboxMessage.Attempts, outboxMessage.NextAttemptAt, outboxMessage.LastError, outboxMessage.CreatedAt); err != nil {
+			return fmt.Errorf("failed to insert row into 'notification_outbox' for userID: '%s': %w", userId, unavailable(err))
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit transaction for userID: '%s': %w", userId, unavailable(err))
 	}
 
 	return nil
 }
 
 // GetRefreshTokenRecord возвращает record токена по jti и userId.
-// Если запись не найдена, возвращает ошибку.
-func (d *Database) GetRefreshTokenRecord(jti, userId string) (*entities.RefreshTokenRecord, error) {
+// Если запись не найдена, возвращает ErrTokenNotFound.
+func (d *Database) GetRefreshTokenRecord(ctx context.Context, jti, userId string) (*entities.RefreshTokenRecord, error) {
+	ctx, cancel := d.operationContext(ctx)
+	defer cancel()
+
 	refreshTokenRecord := &entities.RefreshTokenRecord{}
 	query := `
-	SELECT jti, created_at, expired_at, issued_ip, token_hash
+	SELECT jti, created_at, expired_at, session_started_at, family_id, rotated, issued_ip, user_agent, device_name, last_used_at, token_hash, amr
 	FROM refresh_tokens 
     WHERE jti = $1 AND user_id = $2
 	`
 
-	if err := d.db.Get(refreshTokenRecord, query, jti, userId); err != nil {
-		return nil, fmt.Errorf("failed to select claims from 'refresh_tokens' for jti: '%s': %w", jti, err)
+	err := d.db.GetContext(ctx, refreshTokenRecord, query, jti, userId)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, fmt.Errorf("refresh token with jti '%s' for userID: '%s': %w", jti, userId, storage.ErrTokenNotFound)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to select claims from 'refresh_tokens' for jti: '%s': %w", jti, unavailable(err))
 	}
 
 	return refreshTokenRecord, nil
 }
 
-// GetUserEmail возвращает email пользователя (в данном случае моковые данные).
-// Если email не найден, возвращает ошибку.
-func (d *Database) GetUserEmail(userId string) (string, error) {
-	mockEmail := "user@gmail.com"
+// GetRefreshTokenRecords возвращает активные (не использованные для обновления и не просроченные) refresh-токены пользователя.
+// Записи упорядочены по времени последнего использования, начиная с самой свежей.
+func (d *Database) GetRefreshTokenRecords(ctx context.Context, userId string) ([]*entities.RefreshTokenRecord, error) {
+	ctx, cancel := d.operationContext(ctx)
+	defer cancel()
+
+	refreshTokenRecords := []*entities.RefreshTokenRecord{}
+	query := `
+	SELECT jti, created_at, expired_at, session_started_at, family_id, rotated, issued_ip, user_agent, device_name, last_used_at, token_hash, amr
+	FROM refresh_tokens 
+    WHERE user_id = $1 AND NOT rotated AND expired_at > $2
+	ORDER BY last_used_at DESC
+	`
+
+	if err := d.db.SelectContext(ctx, &refreshTokenRecords, query, userId, time.Now().UTC()); err != nil {
+		return nil, fmt.Errorf("failed to select rows from 'refresh_tokens' for userID: '%s': %w", userId, unavailable(err))
+	}
+
+	return refreshTokenRecords, nil
+}
+
+// RevokeTokenFamily удаляет все refresh-токены семейства (сессии) пользователя и возвращает удаленные записи.
+// Если токены семейства не найдены, возвращает ErrTokenNotFound.
+func (d *Database) RevokeTokenFamily(ctx context.Context, userId, familyId string) ([]*entities.RefreshTokenRecord, error) {
+	ctx, cancel := d.operationContext(ctx)
+	defer cancel()
+
+	revokedRecords := []*entities.RefreshTokenRecord{}
+	query := `
+	DELETE FROM refresh_tokens
+	WHERE user_id = $1 AND family_id = $2
+	RETURNING jti, created_at, expired_at, session_started_at, family_id, rotated, issued_ip, user_agent, device_name, last_used_at, token_hash, amr
+	`
+
+	if err := d.db.SelectContext(ctx, &revokedRecords, query, userId, familyId); err != nil {
+		return nil, fmt.Errorf("failed to delete rows from 'refresh_tokens' for userID: '%s': %w", userId, unavailable(err))
+	}
+	if len(revokedRecords) == 0 {
+		return nil, fmt.Errorf("token family '%s' for userID: '%s': %w", familyId, userId, storage.ErrTokenNotFound)
+	}
+
+	return revokedRecords, nil
+}
+
+// RevokeAllRefreshTokens удаляет все refresh-токены пользователя и возвращает удаленные записи.
+func (d *Database) RevokeAllRefreshTokens(ctx context.Context, userId string) ([]*entities.RefreshTokenRecord, error) {
+	ctx, cancel := d.operationContext(ctx)
+	defer cancel()
+
+	revokedRecords := []*entities.RefreshTokenRecord{}
+	query := `
+	DELETE FROM refresh_tokens
+	WHERE user_id = $1
+	RETURNING jti, created_at, expired_at, session_started_at, family_id, rotated, issued_ip, user_agent, device_name, last_used_at, token_hash, amr
+	`
+
+	if err := d.db.SelectContext(ctx, &revokedRecords, query, userId); err != nil {
+		return nil, fmt.Errorf("failed to delete rows from 'refresh_tokens' for userID: '%s': %w", userId, unavailable(err))
+	}
+
+	return revokedRecords, nil
+}
+
+// RevokeAccessToken добавляет access-токен в таблицу revoked_access_tokens до истечения его срока действия.
+// Попутно удаляет из таблицы записи, срок действия которых уже истек.
+func (d *Database) RevokeAccessToken(ctx context.Context, jti string, expiredAt time.Time) error {
+	ctx, cancel := d.operationContext(ctx)
+	defer cancel()
+
+	tx, err := d.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction for jti: '%s': %w", jti, unavailable(err))
+	}
+	defer tx.Rollback()
+
+	now := time.Now().UTC()
+	if _, err := tx.ExecContext(ctx, "DELETE FROM revoked_access_tokens WHERE expired_at <= $1", now); err != nil {
+		return fmt.Errorf("failed to delete expired rows from 'revoked_access_tokens': %w", unavailable(err))
+	}
+
+	query := `
+	INSERT INTO revoked_access_tokens (jti, expired_at)
+	VALUES ($1, $2)
+	ON CONFLICT (jti) DO UPDATE SET expired_at = GREATEST(revoked_access_tokens.expired_at, EXCLUDED.expired_at)
+	`
+
+	if _, err := tx.ExecContext(ctx, query, jti, expiredAt.UTC()); err != nil {
+		return fmt.Errorf("failed to insert row into 'revoked_access_tokens' for jti: '%s': %w", jti, unavailable(err))
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit transaction for jti: '%s': %w", jti, unavailable(err))
+	}
+
+	return nil
+}
+
+// IsAccessTokenRevoked проверяет, есть ли access-токен в таблице revoked_access_tokens.
+// Записи с истекшим сроком действия не учитываются.
+func (d *Database) IsAccessTokenRevoked(ctx context.Context, jti string) (bool, error) {
+	ctx, cancel := d.operationContext(ctx)
+	defer cancel()
+
+	var revoked bool
+	query := `
+	SELECT EXISTS (
+		SELECT 1 FROM revoked_access_tokens
+		WHERE jti = $1 AND expired_at > $2
+	)
+	`
+
+	if err := d.db.GetContext(ctx, &revoked, query, jti, time.Now().UTC()); err != nil {
+		return false, fmt.Errorf("failed to select row from 'revoked_access_tokens' for jti: '%s': %w", jti, unavailable(err))
+	}
+
+	return revoked, nil
+}
+
+// EnqueueOutboxMessage добавляет сообщение в таблицу notification_outbox.
+// Сообщение с уже существующим ключом идемпотентности игнорируется.
+func (d *Database) EnqueueOutboxMessage(ctx context.Context, outboxMessage *entities.OutboxMessage) error {
+	ctx, cancel := d.operationContext(ctx)
+	defer cancel()
+
+	if _, err := d.db.ExecContext(ctx, insertOutboxMessageQuery, outboxMessage.Id, outboxMessage.Payload, outboxMessage.Status,
+		outboxMessage.Attempts, outboxMessage.NextAttemptAt, outboxMessage.LastError, outboxMessage.CreatedAt); err != nil {
+		return fmt.Errorf("failed to insert row into 'notification_outbox' for id: '%s': %w", outboxMessage.Id, unavailable(err))
+	}
+
+	return nil
+}
+
+// ClaimOutboxMessages возвращает до limit ожидающих доставки сообщений, время доставки которых наступило.
+// Следующая попытка захваченных сообщений откладывается на время lease, а строки, захваченные
+// другими экземплярами сервиса, пропускаются (FOR UPDATE SKIP LOCKED).
+func (d *Database) ClaimOutboxMessages(ctx context.Context, now time.Time, lease time.Duration, limit int) ([]*entities.OutboxMessage, error) {
+	ctx, cancel := d.operationContext(ctx)
+	defer cancel()
+
+	outboxMessages := []*entities.OutboxMessage{}
+	query := `
+	UPDATE notification_outbox
+	SET next_attempt_at = $2
+	WHERE id IN (
+		SELECT id FROM notification_outbox
+		WHERE status = 'pending' AND next_attempt_at <= $1
+		ORDER BY next_attempt_at ASC
+		LIMIT $3
+		FOR UPDATE SKIP LOCKED
+	)
+	RETURNING id, payload, status, attempts, next_attempt_at, last_error, created_at
+	`
+
+	if err := d.db.SelectContext(ctx, &outboxMessages, query, now.UTC(), now.Add(lease).UTC(), limit); err != nil {
+		return nil, fmt.Errorf("failed to claim rows from 'notification_outbox': %w", unavailable(err))
+	}
+
+	return outboxMessages, nil
+}
+
+// MarkOutboxMessageDelivered помечает сообщение как доставленное.
+func (d *Database) MarkOutboxMessageDelivered(ctx context.Context, id string) error {
+	ctx, cancel := d.operationContext(ctx)
+	defer cancel()
+
+	query := `
+	UPDATE notification_outbox
+	SET status = 'delivered'
+	WHERE id = $1
+	`
+
+	return d.updateOutboxMessage(ctx, id, query, id)
+}
+
+// MarkOutboxMessageFailed увеличивает счетчик попыток доставки и назначает время следующей попытки.
+func (d *Database) MarkOutboxMessageFailed(ctx context.Context, id, lastError string, nextAttemptAt time.Time) error {
+	ctx, cancel := d.operationContext(ctx)
+	defer cancel()
+
+	query := `
+	UPDATE notification_outbox
+	SET attempts = attempts + 1, last_error = $2, next_attempt_at = $3
+	WHERE id = $1
+	`
+
+	return d.updateOutboxMessage(ctx, id, query, id, lastError, nextAttemptAt.UTC())
+}
+
+// MarkOutboxMessageDead увеличивает счетчик попыток доставки и помечает сообщение как недоставленное.
+func (d *Database) MarkOutboxMessageDead(ctx context.Context, id, lastError string) error {
+	ctx, cancel := d.operationContext(ctx)
+	defer cancel()
+
+	query := `
+	UPDATE notification_outbox
+	SET attempts = attempts + 1, last_error = $2, status = 'dead'
+	WHERE id = $1
+	`
+
+	return d.updateOutboxMessage(ctx, id, query, id, lastError)
+}
+
+// IsKillLinkUsed проверяет, есть ли ссылка "Это был не я" в таблице kill_link_events.
+func (d *Database) IsKillLinkUsed(ctx context.Context, id string) (bool, error) {
+	ctx, cancel := d.operationContext(ctx)
+	defer cancel()
+
+	var used bool
+	query := `SELECT EXISTS (SELECT 1 FROM kill_link_events WHERE id = $1)`
+	if err := d.db.GetContext(ctx, &used, query, id); err != nil {
+		return false, fmt.Errorf("failed to check kill link '%s': %w", id, unavailable(err))
+	}
+
+	return used, nil
+}
+
+// ConsumeKillLink сохраняет событие использования ссылки "Это был не я" в таблицу kill_link_events.
+// Возвращает false, если ссылка с таким идентификатором уже использована.
+func (d *Database) ConsumeKillLink(ctx context.Context, event *entities.KillLinkEvent) (bool, error) {
+	ctx, cancel := d.operationContext(ctx)
+	defer cancel()
+
+	query := `
+	INSERT INTO kill_link_events (id, user_id, family_id, scope, ip, user_agent, used_at, expired_at)
+	VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+	ON CONFLICT (id) DO NOTHING
+	`
+
+	result, err := d.db.ExecContext(ctx, query, event.Id, event.UserId, event.FamilyId, event.Scope, event.Ip, event.UserAgent,
+		event.UsedAt.UTC(), event.ExpiredAt.UTC())
+	if err != nil {
+		return false, fmt.Errorf("failed to insert row into 'kill_link_events' for id: '%s': %w", event.Id, unavailable(err))
+	}
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return false, fmt.Errorf("failed to get rows affected: %w", unavailable(err))
+	}
+
+	return rowsAffected == 1, nil
+}
+
+// CreateUser добавляет пользователя в таблицу users.
+// Возвращает ErrUserExists, если пользователь с таким идентификатором или email (без учета регистра) уже существует.
+func (d *Database) CreateUser(ctx context.Context, user *entities.User) error {
+	ctx, cancel := d.operationContext(ctx)
+	defer cancel()
+
+	query := `
+	INSERT INTO users (id, email, email_verified, status, locale, created_at)
+	VALUES ($1, $2, $3, $4, $5, $6)
+	`
+
+	_, err := d.db.ExecContext(ctx, query, user.Id, user.Email, user.EmailVerified, user.Status, user.Locale, user.CreatedAt.UTC())
+	if isUniqueViolation(err) {
+		return fmt.Errorf("user '%s' with email '%s': %w", user.Id, user.Email, storage.ErrUserExists)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to insert row into 'users' for id: '%s': %w", user.Id, unavailable(err))
+	}
+
+	return nil
+}
+
+// GetUser возвращает пользователя из таблицы users по идентификатору.
+// Если пользователь не найден, возвращает ErrUserNotFound.
+func (d *Database) GetUser(ctx context.Context, userId string) (*entities.User, error) {
+	ctx, cancel := d.operationContext(ctx)
+	defer cancel()
+
+	var user entities.User
+	query := "SELECT id, email, email_verified, status, locale, created_at FROM users WHERE id = $1"
+
+	err := d.db.GetContext(ctx, &user, query, userId)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, fmt.Errorf("user '%s': %w", userId, storage.ErrUserNotFound)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get user '%s': %w", userId, unavailable(err))
+	}
+
+	return &user, nil
+}
+
+// GetUserByEmail возвращает пользователя из таблицы users по email без учета регистра.
+// Если пользователь не найден, возвращает ErrUserNotFound.
+func (d *Database) GetUserByEmail(ctx context.Context, email string) (*entities.User, error) {
+	ctx, cancel := d.operationContext(ctx)
+	defer cancel()
+
+	var user entities.User
+	query := "SELECT id, email, email_verified, status, locale, created_at FROM users WHERE LOWER(email) = LOWER($1)"
+
+	err := d.db.GetContext(ctx, &user, query, email)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, fmt.Errorf("user with email '%s': %w", email, storage.ErrUserNotFound)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get user by email '%s': %w", email, unavailable(err))
+	}
+
+	return &user, nil
+}
+
+// SavePasswordHash сохраняет хэш пароля пользователя в таблицу user_credentials, заменяя предыдущий.
+// Если пользователь не найден, возвращает ErrUserNotFound.
+func (d *Database) SavePasswordHash(ctx context.Context, userId, passwordHash string) error {
+	ctx, cancel := d.operationContext(ctx)
+	defer cancel()
+
+	query := `
+	INSERT INTO user_credentials (user_id, password_hash, updated_at)
+	VALUES ($1, $2, $3)
+	ON CONFLICT (user_id) DO UPDATE SET password_hash = EXCLUDED.password_hash, updated_at = EXCLUDED.updated_at
+	`
+
+	_, err := d.db.ExecContext(ctx, query, userId, passwordHash, time.Now().UTC())
+	if isForeignKeyViolation(err) {
+		return fmt.Errorf("user '%s': %w", userId, storage.ErrUserNotFound)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to save password hash for user '%s': %w", userId, unavailable(err))
+	}
+
+	return nil
+}
+
+// GetPasswordHash возвращает хэш пароля пользователя из таблицы user_credentials.
+// Если пароль не задан, возвращает ErrCredentialsNotFound.
+func (d *Database) GetPasswordHash(ctx context.Context, userId string) (string, error) {
+	ctx, cancel := d.operationContext(ctx)
+	defer cancel()
+
+	var passwordHash string
+
+	err := d.db.GetContext(ctx, &passwordHash, "SELECT password_hash FROM user_credentials WHERE user_id = $1", userId)
+	if errors.Is(err, sql.ErrNoRows) {
+		return "", fmt.Errorf("user '%s': %w", userId, storage.ErrCredentialsNotFound)
+	}
+	if err != nil {
+		return "", fmt.Errorf("failed to get password hash for user '%s': %w", userId, unavailable(err))
+	}
+
+	return passwordHash, nil
+}
+
+// UpdateUser обновляет email, подтверждение email, статус и язык пользователя в таблице users.
+// Возвращает ErrUserNotFound, если пользователь не найден, и ErrUserExists, если email занят другим пользователем.
+func (d *Database) UpdateUser(ctx context.Context, user *entities.User) error {
+	ctx, cancel := d.operationContext(ctx)
+	defer cancel()
+
+	query := `
+	UPDATE users 
+	SET email = $2, email_verified = $3, status = $4, locale = $5
+	WHERE id = $1
+	`
+
+	result, err := d.db.ExecContext(ctx, query, user.Id, user.Email, user.EmailVerified, user.Status, user.Locale)
+	if isUniqueViolation(err) {
+		return fmt.Errorf("email '%s': %w", user.Email, storage.ErrUserExists)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to update user '%s': %w", user.Id, unavailable(err))
+	}
+
+	return checkUserAffected(result, user.Id)
+}
+
+// DeleteUser удаляет пользователя из таблицы users вместе с хэшем его пароля, одноразовыми токенами и вторым фактором.
+// Если пользователь не найден, возвращает ErrUserNotFound.
+func (d *Database) DeleteUser(ctx context.Context, userId string) error {
+	ctx, cancel := d.operationContext(ctx)
+	defer cancel()
 
-	return mockEmail, nil
+	result, err := d.db.ExecContext(ctx, "DELETE FROM users WHERE id = $1", userId)
+	if err != nil {
+		return fmt.Errorf("failed to delete user '%s': %w", userId, unavailable(err))
+	}
+
+	return checkUserAffected(result, userId)
 }
 
-// checkActiveTokens проверяет количество активных refresh токенов для пользователя.
-// Если лимит превышен, возвращает специальную ошибку.
-func (d *Database) checkActiveTokens(userId string, maxTokensPerUser int) error {
-	var countOfSessions int
+// SaveOneTimeToken сохраняет одноразовый токен пользователя в таблицу one_time_tokens.
+// Попутно удаляет из таблицы токены, срок действия которых истек.
+func (d *Database) SaveOneTimeToken(ctx context.Context, token *entities.OneTimeToken) error {
+	ctx, cancel := d.operationContext(ctx)
+	defer cancel()
+
+	tx, err := d.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction for user '%s': %w", token.UserId, unavailable(err))
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.ExecContext(ctx, "DELETE FROM one_time_tokens WHERE expired_at <= $1", time.Now().UTC()); err != nil {
+		return fmt.Errorf("failed to delete expired rows from 'one_time_tokens': %w", unavailable(err))
+	}
+
 	query := `
-	SELECT COUNT(*) 
-	FROM refresh_tokens
+	INSERT INTO one_time_tokens (token_hash, user_id, purpose, expired_at, created_at)
+	VALUES ($1, $2, $3, $4, $5)
+	`
+
+	_, err = tx.ExecContext(ctx, query, token.TokenHash, token.UserId, token.Purpose, token.ExpiredAt.UTC(), token.CreatedAt.UTC())
+	if isForeignKeyViolation(err) {
+		return fmt.Errorf("user '%s': %w", token.UserId, storage.ErrUserNotFound)
+	}
+	if isUniqueViolation(err) {
+		return fmt.Errorf("one-time token: %w", storage.ErrTokenExists)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to insert row into 'one_time_tokens' for user '%s': %w", token.UserId, unavailable(err))
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit transaction for user '%s': %w", token.UserId, unavailable(err))
+	}
+
+	return nil
+}
+
+// ConsumeOneTimeToken удаляет одноразовый токен с указанным назначением из таблицы one_time_tokens и возвращает его.
+// Удаление выполняется одним запросом, поэтому токен может быть использован только один раз.
+// Если токен не найден, возвращает ErrTokenNotFound, если его срок действия истек к моменту now - ErrTokenExpired.
+func (d *Database) ConsumeOneTimeToken(ctx context.Context, purpose, tokenHash string, now time.Time) (*entities.OneTimeToken, error) {
+	ctx, cancel := d.operationContext(ctx)
+	defer cancel()
+
+	var token entities.OneTimeToken
+	query := `
+	DELETE FROM one_time_tokens
+	WHERE token_hash = $1 AND purpose = $2
+	RETURNING token_hash, user_id, purpose, expired_at, created_at
+	`
+
+	err := d.db.GetContext(ctx, &token, query, tokenHash, purpose)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, fmt.Errorf("purpose '%s': %w", purpose, storage.ErrTokenNotFound)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to delete row from 'one_time_tokens': %w", unavailable(err))
+	}
+	if !token.ExpiredAt.After(now.UTC()) {
+		return nil, fmt.Errorf("purpose '%s': %w", purpose, storage.ErrTokenExpired)
+	}
+
+	return &token, nil
+}
+
+// DeleteOneTimeTokens удаляет из таблицы one_time_tokens все токены пользователя с указанным назначением.
+func (d *Database) DeleteOneTimeTokens(ctx context.Context, userId, purpose string) error {
+	ctx, cancel := d.operationContext(ctx)
+	defer cancel()
+
+	query := "DELETE FROM one_time_tokens WHERE user_id = $1 AND purpose = $2"
+	if _, err := d.db.ExecContext(ctx, query, userId, purpose); err != nil {
+		return fmt.Errorf("failed to delete rows from 'one_time_tokens' for user '%s': %w", userId, unavailable(err))
+	}
+
+	return nil
+}
+
+// SaveTotpCredential сохраняет TOTP-секрет пользователя в таблицу totp_credentials, заменяя предыдущий.
+// Если пользователь не найден, возвращает ErrUserNotFound.
+func (d *Database) SaveTotpCredential(ctx context.Context, credential *entities.TotpCredential) error {
+	ctx, cancel := d.operationContext(ctx)
+	defer cancel()
+
+	query := `
+	INSERT INTO totp_credentials (user_id, encrypted_secret, confirmed, last_used_step, created_at)
+	VALUES ($1, $2, $3, $4, $5)
+	ON CONFLICT (user_id) DO UPDATE SET encrypted_secret = EXCLUDED.encrypted_secret, confirmed = EXCLUDED.confirmed,
+	last_used_step = EXCLUDED.last_used_step, created_at = EXCLUDED.created_at
+	`
+
+	_, err := d.db.ExecContext(ctx, query, credential.UserId, credential.EncryptedSecret, credential.Confirmed, credential.LastUsedStep,
+		credential.CreatedAt.UTC())
+	if isForeignKeyViolation(err) {
+		return fmt.Errorf("user '%s': %w", credential.UserId, storage.ErrUserNotFound)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to save totp credential for user '%s': %w", credential.UserId, unavailable(err))
+	}
+
+	return nil
+}
+
+// GetTotpCredential возвращает TOTP-секрет пользователя из таблицы totp_credentials.
+// Если TOTP не подключен, возвращает ErrCredentialsNotFound.
+func (d *Database) GetTotpCredential(ctx context.Context, userId string) (*entities.TotpCredential, error) {
+	ctx, cancel := d.operationContext(ctx)
+	defer cancel()
+
+	var credential entities.TotpCredential
+	query := `
+	SELECT user_id, encrypted_secret, confirmed, last_used_step, created_at
+	FROM totp_credentials
 	WHERE user_id = $1
 	`
 
-	if err := d.db.Get(&countOfSessions, query, userId); err != nil {
-		return fmt.Errorf("failed to select count of active sessions from 'refresh_tokens' for userID: %s : %w", userId, err)
+	err := d.db.GetContext(ctx, &credential, query, userId)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, fmt.Errorf("user '%s': %w", userId, storage.ErrCredentialsNotFound)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to select row from 'totp_credentials' for user '%s': %w", userId, unavailable(err))
+	}
+
+	return &credential, nil
+}
+
+// UseTotpStep запоминает принятый временной шаг TOTP пользователя.
+// Шаг обновляется одним условным запросом, поэтому один код не может быть принят дважды даже при параллельных запросах.
+// Возвращает false, если шаг не новее последнего принятого.
+func (d *Database) UseTotpStep(ctx context.Context, userId string, step int64) (bool, error) {
+	ctx, cancel := d.operationContext(ctx)
+	defer cancel()
+
+	query := "UPDATE totp_credentials SET last_used_step = $2 WHERE user_id = $1 AND last_used_step < $2"
+
+	result, err := d.db.ExecContext(ctx, query, userId, step)
+	if err != nil {
+		return false, fmt.Errorf("failed to update row from 'totp_credentials' for user '%s': %w", userId, unavailable(err))
+	}
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return false, fmt.Errorf("failed to get rows affected for user '%s': %w", userId, unavailable(err))
 	}
-	if countOfSessions == maxTokensPerUser {
-		return fmt.Errorf("exceeding the limit for userID: '%s'", userId)
+
+	return rowsAffected == 1, nil
+}
+
+// SaveRecoveryCodes заменяет хэши кодов восстановления пользователя в таблице recovery_codes.
+// Если пользователь не найден, возвращает ErrUserNotFound.
+func (d *Database) SaveRecoveryCodes(ctx context.Context, userId string, codeHashes []string) error {
+	ctx, cancel := d.operationContext(ctx)
+	defer cancel()
+
+	tx, err := d.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction for user '%s': %w", userId, unavailable(err))
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.ExecContext(ctx, "DELETE FROM recovery_codes WHERE user_id = $1", userId); err != nil {
+		return fmt.Errorf("failed to delete rows from 'recovery_codes' for user '%s': %w", userId, unavailable(err))
+	}
+	for _, codeHash := range codeHashes {
+		_, err := tx.ExecContext(ctx, "INSERT INTO recovery_codes (user_id, code_hash) VALUES ($1, $2)", userId, codeHash)
+		if isForeignKeyViolation(err) {
+			return fmt.Errorf("user '%s': %w", userId, storage.ErrUserNotFound)
+		}
+		if err != nil {
+			return fmt.Errorf("failed to insert row into 'recovery_codes' for user '%s': %w", userId, unavailable(err))
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit transaction for user '%s': %w", userId, unavailable(err))
+	}
+
+	return nil
+}
+
+// ConsumeRecoveryCode удаляет код восстановления пользователя из таблицы recovery_codes.
+// Возвращает false, если код не найден или уже был использован.
+func (d *Database) ConsumeRecoveryCode(ctx context.Context, userId, codeHash string) (bool, error) {
+	ctx, cancel := d.operationContext(ctx)
+	defer cancel()
+
+	result, err := d.db.ExecContext(ctx, "DELETE FROM recovery_codes WHERE user_id = $1 AND code_hash = $2", userId, codeHash)
+	if err != nil {
+		return false, fmt.Errorf("failed to delete row from 'recovery_codes' for user '%s': %w", userId, unavailable(err))
+	}
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return false, fmt.Errorf("failed to get rows affected for user '%s': %w", userId, unavailable(err))
+	}
+
+	return rowsAffected == 1, nil
+}
+
+// checkUserAffected возвращает ErrUserNotFound, если запрос не изменил ни одной строки таблицы users.
+func checkUserAffected(result sql.Result, userId string) error {
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to get rows affected: %w", unavailable(err))
+	}
+	if rowsAffected == 0 {
+		return fmt.Errorf("user '%s': %w", userId, storage.ErrUserNotFound)
+	}
+
+	return nil
+}
+
+// unavailable помечает ошибку обращения к PostgreSQL как ErrStorageUnavailable, сохраняя исходную ошибку.
+// Ошибки отмены контекста остаются доступными через errors.Is.
+func unavailable(err error) error {
+	return fmt.Errorf("%w: %w", storage.ErrStorageUnavailable, err)
+}
+
+// isUniqueViolation проверяет, что ошибка вызвана нарушением ограничения уникальности.
+func isUniqueViolation(err error) bool {
+	var pgErr *pgconn.PgError
+
+	return errors.As(err, &pgErr) && pgErr.Code == uniqueViolationCode
+}
+
+// isForeignKeyViolation проверяет, что ошибка вызвана нарушением ограничения внешнего ключа.
+func isForeignKeyViolation(err error) bool {
+	var pgErr *pgconn.PgError
+
+	return errors.As(err, &pgErr) && pgErr.Code == foreignKeyViolationCode
+}
+
+// PurgeExpiredRefreshTokens удаляет refresh-токены, срок действия которых истек к now.
+// Токены удаляются пачками по batchSize в отдельных запросах, чтобы не держать долгих блокировок таблицы;
+// время выполнения каждого запроса ограничено DB_QUERY_TIMEOUT, а отмена ctx прекращает очистку.
+// Если очистку выполняет другая реплика, возвращает ErrPurgeInProgress.
+// Возвращает количество удаленных токенов.
+func (d *Database) PurgeExpiredRefreshTokens(ctx context.Context, now time.Time, batchSize int) (int64, error) {
+	if batchSize <= 0 {
+		return 0, fmt.Errorf("batch size must be positive")
+	}
+
+	lockCtx, cancel := d.operationContext(ctx)
+	defer cancel()
+
+	conn, err := d.db.Connx(lockCtx)
+	if err != nil {
+		return 0, fmt.Errorf("failed to get database connection: %w", unavailable(err))
+	}
+	defer conn.Close()
+
+	var locked bool
+	if err := conn.GetContext(lockCtx, &locked, "SELECT pg_try_advisory_lock($1)", purgeLockId); err != nil {
+		return 0, fmt.Errorf("failed to acquire purge lock: %w", unavailable(err))
+	}
+	if !locked {
+		return 0, storage.ErrPurgeInProgress
+	}
+	defer func() {
+		// Блокировка освобождается и после отмены ctx, иначе она останется на соединении в пуле.
+		unlockCtx, cancel := d.operationContext(context.WithoutCancel(ctx))
+		defer cancel()
+		if _, err := conn.ExecContext(unlockCtx, "SELECT pg_advisory_unlock($1)", purgeLockId); err != nil {
+			log.Printf("failed to release purge lock: %v\n", err)
+		}
+	}()
+
+	query := `
+	DELETE FROM refresh_tokens
+	WHERE jti IN (
+		SELECT jti FROM refresh_tokens
+		WHERE expired_at <= $1
+		LIMIT $2
+	)
+	`
+
+	var purged int64
+	for {
+		batchCtx, cancel := d.operationContext(ctx)
+		result, err := conn.ExecContext(batchCtx, query, now.UTC(), batchSize)
+		cancel()
+		if err != nil {
+			return purged, fmt.Errorf("failed to delete expired rows from 'refresh_tokens': %w", unavailable(err))
+		}
+
+		rowsAffected, err := result.RowsAffected()
+		if err != nil {
+			return purged, fmt.Errorf("failed to get rows affected: %w", unavailable(err))
+		}
+		purged += rowsAffected
+		if rowsAffected < int64(batchSize) {
+			return purged, nil
+		}
+	}
+}
+
+// PurgeKillLinkEvents удаляет из kill_link_events события использования ссылок "Это был не я",
+// срок действия которых истек до before. События удаляются пачками по batchSize в отдельных запросах.
+// Возвращает количество удаленных событий.
+func (d *Database) PurgeKillLinkEvents(ctx context.Context, before time.Time, batchSize int) (int64, error) {
+	if batchSize <= 0 {
+		return 0, fmt.Errorf("batch size must be positive")
+	}
+
+	query := `
+	DELETE FROM kill_link_events
+	WHERE id IN (
+		SELECT id FROM kill_link_events
+		WHERE expired_at < $1
+		LIMIT $2
+	)
+	`
+
+	var purged int64
+	for {
+		batchCtx, cancel := d.operationContext(ctx)
+		result, err := d.db.ExecContext(batchCtx, query, before.UTC(), batchSize)
+		cancel()
+		if err != nil {
+			return purged, fmt.Errorf("failed to delete expired rows from 'kill_link_events': %w", unavailable(err))
+		}
+
+		rowsAffected, err := result.RowsAffected()
+		if err != nil {
+			return purged, fmt.Errorf("failed to get rows affected: %w", unavailable(err))
+		}
+		purged += rowsAffected
+		if rowsAffected < int64(batchSize) {
+			return purged, nil
+		}
+	}
+}
+
+// PurgeOutboxMessages удаляет из notification_outbox доставленные (delivered) и недоставленные (dead) сообщения,
+// созданные до before. Сообщения удаляются пачками по batchSize в отдельных запросах; ожидающие доставки не удаляются.
+// Возвращает количество удаленных сообщений.
+func (d *Database) PurgeOutboxMessages(ctx context.Context, before time.Time, batchSize int) (int64, error) {
+	if batchSize <= 0 {
+		return 0, fmt.Errorf("batch size must be positive")
+	}
+
+	query := `
+	DELETE FROM notification_outbox
+	WHERE id IN (
+		SELECT id FROM notification_outbox
+		WHERE status <> $1 AND created_at < $2
+		LIMIT $3
+	)
+	`
+
+	var purged int64
+	for {
+		batchCtx, cancel := d.operationContext(ctx)
+		result, err := d.db.ExecContext(batchCtx, query, entities.OutboxStatusPending, before.UTC(), batchSize)
+		cancel()
+		if err != nil {
+			return purged, fmt.Errorf("failed to delete finished rows from 'notification_outbox': %w", unavailable(err))
+		}
+
+		rowsAffected, err := result.RowsAffected()
+		if err != nil {
+			return purged, fmt.Errorf("failed to get rows affected: %w", unavailable(err))
+		}
+		purged += rowsAffected
+		if rowsAffected < int64(batchSize) {
+			return purged, nil
+		}
+	}
+}
+
+// lockUserSessions берет транзакционную advisory lock refresh-токенов пользователя.
+// Блокировка освобождается при завершении транзакции.
+func lockUserSessions(ctx context.Context, tx *sqlx.Tx, userId string) error {
+	if _, err := tx.ExecContext(ctx, "SELECT pg_advisory_xact_lock($1, hashtext($2))", userSessionsLockNamespace, userId); err != nil {
+		return fmt.Errorf("failed to lock refresh tokens for userID: '%s': %w", userId, unavailable(err))
 	}
 
 	return nil
 }
 
-// deleteOldestRefreshToken удаляет самый старый refresh-токен пользователя.
-// Если количество токенов не равно лимиту, возвращает ошибку.
-func (d *Database) deleteOldestRefreshToken(userId string) error {
+// evictOldestRefreshTokens удаляет самые старые активные refresh-токены пользователя, оставляя keep самых новых.
+// Возвращает количество удаленных токенов.
+func evictOldestRefreshTokens(ctx context.Context, tx *sqlx.Tx, userId string, keep int) (int64, error) {
 	query := `
 	DELETE FROM refresh_tokens
-	WHERE jti = (
+	WHERE jti IN (
 		SELECT jti FROM refresh_tokens
-		WHERE user_id = $1
-		ORDER BY created_at ASC
-		LIMIT 1
+		WHERE user_id = $1 AND NOT rotated
+		ORDER BY created_at DESC, jti DESC
+		OFFSET $2
 	)
 	`
 
-	result, err := d.db.Exec(query, userId)
+	result, err := tx.ExecContext(ctx, query, userId, keep)
+	if err != nil {
+		return 0, fmt.Errorf("failed to delete oldest rows from 'refresh_tokens' for userID: '%s': %w", userId, unavailable(err))
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return 0, fmt.Errorf("failed to get rows affected for userID: '%s': %w", userId, unavailable(err))
+	}
+
+	return rowsAffected, nil
+}
+
+// insertRefreshTokenArgs возвращает аргументы запроса insertRefreshTokenQuery.
+// Время приводится к UTC: столбцы refresh_tokens имеют тип TIMESTAMP, и при записи часовой пояс отбрасывается.
+func insertRefreshTokenArgs(userId string, record *entities.RefreshTokenRecord) []any {
+	return []any{record.Jti, userId, record.CreatedAt.UTC(), record.ExpiredAt.UTC(), record.SessionStartedAt.UTC(), record.FamilyId,
+		record.IssuedIp, record.UserAgent, record.DeviceName, record.LastUsedAt.UTC(), record.TokenHash, record.Amr}
+}
+
+// updateOutboxMessage выполняет запрос на изменение сообщения outbox.
+// Если сообщение не найдено, возвращает ErrOutboxMessageNotFound.
+func (d *Database) updateOutboxMessage(ctx context.Context, id, query string, args ...any) error {
+	result, err := d.db.ExecContext(ctx, query, args...)
 	if err != nil {
-		return fmt.Errorf("failed to delete row from 'refresh_tokens' for userID: '%s': %w", userId, err)
+		return fmt.Errorf("failed to update row from 'notification_outbox' for id: '%s': %w", id, unavailable(err))
 	}
 
 	rowsAffected, err := result.RowsAffected()
 	if err != nil {
-		return fmt.Errorf("failed to get rows affected for userID: '%s': %w", userId, err)
+		return fmt.Errorf("failed to get rows affected for id: '%s': %w", id, unavailable(err))
 	}
 	if rowsAffected == 0 {
-		return fmt.Errorf("no rows updated")
+		return fmt.Errorf("outbox message '%s': %w", id, storage.ErrOutboxMessageNotFound)
 	}
 
 	return nil
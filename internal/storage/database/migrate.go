@@ -0,0 +1,296 @@
+package database
+
+import (
+	"context"
+	"embed"
+	"fmt"
+	"io/fs"
+	"log"
+	"path"
+	"regexp"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+)
+
+// migrationsLockId - ключ advisory lock, под которым применяются миграции.
+// Пока одна реплика сервиса применяет миграции, остальные ожидают освобождения блокировки.
+const migrationsLockId int64 = 4_210_677_301
+
+// createMigrationsTableQuery - запрос на создание таблицы с примененными версиями схемы.
+const createMigrationsTableQuery = `
+	CREATE TABLE IF NOT EXISTS schema_migrations (
+	version BIGINT PRIMARY KEY,
+	name TEXT NOT NULL,
+	applied_at TIMESTAMP NOT NULL
+	)
+	`
+
+// migrationFiles содержит SQL-файлы миграций, встроенные в бинарный файл сервиса.
+// Имя файла имеет вид <версия>_<название>.up.sql или <версия>_<название>.down.sql.
+//
+//go:embed migrations/*.sql
+var migrationFiles embed.FS
+
+// migrationFileName - шаблон имени файла миграции.
+var migrationFileName = regexp.MustCompile(`^(\d+)_([a-z0-9_]+)\.(up|down)\.sql$`)
+
+// Migration описывает одну версию схемы базы данных.
+type Migration struct {
+	Version int64  // Version - номер версии, определяющий порядок применения миграций
+	Name    string // Name - название миграции
+	Up      string // Up - SQL для перехода на эту версию
+	Down    string // Down - SQL для отката этой версии
+}
+
+// MigrationStatus описывает состояние версии схемы в базе данных.
+type MigrationStatus struct {
+	Version   int64      // Version - номер версии
+	Name      string     // Name - название миграции (пустое, если версия неизвестна этой сборке сервиса)
+	AppliedAt *time.Time // AppliedAt - время применения миграции (nil, если миграция не применена)
+}
+
+// loadMigrations читает миграции из fsys и возвращает их в порядке возрастания версий.
+// Возвращает ошибку, если у версии нет одного из файлов up/down или номер версии повторяется.
+func loadMigrations(fsys fs.FS) ([]Migration, error) {
+	entries, err := fs.ReadDir(fsys, "migrations")
+	if err != nil {
+		return nil, fmt.Errorf("failed to read migrations: %w", err)
+	}
+
+	migrations := make(map[int64]*Migration)
+	for _, entry := range entries {
+		match := migrationFileName.FindStringSubmatch(entry.Name())
+		if match == nil {
+			return nil, fmt.Errorf("invalid migration file name '%s'", entry.Name())
+		}
+
+		version, err := strconv.ParseInt(match[1], 10, 64)
+		if err != nil || version <= 0 {
+			return nil, fmt.Errorf("invalid migration version in '%s'", entry.Name())
+		}
+
+		content, err := fs.ReadFile(fsys, path.Join("migrations", entry.Name()))
+		if err != nil {
+			return nil, fmt.Errorf("failed to read migration '%s': %w", entry.Name(), err)
+		}
+
+		migration, ok := migrations[version]
+		if !ok {
+			migration = &Migration{Version: version, Name: match[2]}
+			migrations[version] = migration
+		}
+		if migration.Name != match[2] {
+			return nil, fmt.Errorf("duplicate migration version %d: '%s' and '%s'", version, migration.Name, match[2])
+		}
+
+		if match[3] == "up" {
+			migration.Up = string(content)
+		} else {
+			migration.Down = string(content)
+		}
+	}
+
+	result := make([]Migration, 0, len(migrations))
+	for _, migration := range migrations {
+		if migration.Up == "" || migration.Down == "" {
+			return nil, fmt.Errorf("migration %d_%s must have both up and down files", migration.Version, migration.Name)
+		}
+		result = append(result, *migration)
+	}
+	sort.Slice(result, func(i, j int) bool { return result[i].Version < result[j].Version })
+
+	return result, nil
+}
+
+// MigrateUp применяет все непримененные миграции в порядке возрастания версий.
+// Каждая миграция выполняется в отдельной транзакции вместе с записью в schema_migrations.
+// Возвращает количество примененных миграций.
+func MigrateUp(ctx context.Context, db *sqlx.DB) (int, error) {
+	migrations, err := loadMigrations(migrationFiles)
+	if err != nil {
+		return 0, err
+	}
+
+	applied := 0
+	err = withMigrationsLock(ctx, db, func(conn *sqlx.Conn) error {
+		appliedVersions, err := getAppliedVersions(ctx, conn)
+		if err != nil {
+			return err
+		}
+
+		for _, migration := range migrations {
+			if _, ok := appliedVersions[migration.Version]; ok {
+				continue
+			}
+
+			if err := applyMigration(ctx, conn, migration.Version, migration.Up, func(tx *sqlx.Tx) error {
+				_, err := tx.ExecContext(ctx, "INSERT INTO schema_migrations (version, name, applied_at) VALUES ($1, $2, $3)",
+					migration.Version, migration.Name, time.Now().UTC())
+				return err
+			}); err != nil {
+				return err
+			}
+			log.Printf("Applied migration %d_%s\n", migration.Version, migration.Name)
+			applied++
+		}
+
+		return nil
+	})
+
+	return applied, err
+}
+
+// MigrateDown откатывает steps последних примененных миграций в порядке убывания версий.
+// Возвращает количество откаченных миграций или ошибку, если примененная версия неизвестна этой сборке сервиса.
+func MigrateDown(ctx context.Context, db *sqlx.DB, steps int) (int, error) {
+	if steps <= 0 {
+		return 0, fmt.Errorf("number of migrations to roll back must be positive")
+	}
+
+	migrations, err := loadMigrations(migrationFiles)
+	if err != nil {
+		return 0, err
+	}
+
+	known := make(map[int64]Migration, len(migrations))
+	for _, migration := range migrations {
+		known[migration.Version] = migration
+	}
+
+	rolledBack := 0
+	err = withMigrationsLock(ctx, db, func(conn *sqlx.Conn) error {
+		appliedVersions, err := getAppliedVersions(ctx, conn)
+		if err != nil {
+			return err
+		}
+
+		versions := make([]int64, 0, len(appliedVersions))
+		for version := range appliedVersions {
+			versions = append(versions, version)
+		}
+		sort.Slice(versions, func(i, j int) bool { return versions[i] > versions[j] })
+
+		for _, version := range versions[:min(steps, len(versions))] {
+			migration, ok := known[version]
+			if !ok {
+				return fmt.Errorf("migration %d is applied but unknown to this build", version)
+			}
+
+			if err := applyMigration(ctx, conn, migration.Version, migration.Down, func(tx *sqlx.Tx) error {
+				_, err := tx.ExecContext(ctx, "DELETE FROM schema_migrations WHERE version = $1", migration.Version)
+				return err
+			}); err != nil {
+				return err
+			}
+			log.Printf("Rolled back migration %d_%s\n", migration.Version, migration.Name)
+			rolledBack++
+		}
+
+		return nil
+	})
+
+	return rolledBack, err
+}
+
+// GetMigrationStatus возвращает состояние всех известных миграций, а также примененных версий,
+// которых нет в этой сборке сервиса, в порядке возрастания версий.
+func GetMigrationStatus(ctx context.Context, db *sqlx.DB) ([]MigrationStatus, error) {
+	migrations, err := loadMigrations(migrationFiles)
+	if err != nil {
+		return nil, err
+	}
+
+	var statuses []MigrationStatus
+	err = withMigrationsLock(ctx, db, func(conn *sqlx.Conn) error {
+		appliedVersions, err := getAppliedVersions(ctx, conn)
+		if err != nil {
+			return err
+		}
+
+		for _, migration := range migrations {
+			status := MigrationStatus{Version: migration.Version, Name: migration.Name}
+			if appliedAt, ok := appliedVersions[migration.Version]; ok {
+				status.AppliedAt = &appliedAt
+				delete(appliedVersions, migration.Version)
+			}
+			statuses = append(statuses, status)
+		}
+		for version, appliedAt := range appliedVersions {
+			statuses = append(statuses, MigrationStatus{Version: version, AppliedAt: &appliedAt})
+		}
+
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	sort.Slice(statuses, func(i, j int) bool { return statuses[i].Version < statuses[j].Version })
+
+	return statuses, nil
+}
+
+// withMigrationsLock выполняет fn на отдельном соединении под advisory lock миграций.
+// Таблица schema_migrations создается под блокировкой, чтобы реплики не создавали ее одновременно.
+// Отмена ctx прерывает ожидание блокировки и выполнение миграций.
+func withMigrationsLock(ctx context.Context, db *sqlx.DB, fn func(conn *sqlx.Conn) error) error {
+	conn, err := db.Connx(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to get database connection: %w", err)
+	}
+	defer conn.Close()
+
+	if _, err := conn.ExecContext(ctx, "SELECT pg_advisory_lock($1)", migrationsLockId); err != nil {
+		return fmt.Errorf("failed to acquire migrations lock: %w", err)
+	}
+	defer func() {
+		// Блокировка освобождается и после отмены ctx, иначе она останется на соединении в пуле.
+		if _, err := conn.ExecContext(context.WithoutCancel(ctx), "SELECT pg_advisory_unlock($1)", migrationsLockId); err != nil {
+			log.Printf("failed to release migrations lock: %v\n", err)
+		}
+	}()
+
+	if _, err := conn.ExecContext(ctx, createMigrationsTableQuery); err != nil {
+		return fmt.Errorf("failed to create table schema_migrations: %w", err)
+	}
+
+	return fn(conn)
+}
+
+// getAppliedVersions возвращает примененные версии схемы и время их применения.
+func getAppliedVersions(ctx context.Context, conn *sqlx.Conn) (map[int64]time.Time, error) {
+	var rows []struct {
+		Version   int64     `db:"version"`
+		AppliedAt time.Time `db:"applied_at"`
+	}
+	if err := conn.SelectContext(ctx, &rows, "SELECT version, applied_at FROM schema_migrations"); err != nil {
+		return nil, fmt.Errorf("failed to get applied migrations: %w", err)
+	}
+
+	appliedVersions := make(map[int64]time.Time, len(rows))
+	for _, row := range rows {
+		appliedVersions[row.Version] = row.AppliedAt
+	}
+
+	return appliedVersions, nil
+}
+
+// applyMigration выполняет SQL миграции и record (изменение schema_migrations) в одной транзакции.
+func applyMigration(ctx context.Context, conn *sqlx.Conn, version int64, query string, record func(tx *sqlx.Tx) error) error {
+	tx, err := conn.BeginTxx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.ExecContext(ctx, query); err != nil {
+		return fmt.Errorf("failed to execute migration %d: %w", version, err)
+	}
+	if err := record(tx); err != nil {
+		return fmt.Errorf("failed to record migration %d: %w", version, err)
+	}
+
+	return tx.Commit()
+}
@@ -0,0 +1,23 @@
+CREATE TABLE IF NOT EXISTS refresh_tokens (
+jti TEXT PRIMARY KEY,
+user_id TEXT NOT NULL,
+created_at TIMESTAMP NOT NULL,
+expired_at  TIMESTAMP NOT NULL,
+issued_ip TEXT NOT NULL,
+token_hash TEXT UNIQUE NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS user_id__hash_indx ON refresh_tokens USING hash (user_id);
+CREATE INDEX IF NOT EXISTS token__hash_indx ON refresh_tokens USING hash (token_hash);
+
+ALTER TABLE refresh_tokens ADD COLUMN IF NOT EXISTS session_started_at TIMESTAMP NOT NULL DEFAULT NOW();
+
+ALTER TABLE refresh_tokens ADD COLUMN IF NOT EXISTS family_id TEXT;
+UPDATE refresh_tokens SET family_id = jti WHERE family_id IS NULL;
+ALTER TABLE refresh_tokens ALTER COLUMN family_id SET NOT NULL;
+ALTER TABLE refresh_tokens ADD COLUMN IF NOT EXISTS rotated BOOLEAN NOT NULL DEFAULT FALSE;
+CREATE INDEX IF NOT EXISTS family_id__hash_indx ON refresh_tokens USING hash (family_id);
+
+ALTER TABLE refresh_tokens ADD COLUMN IF NOT EXISTS user_agent TEXT NOT NULL DEFAULT '';
+ALTER TABLE refresh_tokens ADD COLUMN IF NOT EXISTS device_name TEXT NOT NULL DEFAULT '';
+ALTER TABLE refresh_tokens ADD COLUMN IF NOT EXISTS last_used_at TIMESTAMP NOT NULL DEFAULT NOW();
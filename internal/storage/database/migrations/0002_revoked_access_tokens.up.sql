@@ -0,0 +1,6 @@
+CREATE TABLE IF NOT EXISTS revoked_access_tokens (
+jti TEXT PRIMARY KEY,
+expired_at TIMESTAMP NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS expired_at__btree_indx ON revoked_access_tokens (expired_at);
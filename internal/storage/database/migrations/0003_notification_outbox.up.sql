@@ -0,0 +1,11 @@
+CREATE TABLE IF NOT EXISTS notification_outbox (
+id TEXT PRIMARY KEY,
+payload JSONB NOT NULL,
+status TEXT NOT NULL DEFAULT 'pending',
+attempts INTEGER NOT NULL DEFAULT 0,
+next_attempt_at TIMESTAMP NOT NULL,
+last_error TEXT NOT NULL DEFAULT '',
+created_at TIMESTAMP NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS outbox_pending__btree_indx ON notification_outbox (next_attempt_at) WHERE status = 'pending';
@@ -0,0 +1,10 @@
+CREATE TABLE IF NOT EXISTS kill_link_events (
+id TEXT PRIMARY KEY,
+user_id TEXT NOT NULL,
+family_id TEXT NOT NULL DEFAULT '',
+scope TEXT NOT NULL,
+ip TEXT NOT NULL DEFAULT '',
+user_agent TEXT NOT NULL DEFAULT '',
+used_at TIMESTAMP NOT NULL,
+expired_at TIMESTAMP NOT NULL
+);
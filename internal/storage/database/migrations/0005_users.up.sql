@@ -0,0 +1,16 @@
+CREATE TABLE IF NOT EXISTS users (
+id TEXT PRIMARY KEY,
+email TEXT NOT NULL,
+email_verified BOOLEAN NOT NULL DEFAULT FALSE,
+status TEXT NOT NULL DEFAULT 'active',
+locale TEXT NOT NULL DEFAULT '',
+created_at TIMESTAMP NOT NULL DEFAULT NOW()
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS users_email__unique_indx ON users (LOWER(email));
+
+CREATE TABLE IF NOT EXISTS user_credentials (
+user_id TEXT PRIMARY KEY REFERENCES users (id) ON DELETE CASCADE,
+password_hash TEXT NOT NULL,
+updated_at TIMESTAMP NOT NULL DEFAULT NOW()
+);
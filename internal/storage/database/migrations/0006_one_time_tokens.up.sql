@@ -0,0 +1,9 @@
+CREATE TABLE IF NOT EXISTS one_time_tokens (
+token_hash TEXT PRIMARY KEY,
+user_id TEXT NOT NULL REFERENCES users (id) ON DELETE CASCADE,
+purpose TEXT NOT NULL,
+expired_at TIMESTAMP NOT NULL,
+created_at TIMESTAMP NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS one_time_tokens_expired_at__btree_indx ON one_time_tokens (expired_at);
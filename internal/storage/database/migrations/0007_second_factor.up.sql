@@ -0,0 +1,15 @@
+ALTER TABLE refresh_tokens ADD COLUMN IF NOT EXISTS amr TEXT NOT NULL DEFAULT '';
+
+CREATE TABLE IF NOT EXISTS totp_credentials (
+user_id TEXT PRIMARY KEY REFERENCES users (id) ON DELETE CASCADE,
+encrypted_secret TEXT NOT NULL,
+confirmed BOOLEAN NOT NULL DEFAULT FALSE,
+last_used_step BIGINT NOT NULL DEFAULT 0,
+created_at TIMESTAMP NOT NULL
+);
+
+CREATE TABLE IF NOT EXISTS recovery_codes (
+user_id TEXT NOT NULL REFERENCES users (id) ON DELETE CASCADE,
+code_hash TEXT NOT NULL,
+PRIMARY KEY (user_id, code_hash)
+);
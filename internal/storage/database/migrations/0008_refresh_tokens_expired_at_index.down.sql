@@ -0,0 +1 @@
+DROP INDEX IF EXISTS refresh_tokens_expired_at__btree_indx;
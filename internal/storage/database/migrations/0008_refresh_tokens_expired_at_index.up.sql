@@ -0,0 +1 @@
+CREATE INDEX IF NOT EXISTS refresh_tokens_expired_at__btree_indx ON refresh_tokens (expired_at);
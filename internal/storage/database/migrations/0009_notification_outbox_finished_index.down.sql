@@ -0,0 +1 @@
+DROP INDEX IF EXISTS outbox_finished__btree_indx;
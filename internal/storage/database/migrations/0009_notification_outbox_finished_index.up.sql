@@ -0,0 +1 @@
+CREATE INDEX IF NOT EXISTS outbox_finished__btree_indx ON notification_outbox (created_at) WHERE status <> 'pending';
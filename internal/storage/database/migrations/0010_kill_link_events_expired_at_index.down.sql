@@ -0,0 +1 @@
+DROP INDEX IF EXISTS kill_link_events_expired_at__btree_indx;
@@ -0,0 +1 @@
+CREATE INDEX IF NOT EXISTS kill_link_events_expired_at__btree_indx ON kill_link_events (expired_at);
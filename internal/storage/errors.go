@@ -0,0 +1,19 @@
+package storage
+
+import "errors"
+
+var (
+	ErrUserNotFound = errors.New("user not found")      // Возвращается, если пользователь с указанным идентификатором не найден.
+	ErrUserExists   = errors.New("user already exists") // Возвращается при создании пользователя с занятым идентификатором или email.
+
+	ErrCredentialsNotFound = errors.New("credentials not found")  // Возвращается, если у пользователя нет пароля.
+	ErrTokenNotFound       = errors.New("token not found")        // Возвращается, если refresh-токен, семейство токенов или одноразовый токен не найдены.
+	ErrTokenExpired        = errors.New("token is expired")       // Возвращается при использовании одноразового токена с истекшим сроком действия.
+	ErrTokenReused         = errors.New("token was already used") // Возвращается при обновлении refresh-токена, который уже был обменян на новую пару.
+	ErrTokenExists         = errors.New("token already exists")   // Возвращается при сохранении токена с занятым jti или хэшем.
+
+	ErrOutboxMessageNotFound = errors.New("outbox message not found") // Возвращается, если сообщение outbox с указанным идентификатором не найдено.
+
+	ErrStorageUnavailable = errors.New("storage is unavailable")                   // Возвращается, если обращение к хранилищу не удалось (нет соединения, истекло время ожидания и т.п.).
+	ErrPurgeInProgress    = errors.New("purge is in progress on another instance") // Возвращается, если очистку хранилища уже выполняет другая реплика сервиса.
+)
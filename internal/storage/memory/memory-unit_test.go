@@ -3,7 +3,10 @@ package memory_test
 import (
 	"auth_service/internal/config"
 	"auth_service/internal/entities"
+	"auth_service/internal/storage"
 	"auth_service/internal/storage/memory"
+	"context"
+	"fmt"
 	"os"
 	"testing"
 	"time"
@@ -23,6 +26,7 @@ func TestMain(m *testing.M) {
 
 // TestSaveRefreshTokenRecord проверяет сохранение refresh токена в памяти, включая ошибочные кейсы.
 func TestSaveRefreshTokenRecord(t *testing.T) {
+	ctx := context.Background()
 	store := memory.NewMemoryStore()
 	userId := "user123"
 	now := time.Now().UTC().Truncate(time.Microsecond)
@@ -33,22 +37,42 @@ func TestSaveRefreshTokenRecord(t *testing.T) {
 		IssuedIp:  "192.168.0.1",
 		TokenHash: "hash123",
 	}
-	err := store.SaveRefreshTokenRecord(userId, refreshTokenRecord)
+	err := store.SaveRefreshTokenRecord(ctx, userId, refreshTokenRecord)
 	require.NoError(t, err)
 
-	actual, err := store.GetRefreshTokenRecord(refreshTokenRecord.Jti, userId)
+	actual, err := store.GetRefreshTokenRecord(ctx, refreshTokenRecord.Jti, userId)
 	require.NoError(t, err)
 	require.Equal(t, refreshTokenRecord, actual)
 
 	t.Run("duplicate add", func(t *testing.T) {
-		err := store.SaveRefreshTokenRecord(userId, refreshTokenRecord)
+		err := store.SaveRefreshTokenRecord(ctx, userId, refreshTokenRecord)
+		require.ErrorIs(t, err, storage.ErrTokenExists)
+	})
+	t.Run("tokens limit", func(t *testing.T) {
+		store := memory.NewMemoryStore()
+		for i := range 7 {
+			record := &entities.RefreshTokenRecord{
+				Jti:       fmt.Sprintf("jti%d", i),
+				CreatedAt: now.Add(time.Duration(i) * time.Second),
+				ExpiredAt: now.Add(24 * time.Hour),
+				TokenHash: fmt.Sprintf("hash%d", i),
+			}
+			require.NoError(t, store.SaveRefreshTokenRecord(ctx, userId, record))
+		}
+
+		records, err := store.GetRefreshTokenRecords(ctx, userId)
+		require.NoError(t, err)
+		require.Len(t, records, 5)
+		_, err = store.GetRefreshTokenRecord(ctx, "jti1", userId)
 		require.Error(t, err)
-		require.ErrorContains(t, err, "already exists")
+		_, err = store.GetRefreshTokenRecord(ctx, "jti2", userId)
+		require.NoError(t, err)
 	})
 }
 
 // TestUpdateRefreshTokenRecord проверяет обновление refresh токена в памяти, включая ошибочные кейсы.
 func TestUpdateRefreshTokenRecord(t *testing.T) {
+	ctx := context.Background()
 	store := memory.NewMemoryStore()
 	userId := "user123"
 	now := time.Now().UTC().Truncate(time.Microsecond)
@@ -59,7 +83,7 @@ func TestUpdateRefreshTokenRecord(t *testing.T) {
 		IssuedIp:  "192.168.0.1",
 		TokenHash: "hash123",
 	}
-	err := store.SaveRefreshTokenRecord(userId, refreshTokenRecord)
+	err := store.SaveRefreshTokenRecord(ctx, userId, refreshTokenRecord)
 	require.NoError(t, err)
 
 	newNow := now.Add(1 * time.Hour)
@@ -70,22 +94,187 @@ func TestUpdateRefreshTokenRecord(t *testing.T) {
 		IssuedIp:  "192.168.0.2",
 		TokenHash: "hash456",
 	}
-	err = store.UpdateRefreshTokenRecord(refreshTokenRecord.Jti, userId, newRefreshTokenRecord)
+	err = store.UpdateRefreshTokenRecord(ctx, refreshTokenRecord.Jti, userId, newRefreshTokenRecord, nil)
 	require.NoError(t, err)
 
-	actual, err := store.GetRefreshTokenRecord(newRefreshTokenRecord.Jti, userId)
+	actual, err := store.GetRefreshTokenRecord(ctx, newRefreshTokenRecord.Jti, userId)
 	require.NoError(t, err)
 	require.Equal(t, newRefreshTokenRecord, actual)
 
+	oldRecord, err := store.GetRefreshTokenRecord(ctx, refreshTokenRecord.Jti, userId)
+	require.NoError(t, err)
+	require.True(t, oldRecord.Rotated)
+
 	t.Run("update non-existent", func(t *testing.T) {
-		err := store.UpdateRefreshTokenRecord("not_exist_jti", userId, newRefreshTokenRecord)
-		require.Error(t, err)
-		require.ErrorContains(t, err, "not found")
+		err := store.UpdateRefreshTokenRecord(ctx, "not_exist_jti", userId, newRefreshTokenRecord, nil)
+		require.ErrorIs(t, err, storage.ErrTokenNotFound)
+	})
+	t.Run("update already rotated", func(t *testing.T) {
+		err := store.UpdateRefreshTokenRecord(ctx, refreshTokenRecord.Jti, userId, newRefreshTokenRecord, nil)
+		require.ErrorIs(t, err, storage.ErrTokenReused)
+	})
+}
+
+// TestGetRefreshTokenRecords проверяет получение активных refresh-токенов пользователя из памяти.
+func TestGetRefreshTokenRecords(t *testing.T) {
+	ctx := context.Background()
+	store := memory.NewMemoryStore()
+	userId := "user123"
+	now := time.Now().UTC().Truncate(time.Microsecond)
+	olderRecord := &entities.RefreshTokenRecord{
+		Jti:        "jti123",
+		CreatedAt:  now.Add(-time.Hour),
+		ExpiredAt:  now.Add(24 * time.Hour),
+		FamilyId:   "jti123",
+		IssuedIp:   "192.168.0.1",
+		UserAgent:  "test-agent",
+		DeviceName: "Work laptop",
+		LastUsedAt: now.Add(-time.Hour),
+		TokenHash:  "hash123",
+	}
+	rotatedRecord := &entities.RefreshTokenRecord{
+		Jti:        "jti456",
+		CreatedAt:  now.Add(-2 * time.Hour),
+		ExpiredAt:  now.Add(24 * time.Hour),
+		FamilyId:   "jti456",
+		IssuedIp:   "192.168.0.1",
+		LastUsedAt: now.Add(-2 * time.Hour),
+		TokenHash:  "hash456",
+	}
+	newerRecord := &entities.RefreshTokenRecord{
+		Jti:        "jti789",
+		CreatedAt:  now,
+		ExpiredAt:  now.Add(24 * time.Hour),
+		FamilyId:   "jti456",
+		IssuedIp:   "192.168.0.2",
+		LastUsedAt: now,
+		TokenHash:  "hash789",
+	}
+	expiredRecord := &entities.RefreshTokenRecord{
+		Jti:        "jti000",
+		CreatedAt:  now.Add(-48 * time.Hour),
+		ExpiredAt:  now.Add(-24 * time.Hour),
+		FamilyId:   "jti000",
+		IssuedIp:   "192.168.0.1",
+		LastUsedAt: now.Add(-48 * time.Hour),
+		TokenHash:  "hash000",
+	}
+	require.NoError(t, store.SaveRefreshTokenRecord(ctx, userId, olderRecord))
+	require.NoError(t, store.SaveRefreshTokenRecord(ctx, userId, rotatedRecord))
+	require.NoError(t, store.UpdateRefreshTokenRecord(ctx, rotatedRecord.Jti, userId, newerRecord, nil))
+	require.NoError(t, store.SaveRefreshTokenRecord(ctx, userId, expiredRecord))
+
+	actual, err := store.GetRefreshTokenRecords(ctx, userId)
+	require.NoError(t, err)
+	require.Equal(t, []*entities.RefreshTokenRecord{newerRecord, olderRecord}, actual)
+
+	t.Run("user without tokens", func(t *testing.T) {
+		actual, err := store.GetRefreshTokenRecords(ctx, "not_exist_user")
+		require.NoError(t, err)
+		require.Empty(t, actual)
+	})
+}
+
+// TestRevokeTokenFamily проверяет удаление всех токенов семейства из памяти, включая ошибочные кейсы.
+func TestRevokeTokenFamily(t *testing.T) {
+	ctx := context.Background()
+	store := memory.NewMemoryStore()
+	userId := "user123"
+	now := time.Now().UTC().Truncate(time.Microsecond)
+	familyRecord := &entities.RefreshTokenRecord{
+		Jti:       "jti123",
+		CreatedAt: now,
+		ExpiredAt: now.Add(24 * time.Hour),
+		FamilyId:  "family123",
+		IssuedIp:  "192.168.0.1",
+		TokenHash: "hash123",
+	}
+	rotatedFamilyRecord := &entities.RefreshTokenRecord{
+		Jti:       "jti456",
+		CreatedAt: now,
+		ExpiredAt: now.Add(24 * time.Hour),
+		FamilyId:  "family123",
+		IssuedIp:  "192.168.0.1",
+		TokenHash: "hash456",
+	}
+	otherRecord := &entities.RefreshTokenRecord{
+		Jti:       "jti789",
+		CreatedAt: now,
+		ExpiredAt: now.Add(24 * time.Hour),
+		FamilyId:  "family789",
+		IssuedIp:  "192.168.0.1",
+		TokenHash: "hash789",
+	}
+	require.NoError(t, store.SaveRefreshTokenRecord(ctx, userId, familyRecord))
+	require.NoError(t, store.UpdateRefreshTokenRecord(ctx, familyRecord.Jti, userId, rotatedFamilyRecord, nil))
+	require.NoError(t, store.SaveRefreshTokenRecord(ctx, userId, otherRecord))
+
+	revokedRecords, err := store.RevokeTokenFamily(ctx, userId, "family123")
+	require.NoError(t, err)
+	require.Len(t, revokedRecords, 2)
+	require.ElementsMatch(t, []string{familyRecord.Jti, rotatedFamilyRecord.Jti},
+		[]string{revokedRecords[0].Jti, revokedRecords[1].Jti})
+
+	_, err = store.GetRefreshTokenRecord(ctx, familyRecord.Jti, userId)
+	require.ErrorIs(t, err, storage.ErrTokenNotFound)
+	_, err = store.GetRefreshTokenRecord(ctx, rotatedFamilyRecord.Jti, userId)
+	require.ErrorIs(t, err, storage.ErrTokenNotFound)
+	actual, err := store.GetRefreshTokenRecord(ctx, otherRecord.Jti, userId)
+	require.NoError(t, err)
+	require.Equal(t, otherRecord, actual)
+
+	t.Run("revoke non-existent", func(t *testing.T) {
+		_, err := store.RevokeTokenFamily(ctx, userId, "not_exist_family")
+		require.ErrorIs(t, err, storage.ErrTokenNotFound)
+	})
+}
+
+// TestRevokeAllRefreshTokens проверяет удаление всех refresh-токенов пользователя из памяти.
+func TestRevokeAllRefreshTokens(t *testing.T) {
+	ctx := context.Background()
+	store := memory.NewMemoryStore()
+	userId := "user123"
+	otherUserId := "user456"
+	now := time.Now().UTC().Truncate(time.Microsecond)
+	refreshTokenRecord := &entities.RefreshTokenRecord{
+		Jti:       "jti123",
+		CreatedAt: now,
+		ExpiredAt: now.Add(24 * time.Hour),
+		FamilyId:  "jti123",
+		IssuedIp:  "192.168.0.1",
+		TokenHash: "hash123",
+	}
+	otherUserRecord := &entities.RefreshTokenRecord{
+		Jti:       "jti456",
+		CreatedAt: now,
+		ExpiredAt: now.Add(24 * time.Hour),
+		FamilyId:  "jti456",
+		IssuedIp:  "192.168.0.1",
+		TokenHash: "hash456",
+	}
+	require.NoError(t, store.SaveRefreshTokenRecord(ctx, userId, refreshTokenRecord))
+	require.NoError(t, store.SaveRefreshTokenRecord(ctx, otherUserId, otherUserRecord))
+
+	revokedRecords, err := store.RevokeAllRefreshTokens(ctx, userId)
+	require.NoError(t, err)
+	require.Equal(t, []*entities.RefreshTokenRecord{refreshTokenRecord}, revokedRecords)
+
+	_, err = store.GetRefreshTokenRecord(ctx, refreshTokenRecord.Jti, userId)
+	require.ErrorIs(t, err, storage.ErrTokenNotFound)
+	actual, err := store.GetRefreshTokenRecord(ctx, otherUserRecord.Jti, otherUserId)
+	require.NoError(t, err)
+	require.Equal(t, otherUserRecord, actual)
+
+	t.Run("revoke without tokens", func(t *testing.T) {
+		revokedRecords, err := store.RevokeAllRefreshTokens(ctx, "not_exist_user")
+		require.NoError(t, err)
+		require.Empty(t, revokedRecords)
 	})
 }
 
 // TestGetTokenRecord проверяет получение refresh токена из памяти, включая ошибочные кейсы.
 func TestGetTokenRecord(t *testing.T) {
+	ctx := context.Background()
 	store := memory.NewMemoryStore()
 	userId := "user123"
 	now := time.Now().UTC().Truncate(time.Microsecond)
@@ -96,16 +285,515 @@ func TestGetTokenRecord(t *testing.T) {
 		IssuedIp:  "192.168.0.1",
 		TokenHash: "hash123",
 	}
-	err := store.SaveRefreshTokenRecord(userId, refreshTokenRecord)
+	err := store.SaveRefreshTokenRecord(ctx, userId, refreshTokenRecord)
 	require.NoError(t, err)
 
-	actual, err := store.GetRefreshTokenRecord(refreshTokenRecord.Jti, userId)
+	actual, err := store.GetRefreshTokenRecord(ctx, refreshTokenRecord.Jti, userId)
 	require.NoError(t, err)
 	require.Equal(t, refreshTokenRecord, actual)
 
 	t.Run("get non-existent", func(t *testing.T) {
-		_, err := store.GetRefreshTokenRecord("not_exist_jti", userId)
+		_, err := store.GetRefreshTokenRecord(ctx, "not_exist_jti", userId)
+		require.ErrorIs(t, err, storage.ErrTokenNotFound)
+	})
+}
+
+// TestRevokeAccessToken проверяет добавление access-токена в список отозванных и истечение записи.
+func TestRevokeAccessToken(t *testing.T) {
+	ctx := context.Background()
+	store := memory.NewMemoryStore()
+	now := time.Now()
+
+	err := store.RevokeAccessToken(ctx, "jti123", now.Add(time.Hour))
+	require.NoError(t, err)
+	err = store.RevokeAccessToken(ctx, "jti456", now.Add(-time.Second))
+	require.NoError(t, err)
+
+	revoked, err := store.IsAccessTokenRevoked(ctx, "jti123")
+	require.NoError(t, err)
+	require.True(t, revoked)
+
+	t.Run("expired entry", func(t *testing.T) {
+		revoked, err := store.IsAccessTokenRevoked(ctx, "jti456")
+		require.NoError(t, err)
+		require.False(t, revoked)
+	})
+	t.Run("not revoked", func(t *testing.T) {
+		revoked, err := store.IsAccessTokenRevoked(ctx, "not_exist_jti")
+		require.NoError(t, err)
+		require.False(t, revoked)
+	})
+}
+
+// TestOutboxMessages проверяет добавление, выборку и изменение статуса сообщений outbox.
+func TestOutboxMessages(t *testing.T) {
+	ctx := context.Background()
+	store := memory.NewMemoryStore()
+	now := time.Now().UTC().Truncate(time.Microsecond)
+	firstMessage := &entities.OutboxMessage{
+		Id:            "ip_changed:jti123",
+		Payload:       `{"id":"ip_changed:jti123"}`,
+		Status:        entities.OutboxStatusPending,
+		NextAttemptAt: now.Add(-time.Minute),
+		CreatedAt:     now,
+	}
+	secondMessage := &entities.OutboxMessage{
+		Id:            "token_reuse:family123",
+		Payload:       `{"id":"token_reuse:family123"}`,
+		Status:        entities.OutboxStatusPending,
+		NextAttemptAt: now.Add(time.Hour),
+		CreatedAt:     now,
+	}
+	require.NoError(t, store.EnqueueOutboxMessage(ctx, firstMessage))
+	require.NoError(t, store.EnqueueOutboxMessage(ctx, secondMessage))
+	require.NoError(t, store.EnqueueOutboxMessage(ctx, firstMessage))
+
+	claimed, err := store.ClaimOutboxMessages(ctx, now, time.Minute, 10)
+	require.NoError(t, err)
+	require.Len(t, claimed, 1)
+	require.Equal(t, firstMessage.Id, claimed[0].Id)
+	require.Equal(t, firstMessage.Payload, claimed[0].Payload)
+
+	t.Run("claimed message is leased", func(t *testing.T) {
+		claimed, err := store.ClaimOutboxMessages(ctx, now, time.Minute, 10)
+		require.NoError(t, err)
+		require.Empty(t, claimed)
+	})
+	t.Run("failed message is rescheduled", func(t *testing.T) {
+		err := store.MarkOutboxMessageFailed(ctx, firstMessage.Id, "connection refused", now)
+		require.NoError(t, err)
+
+		claimed, err := store.ClaimOutboxMessages(ctx, now, time.Minute, 10)
+		require.NoError(t, err)
+		require.Len(t, claimed, 1)
+		require.Equal(t, 1, claimed[0].Attempts)
+		require.Equal(t, "connection refused", claimed[0].LastError)
+	})
+	t.Run("delivered message is not claimed", func(t *testing.T) {
+		err := store.MarkOutboxMessageDelivered(ctx, firstMessage.Id)
+		require.NoError(t, err)
+
+		claimed, err := store.ClaimOutboxMessages(ctx, now.Add(2*time.Minute), time.Minute, 10)
+		require.NoError(t, err)
+		require.Empty(t, claimed)
+	})
+	t.Run("dead message is not claimed", func(t *testing.T) {
+		err := store.MarkOutboxMessageDead(ctx, secondMessage.Id, "connection refused")
+		require.NoError(t, err)
+
+		claimed, err := store.ClaimOutboxMessages(ctx, now.Add(2*time.Hour), time.Minute, 10)
+		require.NoError(t, err)
+		require.Empty(t, claimed)
+	})
+	t.Run("not exist message", func(t *testing.T) {
+		err := store.MarkOutboxMessageDelivered(ctx, "not_exist_id")
+		require.ErrorIs(t, err, storage.ErrOutboxMessageNotFound)
+	})
+	t.Run("finished messages are purged", func(t *testing.T) {
+		pendingMessage := &entities.OutboxMessage{
+			Id:            "password_reset:user123:1",
+			Payload:       `{"id":"password_reset:user123:1"}`,
+			Status:        entities.OutboxStatusPending,
+			NextAttemptAt: now.Add(time.Hour),
+			CreatedAt:     now,
+		}
+		require.NoError(t, store.EnqueueOutboxMessage(ctx, pendingMessage))
+
+		purged, err := store.PurgeOutboxMessages(ctx, now, 10)
+		require.NoError(t, err)
+		require.Zero(t, purged)
+
+		purged, err = store.PurgeOutboxMessages(ctx, now.Add(time.Minute), 1)
+		require.NoError(t, err)
+		require.Equal(t, int64(2), purged)
+
+		err = store.MarkOutboxMessageDelivered(ctx, firstMessage.Id)
+		require.ErrorIs(t, err, storage.ErrOutboxMessageNotFound)
+		claimed, err := store.ClaimOutboxMessages(ctx, now.Add(2*time.Hour), time.Minute, 10)
+		require.NoError(t, err)
+		require.Len(t, claimed, 1)
+		require.Equal(t, pendingMessage.Id, claimed[0].Id)
+
+		_, err = store.PurgeOutboxMessages(ctx, now, 0)
 		require.Error(t, err)
-		require.ErrorContains(t, err, "not found")
 	})
 }
+
+// TestConsumeKillLink проверяет однократное использование ссылки "Это был не я".
+func TestConsumeKillLink(t *testing.T) {
+	ctx := context.Background()
+	store := memory.NewMemoryStore()
+	event := &entities.KillLinkEvent{
+		Id:        "link123",
+		UserId:    "user123",
+		FamilyId:  "family123",
+		Scope:     entities.KillLinkScopeFamily,
+		Ip:        "192.168.0.1",
+		UserAgent: "Mozilla/5.0",
+		UsedAt:    time.Now(),
+		ExpiredAt: time.Now().Add(time.Hour),
+	}
+
+	used, err := store.IsKillLinkUsed(ctx, event.Id)
+	require.NoError(t, err)
+	require.False(t, used)
+
+	consumed, err := store.ConsumeKillLink(ctx, event)
+	require.NoError(t, err)
+	require.True(t, consumed)
+
+	t.Run("link already used", func(t *testing.T) {
+		used, err := store.IsKillLinkUsed(ctx, event.Id)
+		require.NoError(t, err)
+		require.True(t, used)
+
+		consumed, err := store.ConsumeKillLink(ctx, event)
+		require.NoError(t, err)
+		require.False(t, consumed)
+	})
+	t.Run("other link", func(t *testing.T) {
+		otherEvent := *event
+		otherEvent.Id = "link456"
+		consumed, err := store.ConsumeKillLink(ctx, &otherEvent)
+		require.NoError(t, err)
+		require.True(t, consumed)
+	})
+}
+
+// TestUsers проверяет создание, чтение, обновление и удаление пользователей.
+func TestUsers(t *testing.T) {
+	ctx := context.Background()
+	store := memory.NewMemoryStore()
+	now := time.Now().UTC().Truncate(time.Microsecond)
+	user := &entities.User{
+		Id:        "user123",
+		Email:     "User@Gmail.com",
+		Status:    entities.UserStatusActive,
+		Locale:    "en",
+		CreatedAt: now,
+	}
+	err := store.CreateUser(ctx, user)
+	require.NoError(t, err)
+
+	actual, err := store.GetUser(ctx, user.Id)
+	require.NoError(t, err)
+	require.Equal(t, user.Email, actual.Email)
+	require.Equal(t, user.Status, actual.Status)
+	require.Equal(t, user.Locale, actual.Locale)
+	require.False(t, actual.EmailVerified)
+	require.True(t, user.CreatedAt.Equal(actual.CreatedAt))
+
+	t.Run("duplicate id", func(t *testing.T) {
+		duplicate := *user
+		duplicate.Email = "other@gmail.com"
+		err := store.CreateUser(ctx, &duplicate)
+		require.ErrorIs(t, err, storage.ErrUserExists)
+	})
+	t.Run("duplicate email", func(t *testing.T) {
+		duplicate := *user
+		duplicate.Id = "user456"
+		duplicate.Email = "user@gmail.com"
+		err := store.CreateUser(ctx, &duplicate)
+		require.ErrorIs(t, err, storage.ErrUserExists)
+	})
+	t.Run("update user", func(t *testing.T) {
+		updated := *user
+		updated.EmailVerified = true
+		updated.Status = entities.UserStatusDisabled
+		updated.Locale = "ru"
+		require.NoError(t, store.UpdateUser(ctx, &updated))
+
+		actual, err := store.GetUser(ctx, user.Id)
+		require.NoError(t, err)
+		require.True(t, actual.EmailVerified)
+		require.Equal(t, entities.UserStatusDisabled, actual.Status)
+		require.Equal(t, "ru", actual.Locale)
+	})
+	t.Run("update email to taken", func(t *testing.T) {
+		other := &entities.User{Id: "user789", Email: "other@gmail.com", Status: entities.UserStatusActive, CreatedAt: now}
+		require.NoError(t, store.CreateUser(ctx, other))
+
+		updated := *other
+		updated.Email = "USER@gmail.com"
+		err := store.UpdateUser(ctx, &updated)
+		require.ErrorIs(t, err, storage.ErrUserExists)
+	})
+	t.Run("delete user", func(t *testing.T) {
+		require.NoError(t, store.DeleteUser(ctx, user.Id))
+		_, err := store.GetUser(ctx, user.Id)
+		require.ErrorIs(t, err, storage.ErrUserNotFound)
+	})
+	t.Run("not exist user", func(t *testing.T) {
+		_, err := store.GetUser(ctx, "not_exist_user")
+		require.ErrorIs(t, err, storage.ErrUserNotFound)
+		err = store.UpdateUser(ctx, &entities.User{Id: "not_exist_user", Email: "new@gmail.com"})
+		require.ErrorIs(t, err, storage.ErrUserNotFound)
+		err = store.DeleteUser(ctx, "not_exist_user")
+		require.ErrorIs(t, err, storage.ErrUserNotFound)
+	})
+}
+
+// TestPasswordHash проверяет поиск пользователя по email и сохранение хэша пароля.
+func TestPasswordHash(t *testing.T) {
+	ctx := context.Background()
+	store := memory.NewMemoryStore()
+	user := &entities.User{Id: "user123", Email: "User@Gmail.com", Status: entities.UserStatusActive, CreatedAt: time.Now()}
+	require.NoError(t, store.CreateUser(ctx, user))
+
+	actual, err := store.GetUserByEmail(ctx, "user@GMAIL.com")
+	require.NoError(t, err)
+	require.Equal(t, user.Id, actual.Id)
+
+	_, err = store.GetPasswordHash(ctx, user.Id)
+	require.ErrorIs(t, err, storage.ErrCredentialsNotFound)
+
+	require.NoError(t, store.SavePasswordHash(ctx, user.Id, "hash1"))
+	require.NoError(t, store.SavePasswordHash(ctx, user.Id, "hash2"))
+	passwordHash, err := store.GetPasswordHash(ctx, user.Id)
+	require.NoError(t, err)
+	require.Equal(t, "hash2", passwordHash)
+
+	t.Run("not exist user", func(t *testing.T) {
+		_, err := store.GetUserByEmail(ctx, "unknown@gmail.com")
+		require.ErrorIs(t, err, storage.ErrUserNotFound)
+		err = store.SavePasswordHash(ctx, "not_exist_user", "hash")
+		require.ErrorIs(t, err, storage.ErrUserNotFound)
+	})
+	t.Run("deleted user", func(t *testing.T) {
+		require.NoError(t, store.DeleteUser(ctx, user.Id))
+		_, err := store.GetPasswordHash(ctx, user.Id)
+		require.ErrorIs(t, err, storage.ErrCredentialsNotFound)
+	})
+}
+
+// TestOneTimeTokens проверяет сохранение и однократное использование одноразовых токенов.
+func TestOneTimeTokens(t *testing.T) {
+	ctx := context.Background()
+	store := memory.NewMemoryStore()
+
+	user := &entities.User{Id: "user123", Email: "user@gmail.com", Status: entities.UserStatusActive, CreatedAt: time.Now()}
+	require.NoError(t, store.CreateUser(ctx, user))
+
+	now := time.Now().UTC().Truncate(time.Microsecond)
+	token := &entities.OneTimeToken{
+		TokenHash: "hash1",
+		UserId:    user.Id,
+		Purpose:   entities.OneTimeTokenVerifyEmail,
+		ExpiredAt: now.Add(time.Hour),
+		CreatedAt: now,
+	}
+	require.NoError(t, store.SaveOneTimeToken(ctx, token))
+
+	_, err := store.ConsumeOneTimeToken(ctx, "other_purpose", token.TokenHash, now)
+	require.ErrorIs(t, err, storage.ErrTokenNotFound)
+
+	actual, err := store.ConsumeOneTimeToken(ctx, token.Purpose, token.TokenHash, now)
+	require.NoError(t, err)
+	require.Equal(t, token.UserId, actual.UserId)
+	require.True(t, token.ExpiredAt.Equal(actual.ExpiredAt))
+
+	_, err = store.ConsumeOneTimeToken(ctx, token.Purpose, token.TokenHash, now)
+	require.ErrorIs(t, err, storage.ErrTokenNotFound)
+
+	t.Run("expired token", func(t *testing.T) {
+		expiredToken := *token
+		expiredToken.TokenHash = "hash2"
+		require.NoError(t, store.SaveOneTimeToken(ctx, &expiredToken))
+		_, err := store.ConsumeOneTimeToken(ctx, token.Purpose, expiredToken.TokenHash, now.Add(2*time.Hour))
+		require.ErrorIs(t, err, storage.ErrTokenExpired)
+	})
+	t.Run("not exist user", func(t *testing.T) {
+		otherToken := *token
+		otherToken.TokenHash = "hash3"
+		otherToken.UserId = "not_exist_user"
+		err := store.SaveOneTimeToken(ctx, &otherToken)
+		require.ErrorIs(t, err, storage.ErrUserNotFound)
+	})
+	t.Run("delete tokens by purpose", func(t *testing.T) {
+		resetToken := *token
+		resetToken.TokenHash = "hash5"
+		resetToken.Purpose = entities.OneTimeTokenPasswordReset
+		verifyToken := *token
+		verifyToken.TokenHash = "hash6"
+		require.NoError(t, store.SaveOneTimeToken(ctx, &resetToken))
+		require.NoError(t, store.SaveOneTimeToken(ctx, &verifyToken))
+
+		require.NoError(t, store.DeleteOneTimeTokens(ctx, user.Id, entities.OneTimeTokenPasswordReset))
+		_, err := store.ConsumeOneTimeToken(ctx, resetToken.Purpose, resetToken.TokenHash, now)
+		require.ErrorIs(t, err, storage.ErrTokenNotFound)
+		_, err = store.ConsumeOneTimeToken(ctx, verifyToken.Purpose, verifyToken.TokenHash, now)
+		require.NoError(t, err)
+	})
+	t.Run("deleted user", func(t *testing.T) {
+		deletedToken := *token
+		deletedToken.TokenHash = "hash4"
+		require.NoError(t, store.SaveOneTimeToken(ctx, &deletedToken))
+		require.NoError(t, store.DeleteUser(ctx, user.Id))
+		_, err := store.ConsumeOneTimeToken(ctx, token.Purpose, deletedToken.TokenHash, now)
+		require.ErrorIs(t, err, storage.ErrTokenNotFound)
+	})
+}
+
+// TestSecondFactor проверяет хранение TOTP-секрета, защиту от повторного использования кода и коды восстановления.
+func TestSecondFactor(t *testing.T) {
+	ctx := context.Background()
+	store := memory.NewMemoryStore()
+
+	user := &entities.User{Id: "user123", Email: "user@gmail.com", Status: entities.UserStatusActive, CreatedAt: time.Now()}
+	require.NoError(t, store.CreateUser(ctx, user))
+
+	_, err := store.GetTotpCredential(ctx, user.Id)
+	require.ErrorIs(t, err, storage.ErrCredentialsNotFound)
+
+	credential := &entities.TotpCredential{
+		UserId:          user.Id,
+		EncryptedSecret: "secret1",
+		CreatedAt:       time.Now().UTC().Truncate(time.Microsecond),
+	}
+	require.NoError(t, store.SaveTotpCredential(ctx, credential))
+	credential.EncryptedSecret = "secret2"
+	credential.Confirmed = true
+	credential.LastUsedStep = 10
+	require.NoError(t, store.SaveTotpCredential(ctx, credential))
+	actual, err := store.GetTotpCredential(ctx, user.Id)
+	require.NoError(t, err)
+	require.Equal(t, "secret2", actual.EncryptedSecret)
+	require.True(t, actual.Confirmed)
+	require.Equal(t, int64(10), actual.LastUsedStep)
+
+	used, err := store.UseTotpStep(ctx, user.Id, 10)
+	require.NoError(t, err)
+	require.False(t, used)
+	used, err = store.UseTotpStep(ctx, user.Id, 11)
+	require.NoError(t, err)
+	require.True(t, used)
+	used, err = store.UseTotpStep(ctx, user.Id, 11)
+	require.NoError(t, err)
+	require.False(t, used)
+
+	require.NoError(t, store.SaveRecoveryCodes(ctx, user.Id, []string{"code1", "code2"}))
+	require.NoError(t, store.SaveRecoveryCodes(ctx, user.Id, []string{"code3", "code4"}))
+	consumed, err := store.ConsumeRecoveryCode(ctx, user.Id, "code1")
+	require.NoError(t, err)
+	require.False(t, consumed)
+	consumed, err = store.ConsumeRecoveryCode(ctx, user.Id, "code3")
+	require.NoError(t, err)
+	require.True(t, consumed)
+	consumed, err = store.ConsumeRecoveryCode(ctx, user.Id, "code3")
+	require.NoError(t, err)
+	require.False(t, consumed)
+
+	t.Run("not exist user", func(t *testing.T) {
+		err := store.SaveTotpCredential(ctx, &entities.TotpCredential{UserId: "not_exist_user", CreatedAt: time.Now()})
+		require.ErrorIs(t, err, storage.ErrUserNotFound)
+		err = store.SaveRecoveryCodes(ctx, "not_exist_user", []string{"code"})
+		require.ErrorIs(t, err, storage.ErrUserNotFound)
+	})
+	t.Run("deleted user", func(t *testing.T) {
+		require.NoError(t, store.DeleteUser(ctx, user.Id))
+		_, err := store.GetTotpCredential(ctx, user.Id)
+		require.ErrorIs(t, err, storage.ErrCredentialsNotFound)
+		consumed, err := store.ConsumeRecoveryCode(ctx, user.Id, "code4")
+		require.NoError(t, err)
+		require.False(t, consumed)
+	})
+}
+
+// TestPurgeExpiredRefreshTokens проверяет удаление истекших refresh-токенов пачками.
+func TestPurgeExpiredRefreshTokens(t *testing.T) {
+	ctx := context.Background()
+	store := memory.NewMemoryStore()
+	now := time.Now().UTC()
+	for i := range 5 {
+		expiredAt := now.Add(-time.Minute)
+		if i%2 == 0 {
+			expiredAt = now.Add(time.Hour)
+		}
+		err := store.SaveRefreshTokenRecord(ctx, fmt.Sprintf("user%d", i%2), &entities.RefreshTokenRecord{
+			Jti:       fmt.Sprintf("jti%d", i),
+			CreatedAt: now.Add(-time.Hour),
+			ExpiredAt: expiredAt,
+			TokenHash: fmt.Sprintf("hash%d", i),
+		})
+		require.NoError(t, err)
+	}
+
+	purged, err := store.PurgeExpiredRefreshTokens(ctx, now, 1)
+	require.NoError(t, err)
+	require.Equal(t, int64(2), purged)
+
+	_, err = store.GetRefreshTokenRecord(ctx, "jti1", "user1")
+	require.Error(t, err)
+	_, err = store.GetRefreshTokenRecord(ctx, "jti0", "user0")
+	require.NoError(t, err)
+
+	purged, err = store.PurgeExpiredRefreshTokens(ctx, now.Add(2*time.Hour), 10)
+	require.NoError(t, err)
+	require.Equal(t, int64(3), purged)
+
+	_, err = store.PurgeExpiredRefreshTokens(ctx, now, 0)
+	require.Error(t, err)
+}
+
+// TestPurgeKillLinkEvents проверяет удаление событий ссылок "Это был не я", срок действия которых истек.
+func TestPurgeKillLinkEvents(t *testing.T) {
+	ctx := context.Background()
+	store := memory.NewMemoryStore()
+	now := time.Now().UTC().Truncate(time.Microsecond)
+	for i := range 3 {
+		expiredAt := now.Add(-time.Minute)
+		if i == 0 {
+			expiredAt = now.Add(time.Hour)
+		}
+		consumed, err := store.ConsumeKillLink(ctx, &entities.KillLinkEvent{
+			Id:        fmt.Sprintf("link%d", i),
+			UserId:    "user123",
+			Scope:     entities.KillLinkScopeAll,
+			UsedAt:    now,
+			ExpiredAt: expiredAt,
+		})
+		require.NoError(t, err)
+		require.True(t, consumed)
+	}
+
+	purged, err := store.PurgeKillLinkEvents(ctx, now, 1)
+	require.NoError(t, err)
+	require.Equal(t, int64(2), purged)
+
+	used, err := store.IsKillLinkUsed(ctx, "link0")
+	require.NoError(t, err)
+	require.True(t, used)
+	used, err = store.IsKillLinkUsed(ctx, "link1")
+	require.NoError(t, err)
+	require.False(t, used)
+
+	_, err = store.PurgeKillLinkEvents(ctx, now, 0)
+	require.Error(t, err)
+}
+
+// TestContextCancellation проверяет, что операции с отмененным контекстом не выполняются.
+func TestContextCancellation(t *testing.T) {
+	store := memory.NewMemoryStore()
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	record := &entities.RefreshTokenRecord{
+		Jti:       "jti123",
+		CreatedAt: time.Now().UTC(),
+		ExpiredAt: time.Now().UTC().Add(-time.Minute),
+		TokenHash: "hash123",
+	}
+
+	err := store.SaveRefreshTokenRecord(ctx, "user123", record)
+	require.ErrorIs(t, err, context.Canceled)
+	_, err = store.GetRefreshTokenRecord(context.Background(), record.Jti, "user123")
+	require.Error(t, err)
+
+	require.NoError(t, store.SaveRefreshTokenRecord(context.Background(), "user123", record))
+	_, err = store.GetRefreshTokenRecord(ctx, record.Jti, "user123")
+	require.ErrorIs(t, err, context.Canceled)
+	purged, err := store.PurgeExpiredRefreshTokens(ctx, time.Now().UTC(), 10)
+	require.ErrorIs(t, err, context.Canceled)
+	require.Zero(t, purged)
+	_, err = store.GetRefreshTokenRecord(context.Background(), record.Jti, "user123")
+	require.NoError(t, err)
+}
@@ -3,123 +3,824 @@ package memory
 import (
 	"auth_service/internal/config"
 	"auth_service/internal/entities"
+	"auth_service/internal/storage"
+	"context"
 	"fmt"
 	"log"
 	"slices"
 	"strconv"
+	"strings"
 	"sync"
+	"time"
 )
 
 // Memory реализует in-memory хранилище для refresh-токенов пользователей.
 // Используется для тестирования или работы в режиме без постоянного хранилища.
+// Операции не ожидают ввода-вывода, поэтому контекст проверяется только перед началом операции:
+// отмененный запрос не изменяет хранилище.
 type Memory struct {
-	tokenRecords map[string][]*entities.RefreshTokenRecord // userTokens хранит список активных токенов пользователя по userId.
-	mu           sync.RWMutex                              // mu обеспечивает потокобезопасность операций с хранилищем.
+	tokenRecords        map[string][]*entities.RefreshTokenRecord // userTokens хранит список активных токенов пользователя по userId.
+	revokedAccessTokens map[string]time.Time                      // revokedAccessTokens хранит время истечения отозванных access-токенов по jti.
+	outbox              map[string]*entities.OutboxMessage        // outbox - очередь уведомлений, ожидающих доставки, по ключу идемпотентности.
+	killLinkEvents      map[string]*entities.KillLinkEvent        // killLinkEvents - использованные ссылки "Это был не я" по идентификатору ссылки.
+	users               map[string]*entities.User                 // users - пользователи по идентификатору.
+	passwordHashes      map[string]string                         // passwordHashes - хэши паролей пользователей по идентификатору.
+	oneTimeTokens       map[string]*entities.OneTimeToken         // oneTimeTokens - одноразовые токены по хэшу.
+	totpCredentials     map[string]*entities.TotpCredential       // totpCredentials - TOTP-секреты пользователей по идентификатору.
+	recoveryCodes       map[string][]string                       // recoveryCodes - хэши неиспользованных кодов восстановления по идентификатору пользователя.
+	mu                  sync.RWMutex                              // mu обеспечивает потокобезопасность операций с хранилищем.
 }
 
 // NewMemoryStore создает и возвращает новое in-memory хранилище токенов.
 func NewMemoryStore() *Memory {
 	return &Memory{
-		tokenRecords: make(map[string][]*entities.RefreshTokenRecord),
+		tokenRecords:        make(map[string][]*entities.RefreshTokenRecord),
+		revokedAccessTokens: make(map[string]time.Time),
+		outbox:              make(map[string]*entities.OutboxMessage),
+		killLinkEvents:      make(map[string]*entities.KillLinkEvent),
+		users:               make(map[string]*entities.User),
+		passwordHashes:      make(map[string]string),
+		oneTimeTokens:       make(map[string]*entities.OneTimeToken),
+		totpCredentials:     make(map[string]*entities.TotpCredential),
+		recoveryCodes:       make(map[string][]string),
 	}
 }
 
 // SaveRefreshTokenRecord сохраняет хэш refresh-токена для указанного пользователя.
-// Если у пользователя уже максимальное количество токенов, удаляет самый старый токен.
-// Возвращает ошибку, если токен с таким jti уже существует.
-func (m *Memory) SaveRefreshTokenRecord(userId string, refreshTokenRecord *entities.RefreshTokenRecord) error {
+// Если у пользователя уже максимальное количество активных токенов, удаляет столько самых старых токенов,
+// сколько нужно, чтобы с новым токеном лимит не был превышен.
+// Возвращает ErrTokenExists, если токен с таким jti уже существует.
+func (m *Memory) SaveRefreshTokenRecord(ctx context.Context, userId string, refreshTokenRecord *entities.RefreshTokenRecord) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
 	m.mu.Lock()
 	defer m.mu.Unlock()
 	maxTokensPerUser, err := strconv.Atoi(config.MaxTokensPerUser)
 	if err != nil {
 		return fmt.Errorf("env 'MAX_TOKENS_PER_USER' is not number: %w", err)
 	}
-	if err := m.checkActiveTokens(userId, maxTokensPerUser); err != nil {
-		log.Println(err)
-		if err := m.deleteOldestRefreshToken(userId); err != nil {
-			return fmt.Errorf("failed to delete oldest refresh token for userID: '%s': %w", userId, err)
+	if maxTokensPerUser < 1 {
+		return fmt.Errorf("env 'MAX_TOKENS_PER_USER' must be positive")
+	}
+	for _, record := range m.tokenRecords[userId] {
+		if refreshTokenRecord.Jti == record.Jti {
+			return fmt.Errorf("refresh token with jti '%s': %w", refreshTokenRecord.Jti, storage.ErrTokenExists)
 		}
-		log.Printf("the latest token has been deleted due to exceeding the limit for userID: '%s'\n", userId)
+	}
+
+	if evicted := m.evictOldestRefreshTokens(userId, maxTokensPerUser-1); evicted > 0 {
+		log.Printf("%d oldest token(s) have been deleted due to exceeding the limit for userID: '%s'\n", evicted, userId)
 	}
 	_, has := m.tokenRecords[userId]
 	if !has {
 		m.tokenRecords[userId] = make([]*entities.RefreshTokenRecord, 0, maxTokensPerUser)
 	}
+	m.tokenRecords[userId] = append(m.tokenRecords[userId], refreshTokenRecord)
+
+	return nil
+}
+
+// UpdateRefreshTokenRecord обновляет refresh-токен пользователя по старому jti.
+// Старый токен помечается как использованный и остается в хранилище для обнаружения повторного использования,
+// новый токен добавляется в то же семейство, а сообщение outbox (если задано) - в очередь уведомлений.
+// Возвращает ErrTokenNotFound, если токен не найден, или ErrTokenReused, если токен уже был использован.
+func (m *Memory) UpdateRefreshTokenRecord(ctx context.Context, oldJti, userId string, newRefreshTokenRecord *entities.RefreshTokenRecord,
+	outboxMessage *entities.OutboxMessage) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for i, record := range m.tokenRecords[userId] {
+		if oldJti != record.Jti {
+			continue
+		}
+		if record.Rotated {
+			return fmt.Errorf("refresh token with jti '%s': %w", oldJti, storage.ErrTokenReused)
+		}
+		rotatedRecord := *record
+		rotatedRecord.Rotated = true
+		m.tokenRecords[userId][i] = &rotatedRecord
+		m.tokenRecords[userId] = append(m.tokenRecords[userId], newRefreshTokenRecord)
+		if outboxMessage != nil {
+			m.enqueueOutboxMessage(outboxMessage)
+		}
+		return nil
+	}
 
+	return fmt.Errorf("refresh token with jti '%s' for userID: '%s': %w", oldJti, userId, storage.ErrTokenNotFound)
+}
+
+// GetRefreshTokenRecord возвращает record токена пользователя по jti и userId.
+// Если токен не найден, возвращает ErrTokenNotFound.
+func (m *Memory) GetRefreshTokenRecord(ctx context.Context, jti, userId string) (*entities.RefreshTokenRecord, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	m.mu.RLock()
+	defer m.mu.RUnlock()
 	for _, record := range m.tokenRecords[userId] {
-		if refreshTokenRecord.Jti == record.Jti {
-			return fmt.Errorf("hash of refresh token already exists")
+		if jti == record.Jti {
+			return record, nil
 		}
 	}
-	m.tokenRecords[userId] = append(m.tokenRecords[userId], refreshTokenRecord)
+
+	return nil, fmt.Errorf("refresh token with jti '%s' for userID: '%s': %w", jti, userId, storage.ErrTokenNotFound)
+}
+
+// GetRefreshTokenRecords возвращает активные (не использованные для обновления и не просроченные) refresh-токены пользователя.
+// Записи упорядочены по времени последнего использования, начиная с самой свежей.
+func (m *Memory) GetRefreshTokenRecords(ctx context.Context, userId string) ([]*entities.RefreshTokenRecord, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	now := time.Now()
+	refreshTokenRecords := make([]*entities.RefreshTokenRecord, 0, len(m.tokenRecords[userId]))
+	for _, record := range m.tokenRecords[userId] {
+		if !record.Rotated && record.ExpiredAt.After(now) {
+			refreshTokenRecords = append(refreshTokenRecords, record)
+		}
+	}
+	slices.SortStableFunc(refreshTokenRecords, func(a, b *entities.RefreshTokenRecord) int {
+		return b.LastUsedAt.Compare(a.LastUsedAt)
+	})
+
+	return refreshTokenRecords, nil
+}
+
+// RevokeTokenFamily удаляет все refresh-токены семейства (сессии) пользователя и возвращает удаленные записи.
+// Если токены семейства не найдены, возвращает ErrTokenNotFound.
+func (m *Memory) RevokeTokenFamily(ctx context.Context, userId, familyId string) ([]*entities.RefreshTokenRecord, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	var revoked, actual []*entities.RefreshTokenRecord
+	for _, record := range m.tokenRecords[userId] {
+		if record.FamilyId == familyId {
+			revoked = append(revoked, record)
+		} else {
+			actual = append(actual, record)
+		}
+	}
+	if len(revoked) == 0 {
+		return nil, fmt.Errorf("token family '%s': %w", familyId, storage.ErrTokenNotFound)
+	}
+	m.tokenRecords[userId] = actual
+
+	return revoked, nil
+}
+
+// RevokeAllRefreshTokens удаляет все refresh-токены пользователя и возвращает удаленные записи.
+func (m *Memory) RevokeAllRefreshTokens(ctx context.Context, userId string) ([]*entities.RefreshTokenRecord, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	revoked := m.tokenRecords[userId]
+	delete(m.tokenRecords, userId)
+
+	return revoked, nil
+}
+
+// RevokeAccessToken добавляет access-токен в список отозванных до истечения его срока действия.
+// Попутно удаляет из списка записи, срок действия которых уже истек.
+func (m *Memory) RevokeAccessToken(ctx context.Context, jti string, expiredAt time.Time) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	now := time.Now()
+	for revokedJti, revokedExpiredAt := range m.revokedAccessTokens {
+		if !revokedExpiredAt.After(now) {
+			delete(m.revokedAccessTokens, revokedJti)
+		}
+	}
+	if expiredAt.After(now) {
+		m.revokedAccessTokens[jti] = expiredAt
+	}
 
 	return nil
 }
 
-// UpdateRefreshTokenRecord обновляет refresh-токен пользователя по старому jti.
-// Удаляет старый токен и добавляет новый. Возвращает ошибку, если токен не найден.
-func (m *Memory) UpdateRefreshTokenRecord(oldJti, userId string, newRefreshTokenRecord *entities.RefreshTokenRecord) error {
+// IsAccessTokenRevoked проверяет, отозван ли access-токен с указанным jti.
+// Записи с истекшим сроком действия не учитываются.
+func (m *Memory) IsAccessTokenRevoked(ctx context.Context, jti string) (bool, error) {
+	if err := ctx.Err(); err != nil {
+		return false, err
+	}
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	expiredAt, has := m.revokedAccessTokens[jti]
+
+	return has && expiredAt.After(time.Now()), nil
+}
+
+// EnqueueOutboxMessage добавляет сообщение в очередь уведомлений.
+// Сообщение с уже существующим ключом идемпотентности игнорируется.
+func (m *Memory) EnqueueOutboxMessage(ctx context.Context, outboxMessage *entities.OutboxMessage) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
 	m.mu.Lock()
 	defer m.mu.Unlock()
-	_, has := m.tokenRecords[userId]
+	m.enqueueOutboxMessage(outboxMessage)
+
+	return nil
+}
+
+// ClaimOutboxMessages возвращает до limit ожидающих доставки сообщений, время доставки которых наступило.
+// Следующая попытка захваченных сообщений откладывается на время lease, чтобы их не захватил другой обработчик.
+func (m *Memory) ClaimOutboxMessages(ctx context.Context, now time.Time, lease time.Duration, limit int) ([]*entities.OutboxMessage, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	var due []*entities.OutboxMessage
+	for _, message := range m.outbox {
+		if message.Status == entities.OutboxStatusPending && !message.NextAttemptAt.After(now) {
+			due = append(due, message)
+		}
+	}
+	slices.SortFunc(due, func(a, b *entities.OutboxMessage) int {
+		return a.NextAttemptAt.Compare(b.NextAttemptAt)
+	})
+	if len(due) > limit {
+		due = due[:limit]
+	}
+
+	claimed := make([]*entities.OutboxMessage, 0, len(due))
+	for _, message := range due {
+		message.NextAttemptAt = now.Add(lease)
+		claimedMessage := *message
+		claimed = append(claimed, &claimedMessage)
+	}
+
+	return claimed, nil
+}
+
+// MarkOutboxMessageDelivered помечает сообщение как доставленное.
+func (m *Memory) MarkOutboxMessageDelivered(ctx context.Context, id string) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	message, has := m.outbox[id]
 	if !has {
-		return fmt.Errorf("user with userID: '%s' was not found", userId)
+		return fmt.Errorf("outbox message '%s': %w", id, storage.ErrOutboxMessageNotFound)
 	}
+	message.Status = entities.OutboxStatusDelivered
 
-	for i, record := range m.tokenRecords[userId] {
-		if oldJti == record.Jti {
-			m.tokenRecords[userId] = slices.Delete(m.tokenRecords[userId], i, i+1)
-			m.tokenRecords[userId] = append(m.tokenRecords[userId], newRefreshTokenRecord)
-			return nil
+	return nil
+}
+
+// MarkOutboxMessageFailed увеличивает счетчик попыток доставки и назначает время следующей попытки.
+func (m *Memory) MarkOutboxMessageFailed(ctx context.Context, id, lastError string, nextAttemptAt time.Time) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	message, has := m.outbox[id]
+	if !has {
+		return fmt.Errorf("outbox message '%s': %w", id, storage.ErrOutboxMessageNotFound)
+	}
+	message.Attempts++
+	message.LastError = lastError
+	message.NextAttemptAt = nextAttemptAt
+
+	return nil
+}
+
+// MarkOutboxMessageDead увеличивает счетчик попыток доставки и помечает сообщение как недоставленное.
+func (m *Memory) MarkOutboxMessageDead(ctx context.Context, id, lastError string) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	message, has := m.outbox[id]
+	if !has {
+		return fmt.Errorf("outbox message '%s': %w", id, storage.ErrOutboxMessageNotFound)
+	}
+	message.Attempts++
+	message.LastError = lastError
+	message.Status = entities.OutboxStatusDead
+
+	return nil
+}
+
+// IsKillLinkUsed проверяет, была ли уже использована ссылка "Это был не я".
+func (m *Memory) IsKillLinkUsed(ctx context.Context, id string) (bool, error) {
+	if err := ctx.Err(); err != nil {
+		return false, err
+	}
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	_, ok := m.killLinkEvents[id]
+
+	return ok, nil
+}
+
+// ConsumeKillLink сохраняет событие использования ссылки "Это был не я".
+// Возвращает false, если ссылка с таким идентификатором уже использована.
+func (m *Memory) ConsumeKillLink(ctx context.Context, event *entities.KillLinkEvent) (bool, error) {
+	if err := ctx.Err(); err != nil {
+		return false, err
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if _, ok := m.killLinkEvents[event.Id]; ok {
+		return false, nil
+	}
+	eventCopy := *event
+	m.killLinkEvents[event.Id] = &eventCopy
+
+	return true, nil
+}
+
+// CreateUser сохраняет нового пользователя.
+// Возвращает ErrUserExists, если пользователь с таким идентификатором или email (без учета регистра) уже существует.
+func (m *Memory) CreateUser(ctx context.Context, user *entities.User) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if _, ok := m.users[user.Id]; ok {
+		return fmt.Errorf("user '%s': %w", user.Id, storage.ErrUserExists)
+	}
+	if m.emailTaken(user.Email, "") {
+		return fmt.Errorf("email '%s': %w", user.Email, storage.ErrUserExists)
+	}
+	userCopy := *user
+	m.users[user.Id] = &userCopy
+
+	return nil
+}
+
+// GetUser возвращает пользователя по идентификатору.
+// Если пользователь не найден, возвращает ErrUserNotFound.
+func (m *Memory) GetUser(ctx context.Context, userId string) (*entities.User, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	user, ok := m.users[userId]
+	if !ok {
+		return nil, fmt.Errorf("user '%s': %w", userId, storage.ErrUserNotFound)
+	}
+	userCopy := *user
+
+	return &userCopy, nil
+}
+
+// GetUserByEmail возвращает пользователя по email без учета регистра.
+// Если пользователь не найден, возвращает ErrUserNotFound.
+func (m *Memory) GetUserByEmail(ctx context.Context, email string) (*entities.User, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	for _, user := range m.users {
+		if strings.EqualFold(user.Email, email) {
+			userCopy := *user
+			return &userCopy, nil
 		}
 	}
 
-	return fmt.Errorf("hash of refresh token was not found")
+	return nil, fmt.Errorf("user with email '%s': %w", email, storage.ErrUserNotFound)
 }
 
-// GetRefreshTokenRecord возвращает record токена пользователя по jti и userId.
-// Если токен не найден, возвращает ошибку.
-func (m *Memory) GetRefreshTokenRecord(jti, userId string) (*entities.RefreshTokenRecord, error) {
+// SavePasswordHash сохраняет хэш пароля пользователя, заменяя предыдущий.
+// Если пользователь не найден, возвращает ErrUserNotFound.
+func (m *Memory) SavePasswordHash(ctx context.Context, userId, passwordHash string) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if _, ok := m.users[userId]; !ok {
+		return fmt.Errorf("user '%s': %w", userId, storage.ErrUserNotFound)
+	}
+	m.passwordHashes[userId] = passwordHash
+
+	return nil
+}
+
+// GetPasswordHash возвращает хэш пароля пользователя.
+// Если пароль не задан, возвращает ErrCredentialsNotFound.
+func (m *Memory) GetPasswordHash(ctx context.Context, userId string) (string, error) {
+	if err := ctx.Err(); err != nil {
+		return "", err
+	}
 	m.mu.RLock()
 	defer m.mu.RUnlock()
-	_, has := m.tokenRecords[userId]
-	if !has {
-		return nil, fmt.Errorf("user with userID: '%s' was not found", userId)
+
+	passwordHash, ok := m.passwordHashes[userId]
+	if !ok {
+		return "", fmt.Errorf("user '%s': %w", userId, storage.ErrCredentialsNotFound)
 	}
-	for _, record := range m.tokenRecords[userId] {
-		if jti == record.Jti {
-			return record, nil
+
+	return passwordHash, nil
+}
+
+// UpdateUser обновляет email, подтверждение email, статус и язык пользователя.
+// Возвращает ErrUserNotFound, если пользователь не найден, и ErrUserExists, если email занят другим пользователем.
+func (m *Memory) UpdateUser(ctx context.Context, user *entities.User) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	actual, ok := m.users[user.Id]
+	if !ok {
+		return fmt.Errorf("user '%s': %w", user.Id, storage.ErrUserNotFound)
+	}
+	if m.emailTaken(user.Email, user.Id) {
+		return fmt.Errorf("email '%s': %w", user.Email, storage.ErrUserExists)
+	}
+	actual.Email = user.Email
+	actual.EmailVerified = user.EmailVerified
+	actual.Status = user.Status
+	actual.Locale = user.Locale
+
+	return nil
+}
+
+// DeleteUser удаляет пользователя вместе с хэшем его пароля, одноразовыми токенами и вторым фактором.
+// Если пользователь не найден, возвращает ErrUserNotFound.
+func (m *Memory) DeleteUser(ctx context.Context, userId string) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if _, ok := m.users[userId]; !ok {
+		return fmt.Errorf("user '%s': %w", userId, storage.ErrUserNotFound)
+	}
+	delete(m.users, userId)
+	delete(m.passwordHashes, userId)
+	delete(m.totpCredentials, userId)
+	delete(m.recoveryCodes, userId)
+	for tokenHash, token := range m.oneTimeTokens {
+		if token.UserId == userId {
+			delete(m.oneTimeTokens, tokenHash)
 		}
 	}
 
-	return nil, fmt.Errorf("token record was not found")
+	return nil
 }
 
-// GetUserEmail возвращает email пользователя (в данном случае моковые данные).
-// Если email не найден, возвращает ошибку.
-func (d *Memory) GetUserEmail(userId string) (string, error) {
-	mockEmail := "user@gmail.com"
+// SaveOneTimeToken сохраняет одноразовый токен пользователя.
+// Попутно удаляет токены, срок действия которых истек.
+func (m *Memory) SaveOneTimeToken(ctx context.Context, token *entities.OneTimeToken) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	now := time.Now()
+	for tokenHash, actual := range m.oneTimeTokens {
+		if !actual.ExpiredAt.After(now) {
+			delete(m.oneTimeTokens, tokenHash)
+		}
+	}
+	if _, ok := m.users[token.UserId]; !ok {
+		return fmt.Errorf("user '%s': %w", token.UserId, storage.ErrUserNotFound)
+	}
+	if _, ok := m.oneTimeTokens[token.TokenHash]; ok {
+		return fmt.Errorf("one-time token: %w", storage.ErrTokenExists)
+	}
+	tokenCopy := *token
+	m.oneTimeTokens[token.TokenHash] = &tokenCopy
 
-	return mockEmail, nil
+	return nil
+}
+
+// ConsumeOneTimeToken удаляет одноразовый токен с указанным назначением и возвращает его.
+// Если токен не найден, возвращает ErrTokenNotFound, если его срок действия истек к моменту now - ErrTokenExpired.
+func (m *Memory) ConsumeOneTimeToken(ctx context.Context, purpose, tokenHash string, now time.Time) (*entities.OneTimeToken, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	token, ok := m.oneTimeTokens[tokenHash]
+	if !ok || token.Purpose != purpose {
+		return nil, fmt.Errorf("purpose '%s': %w", purpose, storage.ErrTokenNotFound)
+	}
+	delete(m.oneTimeTokens, tokenHash)
+	if !token.ExpiredAt.After(now) {
+		return nil, fmt.Errorf("purpose '%s': %w", purpose, storage.ErrTokenExpired)
+	}
+
+	return token, nil
 }
 
-// checkActiveTokens проверяет количество активных refresh токенов для пользователя.
-// Если лимит превышен, возвращает специальную ошибку.
-func (m *Memory) checkActiveTokens(userId string, maxTokensPerUser int) error {
-	if len(m.tokenRecords[userId]) == maxTokensPerUser {
-		return fmt.Errorf("exceeding the limit for userID: '%s'", userId)
+// DeleteOneTimeTokens удаляет все одноразовые токены пользователя с указанным назначением.
+func (m *Memory) DeleteOneTimeTokens(ctx context.Context, userId, purpose string) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for tokenHash, token := range m.oneTimeTokens {
+		if token.UserId == userId && token.Purpose == purpose {
+			delete(m.oneTimeTokens, tokenHash)
+		}
 	}
 
 	return nil
 }
 
-// deleteOldestRefreshToken удаляет самый старый refresh-токен пользователя.
-// Если количество токенов не равно лимиту, возвращает ошибку.
-func (m *Memory) deleteOldestRefreshToken(userId string) error {
-	if len(m.tokenRecords[userId]) < 2 {
-		return fmt.Errorf("number of user is less than max limit")
+// SaveTotpCredential сохраняет TOTP-секрет пользователя, заменяя предыдущий.
+// Если пользователь не найден, возвращает ErrUserNotFound.
+func (m *Memory) SaveTotpCredential(ctx context.Context, credential *entities.TotpCredential) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if _, ok := m.users[credential.UserId]; !ok {
+		return fmt.Errorf("user '%s': %w", credential.UserId, storage.ErrUserNotFound)
+	}
+	credentialCopy := *credential
+	m.totpCredentials[credential.UserId] = &credentialCopy
+
+	return nil
+}
+
+// GetTotpCredential возвращает копию TOTP-секрета пользователя.
+// Если TOTP не подключен, возвращает ErrCredentialsNotFound.
+func (m *Memory) GetTotpCredential(ctx context.Context, userId string) (*entities.TotpCredential, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	credential, ok := m.totpCredentials[userId]
+	if !ok {
+		return nil, fmt.Errorf("user '%s': %w", userId, storage.ErrCredentialsNotFound)
+	}
+	credentialCopy := *credential
+
+	return &credentialCopy, nil
+}
+
+// UseTotpStep запоминает принятый временной шаг TOTP пользователя.
+// Возвращает false, если шаг не новее последнего принятого, то есть код уже был использован.
+func (m *Memory) UseTotpStep(ctx context.Context, userId string, step int64) (bool, error) {
+	if err := ctx.Err(); err != nil {
+		return false, err
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	credential, ok := m.totpCredentials[userId]
+	if !ok {
+		return false, fmt.Errorf("user '%s': %w", userId, storage.ErrCredentialsNotFound)
+	}
+	if credential.LastUsedStep >= step {
+		return false, nil
+	}
+	credential.LastUsedStep = step
+
+	return true, nil
+}
+
+// SaveRecoveryCodes заменяет хэши кодов восстановления пользователя.
+// Если пользователь не найден, возвращает ErrUserNotFound.
+func (m *Memory) SaveRecoveryCodes(ctx context.Context, userId string, codeHashes []string) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if _, ok := m.users[userId]; !ok {
+		return fmt.Errorf("user '%s': %w", userId, storage.ErrUserNotFound)
 	}
-	m.tokenRecords[userId] = m.tokenRecords[userId][1:]
+	m.recoveryCodes[userId] = slices.Clone(codeHashes)
 
 	return nil
 }
+
+// ConsumeRecoveryCode удаляет код восстановления пользователя.
+// Возвращает false, если код не найден или уже был использован.
+func (m *Memory) ConsumeRecoveryCode(ctx context.Context, userId, codeHash string) (bool, error) {
+	if err := ctx.Err(); err != nil {
+		return false, err
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	index := slices.Index(m.recoveryCodes[userId], codeHash)
+	if index == -1 {
+		return false, nil
+	}
+	m.recoveryCodes[userId] = slices.Delete(m.recoveryCodes[userId], index, index+1)
+
+	return true, nil
+}
+
+// emailTaken проверяет, использует ли email (без учета регистра) пользователь, отличный от exceptUserId.
+func (m *Memory) emailTaken(email, exceptUserId string) bool {
+	for _, user := range m.users {
+		if user.Id != exceptUserId && strings.EqualFold(user.Email, email) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// PurgeExpiredRefreshTokens удаляет refresh-токены, срок действия которых истек к now.
+// Между пачками по batchSize блокировка хранилища освобождается, чтобы не задерживать запросы пользователей.
+// Возвращает количество удаленных токенов.
+func (m *Memory) PurgeExpiredRefreshTokens(ctx context.Context, now time.Time, batchSize int) (int64, error) {
+	if batchSize <= 0 {
+		return 0, fmt.Errorf("batch size must be positive")
+	}
+
+	var purged int64
+	for {
+		if err := ctx.Err(); err != nil {
+			return purged, err
+		}
+		removed := m.purgeExpiredRefreshTokensBatch(now, batchSize)
+		purged += int64(removed)
+		if removed < batchSize {
+			return purged, nil
+		}
+	}
+}
+
+// PurgeKillLinkEvents удаляет события использования ссылок "Это был не я", срок действия которых истек до before.
+// Просроченная ссылка не принимается при проверке подписи, поэтому событие больше не нужно для защиты от повторного
+// использования. Возвращает количество удаленных событий.
+func (m *Memory) PurgeKillLinkEvents(ctx context.Context, before time.Time, batchSize int) (int64, error) {
+	if batchSize <= 0 {
+		return 0, fmt.Errorf("batch size must be positive")
+	}
+
+	var purged int64
+	for {
+		if err := ctx.Err(); err != nil {
+			return purged, err
+		}
+		removed := m.purgeKillLinkEventsBatch(before, batchSize)
+		purged += int64(removed)
+		if removed < batchSize {
+			return purged, nil
+		}
+	}
+}
+
+// purgeKillLinkEventsBatch удаляет не более batchSize просроченных событий ссылок и возвращает их количество.
+func (m *Memory) purgeKillLinkEventsBatch(before time.Time, batchSize int) int {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	removed := 0
+	for id, event := range m.killLinkEvents {
+		if removed == batchSize {
+			break
+		}
+		if event.ExpiredAt.Before(before) {
+			delete(m.killLinkEvents, id)
+			removed++
+		}
+	}
+
+	return removed
+}
+
+// PurgeOutboxMessages удаляет доставленные (delivered) и недоставленные (dead) сообщения outbox, созданные до before.
+// Ожидающие доставки сообщения не удаляются. Возвращает количество удаленных сообщений.
+func (m *Memory) PurgeOutboxMessages(ctx context.Context, before time.Time, batchSize int) (int64, error) {
+	if batchSize <= 0 {
+		return 0, fmt.Errorf("batch size must be positive")
+	}
+
+	var purged int64
+	for {
+		if err := ctx.Err(); err != nil {
+			return purged, err
+		}
+		removed := m.purgeOutboxMessagesBatch(before, batchSize)
+		purged += int64(removed)
+		if removed < batchSize {
+			return purged, nil
+		}
+	}
+}
+
+// purgeOutboxMessagesBatch удаляет не более batchSize завершенных сообщений outbox и возвращает их количество.
+func (m *Memory) purgeOutboxMessagesBatch(before time.Time, batchSize int) int {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	removed := 0
+	for id, message := range m.outbox {
+		if removed == batchSize {
+			break
+		}
+		if message.Status != entities.OutboxStatusPending && message.CreatedAt.Before(before) {
+			delete(m.outbox, id)
+			removed++
+		}
+	}
+
+	return removed
+}
+
+// purgeExpiredRefreshTokensBatch удаляет не более batchSize истекших refresh-токенов и возвращает их количество.
+func (m *Memory) purgeExpiredRefreshTokensBatch(now time.Time, batchSize int) int {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	removed := 0
+	for userId, records := range m.tokenRecords {
+		records = slices.DeleteFunc(records, func(record *entities.RefreshTokenRecord) bool {
+			if removed == batchSize || record.ExpiredAt.After(now) {
+				return false
+			}
+			removed++
+			return true
+		})
+		if len(records) == 0 {
+			delete(m.tokenRecords, userId)
+		} else {
+			m.tokenRecords[userId] = records
+		}
+		if removed == batchSize {
+			break
+		}
+	}
+
+	return removed
+}
+
+// evictOldestRefreshTokens удаляет самые старые активные refresh-токены пользователя, оставляя keep самых новых.
+// Токены хранятся в порядке добавления, поэтому самые старые активные токены находятся в начале списка.
+// Возвращает количество удаленных токенов.
+func (m *Memory) evictOldestRefreshTokens(userId string, keep int) int {
+	activeTokens := 0
+	for _, record := range m.tokenRecords[userId] {
+		if !record.Rotated {
+			activeTokens++
+		}
+	}
+
+	evict := activeTokens - keep
+	if evict <= 0 {
+		return 0
+	}
+	evicted := 0
+	m.tokenRecords[userId] = slices.DeleteFunc(m.tokenRecords[userId], func(record *entities.RefreshTokenRecord) bool {
+		if record.Rotated || evicted == evict {
+			return false
+		}
+		evicted++
+		return true
+	})
+
+	return evicted
+}
+
+// enqueueOutboxMessage добавляет копию сообщения в очередь, если сообщения с таким ключом еще нет.
+func (m *Memory) enqueueOutboxMessage(outboxMessage *entities.OutboxMessage) {
+	if _, has := m.outbox[outboxMessage.Id]; has {
+		return
+	}
+	message := *outboxMessage
+	m.outbox[message.Id] = &message
+}
@@ -1,12 +1,47 @@
 package storage
 
-import "auth_service/internal/entities"
+import (
+	"auth_service/internal/entities"
+	"context"
+	"time"
+)
 
 // StorageInterface определяет универсальный интерфейс для работы с различными хранилищами данных (in-memory и postgres).
+// Каждая операция принимает контекст запроса: отмена или истечение контекста прерывает обращение к хранилищу.
+// Ожидаемые ситуации (запись не найдена, токен уже использован и т.п.) возвращаются как sentinel-ошибки из errors.go,
+// а сбои обращения к хранилищу оборачивают ErrStorageUnavailable.
 type StorageInterface interface {
-	SaveRefreshTokenRecord(userId string, refreshTokenRecord *entities.RefreshTokenRecord) error              // Сохраняет хэш refresh-токена и claims пользователя.
-	UpdateRefreshTokenRecord(oldJti, userId string, newRefreshTokenRecord *entities.RefreshTokenRecord) error // Обновляет refresh-токен по старому jti.
-	GetRefreshTokenRecord(jti, userId string) (*entities.RefreshTokenRecord, error)                           // Возвращает record токена по jti и userId.
-	GetUserEmail(userId string) (string, error)                                                               // GetUserEmail возвращает email пользователя по его userId.
-
+	SaveRefreshTokenRecord(ctx context.Context, userId string, refreshTokenRecord *entities.RefreshTokenRecord) error                                                     // Сохраняет хэш refresh-токена и claims пользователя; возвращает ErrTokenExists, если jti занят.
+	UpdateRefreshTokenRecord(ctx context.Context, oldJti, userId string, newRefreshTokenRecord *entities.RefreshTokenRecord, outboxMessage *entities.OutboxMessage) error // Помечает старый токен как использованный, сохраняет новый токен семейства и сообщение outbox (если задано); возвращает ErrTokenNotFound или ErrTokenReused.
+	GetRefreshTokenRecord(ctx context.Context, jti, userId string) (*entities.RefreshTokenRecord, error)                                                                  // Возвращает record токена по jti и userId или ErrTokenNotFound.
+	GetRefreshTokenRecords(ctx context.Context, userId string) ([]*entities.RefreshTokenRecord, error)                                                                    // Возвращает активные refresh-токены (сессии) пользователя.
+	RevokeTokenFamily(ctx context.Context, userId, familyId string) ([]*entities.RefreshTokenRecord, error)                                                               // Удаляет все токены семейства (сессии) пользователя и возвращает удаленные записи или ErrTokenNotFound.
+	RevokeAllRefreshTokens(ctx context.Context, userId string) ([]*entities.RefreshTokenRecord, error)                                                                    // Удаляет все refresh-токены пользователя и возвращает удаленные записи.
+	RevokeAccessToken(ctx context.Context, jti string, expiredAt time.Time) error                                                                                         // Добавляет access-токен в список отозванных до истечения его срока действия.
+	IsAccessTokenRevoked(ctx context.Context, jti string) (bool, error)                                                                                                   // Проверяет, отозван ли access-токен.
+	EnqueueOutboxMessage(ctx context.Context, outboxMessage *entities.OutboxMessage) error                                                                                // Добавляет сообщение в outbox, сообщение с существующим ключом идемпотентности игнорируется.
+	ClaimOutboxMessages(ctx context.Context, now time.Time, lease time.Duration, limit int) ([]*entities.OutboxMessage, error)                                            // Захватывает ожидающие доставки сообщения, откладывая их повторный захват на время lease.
+	MarkOutboxMessageDelivered(ctx context.Context, id string) error                                                                                                      // Помечает сообщение outbox как доставленное.
+	MarkOutboxMessageFailed(ctx context.Context, id, lastError string, nextAttemptAt time.Time) error                                                                     // Фиксирует неудачную попытку доставки и время следующей попытки.
+	MarkOutboxMessageDead(ctx context.Context, id, lastError string) error                                                                                                // Фиксирует неудачную попытку доставки и помечает сообщение как недоставленное.
+	IsKillLinkUsed(ctx context.Context, id string) (bool, error)                                                                                                          // Проверяет, была ли уже использована ссылка "Это был не я".
+	ConsumeKillLink(ctx context.Context, event *entities.KillLinkEvent) (bool, error)                                                                                     // Сохраняет использование ссылки "Это был не я"; возвращает false, если ссылка уже использована.
+	CreateUser(ctx context.Context, user *entities.User) error                                                                                                            // Создает пользователя; возвращает ErrUserExists, если идентификатор или email заняты.
+	GetUser(ctx context.Context, userId string) (*entities.User, error)                                                                                                   // Возвращает пользователя по идентификатору или ErrUserNotFound.
+	UpdateUser(ctx context.Context, user *entities.User) error                                                                                                            // Обновляет email, подтверждение email, статус и язык пользователя.
+	GetUserByEmail(ctx context.Context, email string) (*entities.User, error)                                                                                             // Возвращает пользователя по email (без учета регистра) или ErrUserNotFound.
+	SavePasswordHash(ctx context.Context, userId, passwordHash string) error                                                                                              // Сохраняет хэш пароля пользователя, заменяя предыдущий.
+	GetPasswordHash(ctx context.Context, userId string) (string, error)                                                                                                   // Возвращает хэш пароля пользователя или ErrCredentialsNotFound.
+	DeleteUser(ctx context.Context, userId string) error                                                                                                                  // Удаляет пользователя; возвращает ErrUserNotFound, если пользователь не найден.
+	SaveOneTimeToken(ctx context.Context, token *entities.OneTimeToken) error                                                                                             // Сохраняет одноразовый токен пользователя.
+	ConsumeOneTimeToken(ctx context.Context, purpose, tokenHash string, now time.Time) (*entities.OneTimeToken, error)                                                    // Удаляет и возвращает действующий одноразовый токен или ErrTokenNotFound (ErrTokenExpired, если срок действия истек).
+	DeleteOneTimeTokens(ctx context.Context, userId, purpose string) error                                                                                                // Удаляет все одноразовые токены пользователя с указанным назначением.
+	SaveTotpCredential(ctx context.Context, credential *entities.TotpCredential) error                                                                                    // Сохраняет TOTP-секрет пользователя, заменяя предыдущий.
+	GetTotpCredential(ctx context.Context, userId string) (*entities.TotpCredential, error)                                                                               // Возвращает TOTP-секрет пользователя или ErrCredentialsNotFound.
+	UseTotpStep(ctx context.Context, userId string, step int64) (bool, error)                                                                                             // Запоминает принятый временной шаг TOTP, возвращает false, если шаг не новее последнего принятого.
+	SaveRecoveryCodes(ctx context.Context, userId string, codeHashes []string) error                                                                                      // Заменяет хэши кодов восстановления пользователя.
+	ConsumeRecoveryCode(ctx context.Context, userId, codeHash string) (bool, error)                                                                                       // Удаляет код восстановления, возвращает false, если код не найден.
+	PurgeExpiredRefreshTokens(ctx context.Context, now time.Time, batchSize int) (int64, error)                                                                           // Удаляет пачками по batchSize refresh-токены, истекшие к now; возвращает их количество или ErrPurgeInProgress.
+	PurgeOutboxMessages(ctx context.Context, before time.Time, batchSize int) (int64, error)                                                                              // Удаляет пачками по batchSize доставленные и недоставленные сообщения outbox, созданные до before.
+	PurgeKillLinkEvents(ctx context.Context, before time.Time, batchSize int) (int64, error)                                                                              // Удаляет пачками по batchSize события использования ссылок "Это был не я", срок действия которых истек до before.
 }
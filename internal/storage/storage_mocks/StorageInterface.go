@@ -2,34 +2,129 @@
 
 package storage_mocks
 
-import mock "github.com/stretchr/testify/mock"
+import (
+	entities "auth_service/internal/entities"
+	context "context"
+
+	mock "github.com/stretchr/testify/mock"
+
+	time "time"
+)
 
 // StorageInterface is an autogenerated mock type for the StorageInterface type
 type StorageInterface struct {
 	mock.Mock
 }
 
-// GetRefreshTokenHash provides a mock function with given fields: userId
-func (_m *StorageInterface) GetRefreshTokenHash(userId string) (string, error) {
-	ret := _m.Called(userId)
+// ClaimOutboxMessages provides a mock function with given fields: ctx, now, lease, limit
+func (_m *StorageInterface) ClaimOutboxMessages(ctx context.Context, now time.Time, lease time.Duration, limit int) ([]*entities.OutboxMessage, error) {
+	ret := _m.Called(ctx, now, lease, limit)
 
 	if len(ret) == 0 {
-		panic("no return value specified for GetRefreshTokenHash")
+		panic("no return value specified for ClaimOutboxMessages")
 	}
 
-	var r0 string
+	var r0 []*entities.OutboxMessage
 	var r1 error
-	if rf, ok := ret.Get(0).(func(string) (string, error)); ok {
-		return rf(userId)
+	if rf, ok := ret.Get(0).(func(context.Context, time.Time, time.Duration, int) ([]*entities.OutboxMessage, error)); ok {
+		return rf(ctx, now, lease, limit)
 	}
-	if rf, ok := ret.Get(0).(func(string) string); ok {
-		r0 = rf(userId)
+	if rf, ok := ret.Get(0).(func(context.Context, time.Time, time.Duration, int) []*entities.OutboxMessage); ok {
+		r0 = rf(ctx, now, lease, limit)
 	} else {
-		r0 = ret.Get(0).(string)
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]*entities.OutboxMessage)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, time.Time, time.Duration, int) error); ok {
+		r1 = rf(ctx, now, lease, limit)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// ConsumeKillLink provides a mock function with given fields: ctx, event
+func (_m *StorageInterface) ConsumeKillLink(ctx context.Context, event *entities.KillLinkEvent) (bool, error) {
+	ret := _m.Called(ctx, event)
+
+	if len(ret) == 0 {
+		panic("no return value specified for ConsumeKillLink")
+	}
+
+	var r0 bool
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, *entities.KillLinkEvent) (bool, error)); ok {
+		return rf(ctx, event)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, *entities.KillLinkEvent) bool); ok {
+		r0 = rf(ctx, event)
+	} else {
+		r0 = ret.Get(0).(bool)
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, *entities.KillLinkEvent) error); ok {
+		r1 = rf(ctx, event)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// ConsumeOneTimeToken provides a mock function with given fields: ctx, purpose, tokenHash, now
+func (_m *StorageInterface) ConsumeOneTimeToken(ctx context.Context, purpose string, tokenHash string, now time.Time) (*entities.OneTimeToken, error) {
+	ret := _m.Called(ctx, purpose, tokenHash, now)
+
+	if len(ret) == 0 {
+		panic("no return value specified for ConsumeOneTimeToken")
+	}
+
+	var r0 *entities.OneTimeToken
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, string, string, time.Time) (*entities.OneTimeToken, error)); ok {
+		return rf(ctx, purpose, tokenHash, now)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, string, string, time.Time) *entities.OneTimeToken); ok {
+		r0 = rf(ctx, purpose, tokenHash, now)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*entities.OneTimeToken)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, string, string, time.Time) error); ok {
+		r1 = rf(ctx, purpose, tokenHash, now)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// ConsumeRecoveryCode provides a mock function with given fields: ctx, userId, codeHash
+func (_m *StorageInterface) ConsumeRecoveryCode(ctx context.Context, userId string, codeHash string) (bool, error) {
+	ret := _m.Called(ctx, userId, codeHash)
+
+	if len(ret) == 0 {
+		panic("no return value specified for ConsumeRecoveryCode")
+	}
+
+	var r0 bool
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, string, string) (bool, error)); ok {
+		return rf(ctx, userId, codeHash)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, string, string) bool); ok {
+		r0 = rf(ctx, userId, codeHash)
+	} else {
+		r0 = ret.Get(0).(bool)
 	}
 
-	if rf, ok := ret.Get(1).(func(string) error); ok {
-		r1 = rf(userId)
+	if rf, ok := ret.Get(1).(func(context.Context, string, string) error); ok {
+		r1 = rf(ctx, userId, codeHash)
 	} else {
 		r1 = ret.Error(1)
 	}
@@ -37,27 +132,521 @@ func (_m *StorageInterface) GetRefreshTokenHash(userId string) (string, error) {
 	return r0, r1
 }
 
-// GetUserEmail provides a mock function with given fields: userId
-func (_m *StorageInterface) GetUserEmail(userId string) (string, error) {
-	ret := _m.Called(userId)
+// CreateUser provides a mock function with given fields: ctx, user
+func (_m *StorageInterface) CreateUser(ctx context.Context, user *entities.User) error {
+	ret := _m.Called(ctx, user)
 
 	if len(ret) == 0 {
-		panic("no return value specified for GetUserEmail")
+		panic("no return value specified for CreateUser")
+	}
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, *entities.User) error); ok {
+		r0 = rf(ctx, user)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// DeleteOneTimeTokens provides a mock function with given fields: ctx, userId, purpose
+func (_m *StorageInterface) DeleteOneTimeTokens(ctx context.Context, userId string, purpose string) error {
+	ret := _m.Called(ctx, userId, purpose)
+
+	if len(ret) == 0 {
+		panic("no return value specified for DeleteOneTimeTokens")
+	}
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, string, string) error); ok {
+		r0 = rf(ctx, userId, purpose)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// DeleteUser provides a mock function with given fields: ctx, userId
+func (_m *StorageInterface) DeleteUser(ctx context.Context, userId string) error {
+	ret := _m.Called(ctx, userId)
+
+	if len(ret) == 0 {
+		panic("no return value specified for DeleteUser")
+	}
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, string) error); ok {
+		r0 = rf(ctx, userId)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// EnqueueOutboxMessage provides a mock function with given fields: ctx, outboxMessage
+func (_m *StorageInterface) EnqueueOutboxMessage(ctx context.Context, outboxMessage *entities.OutboxMessage) error {
+	ret := _m.Called(ctx, outboxMessage)
+
+	if len(ret) == 0 {
+		panic("no return value specified for EnqueueOutboxMessage")
+	}
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, *entities.OutboxMessage) error); ok {
+		r0 = rf(ctx, outboxMessage)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// GetPasswordHash provides a mock function with given fields: ctx, userId
+func (_m *StorageInterface) GetPasswordHash(ctx context.Context, userId string) (string, error) {
+	ret := _m.Called(ctx, userId)
+
+	if len(ret) == 0 {
+		panic("no return value specified for GetPasswordHash")
 	}
 
 	var r0 string
 	var r1 error
-	if rf, ok := ret.Get(0).(func(string) (string, error)); ok {
-		return rf(userId)
+	if rf, ok := ret.Get(0).(func(context.Context, string) (string, error)); ok {
+		return rf(ctx, userId)
 	}
-	if rf, ok := ret.Get(0).(func(string) string); ok {
-		r0 = rf(userId)
+	if rf, ok := ret.Get(0).(func(context.Context, string) string); ok {
+		r0 = rf(ctx, userId)
 	} else {
 		r0 = ret.Get(0).(string)
 	}
 
-	if rf, ok := ret.Get(1).(func(string) error); ok {
-		r1 = rf(userId)
+	if rf, ok := ret.Get(1).(func(context.Context, string) error); ok {
+		r1 = rf(ctx, userId)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// GetRefreshTokenRecord provides a mock function with given fields: ctx, jti, userId
+func (_m *StorageInterface) GetRefreshTokenRecord(ctx context.Context, jti string, userId string) (*entities.RefreshTokenRecord, error) {
+	ret := _m.Called(ctx, jti, userId)
+
+	if len(ret) == 0 {
+		panic("no return value specified for GetRefreshTokenRecord")
+	}
+
+	var r0 *entities.RefreshTokenRecord
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, string, string) (*entities.RefreshTokenRecord, error)); ok {
+		return rf(ctx, jti, userId)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, string, string) *entities.RefreshTokenRecord); ok {
+		r0 = rf(ctx, jti, userId)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*entities.RefreshTokenRecord)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, string, string) error); ok {
+		r1 = rf(ctx, jti, userId)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// GetRefreshTokenRecords provides a mock function with given fields: ctx, userId
+func (_m *StorageInterface) GetRefreshTokenRecords(ctx context.Context, userId string) ([]*entities.RefreshTokenRecord, error) {
+	ret := _m.Called(ctx, userId)
+
+	if len(ret) == 0 {
+		panic("no return value specified for GetRefreshTokenRecords")
+	}
+
+	var r0 []*entities.RefreshTokenRecord
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, string) ([]*entities.RefreshTokenRecord, error)); ok {
+		return rf(ctx, userId)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, string) []*entities.RefreshTokenRecord); ok {
+		r0 = rf(ctx, userId)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]*entities.RefreshTokenRecord)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, string) error); ok {
+		r1 = rf(ctx, userId)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// GetTotpCredential provides a mock function with given fields: ctx, userId
+func (_m *StorageInterface) GetTotpCredential(ctx context.Context, userId string) (*entities.TotpCredential, error) {
+	ret := _m.Called(ctx, userId)
+
+	if len(ret) == 0 {
+		panic("no return value specified for GetTotpCredential")
+	}
+
+	var r0 *entities.TotpCredential
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, string) (*entities.TotpCredential, error)); ok {
+		return rf(ctx, userId)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, string) *entities.TotpCredential); ok {
+		r0 = rf(ctx, userId)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*entities.TotpCredential)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, string) error); ok {
+		r1 = rf(ctx, userId)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// GetUser provides a mock function with given fields: ctx, userId
+func (_m *StorageInterface) GetUser(ctx context.Context, userId string) (*entities.User, error) {
+	ret := _m.Called(ctx, userId)
+
+	if len(ret) == 0 {
+		panic("no return value specified for GetUser")
+	}
+
+	var r0 *entities.User
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, string) (*entities.User, error)); ok {
+		return rf(ctx, userId)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, string) *entities.User); ok {
+		r0 = rf(ctx, userId)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*entities.User)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, string) error); ok {
+		r1 = rf(ctx, userId)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// GetUserByEmail provides a mock function with given fields: ctx, email
+func (_m *StorageInterface) GetUserByEmail(ctx context.Context, email string) (*entities.User, error) {
+	ret := _m.Called(ctx, email)
+
+	if len(ret) == 0 {
+		panic("no return value specified for GetUserByEmail")
+	}
+
+	var r0 *entities.User
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, string) (*entities.User, error)); ok {
+		return rf(ctx, email)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, string) *entities.User); ok {
+		r0 = rf(ctx, email)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*entities.User)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, string) error); ok {
+		r1 = rf(ctx, email)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// IsAccessTokenRevoked provides a mock function with given fields: ctx, jti
+func (_m *StorageInterface) IsAccessTokenRevoked(ctx context.Context, jti string) (bool, error) {
+	ret := _m.Called(ctx, jti)
+
+	if len(ret) == 0 {
+		panic("no return value specified for IsAccessTokenRevoked")
+	}
+
+	var r0 bool
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, string) (bool, error)); ok {
+		return rf(ctx, jti)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, string) bool); ok {
+		r0 = rf(ctx, jti)
+	} else {
+		r0 = ret.Get(0).(bool)
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, string) error); ok {
+		r1 = rf(ctx, jti)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// IsKillLinkUsed provides a mock function with given fields: ctx, id
+func (_m *StorageInterface) IsKillLinkUsed(ctx context.Context, id string) (bool, error) {
+	ret := _m.Called(ctx, id)
+
+	if len(ret) == 0 {
+		panic("no return value specified for IsKillLinkUsed")
+	}
+
+	var r0 bool
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, string) (bool, error)); ok {
+		return rf(ctx, id)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, string) bool); ok {
+		r0 = rf(ctx, id)
+	} else {
+		r0 = ret.Get(0).(bool)
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, string) error); ok {
+		r1 = rf(ctx, id)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// MarkOutboxMessageDead provides a mock function with given fields: ctx, id, lastError
+func (_m *StorageInterface) MarkOutboxMessageDead(ctx context.Context, id string, lastError string) error {
+	ret := _m.Called(ctx, id, lastError)
+
+	if len(ret) == 0 {
+		panic("no return value specified for MarkOutboxMessageDead")
+	}
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, string, string) error); ok {
+		r0 = rf(ctx, id, lastError)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// MarkOutboxMessageDelivered provides a mock function with given fields: ctx, id
+func (_m *StorageInterface) MarkOutboxMessageDelivered(ctx context.Context, id string) error {
+	ret := _m.Called(ctx, id)
+
+	if len(ret) == 0 {
+		panic("no return value specified for MarkOutboxMessageDelivered")
+	}
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, string) error); ok {
+		r0 = rf(ctx, id)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// MarkOutboxMessageFailed provides a mock function with given fields: ctx, id, lastError, nextAttemptAt
+func (_m *StorageInterface) MarkOutboxMessageFailed(ctx context.Context, id string, lastError string, nextAttemptAt time.Time) error {
+	ret := _m.Called(ctx, id, lastError, nextAttemptAt)
+
+	if len(ret) == 0 {
+		panic("no return value specified for MarkOutboxMessageFailed")
+	}
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, string, string, time.Time) error); ok {
+		r0 = rf(ctx, id, lastError, nextAttemptAt)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// PurgeExpiredRefreshTokens provides a mock function with given fields: ctx, now, batchSize
+func (_m *StorageInterface) PurgeExpiredRefreshTokens(ctx context.Context, now time.Time, batchSize int) (int64, error) {
+	ret := _m.Called(ctx, now, batchSize)
+
+	if len(ret) == 0 {
+		panic("no return value specified for PurgeExpiredRefreshTokens")
+	}
+
+	var r0 int64
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, time.Time, int) (int64, error)); ok {
+		return rf(ctx, now, batchSize)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, time.Time, int) int64); ok {
+		r0 = rf(ctx, now, batchSize)
+	} else {
+		r0 = ret.Get(0).(int64)
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, time.Time, int) error); ok {
+		r1 = rf(ctx, now, batchSize)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// PurgeKillLinkEvents provides a mock function with given fields: ctx, before, batchSize
+func (_m *StorageInterface) PurgeKillLinkEvents(ctx context.Context, before time.Time, batchSize int) (int64, error) {
+	ret := _m.Called(ctx, before, batchSize)
+
+	if len(ret) == 0 {
+		panic("no return value specified for PurgeKillLinkEvents")
+	}
+
+	var r0 int64
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, time.Time, int) (int64, error)); ok {
+		return rf(ctx, before, batchSize)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, time.Time, int) int64); ok {
+		r0 = rf(ctx, before, batchSize)
+	} else {
+		r0 = ret.Get(0).(int64)
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, time.Time, int) error); ok {
+		r1 = rf(ctx, before, batchSize)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// PurgeOutboxMessages provides a mock function with given fields: ctx, before, batchSize
+func (_m *StorageInterface) PurgeOutboxMessages(ctx context.Context, before time.Time, batchSize int) (int64, error) {
+	ret := _m.Called(ctx, before, batchSize)
+
+	if len(ret) == 0 {
+		panic("no return value specified for PurgeOutboxMessages")
+	}
+
+	var r0 int64
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, time.Time, int) (int64, error)); ok {
+		return rf(ctx, before, batchSize)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, time.Time, int) int64); ok {
+		r0 = rf(ctx, before, batchSize)
+	} else {
+		r0 = ret.Get(0).(int64)
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, time.Time, int) error); ok {
+		r1 = rf(ctx, before, batchSize)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// RevokeAccessToken provides a mock function with given fields: ctx, jti, expiredAt
+func (_m *StorageInterface) RevokeAccessToken(ctx context.Context, jti string, expiredAt time.Time) error {
+	ret := _m.Called(ctx, jti, expiredAt)
+
+	if len(ret) == 0 {
+		panic("no return value specified for RevokeAccessToken")
+	}
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, string, time.Time) error); ok {
+		r0 = rf(ctx, jti, expiredAt)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// RevokeAllRefreshTokens provides a mock function with given fields: ctx, userId
+func (_m *StorageInterface) RevokeAllRefreshTokens(ctx context.Context, userId string) ([]*entities.RefreshTokenRecord, error) {
+	ret := _m.Called(ctx, userId)
+
+	if len(ret) == 0 {
+		panic("no return value specified for RevokeAllRefreshTokens")
+	}
+
+	var r0 []*entities.RefreshTokenRecord
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, string) ([]*entities.RefreshTokenRecord, error)); ok {
+		return rf(ctx, userId)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, string) []*entities.RefreshTokenRecord); ok {
+		r0 = rf(ctx, userId)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]*entities.RefreshTokenRecord)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, string) error); ok {
+		r1 = rf(ctx, userId)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// RevokeTokenFamily provides a mock function with given fields: ctx, userId, familyId
+func (_m *StorageInterface) RevokeTokenFamily(ctx context.Context, userId string, familyId string) ([]*entities.RefreshTokenRecord, error) {
+	ret := _m.Called(ctx, userId, familyId)
+
+	if len(ret) == 0 {
+		panic("no return value specified for RevokeTokenFamily")
+	}
+
+	var r0 []*entities.RefreshTokenRecord
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, string, string) ([]*entities.RefreshTokenRecord, error)); ok {
+		return rf(ctx, userId, familyId)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, string, string) []*entities.RefreshTokenRecord); ok {
+		r0 = rf(ctx, userId, familyId)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]*entities.RefreshTokenRecord)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, string, string) error); ok {
+		r1 = rf(ctx, userId, familyId)
 	} else {
 		r1 = ret.Error(1)
 	}
@@ -65,17 +654,53 @@ func (_m *StorageInterface) GetUserEmail(userId string) (string, error) {
 	return r0, r1
 }
 
-// SaveRefreshTokenHash provides a mock function with given fields: userId, refrTokenHash
-func (_m *StorageInterface) SaveRefreshTokenHash(userId string, refrTokenHash string) error {
-	ret := _m.Called(userId, refrTokenHash)
+// SaveOneTimeToken provides a mock function with given fields: ctx, token
+func (_m *StorageInterface) SaveOneTimeToken(ctx context.Context, token *entities.OneTimeToken) error {
+	ret := _m.Called(ctx, token)
+
+	if len(ret) == 0 {
+		panic("no return value specified for SaveOneTimeToken")
+	}
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, *entities.OneTimeToken) error); ok {
+		r0 = rf(ctx, token)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// SavePasswordHash provides a mock function with given fields: ctx, userId, passwordHash
+func (_m *StorageInterface) SavePasswordHash(ctx context.Context, userId string, passwordHash string) error {
+	ret := _m.Called(ctx, userId, passwordHash)
+
+	if len(ret) == 0 {
+		panic("no return value specified for SavePasswordHash")
+	}
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, string, string) error); ok {
+		r0 = rf(ctx, userId, passwordHash)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// SaveRecoveryCodes provides a mock function with given fields: ctx, userId, codeHashes
+func (_m *StorageInterface) SaveRecoveryCodes(ctx context.Context, userId string, codeHashes []string) error {
+	ret := _m.Called(ctx, userId, codeHashes)
 
 	if len(ret) == 0 {
-		panic("no return value specified for SaveRefreshTokenHash")
+		panic("no return value specified for SaveRecoveryCodes")
 	}
 
 	var r0 error
-	if rf, ok := ret.Get(0).(func(string, string) error); ok {
-		r0 = rf(userId, refrTokenHash)
+	if rf, ok := ret.Get(0).(func(context.Context, string, []string) error); ok {
+		r0 = rf(ctx, userId, codeHashes)
 	} else {
 		r0 = ret.Error(0)
 	}
@@ -83,17 +708,17 @@ func (_m *StorageInterface) SaveRefreshTokenHash(userId string, refrTokenHash st
 	return r0
 }
 
-// UpdateRefreshTokenHash provides a mock function with given fields: userId, newRefrTokenHash
-func (_m *StorageInterface) UpdateRefreshTokenHash(userId string, newRefrTokenHash string) error {
-	ret := _m.Called(userId, newRefrTokenHash)
+// SaveRefreshTokenRecord provides a mock function with given fields: ctx, userId, refreshTokenRecord
+func (_m *StorageInterface) SaveRefreshTokenRecord(ctx context.Context, userId string, refreshTokenRecord *entities.RefreshTokenRecord) error {
+	ret := _m.Called(ctx, userId, refreshTokenRecord)
 
 	if len(ret) == 0 {
-		panic("no return value specified for UpdateRefreshTokenHash")
+		panic("no return value specified for SaveRefreshTokenRecord")
 	}
 
 	var r0 error
-	if rf, ok := ret.Get(0).(func(string, string) error); ok {
-		r0 = rf(userId, newRefrTokenHash)
+	if rf, ok := ret.Get(0).(func(context.Context, string, *entities.RefreshTokenRecord) error); ok {
+		r0 = rf(ctx, userId, refreshTokenRecord)
 	} else {
 		r0 = ret.Error(0)
 	}
@@ -101,6 +726,88 @@ func (_m *StorageInterface) UpdateRefreshTokenHash(userId string, newRefrTokenHa
 	return r0
 }
 
+// SaveTotpCredential provides a mock function with given fields: ctx, credential
+func (_m *StorageInterface) SaveTotpCredential(ctx context.Context, credential *entities.TotpCredential) error {
+	ret := _m.Called(ctx, credential)
+
+	if len(ret) == 0 {
+		panic("no return value specified for SaveTotpCredential")
+	}
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, *entities.TotpCredential) error); ok {
+		r0 = rf(ctx, credential)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// UpdateRefreshTokenRecord provides a mock function with given fields: ctx, oldJti, userId, newRefreshTokenRecord, outboxMessage
+func (_m *StorageInterface) UpdateRefreshTokenRecord(ctx context.Context, oldJti string, userId string, newRefreshTokenRecord *entities.RefreshTokenRecord, outboxMessage *entities.OutboxMessage) error {
+	ret := _m.Called(ctx, oldJti, userId, newRefreshTokenRecord, outboxMessage)
+
+	if len(ret) == 0 {
+		panic("no return value specified for UpdateRefreshTokenRecord")
+	}
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, string, string, *entities.RefreshTokenRecord, *entities.OutboxMessage) error); ok {
+		r0 = rf(ctx, oldJti, userId, newRefreshTokenRecord, outboxMessage)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// UpdateUser provides a mock function with given fields: ctx, user
+func (_m *StorageInterface) UpdateUser(ctx context.Context, user *entities.User) error {
+	ret := _m.Called(ctx, user)
+
+	if len(ret) == 0 {
+		panic("no return value specified for UpdateUser")
+	}
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, *entities.User) error); ok {
+		r0 = rf(ctx, user)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// UseTotpStep provides a mock function with given fields: ctx, userId, step
+func (_m *StorageInterface) UseTotpStep(ctx context.Context, userId string, step int64) (bool, error) {
+	ret := _m.Called(ctx, userId, step)
+
+	if len(ret) == 0 {
+		panic("no return value specified for UseTotpStep")
+	}
+
+	var r0 bool
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, string, int64) (bool, error)); ok {
+		return rf(ctx, userId, step)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, string, int64) bool); ok {
+		r0 = rf(ctx, userId, step)
+	} else {
+		r0 = ret.Get(0).(bool)
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, string, int64) error); ok {
+		r1 = rf(ctx, userId, step)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
 // NewStorageInterface creates a new instance of StorageInterface. It also registers a testing interface on the mock and a cleanup function to assert the mocks expectations.
 // The first argument is typically a *testing.T value.
 func NewStorageInterface(t interface {